@@ -16,6 +16,9 @@ import (
 	"postgresus-backend/internal/downdetect"
 	"postgresus-backend/internal/features/backups/backups"
 	backups_config "postgresus-backend/internal/features/backups/config"
+	backups_encryption "postgresus-backend/internal/features/backups/encryption"
+	backups_verification "postgresus-backend/internal/features/backups/verification"
+	backups_wal "postgresus-backend/internal/features/backups/wal"
 	"postgresus-backend/internal/features/databases"
 	"postgresus-backend/internal/features/disk"
 	healthcheck_attempt "postgresus-backend/internal/features/healthcheck/attempt"
@@ -24,6 +27,7 @@ import (
 	"postgresus-backend/internal/features/restores"
 	"postgresus-backend/internal/features/storages"
 	system_healthcheck "postgresus-backend/internal/features/system/healthcheck"
+	system_instances "postgresus-backend/internal/features/system/instances"
 	"postgresus-backend/internal/features/users"
 	env_utils "postgresus-backend/internal/util/env"
 	files_utils "postgresus-backend/internal/util/files"
@@ -54,6 +58,7 @@ func main() {
 	err := files_utils.EnsureDirectories([]string{
 		config.GetEnv().TempFolder,
 		config.GetEnv().DataFolder,
+		config.GetEnv().RestoreFolder,
 	})
 
 	if err != nil {
@@ -68,6 +73,21 @@ func main() {
 		resetPassword(*newPassword, log)
 	}
 
+	config.StartListeningForShutdownSignal()
+
+	setUpDependencies()
+	registerInstance(log)
+
+	if config.GetEnv().IsBackgroundEnabled() {
+		runBackgroundTasks(log)
+	}
+
+	if !config.GetEnv().IsWebEnabled() {
+		waitForShutdownSignal(log)
+		unregisterInstance(log)
+		return
+	}
+
 	go generateSwaggerDocs(log)
 
 	gin.SetMode(gin.ReleaseMode)
@@ -84,11 +104,10 @@ func main() {
 
 	enableCors(ginApp)
 	setUpRoutes(ginApp)
-	setUpDependencies()
-	runBackgroundTasks(log)
 	mountFrontend(ginApp)
 
 	startServerWithGracefulShutdown(log, ginApp)
+	unregisterInstance(log)
 }
 
 func resetPassword(newPassword string, log *slog.Logger) {
@@ -140,6 +159,51 @@ func startServerWithGracefulShutdown(log *slog.Logger, app *gin.Engine) {
 	log.Info("Server gracefully stopped")
 }
 
+// waitForShutdownSignal blocks background instance which has no server
+func waitForShutdownSignal(log *slog.Logger) {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	log.Info("Shutdown signal received")
+}
+
+// registerInstance saves the first heartbeat before jobs are started,
+// so other instances do not treat jobs of this one as orphaned
+func registerInstance(log *slog.Logger) {
+	instanceService := system_instances.GetInstanceService()
+
+	if err := instanceService.Register(); err != nil {
+		log.Error("Failed to register instance", "error", err)
+		os.Exit(1)
+	}
+
+	log.Info(
+		"Instance registered",
+		"instanceId",
+		instanceService.GetInstanceID(),
+		"isWeb",
+		config.GetEnv().IsWebEnabled(),
+		"isBackground",
+		config.GetEnv().IsBackgroundEnabled(),
+	)
+
+	go runWithPanicLogging(log, "instance heartbeat background service", func() {
+		system_instances.GetInstanceHeartbeatBackgroundService().Run()
+	})
+}
+
+func unregisterInstance(log *slog.Logger) {
+	// leases are released with the instance, so WAL received by this
+	// instance is uploaded before the next holder takes over
+	if config.GetEnv().IsBackgroundEnabled() {
+		backups_wal.GetWalArchivingBackgroundService().WaitForStop(1 * time.Minute)
+	}
+
+	if err := system_instances.GetInstanceService().Unregister(); err != nil {
+		log.Error("Failed to unregister instance", "error", err)
+	}
+}
+
 func setUpRoutes(r *gin.Engine) {
 	v1 := r.Group("/api/v1")
 
@@ -158,6 +222,8 @@ func setUpRoutes(r *gin.Engine) {
 	healthcheckAttemptController := healthcheck_attempt.GetHealthcheckAttemptController()
 	diskController := disk.GetDiskController()
 	backupConfigController := backups_config.GetBackupConfigController()
+	backupEncryptionController := backups_encryption.GetBackupEncryptionController()
+	walArchivingController := backups_wal.GetWalArchivingController()
 
 	downdetectContoller.RegisterRoutes(v1)
 	userController.RegisterRoutes(v1)
@@ -171,10 +237,14 @@ func setUpRoutes(r *gin.Engine) {
 	healthcheckConfigController.RegisterRoutes(v1)
 	healthcheckAttemptController.RegisterRoutes(v1)
 	backupConfigController.RegisterRoutes(v1)
+	backupEncryptionController.RegisterRoutes(v1)
+	walArchivingController.RegisterRoutes(v1)
 }
 
 func setUpDependencies() {
 	backups.SetupDependencies()
+	backups_wal.SetupDependencies()
+	backups_verification.SetupDependencies()
 	restores.SetupDependencies()
 	healthcheck_config.SetupDependencies()
 }
@@ -191,10 +261,26 @@ func runBackgroundTasks(log *slog.Logger) {
 		backups.GetBackupBackgroundService().Run()
 	})
 
+	go runWithPanicLogging(log, "backup queue background service", func() {
+		backups.GetBackupQueueBackgroundService().Run()
+	})
+
+	go runWithPanicLogging(log, "backup storage migration background service", func() {
+		backups.GetBackupStorageMigrationBackgroundService().Run()
+	})
+
+	go runWithPanicLogging(log, "WAL archiving background service", func() {
+		backups_wal.GetWalArchivingBackgroundService().Run()
+	})
+
 	go runWithPanicLogging(log, "restore background service", func() {
 		restores.GetRestoreBackgroundService().Run()
 	})
 
+	go runWithPanicLogging(log, "backup verification background service", func() {
+		backups_verification.GetBackupVerificationBackgroundService().Run()
+	})
+
 	go runWithPanicLogging(log, "healthcheck attempt background service", func() {
 		healthcheck_attempt.GetHealthcheckAttemptBackgroundService().Run()
 	})
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"encoding/hex"
 	"os"
 	"path/filepath"
+	encryption_utils "postgresus-backend/internal/util/encryption"
 	env_utils "postgresus-backend/internal/util/env"
 	"postgresus-backend/internal/util/logger"
 	"postgresus-backend/internal/util/tools"
@@ -26,8 +28,28 @@ type EnvVariables struct {
 	EnvMode              env_utils.EnvMode `env:"ENV_MODE"             required:"true"`
 	PostgresesInstallDir string            `env:"POSTGRES_INSTALL_DIR"`
 
+	// AppMode splits instances into web and background (worker) ones,
+	// empty mode runs both in one process
+	AppMode string `env:"APP_MODE"`
+
+	// EncryptionMasterKey is the hex master key shared by all instances.
+	// Single instance generates the key file when it is empty
+	EncryptionMasterKey string `env:"ENCRYPTION_MASTER_KEY"`
+
 	DataFolder string
 	TempFolder string
+	// WAL segments received by pg_receivewal before upload to storage
+	WalFolder string
+	// physical backups are restored only into directories below this one
+	RestoreFolder string
+	// master key that wraps backup encryption keys stored in the DB
+	EncryptionKeyPath string
+
+	// Limits of backups running at once, the rest wait in the queue.
+	// Zero host or storage limit means no limit
+	BackupMaxConcurrency           int `env:"BACKUP_MAX_CONCURRENCY"             env-default:"4"`
+	BackupMaxConcurrencyPerHost    int `env:"BACKUP_MAX_CONCURRENCY_PER_HOST"    env-default:"2"`
+	BackupMaxConcurrencyPerStorage int `env:"BACKUP_MAX_CONCURRENCY_PER_STORAGE" env-default:"0"`
 
 	TestGoogleDriveClientID     string `env:"TEST_GOOGLE_DRIVE_CLIENT_ID"`
 	TestGoogleDriveClientSecret string `env:"TEST_GOOGLE_DRIVE_CLIENT_SECRET"`
@@ -56,6 +78,17 @@ func GetEnv() EnvVariables {
 	return env
 }
 
+// IsWebEnabled tells whether the instance serves API and UI
+func (e EnvVariables) IsWebEnabled() bool {
+	return e.AppMode == "" || e.AppMode == AppModeWeb
+}
+
+// IsBackgroundEnabled tells whether the instance runs scheduled
+// and queued jobs
+func (e EnvVariables) IsBackgroundEnabled() bool {
+	return e.AppMode == "" || e.AppMode == AppModeBackground
+}
+
 func loadEnvVariables() {
 	// Get current working directory
 	cwd, err := os.Getwd()
@@ -126,6 +159,32 @@ func loadEnvVariables() {
 	}
 	log.Info("ENV_MODE loaded", "mode", env.EnvMode)
 
+	if env.AppMode != "" && env.AppMode != AppModeWeb && env.AppMode != AppModeBackground {
+		log.Error("APP_MODE is invalid", "mode", env.AppMode)
+		os.Exit(1)
+	}
+
+	// each instance would generate its own key file, so backups
+	// encrypted by one instance could not be restored by another
+	if env.AppMode != "" && env.EncryptionMasterKey == "" {
+		log.Error("ENCRYPTION_MASTER_KEY is required when APP_MODE is set")
+		os.Exit(1)
+	}
+	if masterKey, err := hex.DecodeString(env.EncryptionMasterKey); err != nil ||
+		(env.EncryptionMasterKey != "" && len(masterKey) != encryption_utils.KeySize) {
+		log.Error("ENCRYPTION_MASTER_KEY should be 64 hex characters")
+		os.Exit(1)
+	}
+
+	if env.BackupMaxConcurrency < 1 {
+		log.Error("BACKUP_MAX_CONCURRENCY should be at least 1")
+		os.Exit(1)
+	}
+	if env.BackupMaxConcurrencyPerHost < 0 || env.BackupMaxConcurrencyPerStorage < 0 {
+		log.Error("BACKUP_MAX_CONCURRENCY_PER_HOST and BACKUP_MAX_CONCURRENCY_PER_STORAGE cannot be negative")
+		os.Exit(1)
+	}
+
 	env.PostgresesInstallDir = filepath.Join(backendRoot, "tools", "postgresql")
 	tools.VerifyPostgresesInstallation(log, env.EnvMode, env.PostgresesInstallDir)
 
@@ -133,6 +192,13 @@ func loadEnvVariables() {
 	// (projectRoot/postgresus-data -> /postgresus-data)
 	env.DataFolder = filepath.Join(filepath.Dir(backendRoot), "postgresus-data", "backups")
 	env.TempFolder = filepath.Join(filepath.Dir(backendRoot), "postgresus-data", "temp")
+	env.WalFolder = filepath.Join(filepath.Dir(backendRoot), "postgresus-data", "wal")
+	env.RestoreFolder = filepath.Join(filepath.Dir(backendRoot), "postgresus-data", "restores")
+	env.EncryptionKeyPath = filepath.Join(
+		filepath.Dir(backendRoot),
+		"postgresus-data",
+		"encryption.key",
+	)
 
 	if env.IsTesting {
 		if env.TestPostgres13Port == "" {
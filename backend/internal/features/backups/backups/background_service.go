@@ -6,10 +6,10 @@ import (
 	backups_config "postgresus-backend/internal/features/backups/config"
 	"postgresus-backend/internal/features/databases"
 	"postgresus-backend/internal/features/storages"
+	system_instances "postgresus-backend/internal/features/system/instances"
 	"postgresus-backend/internal/features/users"
 	user_enums "postgresus-backend/internal/features/users/enums"
 	user_repositories "postgresus-backend/internal/features/users/repositories"
-	"postgresus-backend/internal/util/period"
 	"time"
 )
 
@@ -21,7 +21,9 @@ type BackupBackgroundService struct {
 	userService         *users.UserService
 	userRepository      *user_repositories.UserRepository
 	databaseService     *databases.DatabaseService
+	instanceService     *system_instances.InstanceService
 
+	schedulerLease *system_instances.Lease
 	lastBackupTime time.Time
 	logger         *slog.Logger
 }
@@ -29,26 +31,29 @@ type BackupBackgroundService struct {
 func (s *BackupBackgroundService) Run() {
 	s.lastBackupTime = time.Now().UTC()
 
-	if err := s.failBackupsInProgress(); err != nil {
-		s.logger.Error("Failed to fail backups in progress", "error", err)
-		panic(err)
-	}
-
-	if config.IsShouldShutdown() {
-		return
-	}
-
 	for {
 		if config.IsShouldShutdown() {
 			return
 		}
 
-		if err := s.cleanOldBackups(); err != nil {
-			s.logger.Error("Failed to clean old backups", "error", err)
-		}
+		// backups are scheduled by one instance, queued
+		// backups are run by all background instances
+		if isHeld, _ := s.schedulerLease.Acquire(); isHeld {
+			if err := s.failOrphanedBackups(); err != nil {
+				s.logger.Error("Failed to fail orphaned backups", "error", err)
+			}
+
+			if err := s.cleanOldBackups(); err != nil {
+				s.logger.Error("Failed to clean old backups", "error", err)
+			}
 
-		if err := s.runPendingBackups(); err != nil {
-			s.logger.Error("Failed to run pending backups", "error", err)
+			if err := s.enforceQuotas(); err != nil {
+				s.logger.Error("Failed to enforce backup quotas", "error", err)
+			}
+
+			if err := s.runPendingBackups(); err != nil {
+				s.logger.Error("Failed to run pending backups", "error", err)
+			}
 		}
 
 		s.lastBackupTime = time.Now().UTC()
@@ -61,20 +66,34 @@ func (s *BackupBackgroundService) IsBackupsWorkerRunning() bool {
 	return s.lastBackupTime.After(time.Now().UTC().Add(-5 * time.Minute))
 }
 
-func (s *BackupBackgroundService) failBackupsInProgress() error {
+// failOrphanedBackups fails backups in progress whose instance stopped
+// sending heartbeats, e.g. it was restarted or lost. Backups run by
+// alive instances are not touched
+func (s *BackupBackgroundService) failOrphanedBackups() error {
 	backupsInProgress, err := s.backupRepository.FindByStatus(BackupStatusInProgress)
 	if err != nil {
 		return err
 	}
 
+	// read after the backups, so instance which started
+	// one of them is already registered
+	aliveInstances, err := s.instanceService.GetAliveInstances()
+	if err != nil {
+		return err
+	}
+
 	for _, backup := range backupsInProgress {
+		if aliveInstances.Contains(backup.InstanceID) {
+			continue
+		}
+
 		backupConfig, err := s.backupConfigService.GetBackupConfigByDbId(backup.DatabaseID)
 		if err != nil {
 			s.logger.Error("Failed to get backup config by database ID", "error", err)
 			continue
 		}
 
-		failMessage := "Backup failed due to application restart"
+		failMessage := "Backup failed because its instance stopped"
 		backup.FailMessage = &failMessage
 		backup.Status = BackupStatusFailed
 		backup.BackupSizeMb = 0
@@ -94,6 +113,7 @@ func (s *BackupBackgroundService) failBackupsInProgress() error {
 	return nil
 }
 
+// cleanOldBackups prunes backups which are not kept by retention of the config
 func (s *BackupBackgroundService) cleanOldBackups() error {
 	enabledBackupConfigs, err := s.backupConfigService.GetBackupConfigsWithEnabledBackups()
 	if err != nil {
@@ -101,22 +121,40 @@ func (s *BackupBackgroundService) cleanOldBackups() error {
 	}
 
 	for _, backupConfig := range enabledBackupConfigs {
-		backupStorePeriod := backupConfig.StorePeriod
-
-		if backupStorePeriod == period.PeriodForever {
+		dbBackups, err := s.backupRepository.FindByDatabaseID(backupConfig.DatabaseID)
+		if err != nil {
+			s.logger.Error(
+				"Failed to find backups for database",
+				"databaseId",
+				backupConfig.DatabaseID,
+				"error",
+				err,
+			)
 			continue
 		}
 
-		storeDuration := backupStorePeriod.ToDuration()
-		dateBeforeBackupsShouldBeDeleted := time.Now().UTC().Add(-storeDuration)
-
-		oldBackups, err := s.backupRepository.FindBackupsBeforeDate(
-			backupConfig.DatabaseID,
-			dateBeforeBackupsShouldBeDeleted,
+		s.backupService.pruneBackups(
+			getBackupsToPrune(dbBackups, backupConfig, time.Now().UTC()),
+			"of the retention policy",
 		)
+	}
+
+	return nil
+}
+
+// enforceQuotas prunes backups over the caps between backups, e.g.
+// after the caps were lowered or a backup was larger than estimated
+func (s *BackupBackgroundService) enforceQuotas() error {
+	enabledBackupConfigs, err := s.backupConfigService.GetBackupConfigsWithEnabledBackups()
+	if err != nil {
+		return err
+	}
+
+	for _, backupConfig := range enabledBackupConfigs {
+		backupStorages, err := s.backupService.getConfigStorages(backupConfig)
 		if err != nil {
 			s.logger.Error(
-				"Failed to find old backups for database",
+				"Failed to get backup storages",
 				"databaseId",
 				backupConfig.DatabaseID,
 				"error",
@@ -125,35 +163,17 @@ func (s *BackupBackgroundService) cleanOldBackups() error {
 			continue
 		}
 
-		for _, backup := range oldBackups {
-			storage, err := s.storageService.GetStorageByID(backup.StorageID)
-			if err != nil {
-				s.logger.Error(
-					"Failed to get storage by ID",
-					"storageId",
-					backup.StorageID,
-					"error",
-					err,
-				)
-				continue
-			}
-
-			err = storage.DeleteFile(backup.ID)
-			if err != nil {
-				s.logger.Error("Failed to delete backup file", "backupId", backup.ID, "error", err)
-			}
-
-			if err := s.backupRepository.DeleteByID(backup.ID); err != nil {
-				s.logger.Error("Failed to delete old backup", "backupId", backup.ID, "error", err)
-				continue
-			}
-
-			s.logger.Info(
-				"Deleted old backup",
-				"backupId",
-				backup.ID,
+		if err := s.backupService.enforceQuotas(
+			backupConfig,
+			backupStorages,
+			nextBackup{},
+		); err != nil {
+			s.logger.Error(
+				"Failed to enforce backup quotas",
 				"databaseId",
 				backupConfig.DatabaseID,
+				"error",
+				err,
 			)
 		}
 	}
@@ -243,9 +263,24 @@ func (s *BackupBackgroundService) runPendingBackups() error {
 				backupConfig.BackupInterval.Interval,
 			)
 
-			go s.backupService.MakeBackup(backupConfig.DatabaseID, remainedBackupTryCount == 1)
+			if err := s.backupService.enqueueBackup(
+				backupConfig.DatabaseID,
+				false,
+				remainedBackupTryCount == 1,
+				nil,
+			); err != nil {
+				s.logger.Error(
+					"Failed to queue scheduled backup",
+					"databaseId",
+					backupConfig.DatabaseID,
+					"error",
+					err,
+				)
+				continue
+			}
+
 			s.logger.Info(
-				"Successfully triggered scheduled backup",
+				"Successfully queued scheduled backup",
 				"databaseId",
 				backupConfig.DatabaseID,
 			)
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"postgresus-backend/internal/features/users"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -19,15 +20,24 @@ func (c *BackupController) RegisterRoutes(router *gin.RouterGroup) {
 	router.GET("/backups", c.GetBackups)
 	router.POST("/backups", c.MakeBackup)
 	router.GET("/backups/:id/file", c.GetFile)
+	router.GET("/backups/:id/objects", c.GetObjects)
 	router.DELETE("/backups/:id", c.DeleteBackup)
+	router.POST("/backups/:id/cancel", c.CancelBackup)
+	router.PUT("/backups/:id/hold", c.SetBackupHold)
+	router.DELETE("/backups/:id/hold", c.ClearBackupHold)
+	router.GET("/backups/queue", c.GetQueue)
+	router.GET("/backups/storage-migrations", c.GetStorageMigration)
+	router.POST("/backups/storage-migrations/:id/resume", c.ResumeStorageMigration)
+	router.POST("/backups/retention/dry-run", c.DryRunRetention)
 }
 
 // GetBackups
 // @Summary Get backups for a database
-// @Description Get all backups for the specified database
+// @Description Get all backups for the specified database, backups can be filtered by tags
 // @Tags backups
 // @Produce json
 // @Param database_id query string true "Database ID"
+// @Param tag query []string false "Tag in key:value format, backups should have all the tags" collectionFormat(multi)
 // @Success 200 {array} Backup
 // @Failure 400
 // @Failure 401
@@ -58,7 +68,18 @@ func (c *BackupController) GetBackups(ctx *gin.Context) {
 		return
 	}
 
-	backups, err := c.backupService.GetBackups(user, databaseID)
+	var tags []*BackupTag
+	for _, tagFilter := range ctx.QueryArray("tag") {
+		tag, err := ParseBackupTag(tagFilter)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		tags = append(tags, tag)
+	}
+
+	backups, err := c.backupService.GetBackups(user, databaseID, tags)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -69,7 +90,7 @@ func (c *BackupController) GetBackups(ctx *gin.Context) {
 
 // MakeBackup
 // @Summary Create a backup
-// @Description Create a new backup for the specified database
+// @Description Queue a new backup for the specified database, it starts when there is a free slot
 // @Tags backups
 // @Accept json
 // @Produce json
@@ -98,12 +119,18 @@ func (c *BackupController) MakeBackup(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.backupService.MakeBackupWithAuth(user, request.DatabaseID); err != nil {
+	labels := &BackupLabels{
+		Label: request.Label,
+		Notes: request.Notes,
+		Tags:  request.Tags,
+	}
+
+	if err := c.backupService.MakeBackupWithAuth(user, request.DatabaseID, labels); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "backup started successfully"})
+	ctx.JSON(http.StatusOK, gin.H{"message": "backup queued successfully"})
 }
 
 // DeleteBackup
@@ -143,12 +170,95 @@ func (c *BackupController) DeleteBackup(ctx *gin.Context) {
 	ctx.Status(http.StatusNoContent)
 }
 
+// CancelBackup
+// @Summary Cancel a backup
+// @Description Stop the in-progress backup and remove its partial file from storage
+// @Tags backups
+// @Param id path string true "Backup ID"
+// @Success 204
+// @Failure 400
+// @Failure 401
+// @Router /backups/{id}/cancel [post]
+func (c *BackupController) CancelBackup(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid backup ID"})
+		return
+	}
+
+	authorizationHeader := ctx.GetHeader("Authorization")
+	if authorizationHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+		return
+	}
+
+	user, err := c.userService.GetUserFromToken(authorizationHeader)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	if err := c.backupService.CancelBackupWithAuth(user, id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetObjects
+// @Summary Get objects of a backup
+// @Description Get table of contents of the logical backup without downloading it
+// @Tags backups
+// @Produce json
+// @Param id path string true "Backup ID"
+// @Param type query string false "Object type, e.g. TABLE"
+// @Param search query string false "Part of schema or object name"
+// @Success 200 {array} BackupObject
+// @Failure 400
+// @Failure 401
+// @Router /backups/{id}/objects [get]
+func (c *BackupController) GetObjects(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid backup ID"})
+		return
+	}
+
+	authorizationHeader := ctx.GetHeader("Authorization")
+	if authorizationHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+		return
+	}
+
+	user, err := c.userService.GetUserFromToken(authorizationHeader)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	objects, err := c.backupService.GetBackupObjects(
+		user,
+		id,
+		ctx.Query("type"),
+		ctx.Query("search"),
+	)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, objects)
+}
+
 // GetFile
 // @Summary Download a backup file
 // @Description Download the backup file for the specified backup
 // @Tags backups
 // @Param id path string true "Backup ID"
+// @Param entry_id query string false "Entry ID, required for whole server backups"
 // @Success 200 {file} file
+// @Header 200 {string} X-Backup-Sha256 "SHA-256 of the file, if known"
 // @Failure 400
 // @Failure 401
 // @Failure 500
@@ -172,7 +282,18 @@ func (c *BackupController) GetFile(ctx *gin.Context) {
 		return
 	}
 
-	fileReader, err := c.backupService.GetBackupFile(user, id)
+	var entryID *uuid.UUID
+	if entryIDStr := ctx.Query("entry_id"); entryIDStr != "" {
+		parsedEntryID, err := uuid.Parse(entryIDStr)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+			return
+		}
+
+		entryID = &parsedEntryID
+	}
+
+	fileInfo, fileReader, err := c.backupService.GetBackupFile(user, id, entryID)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -188,9 +309,19 @@ func (c *BackupController) GetFile(ctx *gin.Context) {
 	ctx.Header("Content-Type", "application/octet-stream")
 	ctx.Header(
 		"Content-Disposition",
-		fmt.Sprintf("attachment; filename=\"backup_%s.dump\"", id.String()),
+		fmt.Sprintf("attachment; filename=\"backup_%s.dump\"", fileInfo.FileID.String()),
 	)
 
+	// Let clients verify the download, file is always returned decrypted
+	// so it matches the checksum of the original pg_dump output
+	if fileInfo.Sha256 != nil {
+		ctx.Header("X-Backup-Sha256", *fileInfo.Sha256)
+	}
+
+	if fileInfo.SizeBytes != nil {
+		ctx.Header("Content-Length", strconv.FormatInt(*fileInfo.SizeBytes, 10))
+	}
+
 	// Stream the file content
 	_, err = io.Copy(ctx.Writer, fileReader)
 	if err != nil {
@@ -201,4 +332,239 @@ func (c *BackupController) GetFile(ctx *gin.Context) {
 
 type MakeBackupRequest struct {
 	DatabaseID uuid.UUID `json:"database_id" binding:"required"`
+
+	// Label, notes and key/value tags to find the backup later
+	Label *string      `json:"label"`
+	Notes *string      `json:"notes"`
+	Tags  []*BackupTag `json:"tags"`
+}
+
+// GetStorageMigration
+// @Summary Get backups storage migration
+// @Description Get progress of moving backups to the new storage of the database, null if storage was never changed
+// @Tags backups
+// @Produce json
+// @Param database_id query string true "Database ID"
+// @Success 200 {object} BackupStorageMigration
+// @Failure 400
+// @Failure 401
+// @Router /backups/storage-migrations [get]
+func (c *BackupController) GetStorageMigration(ctx *gin.Context) {
+	databaseID, err := uuid.Parse(ctx.Query("database_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid database_id"})
+		return
+	}
+
+	authorizationHeader := ctx.GetHeader("Authorization")
+	if authorizationHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+		return
+	}
+
+	user, err := c.userService.GetUserFromToken(authorizationHeader)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	migration, err := c.backupService.GetLastStorageMigrationWithAuth(user, databaseID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, migration)
+}
+
+// ResumeStorageMigration
+// @Summary Resume backups storage migration
+// @Description Retry moving backups which failed to move to the new storage
+// @Tags backups
+// @Produce json
+// @Param id path string true "Storage migration ID"
+// @Success 200 {object} BackupStorageMigration
+// @Failure 400
+// @Failure 401
+// @Router /backups/storage-migrations/{id}/resume [post]
+func (c *BackupController) ResumeStorageMigration(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid storage migration ID"})
+		return
+	}
+
+	authorizationHeader := ctx.GetHeader("Authorization")
+	if authorizationHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+		return
+	}
+
+	user, err := c.userService.GetUserFromToken(authorizationHeader)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	migration, err := c.backupService.ResumeStorageMigrationWithAuth(user, id)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, migration)
+}
+
+// GetQueue
+// @Summary Get backup queue
+// @Description Get running and queued backups of the user databases. Position is in the whole queue, waiting backups are held by the host or storage limit
+// @Tags backups
+// @Produce json
+// @Success 200 {array} BackupQueueItem
+// @Failure 401
+// @Failure 500
+// @Router /backups/queue [get]
+func (c *BackupController) GetQueue(ctx *gin.Context) {
+	authorizationHeader := ctx.GetHeader("Authorization")
+	if authorizationHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+		return
+	}
+
+	user, err := c.userService.GetUserFromToken(authorizationHeader)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	items, err := c.backupService.GetBackupQueueWithAuth(user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, items)
+}
+
+// DryRunRetention
+// @Summary Dry run backups retention
+// @Description Show which backups of the database would be pruned by the given retention, nothing is deleted
+// @Tags backups
+// @Accept json
+// @Produce json
+// @Param request body RetentionDryRunRequest true "Retention to check"
+// @Success 200 {object} RetentionDryRunResponse
+// @Failure 400
+// @Failure 401
+// @Router /backups/retention/dry-run [post]
+func (c *BackupController) DryRunRetention(ctx *gin.Context) {
+	var request RetentionDryRunRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	authorizationHeader := ctx.GetHeader("Authorization")
+	if authorizationHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+		return
+	}
+
+	user, err := c.userService.GetUserFromToken(authorizationHeader)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	response, err := c.backupService.DryRunRetentionWithAuth(user, request)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// SetBackupHold
+// @Summary Hold a backup
+// @Description Pin the backup or hold it until the date, held backup is not removed by retention, caps or removal of the database
+// @Tags backups
+// @Accept json
+// @Produce json
+// @Param id path string true "Backup ID"
+// @Param request body BackupHoldRequest true "Hold of the backup"
+// @Success 200 {object} Backup
+// @Failure 400
+// @Failure 401
+// @Router /backups/{id}/hold [put]
+func (c *BackupController) SetBackupHold(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid backup ID"})
+		return
+	}
+
+	var request BackupHoldRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	authorizationHeader := ctx.GetHeader("Authorization")
+	if authorizationHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+		return
+	}
+
+	user, err := c.userService.GetUserFromToken(authorizationHeader)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	backup, err := c.backupService.SetBackupHoldWithAuth(user, id, request)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, backup)
+}
+
+// ClearBackupHold
+// @Summary Clear hold of a backup
+// @Description Unpin the backup and clear its hold date, so retention can remove it again
+// @Tags backups
+// @Produce json
+// @Param id path string true "Backup ID"
+// @Success 200 {object} Backup
+// @Failure 400
+// @Failure 401
+// @Router /backups/{id}/hold [delete]
+func (c *BackupController) ClearBackupHold(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid backup ID"})
+		return
+	}
+
+	authorizationHeader := ctx.GetHeader("Authorization")
+	if authorizationHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+		return
+	}
+
+	user, err := c.userService.GetUserFromToken(authorizationHeader)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	backup, err := c.backupService.ClearBackupHoldWithAuth(user, id)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, backup)
 }
@@ -0,0 +1,151 @@
+package backups
+
+import (
+	"errors"
+	"io"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"postgresus-backend/internal/features/storages"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GetBackupStorages returns storages with a completed copy of the backup,
+// the main storage goes first. Restore falls back to the next storage when
+// the file cannot be read from the previous one
+func (s *BackupService) GetBackupStorages(backup *Backup) ([]*storages.Storage, error) {
+	var backupStorages []*storages.Storage
+
+	for _, storageID := range backup.GetCompletedStorageIDs() {
+		storage, err := s.storageService.GetStorageByID(storageID)
+		if err != nil {
+			s.logger.Warn("Failed to get storage of backup copy", "storageId", storageID, "error", err)
+			continue
+		}
+
+		backupStorages = append(backupStorages, storage)
+	}
+
+	if len(backupStorages) == 0 {
+		return nil, errors.New("backup has no completed copy in any storage")
+	}
+
+	return backupStorages, nil
+}
+
+// getConfigStorages returns the main storage followed by extra ones. Extra
+// storage which cannot be loaded is skipped, the backup is still made
+func (s *BackupService) getConfigStorages(
+	backupConfig *backups_config.BackupConfig,
+) ([]*storages.Storage, error) {
+	if backupConfig.StorageID == nil {
+		return nil, errors.New("backup config storage ID is not defined")
+	}
+
+	mainStorage, err := s.storageService.GetStorageByID(*backupConfig.StorageID)
+	if err != nil {
+		return nil, err
+	}
+
+	backupStorages := []*storages.Storage{mainStorage}
+
+	for _, storageID := range backupConfig.ExtraStorageIDs {
+		storage, err := s.storageService.GetStorageByID(storageID)
+		if err != nil {
+			s.logger.Error("Failed to get extra storage by ID", "storageId", storageID, "error", err)
+			continue
+		}
+
+		backupStorages = append(backupStorages, storage)
+	}
+
+	return backupStorages, nil
+}
+
+func (s *BackupService) toBackupCopies(
+	backupID uuid.UUID,
+	backupStorages []*storages.Storage,
+	failedStorages map[uuid.UUID]string,
+) []*BackupCopy {
+	copies := make([]*BackupCopy, 0, len(backupStorages))
+
+	for _, storage := range backupStorages {
+		storageCopy := &BackupCopy{
+			ID:        uuid.New(),
+			BackupID:  backupID,
+			StorageID: storage.ID,
+			Status:    BackupCopyStatusCompleted,
+			CreatedAt: time.Now().UTC(),
+		}
+
+		if failMessage, isFailed := failedStorages[storage.ID]; isFailed {
+			storageCopy.Status = BackupCopyStatusFailed
+			storageCopy.FailMessage = &failMessage
+		}
+
+		copies = append(copies, storageCopy)
+	}
+
+	return copies
+}
+
+// openBackupFile opens the file from the first storage which can read it
+func (s *BackupService) openBackupFile(backup *Backup, fileID uuid.UUID) (io.ReadCloser, error) {
+	backupStorages, err := s.GetBackupStorages(backup)
+	if err != nil {
+		return nil, err
+	}
+
+	var openErrs []error
+
+	for _, storage := range backupStorages {
+		fileReader, err := storage.GetFile(fileID)
+		if err == nil {
+			return fileReader, nil
+		}
+
+		s.logger.Warn(
+			"Failed to open backup file, trying the next storage",
+			"backupId",
+			backup.ID,
+			"storageId",
+			storage.ID,
+			"error",
+			err,
+		)
+
+		openErrs = append(openErrs, err)
+	}
+
+	return nil, errors.Join(openErrs...)
+}
+
+// deleteBackupFiles removes files of all copies. Failed copies may have
+// no file or a partial one, so errors of them are only logged
+func (s *BackupService) deleteBackupFiles(backup *Backup) error {
+	for _, storageCopy := range backup.getStorageCopies() {
+		isCompleted := storageCopy.Status == BackupCopyStatusCompleted
+
+		storage, err := s.storageService.GetStorageByID(storageCopy.StorageID)
+		if err != nil {
+			if isCompleted {
+				return err
+			}
+
+			s.logger.Warn("Failed to get storage of failed backup copy", "backupId", backup.ID, "error", err)
+			continue
+		}
+
+		for _, fileID := range backup.GetStorageFileIDs() {
+			if err := storage.DeleteFile(fileID); err != nil {
+				if isCompleted {
+					return err
+				}
+
+				s.logger.Warn("Failed to delete file of failed backup copy", "backupId", backup.ID, "error", err)
+			}
+		}
+	}
+
+	return nil
+}
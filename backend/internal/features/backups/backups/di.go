@@ -3,11 +3,14 @@ package backups
 import (
 	"postgresus-backend/internal/features/backups/backups/usecases"
 	backups_config "postgresus-backend/internal/features/backups/config"
+	backups_encryption "postgresus-backend/internal/features/backups/encryption"
 	"postgresus-backend/internal/features/databases"
 	"postgresus-backend/internal/features/notifiers"
 	"postgresus-backend/internal/features/storages"
+	system_instances "postgresus-backend/internal/features/system/instances"
 	"postgresus-backend/internal/features/users"
 	user_repositories "postgresus-backend/internal/features/users/repositories"
+	cancellation_utils "postgresus-backend/internal/util/cancellation"
 	"postgresus-backend/internal/util/logger"
 	"time"
 )
@@ -20,7 +23,10 @@ var backupService = &BackupService{
 	notifiers.GetNotifierService(),
 	notifiers.GetNotifierService(),
 	backups_config.GetBackupConfigService(),
+	backups_encryption.GetBackupEncryptionService(),
 	usecases.GetCreateBackupUsecase(),
+	cancellation_utils.NewCancellationTracker(),
+	system_instances.GetInstanceService(),
 	logger.GetLogger(),
 	[]BackupRemoveListener{},
 }
@@ -33,10 +39,28 @@ var backupBackgroundService = &BackupBackgroundService{
 	userService:         users.GetUserService(),
 	userRepository:      &user_repositories.UserRepository{},
 	databaseService:     databases.GetDatabaseService(),
+	instanceService:     system_instances.GetInstanceService(),
+	schedulerLease:      system_instances.GetInstanceService().NewLease("backup-scheduler"),
 	lastBackupTime:      time.Now().UTC(),
 	logger:              logger.GetLogger(),
 }
 
+var backupStorageMigrationBackgroundService = &BackupStorageMigrationBackgroundService{
+	backupService:    backupService,
+	backupRepository: backupRepository,
+	migrationLease: system_instances.GetInstanceService().NewLease(
+		"backup-storage-migration",
+	),
+	logger: logger.GetLogger(),
+}
+
+var backupQueueBackgroundService = &BackupQueueBackgroundService{
+	backupService:    backupService,
+	backupRepository: backupRepository,
+	instanceService:  system_instances.GetInstanceService(),
+	logger:           logger.GetLogger(),
+}
+
 var backupController = &BackupController{
 	backupService,
 	users.GetUserService(),
@@ -62,3 +86,11 @@ func GetBackupController() *BackupController {
 func GetBackupBackgroundService() *BackupBackgroundService {
 	return backupBackgroundService
 }
+
+func GetBackupStorageMigrationBackgroundService() *BackupStorageMigrationBackgroundService {
+	return backupStorageMigrationBackgroundService
+}
+
+func GetBackupQueueBackgroundService() *BackupQueueBackgroundService {
+	return backupQueueBackgroundService
+}
@@ -6,4 +6,53 @@ const (
 	BackupStatusInProgress BackupStatus = "IN_PROGRESS"
 	BackupStatusCompleted  BackupStatus = "COMPLETED"
 	BackupStatusFailed     BackupStatus = "FAILED"
+	BackupStatusCancelled  BackupStatus = "CANCELLED"
+)
+
+type BackupEntryKind string
+
+const (
+	BackupEntryKindGlobals  BackupEntryKind = "GLOBALS"
+	BackupEntryKindDatabase BackupEntryKind = "DATABASE"
+)
+
+type BackupHookStatus string
+
+const (
+	BackupHookStatusSuccess BackupHookStatus = "SUCCESS"
+	BackupHookStatusFailed  BackupHookStatus = "FAILED"
+)
+
+type BackupVerificationStatus string
+
+const (
+	BackupVerificationStatusInProgress BackupVerificationStatus = "IN_PROGRESS"
+	BackupVerificationStatusVerified   BackupVerificationStatus = "VERIFIED"
+	BackupVerificationStatusFailed     BackupVerificationStatus = "VERIFICATION_FAILED"
+)
+
+type BackupCopyStatus string
+
+const (
+	BackupCopyStatusCompleted BackupCopyStatus = "COMPLETED"
+	BackupCopyStatusFailed    BackupCopyStatus = "FAILED"
+)
+
+type BackupStorageMigrationStatus string
+
+const (
+	BackupStorageMigrationStatusInProgress BackupStorageMigrationStatus = "IN_PROGRESS"
+	BackupStorageMigrationStatusCompleted  BackupStorageMigrationStatus = "COMPLETED"
+	BackupStorageMigrationStatusFailed     BackupStorageMigrationStatus = "FAILED"
+	BackupStorageMigrationStatusCancelled  BackupStorageMigrationStatus = "CANCELLED"
+)
+
+type BackupQueueItemStatus string
+
+const (
+	BackupQueueItemStatusQueued  BackupQueueItemStatus = "QUEUED"
+	BackupQueueItemStatusRunning BackupQueueItemStatus = "RUNNING"
+	// Waiting is not stored, it is a queued item which cannot
+	// start because of the host or storage limit
+	BackupQueueItemStatusWaiting BackupQueueItemStatus = "WAITING"
 )
@@ -0,0 +1,114 @@
+package backups
+
+import (
+	"errors"
+	users_models "postgresus-backend/internal/features/users/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BackupHoldRequest struct {
+	IsPinned   bool       `json:"isPinned"`
+	HoldUntil  *time.Time `json:"holdUntil"`
+	HoldReason *string    `json:"holdReason"`
+}
+
+// SetBackupHoldWithAuth pins the backup or holds it until the given time,
+// e.g. before a migration, so it is not pruned while it may be needed
+func (s *BackupService) SetBackupHoldWithAuth(
+	user *users_models.User,
+	backupID uuid.UUID,
+	request BackupHoldRequest,
+) (*Backup, error) {
+	backup, err := s.findUserBackup(user, backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if backup.Status != BackupStatusCompleted {
+		return nil, errors.New("only completed backup can be held")
+	}
+
+	if !request.IsPinned && request.HoldUntil == nil {
+		return nil, errors.New("backup should be pinned or held until a date")
+	}
+
+	if request.HoldUntil != nil && !request.HoldUntil.After(time.Now().UTC()) {
+		return nil, errors.New("hold date should be in the future")
+	}
+
+	if request.HoldReason != nil && strings.TrimSpace(*request.HoldReason) == "" {
+		request.HoldReason = nil
+	}
+
+	backup.IsPinned = request.IsPinned
+	backup.HoldUntil = request.HoldUntil
+	backup.HoldReason = request.HoldReason
+
+	if backup.HoldUntil != nil {
+		holdUntil := backup.HoldUntil.UTC()
+		backup.HoldUntil = &holdUntil
+	}
+
+	if err := s.backupRepository.UpdateHold(backup); err != nil {
+		return nil, err
+	}
+
+	return backup, nil
+}
+
+func (s *BackupService) ClearBackupHoldWithAuth(
+	user *users_models.User,
+	backupID uuid.UUID,
+) (*Backup, error) {
+	backup, err := s.findUserBackup(user, backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	backup.IsPinned = false
+	backup.HoldUntil = nil
+	backup.HoldReason = nil
+
+	if err := s.backupRepository.UpdateHold(backup); err != nil {
+		return nil, err
+	}
+
+	return backup, nil
+}
+
+func (s *BackupService) findUserBackup(
+	user *users_models.User,
+	backupID uuid.UUID,
+) (*Backup, error) {
+	backup, err := s.backupRepository.FindByID(backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if backup.Database.UserID != user.ID {
+		return nil, errors.New("user does not have access to this backup")
+	}
+
+	return backup, nil
+}
+
+// ensureNoHeldBackups refuses removal of all database backups,
+// the hold should be cleared first
+func (s *BackupService) ensureNoHeldBackups(databaseID uuid.UUID) error {
+	dbBackups, err := s.backupRepository.FindByDatabaseID(databaseID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, backup := range dbBackups {
+		if backup.IsHeld(now) {
+			return errors.New("database has held backups, clear the hold before removal")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package backups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"postgresus-backend/internal/features/databases"
+	cancellation_utils "postgresus-backend/internal/util/cancellation"
+	"strings"
+	"time"
+)
+
+// maxHookOutputBytes keeps the end of long output, errors are usually there
+const maxHookOutputBytes = 64 * 1024
+
+// runPreBackupHooks returns error if any hook failed, the backup
+// should not be started then. Output is saved to the backup anyway
+func (s *BackupService) runPreBackupHooks(
+	ctx context.Context,
+	backupConfig *backups_config.BackupConfig,
+	database *databases.Database,
+	backup *Backup,
+) error {
+	hooks := &backupConfig.BackupHooks
+	if !hooks.HasPreBackupHooks() {
+		return nil
+	}
+
+	output, err := runBackupHooks(
+		ctx,
+		database,
+		hooks.PreBackupSql,
+		hooks.PreBackupCommand,
+		hooks.GetTimeout(),
+		buildHookEnv(database, backup, BackupStatusInProgress),
+	)
+
+	backup.PreHookStatus, backup.PreHookOutput = toHookResult(output, err)
+
+	if err != nil {
+		return fmt.Errorf("pre-backup hook failed: %w", err)
+	}
+
+	return nil
+}
+
+// runPostBackupHooks runs even when the backup failed or was cancelled, so
+// anything paused by pre hooks is resumed. Failed post hook does not fail
+// the backup, because the dump itself is already made
+func (s *BackupService) runPostBackupHooks(
+	backupConfig *backups_config.BackupConfig,
+	database *databases.Database,
+	backup *Backup,
+	backupStatus BackupStatus,
+) {
+	hooks := &backupConfig.BackupHooks
+	if !hooks.HasPostBackupHooks() {
+		return
+	}
+
+	// context of the backup may be already cancelled
+	output, err := runBackupHooks(
+		context.Background(),
+		database,
+		hooks.PostBackupSql,
+		hooks.PostBackupCommand,
+		hooks.GetTimeout(),
+		buildHookEnv(database, backup, backupStatus),
+	)
+	if err != nil {
+		s.logger.Error("Post-backup hook failed", "backupId", backup.ID, "error", err)
+	}
+
+	backup.PostHookStatus, backup.PostHookOutput = toHookResult(output, err)
+}
+
+// getBackupResultStatus returns status the backup will get
+// after the use case returned, it is passed to post hooks
+func getBackupResultStatus(ctx context.Context, err error) BackupStatus {
+	if err == nil {
+		return BackupStatusCompleted
+	}
+
+	if cancellation_utils.IsCancelledByUser(ctx) {
+		return BackupStatusCancelled
+	}
+
+	return BackupStatusFailed
+}
+
+func runBackupHooks(
+	ctx context.Context,
+	database *databases.Database,
+	sql string,
+	command string,
+	timeout time.Duration,
+	env []string,
+) (string, error) {
+	var output strings.Builder
+
+	if strings.TrimSpace(sql) != "" {
+		sqlOutput, err := runSqlHook(ctx, database, sql, timeout)
+		output.WriteString(sqlOutput)
+
+		if err != nil {
+			return output.String(), fmt.Errorf("SQL hook failed: %w", err)
+		}
+	}
+
+	if strings.TrimSpace(command) != "" {
+		commandOutput, err := runCommandHook(ctx, command, timeout, env)
+		output.WriteString(commandOutput)
+
+		if err != nil {
+			return output.String(), fmt.Errorf("command hook failed: %w", err)
+		}
+	}
+
+	return output.String(), nil
+}
+
+func runSqlHook(
+	ctx context.Context,
+	database *databases.Database,
+	sql string,
+	timeout time.Duration,
+) (string, error) {
+	if database.Postgresql == nil {
+		return "", errors.New("SQL hooks are supported only for PostgreSQL databases")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output, err := database.Postgresql.ExecuteScript(ctx, sql)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return output, fmt.Errorf("timed out after %s", timeout)
+	}
+
+	return output, err
+}
+
+func runCommandHook(
+	ctx context.Context,
+	command string,
+	timeout time.Duration,
+	env []string,
+) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+
+	// child processes may keep the output open after sh is killed
+	cmd.WaitDelay = 5 * time.Second
+
+	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return string(output), fmt.Errorf("timed out after %s", timeout)
+	}
+
+	return string(output), err
+}
+
+func buildHookEnv(
+	database *databases.Database,
+	backup *Backup,
+	backupStatus BackupStatus,
+) []string {
+	return []string{
+		"POSTGRESUS_DATABASE_ID=" + database.ID.String(),
+		"POSTGRESUS_DATABASE_NAME=" + database.Name,
+		"POSTGRESUS_BACKUP_ID=" + backup.ID.String(),
+		"POSTGRESUS_BACKUP_STATUS=" + string(backupStatus),
+	}
+}
+
+func toHookResult(output string, err error) (*BackupHookStatus, *string) {
+	status := BackupHookStatusSuccess
+
+	if err != nil {
+		status = BackupHookStatusFailed
+		output += err.Error()
+	}
+
+	if len(output) > maxHookOutputBytes {
+		output = "...\n" + output[len(output)-maxHookOutputBytes:]
+	}
+
+	// text column rejects NUL and invalid UTF-8, e.g. cut in the middle of a rune
+	output = strings.ToValidUTF8(strings.ReplaceAll(output, "\x00", ""), "?")
+
+	return &status, &output
+}
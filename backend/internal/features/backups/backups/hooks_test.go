@@ -0,0 +1,140 @@
+package backups
+
+import (
+	"context"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	backups_encryption "postgresus-backend/internal/features/backups/encryption"
+	"postgresus-backend/internal/features/databases"
+	"postgresus-backend/internal/features/notifiers"
+	"postgresus-backend/internal/features/storages"
+	system_instances "postgresus-backend/internal/features/system/instances"
+	"postgresus-backend/internal/features/users"
+	cancellation_utils "postgresus-backend/internal/util/cancellation"
+	"postgresus-backend/internal/util/logger"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RunBackupHooks_WithCommand_ReturnsOutputAndEnv(t *testing.T) {
+	output, err := runBackupHooks(
+		context.Background(),
+		&databases.Database{},
+		"",
+		"echo started $POSTGRESUS_BACKUP_STATUS",
+		10*time.Second,
+		[]string{"POSTGRESUS_BACKUP_STATUS=IN_PROGRESS"},
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, "started IN_PROGRESS\n", output)
+}
+
+func Test_RunBackupHooks_WhenCommandFails_ReturnsExitStatus(t *testing.T) {
+	output, err := runBackupHooks(
+		context.Background(),
+		&databases.Database{},
+		"",
+		"echo queue is busy; exit 3",
+		10*time.Second,
+		nil,
+	)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit status 3")
+	assert.Equal(t, "queue is busy\n", output)
+}
+
+func Test_RunBackupHooks_WhenCommandHangs_TimesOut(t *testing.T) {
+	_, err := runBackupHooks(
+		context.Background(),
+		&databases.Database{},
+		"",
+		"sleep 10",
+		100*time.Millisecond,
+		nil,
+	)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+}
+
+func Test_ToHookResult_WithLongOutput_KeepsTheEnd(t *testing.T) {
+	output := strings.Repeat("a", maxHookOutputBytes) + "last line\n"
+
+	status, savedOutput := toHookResult(output, nil)
+
+	assert.Equal(t, BackupHookStatusSuccess, *status)
+	assert.True(t, strings.HasPrefix(*savedOutput, "...\n"))
+	assert.True(t, strings.HasSuffix(*savedOutput, "last line\n"))
+}
+
+func Test_MakeBackup_WhenPreHookFails_BackupFailedWithHookOutput(t *testing.T) {
+	user := users.GetTestUser()
+	storage := storages.CreateTestStorage(user.UserID)
+	notifier := notifiers.CreateTestNotifier(user.UserID)
+	database := databases.CreateTestDatabase(user.UserID, storage, notifier)
+	backupConfig := backups_config.EnableBackupsForTestDatabase(database.ID, storage)
+
+	defer storages.RemoveTestStorage(storage.ID)
+	defer notifiers.RemoveTestNotifier(notifier)
+	defer databases.RemoveTestDatabase(database)
+
+	backupConfig.PreBackupCommand = "echo pausing jobs && exit 3"
+	_, err := backups_config.GetBackupConfigService().SaveBackupConfig(backupConfig)
+	require.NoError(t, err)
+
+	mockNotificationSender := &MockNotificationSender{}
+	mockNotificationSender.On("SendNotification",
+		mock.Anything,
+		mock.MatchedBy(func(title string) bool {
+			return strings.Contains(title, "❌ Backup failed")
+		}),
+		mock.MatchedBy(func(message string) bool {
+			return strings.Contains(message, "pre-backup hook failed")
+		}),
+	).Once()
+
+	backupService := &BackupService{
+		databases.GetDatabaseService(),
+		storages.GetStorageService(),
+		backupRepository,
+		notifiers.GetNotifierService(),
+		mockNotificationSender,
+		backups_config.GetBackupConfigService(),
+		backups_encryption.GetBackupEncryptionService(),
+		&CreateSuccessBackupUsecase{},
+		cancellation_utils.NewCancellationTracker(),
+		system_instances.GetInstanceService(),
+		logger.GetLogger(),
+		[]BackupRemoveListener{},
+	}
+
+	backupService.MakeBackup(database.ID, true, nil)
+
+	backup, err := backupRepository.FindLastByDatabaseID(database.ID)
+	require.NoError(t, err)
+	require.NotNil(t, backup)
+	defer func() {
+		_ = backupRepository.DeleteByID(backup.ID)
+	}()
+
+	assert.Equal(t, BackupStatusFailed, backup.Status)
+	require.NotNil(t, backup.FailMessage)
+	assert.Contains(t, *backup.FailMessage, "pre-backup hook failed")
+	assert.Contains(t, *backup.FailMessage, "exit status 3")
+
+	require.NotNil(t, backup.PreHookStatus)
+	assert.Equal(t, BackupHookStatusFailed, *backup.PreHookStatus)
+	require.NotNil(t, backup.PreHookOutput)
+	assert.Contains(t, *backup.PreHookOutput, "pausing jobs")
+
+	// dump is not started, so there is nothing to resume
+	assert.Nil(t, backup.PostHookStatus)
+
+	mockNotificationSender.AssertExpectations(t)
+}
@@ -1,6 +1,9 @@
 package backups
 
 import (
+	"context"
+	"io"
+	usecases_common "postgresus-backend/internal/features/backups/backups/usecases/common"
 	backups_config "postgresus-backend/internal/features/backups/config"
 	"postgresus-backend/internal/features/databases"
 	"postgresus-backend/internal/features/notifiers"
@@ -19,14 +22,28 @@ type NotificationSender interface {
 
 type CreateBackupUsecase interface {
 	Execute(
+		ctx context.Context,
 		backupID uuid.UUID,
 		backupConfig *backups_config.BackupConfig,
 		database *databases.Database,
-		storage *storages.Storage,
+		backupStorages []*storages.Storage,
+		encryptionKey []byte,
 		backupProgressListener func(
 			completedMBs float64,
 		),
-	) error
+	) (*usecases_common.BackupMetadata, error)
+
+	GetSourceSize(
+		backupConfig *backups_config.BackupConfig,
+		database *databases.Database,
+	) (int64, error)
+
+	ListBackupObjects(
+		ctx context.Context,
+		database *databases.Database,
+		dumpFormat backups_config.BackupDumpFormat,
+		dumpReader io.Reader,
+	) ([]*usecases_common.BackupObjectMetadata, error)
 }
 
 type BackupRemoveListener interface {
@@ -0,0 +1,176 @@
+package backups
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxBackupLabelLength    = 100
+	maxBackupNotesLength    = 2000
+	maxBackupTagsCount      = 20
+	maxBackupTagValueLength = 200
+)
+
+// tag key cannot contain ":" and "=", they separate the key
+// from the value in the filter and in the queue item
+var backupTagKeyRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.\-]{1,64}$`)
+
+// BackupTag is a key/value pair set by the user on manual backup,
+// key is unique within the backup
+type BackupTag struct {
+	ID       uuid.UUID `json:"-"     gorm:"column:id;type:uuid;primaryKey"`
+	BackupID uuid.UUID `json:"-"     gorm:"column:backup_id;type:uuid;not null"`
+	Key      string    `json:"key"   gorm:"column:key;type:text;not null"`
+	Value    string    `json:"value" gorm:"column:value;type:text;not null"`
+}
+
+func (t *BackupTag) TableName() string {
+	return "backup_tags"
+}
+
+// BackupLabels are set by the user on manual backup to find it later,
+// e.g. label "pre-release-2.14" with tag "release=2.14"
+type BackupLabels struct {
+	Label *string      `json:"label"`
+	Notes *string      `json:"notes"`
+	Tags  []*BackupTag `json:"tags"`
+}
+
+// Validate trims the label and notes, blank ones become nil
+func (l *BackupLabels) Validate() error {
+	l.Label = trimToNil(l.Label)
+	l.Notes = trimToNil(l.Notes)
+
+	if l.Label != nil && utf8.RuneCountInString(*l.Label) > maxBackupLabelLength {
+		return fmt.Errorf("label should not be longer than %d characters", maxBackupLabelLength)
+	}
+
+	if l.Label != nil && strings.ContainsAny(*l.Label, "\r\n") {
+		return errors.New("label should be a single line")
+	}
+
+	if l.Notes != nil && utf8.RuneCountInString(*l.Notes) > maxBackupNotesLength {
+		return fmt.Errorf("notes should not be longer than %d characters", maxBackupNotesLength)
+	}
+
+	if len(l.Tags) > maxBackupTagsCount {
+		return fmt.Errorf("backup should not have more than %d tags", maxBackupTagsCount)
+	}
+
+	keys := map[string]bool{}
+	for _, tag := range l.Tags {
+		if tag == nil {
+			return errors.New("tag should not be empty")
+		}
+
+		if err := tag.validate(); err != nil {
+			return err
+		}
+
+		if keys[tag.Key] {
+			return fmt.Errorf("tag \"%s\" is set more than once", tag.Key)
+		}
+
+		keys[tag.Key] = true
+	}
+
+	return nil
+}
+
+// ParseBackupTag parses the "key:value" tag filter
+func ParseBackupTag(filter string) (*BackupTag, error) {
+	key, value, isFound := strings.Cut(filter, ":")
+	if !isFound {
+		return nil, fmt.Errorf("tag filter \"%s\" should be in key:value format", filter)
+	}
+
+	tag := &BackupTag{Key: key, Value: value}
+	if err := tag.validate(); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (t *BackupTag) validate() error {
+	if !backupTagKeyRegexp.MatchString(t.Key) {
+		return fmt.Errorf(
+			"tag key \"%s\" should be 1-64 letters, digits, \"_\", \".\" or \"-\"",
+			t.Key,
+		)
+	}
+
+	if utf8.RuneCountInString(t.Value) > maxBackupTagValueLength {
+		return fmt.Errorf(
+			"value of tag \"%s\" should not be longer than %d characters",
+			t.Key,
+			maxBackupTagValueLength,
+		)
+	}
+
+	if strings.ContainsAny(t.Value, "\r\n") {
+		return fmt.Errorf("value of tag \"%s\" should be a single line", t.Key)
+	}
+
+	return nil
+}
+
+// copyBackupTags returns new tags for the backup, backup ID
+// is set when the backup is created with its tags
+func copyBackupTags(tags []*BackupTag) []*BackupTag {
+	backupTags := make([]*BackupTag, 0, len(tags))
+
+	for _, tag := range tags {
+		backupTags = append(backupTags, &BackupTag{
+			ID:    uuid.New(),
+			Key:   tag.Key,
+			Value: tag.Value,
+		})
+	}
+
+	return backupTags
+}
+
+// encodeBackupTags stores tags of the queue item as "key=value"
+// lines, key has no "=" and value has no line breaks
+func encodeBackupTags(tags []*BackupTag) string {
+	lines := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		lines = append(lines, tag.Key+"="+tag.Value)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func decodeBackupTags(encoded string) []*BackupTag {
+	tags := []*BackupTag{}
+	if encoded == "" {
+		return tags
+	}
+
+	for _, line := range strings.Split(encoded, "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		tags = append(tags, &BackupTag{Key: key, Value: value})
+	}
+
+	return tags
+}
+
+func trimToNil(value *string) *string {
+	if value == nil {
+		return nil
+	}
+
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+
+	return &trimmed
+}
@@ -0,0 +1,64 @@
+package backups
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ValidateBackupLabels_WhenLabelIsBlank_LabelIsCleared(t *testing.T) {
+	label := "  pre-release-2.14 "
+	notes := "   "
+
+	labels := &BackupLabels{Label: &label, Notes: &notes}
+
+	require.NoError(t, labels.Validate())
+	require.NotNil(t, labels.Label)
+	assert.Equal(t, "pre-release-2.14", *labels.Label)
+	assert.Nil(t, labels.Notes)
+}
+
+func Test_ValidateBackupLabels_WhenTagsAreInvalid_ReturnsError(t *testing.T) {
+	longValue := strings.Repeat("x", maxBackupTagValueLength+1)
+
+	testCases := map[string][]*BackupTag{
+		"empty key":      {{Key: "", Value: "prod"}},
+		"key with colon": {{Key: "env:name", Value: "prod"}},
+		"multiline":      {{Key: "env", Value: "prod\nstaging"}},
+		"long value":     {{Key: "env", Value: longValue}},
+		"duplicate key":  {{Key: "env", Value: "prod"}, {Key: "env", Value: "staging"}},
+	}
+
+	for name, tags := range testCases {
+		t.Run(name, func(t *testing.T) {
+			labels := &BackupLabels{Tags: tags}
+			assert.Error(t, labels.Validate())
+		})
+	}
+}
+
+func Test_ParseBackupTag_WhenValueHasColon_KeyIsBeforeFirstColon(t *testing.T) {
+	tag, err := ParseBackupTag("release:2.14:rc1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "release", tag.Key)
+	assert.Equal(t, "2.14:rc1", tag.Value)
+
+	_, err = ParseBackupTag("release")
+	assert.Error(t, err)
+}
+
+func Test_EncodeBackupTags_WhenDecoded_TagsAreSame(t *testing.T) {
+	tags := []*BackupTag{
+		{Key: "release", Value: "2.14"},
+		{Key: "ticket", Value: "OPS=42"},
+		{Key: "empty", Value: ""},
+	}
+
+	decodedTags := decodeBackupTags(encodeBackupTags(tags))
+
+	assert.Equal(t, tags, decodedTags)
+	assert.Empty(t, decodeBackupTags(encodeBackupTags(nil)))
+}
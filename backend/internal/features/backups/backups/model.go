@@ -1,11 +1,13 @@
 package backups
 
 import (
+	backups_config "postgresus-backend/internal/features/backups/config"
 	"postgresus-backend/internal/features/databases"
 	"postgresus-backend/internal/features/storages"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Backup struct {
@@ -17,6 +19,11 @@ type Backup struct {
 	Storage   *storages.Storage `json:"storage"   gorm:"foreignKey:StorageID"`
 	StorageID uuid.UUID         `json:"storageId" gorm:"column:storage_id;type:uuid;not null"`
 
+	// Copies of the backup in the main and extra storages. Backups made
+	// before extra storages were introduced have no copies, their only
+	// copy is in the main storage
+	Copies []*BackupCopy `json:"copies" gorm:"foreignKey:BackupID"`
+
 	Status      BackupStatus `json:"status"      gorm:"column:status;not null"`
 	FailMessage *string      `json:"failMessage" gorm:"column:fail_message"`
 
@@ -24,5 +31,352 @@ type Backup struct {
 
 	BackupDurationMs int64 `json:"backupDurationMs" gorm:"column:backup_duration_ms;default:0"`
 
+	// SourceSizeBytes is size of the data read by the backup, measured
+	// before the start. Backup size to source size ratio of finished
+	// backups is used to estimate size of the next ones
+	SourceSizeBytes *int64 `json:"sourceSizeBytes" gorm:"column:source_size_bytes"`
+
+	// Progress of the running backup, nil when size cannot be estimated
+	EstimatedSizeMb   *float64   `json:"estimatedSizeMb"   gorm:"column:estimated_size_mb"`
+	ProgressPercent   *float64   `json:"progressPercent"   gorm:"column:progress_percent"`
+	EstimatedFinishAt *time.Time `json:"estimatedFinishAt" gorm:"column:estimated_finish_at"`
+
+	// SourceHost is "host:port" of the server the backup was taken from,
+	// it is a replica when a healthy one was found
+	SourceHost *string `json:"sourceHost" gorm:"column:source_host"`
+
+	// Label, notes and tags are set by the user on manual backup, so
+	// the backup can be found later without knowing its time
+	Label *string      `json:"label" gorm:"column:label"`
+	Notes *string      `json:"notes" gorm:"column:notes"`
+	Tags  []*BackupTag `json:"tags"  gorm:"foreignKey:BackupID"`
+
+	// Held backup is not removed by retention, caps, disabling of backups
+	// or removal of the database. Pinned backup is held until the hold is
+	// cleared, otherwise the backup is held until HoldUntil
+	IsPinned   bool       `json:"isPinned"   gorm:"column:is_pinned;default:false"`
+	HoldUntil  *time.Time `json:"holdUntil"  gorm:"column:hold_until"`
+	HoldReason *string    `json:"holdReason" gorm:"column:hold_reason"`
+
+	// InstanceID of the process which runs the backup. Backup in progress
+	// is failed only when heartbeat of the instance expires
+	InstanceID *uuid.UUID `json:"instanceId" gorm:"column:instance_id;type:uuid"`
+
+	// CancelRequestedAt is set when cancel is requested on an instance which
+	// does not run the backup, the running instance polls it and stops
+	CancelRequestedAt *time.Time `json:"cancelRequestedAt" gorm:"column:cancel_requested_at"`
+
+	// Method is copied from the config, so restore knows the
+	// format even if the config was changed after the backup
+	BackupMethod backups_config.BackupMethod `json:"backupMethod" gorm:"column:backup_method;type:text;not null"`
+
+	// DumpFormat of logical backup, directory dumps are stored as tar
+	DumpFormat backups_config.BackupDumpFormat `json:"dumpFormat" gorm:"column:dump_format;type:text;not null"`
+
+	// Checksum and exact size of the backup file as produced by pg_dump (before
+	// encryption). Empty for backups made before checksums were introduced
+	Sha256    *string `json:"sha256"    gorm:"column:sha256"`
+	SizeBytes *int64  `json:"sizeBytes" gorm:"column:size_bytes"`
+
+	IsEncrypted          bool `json:"isEncrypted"          gorm:"column:is_encrypted;default:false"`
+	EncryptionKeyVersion *int `json:"encryptionKeyVersion" gorm:"column:encryption_key_version"`
+
+	// Whole server backup has no file of its own, each database
+	// and globals are stored as separate entries
+	IsWholeServer bool           `json:"isWholeServer" gorm:"column:is_whole_server;default:false"`
+	Entries       []*BackupEntry `json:"entries"       gorm:"foreignKey:BackupID"`
+
+	// Results of hooks run around the backup, nil when there are no hooks.
+	// Output contains SQL command tags and notices, command output and error
+	PreHookStatus  *BackupHookStatus `json:"preHookStatus"  gorm:"column:pre_hook_status"`
+	PreHookOutput  *string           `json:"preHookOutput"  gorm:"column:pre_hook_output"`
+	PostHookStatus *BackupHookStatus `json:"postHookStatus" gorm:"column:post_hook_status"`
+	PostHookOutput *string           `json:"postHookOutput" gorm:"column:post_hook_output"`
+
+	// Result of restoring the completed backup into a scratch
+	// database, nil when the backup was not verified
+	VerificationStatus  *BackupVerificationStatus `json:"verificationStatus"  gorm:"column:verification_status"`
+	VerificationMessage *string                   `json:"verificationMessage" gorm:"column:verification_message"`
+	VerifiedAt          *time.Time                `json:"verifiedAt"          gorm:"column:verified_at"`
+
+	// Filters are copied from the config to know what the dump contains
+	backups_config.BackupFilters `gorm:"embedded"`
+
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at"`
+}
+
+// BackupEntry is a single file of the whole server backup. Entry
+// ID is used as the file name in storage
+type BackupEntry struct {
+	ID       uuid.UUID       `json:"id"       gorm:"column:id;type:uuid;primaryKey"`
+	BackupID uuid.UUID       `json:"backupId" gorm:"column:backup_id;type:uuid;not null"`
+	Kind     BackupEntryKind `json:"kind"     gorm:"column:kind;type:text;not null"`
+
+	// DatabaseName is empty for globals entry
+	DatabaseName *string `json:"databaseName" gorm:"column:database_name"`
+
+	Sha256    string `json:"sha256"    gorm:"column:sha256;not null"`
+	SizeBytes int64  `json:"sizeBytes" gorm:"column:size_bytes;not null"`
+
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at"`
+}
+
+func (e *BackupEntry) TableName() string {
+	return "backup_entries"
+}
+
+func (e *BackupEntry) GetFileInfo() *BackupFileInfo {
+	return &BackupFileInfo{
+		FileID:    e.ID,
+		Sha256:    &e.Sha256,
+		SizeBytes: &e.SizeBytes,
+	}
+}
+
+// BackupObject is an entry of the table of contents of logical backup,
+// it tells what the backup contains without downloading it
+type BackupObject struct {
+	ID       uuid.UUID `json:"id"       gorm:"column:id;type:uuid;primaryKey"`
+	BackupID uuid.UUID `json:"backupId" gorm:"column:backup_id;type:uuid;not null"`
+	DumpID   int       `json:"dumpId"   gorm:"column:dump_id;not null"`
+
+	// Type is pg_dump object type, e.g. "TABLE", "TABLE DATA" or "INDEX"
+	Type   string `json:"type"   gorm:"column:object_type;type:text;not null"`
+	Schema string `json:"schema" gorm:"column:schema_name;type:text;not null"`
+	Name   string `json:"name"   gorm:"column:name;type:text;not null"`
+	Owner  string `json:"owner"  gorm:"column:owner;type:text;not null"`
+
+	// SizeBytes is size of the relation in the database when the
+	// backup was made, nil for objects without data of their own
+	SizeBytes *int64 `json:"sizeBytes" gorm:"column:size_bytes"`
+}
+
+func (o *BackupObject) TableName() string {
+	return "backup_objects"
+}
+
+// BackupCopy is the result of upload of the backup to one of the storages
+type BackupCopy struct {
+	ID          uuid.UUID        `json:"id"          gorm:"column:id;type:uuid;primaryKey"`
+	BackupID    uuid.UUID        `json:"backupId"    gorm:"column:backup_id;type:uuid;not null"`
+	StorageID   uuid.UUID        `json:"storageId"   gorm:"column:storage_id;type:uuid;not null"`
+	Status      BackupCopyStatus `json:"status"      gorm:"column:status;type:text;not null"`
+	FailMessage *string          `json:"failMessage" gorm:"column:fail_message"`
+
 	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at"`
 }
+
+func (c *BackupCopy) TableName() string {
+	return "backup_copies"
+}
+
+// BackupStorageMigration moves backups of the database to the storage the
+// database was switched to. Backups are moved one by one and the source file
+// is removed only after the backup points to the new storage, so migration
+// resumes from the next backup after restart
+type BackupStorageMigration struct {
+	ID         uuid.UUID                    `json:"id"         gorm:"column:id;type:uuid;primaryKey"`
+	DatabaseID uuid.UUID                    `json:"databaseId" gorm:"column:database_id;type:uuid;not null"`
+	StorageID  uuid.UUID                    `json:"storageId"  gorm:"column:storage_id;type:uuid;not null"`
+	Status     BackupStorageMigrationStatus `json:"status"     gorm:"column:status;type:text;not null"`
+
+	TotalBackups    int `json:"totalBackups"    gorm:"column:total_backups;not null"`
+	MigratedBackups int `json:"migratedBackups" gorm:"column:migrated_backups;not null"`
+	FailedBackups   int `json:"failedBackups"   gorm:"column:failed_backups;not null"`
+
+	// FailMessage is the error of the last backup which failed to move
+	FailMessage *string `json:"failMessage" gorm:"column:fail_message"`
+
+	CreatedAt  time.Time  `json:"createdAt"  gorm:"column:created_at"`
+	FinishedAt *time.Time `json:"finishedAt" gorm:"column:finished_at"`
+}
+
+func (m *BackupStorageMigration) TableName() string {
+	return "backup_storage_migrations"
+}
+
+// BackupQueueItem is a backup waiting for a free slot or running. Items
+// are stored, so queued backups survive restart and are removed when
+// the backup is finished
+type BackupQueueItem struct {
+	ID         uuid.UUID           `json:"id"         gorm:"column:id;type:uuid;primaryKey"`
+	DatabaseID uuid.UUID           `json:"databaseId" gorm:"column:database_id;type:uuid;not null"`
+	Database   *databases.Database `json:"-"          gorm:"foreignKey:DatabaseID"`
+
+	// StorageID, ExtraStorageIDs and SourceHost ("host:port") are used
+	// for the per storage and per host limits. The backup is written to
+	// all storages at once, so it takes a slot of each of them. Source
+	// host is the primary, also when the dump is taken from a replica:
+	// replicas replay every write of the primary, so the limit keeps
+	// backups from loading the whole cluster at once
+	StorageID             uuid.UUID   `json:"storageId"       gorm:"column:storage_id;type:uuid;not null"`
+	ExtraStorageIDs       []uuid.UUID `json:"extraStorageIds" gorm:"-"`
+	ExtraStorageIDsString string      `json:"-"               gorm:"column:extra_storage_ids;type:text;not null;default:''"`
+	SourceHost            string      `json:"sourceHost"      gorm:"column:source_host;type:text;not null"`
+
+	Priority  int                   `json:"priority"  gorm:"column:priority;not null"`
+	IsManual  bool                  `json:"isManual"  gorm:"column:is_manual;not null"`
+	IsLastTry bool                  `json:"isLastTry" gorm:"column:is_last_try;not null"`
+	Status    BackupQueueItemStatus `json:"status"    gorm:"column:status;type:text;not null"`
+
+	QueuedAt  time.Time  `json:"queuedAt"  gorm:"column:queued_at"`
+	StartedAt *time.Time `json:"startedAt" gorm:"column:started_at"`
+
+	// Labels of the manual backup, they are copied to the backup when it
+	// starts. Tags are stored as "key=value" lines, see encodeBackupTags
+	Label      *string      `json:"label" gorm:"column:label"`
+	Notes      *string      `json:"notes" gorm:"column:notes"`
+	TagsString string       `json:"-"     gorm:"column:tags;type:text;not null;default:''"`
+	Tags       []*BackupTag `json:"tags"  gorm:"-"`
+
+	// InstanceID of the worker which claimed the running item
+	InstanceID *uuid.UUID `json:"instanceId" gorm:"column:instance_id;type:uuid"`
+
+	// Position of not started item in the queue, starting from 1.
+	// WaitingReason tells which limit holds the waiting item
+	Position      int     `json:"position"      gorm:"-"`
+	WaitingReason *string `json:"waitingReason" gorm:"-"`
+}
+
+func (i *BackupQueueItem) TableName() string {
+	return "backup_queue_items"
+}
+
+func (i *BackupQueueItem) BeforeSave(tx *gorm.DB) error {
+	i.TagsString = encodeBackupTags(i.Tags)
+	i.ExtraStorageIDsString = encodeStorageIDs(i.ExtraStorageIDs)
+	return nil
+}
+
+func (i *BackupQueueItem) AfterFind(tx *gorm.DB) error {
+	i.Tags = decodeBackupTags(i.TagsString)
+
+	extraStorageIDs, err := decodeStorageIDs(i.ExtraStorageIDsString)
+	if err != nil {
+		return err
+	}
+
+	i.ExtraStorageIDs = extraStorageIDs
+	return nil
+}
+
+// GetStorageIDs returns the main storage followed by extra ones
+func (i *BackupQueueItem) GetStorageIDs() []uuid.UUID {
+	return append([]uuid.UUID{i.StorageID}, i.ExtraStorageIDs...)
+}
+
+func (i *BackupQueueItem) GetLabels() *BackupLabels {
+	return &BackupLabels{
+		Label: i.Label,
+		Notes: i.Notes,
+		Tags:  i.Tags,
+	}
+}
+
+func (b *Backup) BeforeSave(tx *gorm.DB) error {
+	b.BackupFilters.EncodeLists()
+	return nil
+}
+
+func (b *Backup) AfterFind(tx *gorm.DB) error {
+	b.BackupFilters.DecodeLists()
+	return nil
+}
+
+func (b *Backup) IsHeld(now time.Time) bool {
+	return b.IsPinned || (b.HoldUntil != nil && now.Before(*b.HoldUntil))
+}
+
+// GetFinishedAt returns time when the backup became consistent
+func (b *Backup) GetFinishedAt() time.Time {
+	return b.CreatedAt.Add(time.Duration(b.BackupDurationMs) * time.Millisecond)
+}
+
+// GetFileInfo returns the file of the backup, it is
+// not applicable to whole server backups
+func (b *Backup) GetFileInfo() *BackupFileInfo {
+	return &BackupFileInfo{
+		FileID:    b.ID,
+		Sha256:    b.Sha256,
+		SizeBytes: b.SizeBytes,
+	}
+}
+
+// GetStorageFileIDs returns IDs of all files the backup consists of
+func (b *Backup) GetStorageFileIDs() []uuid.UUID {
+	if !b.IsWholeServer {
+		return []uuid.UUID{b.ID}
+	}
+
+	fileIDs := make([]uuid.UUID, 0, len(b.Entries))
+	for _, entry := range b.Entries {
+		fileIDs = append(fileIDs, entry.ID)
+	}
+
+	return fileIDs
+}
+
+// GetCompletedStorageIDs returns storages which have a full copy of
+// the backup, the main storage goes first
+func (b *Backup) GetCompletedStorageIDs() []uuid.UUID {
+	storageIDs := []uuid.UUID{}
+
+	for _, storageCopy := range b.getStorageCopies() {
+		if storageCopy.Status != BackupCopyStatusCompleted {
+			continue
+		}
+
+		if storageCopy.StorageID == b.StorageID {
+			storageIDs = append([]uuid.UUID{storageCopy.StorageID}, storageIDs...)
+		} else {
+			storageIDs = append(storageIDs, storageCopy.StorageID)
+		}
+	}
+
+	return storageIDs
+}
+
+// getStorageCopies treats the main storage of backups made before
+// extra storages were introduced as the only completed copy
+func (b *Backup) getStorageCopies() []*BackupCopy {
+	if len(b.Copies) > 0 {
+		return b.Copies
+	}
+
+	return []*BackupCopy{{
+		BackupID:  b.ID,
+		StorageID: b.StorageID,
+		Status:    BackupCopyStatusCompleted,
+	}}
+}
+
+// GetGlobalsEntry returns roles and tablespaces of the whole server backup
+func (b *Backup) GetGlobalsEntry() *BackupEntry {
+	for _, entry := range b.Entries {
+		if entry.Kind == BackupEntryKindGlobals {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+func (b *Backup) GetDatabaseEntry(databaseName string) *BackupEntry {
+	for _, entry := range b.Entries {
+		if entry.Kind == BackupEntryKindDatabase &&
+			entry.DatabaseName != nil &&
+			*entry.DatabaseName == databaseName {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// BackupFileInfo points to a single file of the backup in storage
+// with the checksum it should match after decryption
+type BackupFileInfo struct {
+	FileID    uuid.UUID
+	Sha256    *string
+	SizeBytes *int64
+}
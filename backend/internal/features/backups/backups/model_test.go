@@ -0,0 +1,81 @@
+package backups
+
+import (
+	usecases_common "postgresus-backend/internal/features/backups/backups/usecases/common"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ToBackupEntries_WithGlobalsAndDatabases_EachDumpIsSeparateEntry(t *testing.T) {
+	backupID := uuid.New()
+	globalsID := uuid.New()
+	shopID := uuid.New()
+	analyticsID := uuid.New()
+
+	entries := (&BackupService{}).toBackupEntries(backupID, []*usecases_common.BackupEntryMetadata{
+		{ID: globalsID, IsGlobals: true, Sha256: "globals-sha", SizeBytes: 10},
+		{ID: shopID, DatabaseName: "shop", Sha256: "shop-sha", SizeBytes: 20},
+		{ID: analyticsID, DatabaseName: "analytics", Sha256: "analytics-sha", SizeBytes: 30},
+	})
+
+	require.Len(t, entries, 3)
+
+	backup := &Backup{ID: backupID, IsWholeServer: true, Entries: entries}
+
+	globalsEntry := backup.GetGlobalsEntry()
+	require.NotNil(t, globalsEntry)
+	assert.Equal(t, globalsID, globalsEntry.ID)
+	assert.Equal(t, BackupEntryKindGlobals, globalsEntry.Kind)
+	assert.Nil(t, globalsEntry.DatabaseName)
+
+	shopEntry := backup.GetDatabaseEntry("shop")
+	require.NotNil(t, shopEntry)
+	assert.Equal(t, shopID, shopEntry.ID)
+	assert.Equal(t, BackupEntryKindDatabase, shopEntry.Kind)
+	assert.Equal(t, "shop-sha", shopEntry.Sha256)
+	assert.Equal(t, int64(20), shopEntry.SizeBytes)
+
+	assert.Nil(t, backup.GetDatabaseEntry("missing"))
+
+	for _, entry := range entries {
+		assert.Equal(t, backupID, entry.BackupID)
+	}
+
+	// whole server backup has no file of its own
+	assert.Equal(t, []uuid.UUID{globalsID, shopID, analyticsID}, backup.GetStorageFileIDs())
+}
+
+func Test_GetBackupFileInfo_WhenWholeServerBackup_EntryIsRequired(t *testing.T) {
+	entry := &BackupEntry{
+		ID:        uuid.New(),
+		Kind:      BackupEntryKindGlobals,
+		Sha256:    "globals-sha",
+		SizeBytes: 10,
+	}
+	backup := &Backup{ID: uuid.New(), IsWholeServer: true, Entries: []*BackupEntry{entry}}
+
+	backupService := &BackupService{}
+
+	_, err := backupService.getBackupFileInfo(backup, nil)
+	assert.Error(t, err)
+
+	missingID := uuid.New()
+	_, err = backupService.getBackupFileInfo(backup, &missingID)
+	assert.Error(t, err)
+
+	fileInfo, err := backupService.getBackupFileInfo(backup, &entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entry.ID, fileInfo.FileID)
+	assert.Equal(t, "globals-sha", *fileInfo.Sha256)
+	assert.Equal(t, int64(10), *fileInfo.SizeBytes)
+
+	singleBackup := &Backup{ID: uuid.New()}
+
+	_, err = backupService.getBackupFileInfo(singleBackup, &entry.ID)
+	assert.Error(t, err)
+
+	assert.Equal(t, []uuid.UUID{singleBackup.ID}, singleBackup.GetStorageFileIDs())
+}
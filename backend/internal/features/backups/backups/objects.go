@@ -0,0 +1,107 @@
+package backups
+
+import (
+	"context"
+	"errors"
+	usecases_common "postgresus-backend/internal/features/backups/backups/usecases/common"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"postgresus-backend/internal/features/databases"
+	users_models "postgresus-backend/internal/features/users/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const indexBackupObjectsTimeout = 30 * time.Minute
+
+// GetBackupObjects returns table of contents of the backup. It is empty
+// for physical and whole server backups and backups made before objects
+// were indexed
+func (s *BackupService) GetBackupObjects(
+	user *users_models.User,
+	backupID uuid.UUID,
+	objectType string,
+	search string,
+) ([]*BackupObject, error) {
+	backup, err := s.backupRepository.FindByID(backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if backup.Database.UserID != user.ID {
+		return nil, errors.New("user does not have access to this backup")
+	}
+
+	return s.backupRepository.FindObjectsByBackupID(backupID, objectType, search)
+}
+
+// indexBackupObjects reads table of contents of the stored backup, so the
+// stored file is what is listed. It is best effort, the backup stays
+// completed if objects cannot be listed
+func (s *BackupService) indexBackupObjects(
+	database *databases.Database,
+	backup *Backup,
+) {
+	if backup.BackupMethod != backups_config.BackupMethodLogical || backup.IsWholeServer {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), indexBackupObjectsTimeout)
+	defer cancel()
+
+	fileReader, err := s.openBackupFile(backup, backup.ID)
+	if err != nil {
+		s.logger.Warn("Failed to open backup to list objects", "backupId", backup.ID, "error", err)
+		return
+	}
+
+	if backup.IsEncrypted {
+		fileReader, err = s.decryptBackupFile(backup, fileReader)
+		if err != nil {
+			s.logger.Warn("Failed to decrypt backup to list objects", "backupId", backup.ID, "error", err)
+			return
+		}
+	}
+	defer func() {
+		_ = fileReader.Close()
+	}()
+
+	objectsMetadata, err := s.createBackupUseCase.ListBackupObjects(
+		ctx,
+		database,
+		backup.DumpFormat,
+		fileReader,
+	)
+	if err != nil {
+		s.logger.Warn("Failed to list backup objects", "backupId", backup.ID, "error", err)
+		return
+	}
+
+	if err := s.backupRepository.SaveObjects(
+		s.toBackupObjects(backup.ID, objectsMetadata),
+	); err != nil {
+		s.logger.Error("Failed to save backup objects", "backupId", backup.ID, "error", err)
+	}
+}
+
+func (s *BackupService) toBackupObjects(
+	backupID uuid.UUID,
+	objectsMetadata []*usecases_common.BackupObjectMetadata,
+) []*BackupObject {
+	objects := make([]*BackupObject, 0, len(objectsMetadata))
+
+	for _, objectMetadata := range objectsMetadata {
+		objects = append(objects, &BackupObject{
+			ID:        uuid.New(),
+			BackupID:  backupID,
+			DumpID:    objectMetadata.DumpID,
+			Type:      objectMetadata.Type,
+			Schema:    objectMetadata.Schema,
+			Name:      objectMetadata.Name,
+			Owner:     objectMetadata.Owner,
+			SizeBytes: objectMetadata.SizeBytes,
+		})
+	}
+
+	return objects
+}
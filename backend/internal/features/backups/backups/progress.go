@@ -0,0 +1,106 @@
+package backups
+
+import (
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"time"
+)
+
+const (
+	// compressionRatioSampleSize is how many latest backups
+	// are used to calculate compression ratio of the database
+	compressionRatioSampleSize = 5
+
+	// estimate can be lower than the real size, so 100% is
+	// shown only when the backup is completed
+	maxInProgressPercent = 99.0
+)
+
+// estimateBackupSizeMb predicts backup size from size of the source data.
+// Ratio of past backups is preferred over the default one, because it
+// already accounts for filters, compression and how well the data compresses
+func estimateBackupSizeMb(
+	sourceSizeBytes int64,
+	pastBackups []*Backup,
+	backupConfig *backups_config.BackupConfig,
+) float64 {
+	if sourceSizeBytes <= 0 {
+		return 0
+	}
+
+	sourceSizeMb := float64(sourceSizeBytes) / (1024 * 1024)
+
+	var pastSourceSizeMb float64
+	var pastBackupSizeMb float64
+
+	for _, backup := range pastBackups {
+		if backup.SourceSizeBytes == nil ||
+			*backup.SourceSizeBytes <= 0 ||
+			backup.BackupSizeMb <= 0 {
+			continue
+		}
+
+		pastSourceSizeMb += float64(*backup.SourceSizeBytes) / (1024 * 1024)
+		pastBackupSizeMb += backup.BackupSizeMb
+	}
+
+	if pastSourceSizeMb > 0 {
+		return sourceSizeMb * pastBackupSizeMb / pastSourceSizeMb
+	}
+
+	return sourceSizeMb * getDefaultCompressionRatio(backupConfig)
+}
+
+// getDefaultCompressionRatio is a rough guess used
+// until the database has finished backups
+func getDefaultCompressionRatio(backupConfig *backups_config.BackupConfig) float64 {
+	// pg_basebackup output is uncompressed tar of data files
+	if backupConfig.BackupMethod == backups_config.BackupMethodPhysical {
+		return 1
+	}
+
+	switch backupConfig.Compression {
+	case backups_config.BackupCompressionNone:
+		return 1
+	case backups_config.BackupCompressionLz4:
+		return 0.45
+	default:
+		return 0.3
+	}
+}
+
+// updateProgress refreshes percentage and ETA of the running backup.
+// ETA assumes the rest is written with the average speed so far
+func (b *Backup) updateProgress(completedMBs float64, elapsed time.Duration, now time.Time) {
+	b.BackupSizeMb = completedMBs
+	b.BackupDurationMs = elapsed.Milliseconds()
+
+	if b.EstimatedSizeMb == nil || *b.EstimatedSizeMb <= 0 {
+		return
+	}
+
+	percent := min(completedMBs / *b.EstimatedSizeMb * 100, maxInProgressPercent)
+	b.ProgressPercent = &percent
+
+	// the estimate is exceeded, so there is nothing to base ETA on
+	if completedMBs <= 0 || completedMBs >= *b.EstimatedSizeMb {
+		b.EstimatedFinishAt = nil
+		return
+	}
+
+	remaining := time.Duration(
+		float64(elapsed) * (*b.EstimatedSizeMb - completedMBs) / completedMBs,
+	)
+
+	estimatedFinishAt := now.Add(remaining)
+	b.EstimatedFinishAt = &estimatedFinishAt
+}
+
+// finishProgress is called when the backup is not running anymore
+func (b *Backup) finishProgress(isCompleted bool) {
+	b.EstimatedFinishAt = nil
+
+	if isCompleted && b.EstimatedSizeMb != nil {
+		percent := 100.0
+		b.ProgressPercent = &percent
+	}
+}
@@ -0,0 +1,90 @@
+package backups
+
+import (
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EstimateBackupSizeMb_WithPastBackups_UsesTheirRatio(t *testing.T) {
+	backupConfig := &backups_config.BackupConfig{
+		BackupMethod: backups_config.BackupMethodLogical,
+		Compression:  backups_config.BackupCompressionGzip,
+	}
+
+	pastBackups := []*Backup{
+		newPastBackup(100, 20),
+		newPastBackup(300, 40),
+		{BackupSizeMb: 500}, // made before source size was measured
+	}
+
+	estimatedSizeMb := estimateBackupSizeMb(200*1024*1024, pastBackups, backupConfig)
+
+	assert.InDelta(t, 30.0, estimatedSizeMb, 0.001)
+}
+
+func Test_EstimateBackupSizeMb_WithoutPastBackups_UsesDefaultRatio(t *testing.T) {
+	logicalConfig := &backups_config.BackupConfig{
+		BackupMethod: backups_config.BackupMethodLogical,
+		Compression:  backups_config.BackupCompressionNone,
+	}
+	physicalConfig := &backups_config.BackupConfig{
+		BackupMethod: backups_config.BackupMethodPhysical,
+	}
+
+	assert.InDelta(t, 100.0, estimateBackupSizeMb(100*1024*1024, nil, logicalConfig), 0.001)
+	assert.InDelta(t, 100.0, estimateBackupSizeMb(100*1024*1024, nil, physicalConfig), 0.001)
+	assert.Equal(t, 0.0, estimateBackupSizeMb(0, nil, logicalConfig))
+}
+
+func Test_UpdateProgress_WithEstimate_CalculatesPercentAndEta(t *testing.T) {
+	estimatedSizeMb := 100.0
+	backup := &Backup{EstimatedSizeMb: &estimatedSizeMb}
+	now := time.Date(2025, 11, 22, 10, 0, 0, 0, time.UTC)
+
+	backup.updateProgress(25, 1*time.Minute, now)
+
+	require.NotNil(t, backup.ProgressPercent)
+	require.NotNil(t, backup.EstimatedFinishAt)
+	assert.InDelta(t, 25.0, *backup.ProgressPercent, 0.001)
+	assert.Equal(t, now.Add(3*time.Minute), *backup.EstimatedFinishAt)
+	assert.Equal(t, 25.0, backup.BackupSizeMb)
+	assert.Equal(t, int64(60000), backup.BackupDurationMs)
+}
+
+func Test_UpdateProgress_WhenEstimateExceeded_KeepsPercentBelowHundred(t *testing.T) {
+	estimatedSizeMb := 100.0
+	backup := &Backup{EstimatedSizeMb: &estimatedSizeMb}
+
+	backup.updateProgress(120, 1*time.Minute, time.Now().UTC())
+
+	require.NotNil(t, backup.ProgressPercent)
+	assert.Equal(t, maxInProgressPercent, *backup.ProgressPercent)
+	assert.Nil(t, backup.EstimatedFinishAt)
+
+	backup.finishProgress(true)
+
+	assert.Equal(t, 100.0, *backup.ProgressPercent)
+}
+
+func Test_UpdateProgress_WithoutEstimate_UpdatesOnlySize(t *testing.T) {
+	backup := &Backup{}
+
+	backup.updateProgress(10, 1*time.Minute, time.Now().UTC())
+
+	assert.Equal(t, 10.0, backup.BackupSizeMb)
+	assert.Nil(t, backup.ProgressPercent)
+	assert.Nil(t, backup.EstimatedFinishAt)
+}
+
+func newPastBackup(sourceSizeMb int64, backupSizeMb float64) *Backup {
+	sourceSizeBytes := sourceSizeMb * 1024 * 1024
+
+	return &Backup{
+		SourceSizeBytes: &sourceSizeBytes,
+		BackupSizeMb:    backupSizeMb,
+	}
+}
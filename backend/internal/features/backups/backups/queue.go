@@ -0,0 +1,236 @@
+package backups
+
+import (
+	"errors"
+	"fmt"
+	"postgresus-backend/internal/config"
+	users_models "postgresus-backend/internal/features/users/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BackupQueueLimits struct {
+	MaxConcurrency int
+
+	// zero means no limit
+	MaxConcurrencyPerHost    int
+	MaxConcurrencyPerStorage int
+}
+
+func GetBackupQueueLimits() BackupQueueLimits {
+	return BackupQueueLimits{
+		MaxConcurrency:           config.GetEnv().BackupMaxConcurrency,
+		MaxConcurrencyPerHost:    config.GetEnv().BackupMaxConcurrencyPerHost,
+		MaxConcurrencyPerStorage: config.GetEnv().BackupMaxConcurrencyPerStorage,
+	}
+}
+
+// GetBackupQueueWithAuth returns running and queued backups of the user
+// databases. Positions are in the whole queue, so they include backups
+// of other users
+func (s *BackupService) GetBackupQueueWithAuth(
+	user *users_models.User,
+) ([]*BackupQueueItem, error) {
+	items, err := s.backupRepository.FindQueueItems()
+	if err != nil {
+		return nil, err
+	}
+
+	planBackupQueue(items, GetBackupQueueLimits())
+
+	userItems := []*BackupQueueItem{}
+	for _, item := range items {
+		if item.Database != nil && item.Database.UserID == user.ID {
+			userItems = append(userItems, item)
+		}
+	}
+
+	return userItems, nil
+}
+
+// enqueueBackup adds backup of the database to the queue. Database has
+// at most one item, so a due backup is not queued twice while it waits.
+// Labels are nil for scheduled backups
+func (s *BackupService) enqueueBackup(
+	databaseID uuid.UUID,
+	isManual, isLastTry bool,
+	labels *BackupLabels,
+) error {
+	existingItem, err := s.backupRepository.FindQueueItemByDatabaseID(databaseID)
+	if err != nil {
+		return err
+	}
+
+	if existingItem != nil {
+		if isManual {
+			return errors.New("backup of the database is already queued")
+		}
+
+		return nil
+	}
+
+	database, err := s.databaseService.GetDatabaseByID(databaseID)
+	if err != nil {
+		return err
+	}
+
+	backupConfig, err := s.backupConfigService.GetBackupConfigByDbId(databaseID)
+	if err != nil {
+		return err
+	}
+
+	if !backupConfig.IsBackupsEnabled {
+		return errors.New("backups are not enabled for this database")
+	}
+
+	if backupConfig.StorageID == nil {
+		return errors.New("backup config storage ID is not defined")
+	}
+
+	// the limit is per primary, see BackupQueueItem
+	sourceHost := ""
+	if database.Postgresql != nil {
+		sourceHost = database.Postgresql.GetEndpoint()
+	}
+
+	item := &BackupQueueItem{
+		DatabaseID:      databaseID,
+		StorageID:       *backupConfig.StorageID,
+		ExtraStorageIDs: append([]uuid.UUID{}, backupConfig.ExtraStorageIDs...),
+		SourceHost:      sourceHost,
+		Priority:        backupConfig.BackupPriority,
+		IsManual:        isManual,
+		IsLastTry:       isLastTry,
+		Status:          BackupQueueItemStatusQueued,
+		QueuedAt:        time.Now().UTC(),
+	}
+
+	if labels != nil {
+		item.Label = labels.Label
+		item.Notes = labels.Notes
+		item.Tags = labels.Tags
+	}
+
+	return s.backupRepository.SaveQueueItem(item)
+}
+
+// runQueueItem makes the backup and frees the slot of the item
+func (s *BackupService) runQueueItem(item *BackupQueueItem) {
+	defer func() {
+		if err := s.backupRepository.DeleteQueueItem(item.ID); err != nil {
+			s.logger.Error("Failed to remove backup queue item", "itemId", item.ID, "error", err)
+		}
+	}()
+
+	s.MakeBackup(item.DatabaseID, item.IsLastTry, item.GetLabels())
+}
+
+// planBackupQueue returns queued items which can start now. Items should
+// be ordered as they should start with running items first. Running items
+// take their slots first, then each queued item takes a slot if limits of
+// its host and storage allow it. Queued items get position, items held by
+// the host or storage limit become waiting, so an item of a busy host does
+// not hold the items behind it
+func planBackupQueue(items []*BackupQueueItem, limits BackupQueueLimits) []*BackupQueueItem {
+	runningCount := 0
+	runningByHost := map[string]int{}
+	runningByStorage := map[uuid.UUID]int{}
+
+	for _, item := range items {
+		if item.Status == BackupQueueItemStatusRunning {
+			runningCount++
+			runningByHost[item.SourceHost]++
+
+			for _, storageID := range item.GetStorageIDs() {
+				runningByStorage[storageID]++
+			}
+		}
+	}
+
+	itemsToStart := []*BackupQueueItem{}
+	position := 0
+
+	for _, item := range items {
+		if item.Status == BackupQueueItemStatusRunning {
+			continue
+		}
+
+		position++
+		item.Position = position
+
+		if runningCount >= limits.MaxConcurrency {
+			continue
+		}
+
+		var waitingReason string
+		if limits.MaxConcurrencyPerHost > 0 &&
+			runningByHost[item.SourceHost] >= limits.MaxConcurrencyPerHost {
+			waitingReason = fmt.Sprintf(
+				"%d backups of host %s are running",
+				runningByHost[item.SourceHost],
+				item.SourceHost,
+			)
+		}
+
+		// backup writes to all its storages, so any busy one holds it
+		if waitingReason == "" && limits.MaxConcurrencyPerStorage > 0 {
+			for _, storageID := range item.GetStorageIDs() {
+				if runningByStorage[storageID] >= limits.MaxConcurrencyPerStorage {
+					waitingReason = fmt.Sprintf(
+						"%d backups to the storage are running",
+						runningByStorage[storageID],
+					)
+					break
+				}
+			}
+		}
+
+		if waitingReason != "" {
+			item.Status = BackupQueueItemStatusWaiting
+			item.WaitingReason = &waitingReason
+			continue
+		}
+
+		runningCount++
+		runningByHost[item.SourceHost]++
+
+		for _, storageID := range item.GetStorageIDs() {
+			runningByStorage[storageID]++
+		}
+
+		itemsToStart = append(itemsToStart, item)
+	}
+
+	return itemsToStart
+}
+
+// encodeStorageIDs stores storage IDs of the queue item as a comma
+// separated list, the same way as extra storages of the config
+func encodeStorageIDs(storageIDs []uuid.UUID) string {
+	storageIDTexts := make([]string, len(storageIDs))
+	for i, storageID := range storageIDs {
+		storageIDTexts[i] = storageID.String()
+	}
+
+	return strings.Join(storageIDTexts, ",")
+}
+
+func decodeStorageIDs(encoded string) ([]uuid.UUID, error) {
+	storageIDs := []uuid.UUID{}
+	if encoded == "" {
+		return storageIDs, nil
+	}
+
+	for _, storageIDText := range strings.Split(encoded, ",") {
+		storageID, err := uuid.Parse(storageIDText)
+		if err != nil {
+			return nil, err
+		}
+
+		storageIDs = append(storageIDs, storageID)
+	}
+
+	return storageIDs, nil
+}
@@ -0,0 +1,108 @@
+package backups
+
+import (
+	"log/slog"
+	"postgresus-backend/internal/config"
+	system_instances "postgresus-backend/internal/features/system/instances"
+	"time"
+)
+
+// queue is checked more often than schedules, so the next backup
+// starts soon after a slot is freed
+const backupQueueDispatchInterval = 5 * time.Second
+
+// backupQueueAdvisoryLockKey serializes claims of queue items between workers
+const backupQueueAdvisoryLockKey = 40050001
+
+// BackupQueueBackgroundService starts queued backups when there are free
+// slots. Each background instance claims items for itself, so backups are
+// spread between workers. Running items of dead workers are removed
+type BackupQueueBackgroundService struct {
+	backupService    *BackupService
+	backupRepository *BackupRepository
+	instanceService  *system_instances.InstanceService
+	logger           *slog.Logger
+}
+
+func (s *BackupQueueBackgroundService) Run() {
+	for {
+		if config.IsShouldShutdown() {
+			return
+		}
+
+		if err := s.removeOrphanedQueueItems(); err != nil {
+			s.logger.Error("Failed to remove orphaned backup queue items", "error", err)
+		}
+
+		if err := s.dispatchQueuedBackups(); err != nil {
+			s.logger.Error("Failed to dispatch queued backups", "error", err)
+		}
+
+		time.Sleep(backupQueueDispatchInterval)
+	}
+}
+
+func (s *BackupQueueBackgroundService) dispatchQueuedBackups() error {
+	claimedItems, err := s.backupRepository.ClaimQueueItems(
+		s.instanceService.GetInstanceID(),
+		func(items []*BackupQueueItem) []*BackupQueueItem {
+			return planBackupQueue(items, GetBackupQueueLimits())
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range claimedItems {
+		s.logger.Info(
+			"Starting queued backup",
+			"databaseId",
+			item.DatabaseID,
+			"priority",
+			item.Priority,
+			"waitedFor",
+			item.StartedAt.Sub(item.QueuedAt),
+		)
+
+		go s.backupService.runQueueItem(item)
+	}
+
+	return nil
+}
+
+// removeOrphanedQueueItems frees slots of items whose worker stopped sending
+// heartbeats. Their backups are failed by the scheduler and retried as usual
+func (s *BackupQueueBackgroundService) removeOrphanedQueueItems() error {
+	items, err := s.backupRepository.FindQueueItems()
+	if err != nil {
+		return err
+	}
+
+	// read after the items, so worker which claimed
+	// one of them is already registered
+	aliveInstances, err := s.instanceService.GetAliveInstances()
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if item.Status != BackupQueueItemStatusRunning ||
+			aliveInstances.Contains(item.InstanceID) {
+			continue
+		}
+
+		s.logger.Warn(
+			"Removing backup queue item of stopped instance",
+			"databaseId",
+			item.DatabaseID,
+			"instanceId",
+			item.InstanceID,
+		)
+
+		if err := s.backupRepository.DeleteQueueItem(item.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package backups
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PlanBackupQueue_WhenHostIsBusy_NextItemsStart(t *testing.T) {
+	storageID := uuid.New()
+
+	items := []*BackupQueueItem{
+		{SourceHost: "db-1:5432", StorageID: storageID, Status: BackupQueueItemStatusRunning},
+		{SourceHost: "db-1:5432", StorageID: storageID, Status: BackupQueueItemStatusQueued},
+		{SourceHost: "db-2:5432", StorageID: storageID, Status: BackupQueueItemStatusQueued},
+		{SourceHost: "db-3:5432", StorageID: storageID, Status: BackupQueueItemStatusQueued},
+	}
+
+	itemsToStart := planBackupQueue(items, BackupQueueLimits{
+		MaxConcurrency:        3,
+		MaxConcurrencyPerHost: 1,
+	})
+
+	require.Len(t, itemsToStart, 2)
+	assert.Equal(t, "db-2:5432", itemsToStart[0].SourceHost)
+	assert.Equal(t, "db-3:5432", itemsToStart[1].SourceHost)
+
+	assert.Equal(t, BackupQueueItemStatusWaiting, items[1].Status)
+	assert.NotNil(t, items[1].WaitingReason)
+	assert.Equal(t, []int{0, 1, 2, 3}, []int{
+		items[0].Position,
+		items[1].Position,
+		items[2].Position,
+		items[3].Position,
+	})
+}
+
+func Test_PlanBackupQueue_WhenSlotsAreTaken_ItemsStayQueued(t *testing.T) {
+	firstStorageID := uuid.New()
+	secondStorageID := uuid.New()
+
+	items := []*BackupQueueItem{
+		{SourceHost: "db-1:5432", StorageID: firstStorageID, Status: BackupQueueItemStatusQueued},
+		{SourceHost: "db-2:5432", StorageID: firstStorageID, Status: BackupQueueItemStatusQueued},
+		{SourceHost: "db-3:5432", StorageID: secondStorageID, Status: BackupQueueItemStatusQueued},
+		{SourceHost: "db-4:5432", StorageID: secondStorageID, Status: BackupQueueItemStatusQueued},
+	}
+
+	itemsToStart := planBackupQueue(items, BackupQueueLimits{
+		MaxConcurrency:           2,
+		MaxConcurrencyPerStorage: 1,
+	})
+
+	require.Len(t, itemsToStart, 2)
+	assert.Same(t, items[0], itemsToStart[0])
+	assert.Same(t, items[2], itemsToStart[1])
+
+	// held by the storage limit first, the global limit is reached later
+	assert.Equal(t, BackupQueueItemStatusWaiting, items[1].Status)
+	assert.Equal(t, BackupQueueItemStatusQueued, items[3].Status)
+	assert.Nil(t, items[3].WaitingReason)
+}
+
+func Test_PlanBackupQueue_WhenExtraStorageIsBusy_ItemWaits(t *testing.T) {
+	s3StorageID := uuid.New()
+	nasStorageID := uuid.New()
+	localStorageID := uuid.New()
+
+	items := []*BackupQueueItem{
+		{
+			SourceHost:      "db-1:5432",
+			StorageID:       s3StorageID,
+			ExtraStorageIDs: []uuid.UUID{nasStorageID},
+			Status:          BackupQueueItemStatusRunning,
+		},
+		{SourceHost: "db-2:5432", StorageID: nasStorageID, Status: BackupQueueItemStatusQueued},
+		{
+			SourceHost:      "db-3:5432",
+			StorageID:       localStorageID,
+			ExtraStorageIDs: []uuid.UUID{s3StorageID},
+			Status:          BackupQueueItemStatusQueued,
+		},
+		{SourceHost: "db-4:5432", StorageID: localStorageID, Status: BackupQueueItemStatusQueued},
+	}
+
+	itemsToStart := planBackupQueue(items, BackupQueueLimits{
+		MaxConcurrency:           3,
+		MaxConcurrencyPerStorage: 1,
+	})
+
+	require.Len(t, itemsToStart, 1)
+	assert.Same(t, items[3], itemsToStart[0])
+
+	// running backup writes to the NAS as well, so the NAS is busy
+	assert.Equal(t, BackupQueueItemStatusWaiting, items[1].Status)
+	assert.Equal(t, BackupQueueItemStatusWaiting, items[2].Status)
+}
+
+func Test_EncodeStorageIDs_WhenDecoded_StorageIDsAreSame(t *testing.T) {
+	storageIDs := []uuid.UUID{uuid.New(), uuid.New()}
+
+	decodedStorageIDs, err := decodeStorageIDs(encodeStorageIDs(storageIDs))
+	require.NoError(t, err)
+	assert.Equal(t, storageIDs, decodedStorageIDs)
+
+	decodedStorageIDs, err = decodeStorageIDs(encodeStorageIDs(nil))
+	require.NoError(t, err)
+	assert.Empty(t, decodedStorageIDs)
+}
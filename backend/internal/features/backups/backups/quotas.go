@@ -0,0 +1,315 @@
+package backups
+
+import (
+	"fmt"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"postgresus-backend/internal/features/storages"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// backupQuota caps completed backups, zero means no cap
+type backupQuota struct {
+	MaxCount  int
+	MaxSizeMb float64
+}
+
+// nextBackup is room reserved for the backup which is about to start
+type nextBackup struct {
+	Count  int
+	SizeMb float64
+}
+
+// enforceQuotas prunes the oldest backups, so backups of the database and
+// its storages fit their caps. Before a backup starts, room for it is
+// reserved. Error is returned when storage quota cannot be met, so the
+// backup fails before the dump instead of filling the storage
+func (s *BackupService) enforceQuotas(
+	backupConfig *backups_config.BackupConfig,
+	backupStorages []*storages.Storage,
+	next nextBackup,
+) error {
+	if backupConfig.HasCaps() {
+		dbBackups, err := s.backupRepository.FindByDatabaseID(backupConfig.DatabaseID)
+		if err != nil {
+			return err
+		}
+
+		// database caps never block the backup, the newest one is kept
+		backupsToPrune, _ := getBackupsToPruneByQuota(
+			dbBackups,
+			backupQuota{
+				MaxCount:  backupConfig.MaxBackupsCount,
+				MaxSizeMb: float64(backupConfig.MaxBackupsSizeMb),
+			},
+			next,
+			time.Now().UTC(),
+		)
+
+		s.pruneBackups(backupsToPrune, "the database backups cap is reached")
+	}
+
+	for _, storage := range backupStorages {
+		if storage.QuotaMb <= 0 {
+			continue
+		}
+
+		storageBackups, err := s.backupRepository.FindCompletedByStorageID(storage.ID)
+		if err != nil {
+			return err
+		}
+
+		backupsToPrune, _ := getBackupsToPruneByQuota(
+			storageBackups,
+			backupQuota{MaxSizeMb: float64(storage.QuotaMb)},
+			nextBackup{SizeMb: next.SizeMb},
+			time.Now().UTC(),
+		)
+
+		s.pruneStorageCopies(
+			storage,
+			backupsToPrune,
+			fmt.Sprintf("quota of storage \"%s\" is reached", storage.Name),
+		)
+
+		if next.Count == 0 {
+			continue
+		}
+
+		// some backups may be not pruned, e.g. while they are restored,
+		// so the quota is checked against what is left in the storage
+		storageBackups, err = s.backupRepository.FindCompletedByStorageID(storage.ID)
+		if err != nil {
+			return err
+		}
+
+		if getBackupsSizeMb(storageBackups)+next.SizeMb > float64(storage.QuotaMb) {
+			return fmt.Errorf(
+				"quota of storage \"%s\" (%d MB) is not enough for the backup, estimated size is %.2f MB",
+				storage.Name,
+				storage.QuotaMb,
+				next.SizeMb,
+			)
+		}
+	}
+
+	return nil
+}
+
+// pruneBackups removes backups and notifies each database about its
+// pruned backups. Backups which cannot be removed are skipped
+func (s *BackupService) pruneBackups(backups []*Backup, reason string) {
+	var prunedBackups []*Backup
+
+	for _, backup := range backups {
+		if err := s.deleteBackup(backup); err != nil {
+			s.logger.Error("Failed to prune backup", "backupId", backup.ID, "error", err)
+			continue
+		}
+
+		s.logger.Info(
+			"Pruned backup",
+			"backupId",
+			backup.ID,
+			"databaseId",
+			backup.DatabaseID,
+			"reason",
+			reason,
+		)
+
+		prunedBackups = append(prunedBackups, backup)
+	}
+
+	s.notifyPrunedBackups(prunedBackups, "oldest backups", reason)
+}
+
+// pruneStorageCopies frees the storage only, copies of the backups in
+// other storages are kept. Backup which has no other completed copy is
+// removed as a whole
+func (s *BackupService) pruneStorageCopies(
+	storage *storages.Storage,
+	backups []*Backup,
+	reason string,
+) {
+	var backupsToRemove []*Backup
+	var prunedBackups []*Backup
+
+	for _, backup := range backups {
+		otherStorageIDs := slices.DeleteFunc(
+			backup.GetCompletedStorageIDs(),
+			func(storageID uuid.UUID) bool { return storageID == storage.ID },
+		)
+
+		if len(otherStorageIDs) == 0 {
+			backupsToRemove = append(backupsToRemove, backup)
+			continue
+		}
+
+		if err := s.deleteStorageCopy(backup, storage, otherStorageIDs[0]); err != nil {
+			s.logger.Error(
+				"Failed to prune backup copy",
+				"backupId",
+				backup.ID,
+				"storageId",
+				storage.ID,
+				"error",
+				err,
+			)
+			continue
+		}
+
+		s.logger.Info(
+			"Pruned backup copy",
+			"backupId",
+			backup.ID,
+			"storageId",
+			storage.ID,
+			"reason",
+			reason,
+		)
+
+		prunedBackups = append(prunedBackups, backup)
+	}
+
+	s.pruneBackups(backupsToRemove, reason)
+
+	s.notifyPrunedBackups(
+		prunedBackups,
+		fmt.Sprintf("oldest backup copies in storage \"%s\"", storage.Name),
+		reason,
+	)
+}
+
+// deleteStorageCopy removes files of the backup from the storage. Backup
+// which was made to this storage is moved to the storage of another copy,
+// so it keeps pointing to a readable file
+func (s *BackupService) deleteStorageCopy(
+	backup *Backup,
+	storage *storages.Storage,
+	nextStorageID uuid.UUID,
+) error {
+	for _, fileID := range backup.GetStorageFileIDs() {
+		if err := storage.DeleteFile(fileID); err != nil {
+			return err
+		}
+	}
+
+	if backup.StorageID == storage.ID {
+		if _, err := s.backupRepository.UpdateStorageID(backup.ID, nextStorageID); err != nil {
+			return err
+		}
+	}
+
+	return s.backupRepository.DeleteCopy(backup.ID, storage.ID)
+}
+
+func (s *BackupService) notifyPrunedBackups(backups []*Backup, what string, reason string) {
+	prunedByDatabase := map[uuid.UUID][]*Backup{}
+	var databaseIDs []uuid.UUID
+
+	for _, backup := range backups {
+		if !slices.Contains(databaseIDs, backup.DatabaseID) {
+			databaseIDs = append(databaseIDs, backup.DatabaseID)
+		}
+
+		prunedByDatabase[backup.DatabaseID] = append(prunedByDatabase[backup.DatabaseID], backup)
+	}
+
+	for _, databaseID := range databaseIDs {
+		backupConfig, err := s.backupConfigService.GetBackupConfigByDbId(databaseID)
+		if err != nil {
+			s.logger.Error("Failed to get backup config by database ID", "error", err)
+			continue
+		}
+
+		prunedBackups := prunedByDatabase[databaseID]
+
+		message := fmt.Sprintf(
+			"%d %s (%.2f MB) were pruned because %s",
+			len(prunedBackups),
+			what,
+			getBackupsSizeMb(prunedBackups),
+			reason,
+		)
+
+		s.SendBackupNotification(
+			backupConfig,
+			nil,
+			backups_config.NotificationBackupsPruned,
+			&message,
+		)
+	}
+}
+
+// getBackupsToPruneByQuota returns the oldest completed backups to prune,
+// so the rest and the next backup fit the quota. Backups should be ordered
+// from the newest to the oldest ones. The newest backup of each database and
+// held backups are never pruned, so it returns false when the quota still
+// cannot be met
+func getBackupsToPruneByQuota(
+	backups []*Backup,
+	quota backupQuota,
+	next nextBackup,
+	now time.Time,
+) ([]*Backup, bool) {
+	var completedBackups []*Backup
+	for _, backup := range backups {
+		if backup.Status == BackupStatusCompleted {
+			completedBackups = append(completedBackups, backup)
+		}
+	}
+
+	count := len(completedBackups)
+	sizeMb := getBackupsSizeMb(completedBackups)
+
+	isFit := func() bool {
+		return (quota.MaxCount == 0 || count+next.Count <= quota.MaxCount) &&
+			(quota.MaxSizeMb == 0 || sizeMb+next.SizeMb <= quota.MaxSizeMb)
+	}
+
+	newestBackupIDs := map[uuid.UUID]bool{}
+	seenDatabaseIDs := map[uuid.UUID]bool{}
+	for _, backup := range completedBackups {
+		if !seenDatabaseIDs[backup.DatabaseID] {
+			seenDatabaseIDs[backup.DatabaseID] = true
+			newestBackupIDs[backup.ID] = true
+		}
+	}
+
+	var backupsToPrune []*Backup
+
+	for i := len(completedBackups) - 1; i >= 0 && !isFit(); i-- {
+		backup := completedBackups[i]
+
+		if newestBackupIDs[backup.ID] || backup.IsHeld(now) {
+			continue
+		}
+
+		backupsToPrune = append(backupsToPrune, backup)
+		count--
+		sizeMb -= backup.BackupSizeMb
+	}
+
+	return backupsToPrune, isFit()
+}
+
+func getBackupsSizeMb(backups []*Backup) float64 {
+	sizeMb := 0.0
+	for _, backup := range backups {
+		sizeMb += backup.BackupSizeMb
+	}
+
+	return sizeMb
+}
+
+func getNextBackup(backup *Backup) nextBackup {
+	next := nextBackup{Count: 1}
+
+	if backup.EstimatedSizeMb != nil {
+		next.SizeMb = *backup.EstimatedSizeMb
+	}
+
+	return next
+}
@@ -0,0 +1,210 @@
+package backups
+
+import (
+	backups_config "postgresus-backend/internal/features/backups/config"
+	backups_encryption "postgresus-backend/internal/features/backups/encryption"
+	"postgresus-backend/internal/features/databases"
+	"postgresus-backend/internal/features/notifiers"
+	"postgresus-backend/internal/features/storages"
+	system_instances "postgresus-backend/internal/features/system/instances"
+	"postgresus-backend/internal/features/users"
+	cancellation_utils "postgresus-backend/internal/util/cancellation"
+	"postgresus-backend/internal/util/logger"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetBackupsToPruneByQuota_WhenCountCapReached_OldestBackupsPrunedWithRoomForNext(
+	t *testing.T,
+) {
+	databaseID := uuid.New()
+
+	newest := newQuotaTestBackup(databaseID, BackupStatusCompleted, 10)
+	failed := newQuotaTestBackup(databaseID, BackupStatusFailed, 0)
+	second := newQuotaTestBackup(databaseID, BackupStatusCompleted, 10)
+	third := newQuotaTestBackup(databaseID, BackupStatusCompleted, 10)
+	oldest := newQuotaTestBackup(databaseID, BackupStatusCompleted, 10)
+
+	backupsToPrune, isQuotaMet := getBackupsToPruneByQuota(
+		[]*Backup{newest, failed, second, third, oldest},
+		backupQuota{MaxCount: 3},
+		nextBackup{Count: 1},
+		time.Now().UTC(),
+	)
+
+	assert.True(t, isQuotaMet)
+	assert.Equal(t, []*Backup{oldest, third}, backupsToPrune)
+}
+
+func Test_GetBackupsToPruneByQuota_WhenSizeQuotaCannotBeMet_NewestBackupOfEachDatabaseKept(
+	t *testing.T,
+) {
+	firstDatabaseID := uuid.New()
+	secondDatabaseID := uuid.New()
+
+	firstNewest := newQuotaTestBackup(firstDatabaseID, BackupStatusCompleted, 300)
+	secondNewest := newQuotaTestBackup(secondDatabaseID, BackupStatusCompleted, 300)
+	firstOldest := newQuotaTestBackup(firstDatabaseID, BackupStatusCompleted, 300)
+
+	backupsToPrune, isQuotaMet := getBackupsToPruneByQuota(
+		[]*Backup{firstNewest, secondNewest, firstOldest},
+		backupQuota{MaxSizeMb: 500},
+		nextBackup{SizeMb: 100},
+		time.Now().UTC(),
+	)
+
+	assert.False(t, isQuotaMet)
+	assert.Equal(t, []*Backup{firstOldest}, backupsToPrune)
+}
+
+func Test_GetBackupsToPruneByQuota_WhenBackupIsPinned_NextOldestBackupPruned(t *testing.T) {
+	databaseID := uuid.New()
+
+	newest := newQuotaTestBackup(databaseID, BackupStatusCompleted, 10)
+	second := newQuotaTestBackup(databaseID, BackupStatusCompleted, 10)
+	pinned := newQuotaTestBackup(databaseID, BackupStatusCompleted, 10)
+	pinned.IsPinned = true
+
+	backupsToPrune, isQuotaMet := getBackupsToPruneByQuota(
+		[]*Backup{newest, second, pinned},
+		backupQuota{MaxCount: 2},
+		nextBackup{},
+		time.Now().UTC(),
+	)
+
+	assert.True(t, isQuotaMet)
+	assert.Equal(t, []*Backup{second}, backupsToPrune)
+}
+
+func Test_GetBackupsToPruneByQuota_WhenBackupsFit_NothingPruned(t *testing.T) {
+	databaseID := uuid.New()
+
+	backupsToPrune, isQuotaMet := getBackupsToPruneByQuota(
+		[]*Backup{
+			newQuotaTestBackup(databaseID, BackupStatusCompleted, 100),
+			newQuotaTestBackup(databaseID, BackupStatusCompleted, 100),
+		},
+		backupQuota{MaxCount: 5, MaxSizeMb: 500},
+		nextBackup{Count: 1, SizeMb: 100},
+		time.Now().UTC(),
+	)
+
+	assert.True(t, isQuotaMet)
+	assert.Empty(t, backupsToPrune)
+}
+
+func newQuotaTestBackup(databaseID uuid.UUID, status BackupStatus, sizeMb float64) *Backup {
+	return &Backup{
+		ID:           uuid.New(),
+		DatabaseID:   databaseID,
+		Status:       status,
+		BackupSizeMb: sizeMb,
+	}
+}
+
+func Test_EnforceQuotas_WhenStorageQuotaReached_OnlyCopyInThisStoragePruned(t *testing.T) {
+	user := users.GetTestUser()
+	localStorage := storages.CreateTestStorage(user.UserID)
+	offsiteStorage := storages.CreateTestStorage(user.UserID)
+	notifier := notifiers.CreateTestNotifier(user.UserID)
+	database := databases.CreateTestDatabase(user.UserID, localStorage, notifier)
+	backupConfig := backups_config.EnableBackupsForTestDatabase(database.ID, localStorage)
+
+	defer storages.RemoveTestStorage(localStorage.ID)
+	defer storages.RemoveTestStorage(offsiteStorage.ID)
+	defer notifiers.RemoveTestNotifier(notifier)
+	defer databases.RemoveTestDatabase(database)
+
+	now := time.Now().UTC()
+	oldBackup := createTestBackupWithCopies(t, database, now.Add(-48*time.Hour), localStorage, offsiteStorage)
+	defer func() { _ = backupRepository.DeleteByID(oldBackup.ID) }()
+
+	newBackup := createTestBackupWithCopies(t, database, now.Add(-24*time.Hour), localStorage, offsiteStorage)
+	defer func() { _ = backupRepository.DeleteByID(newBackup.ID) }()
+
+	mockNotificationSender := &MockNotificationSender{}
+	mockNotificationSender.On("SendNotification",
+		mock.Anything,
+		mock.MatchedBy(func(title string) bool {
+			return strings.Contains(title, "Backups pruned")
+		}),
+		mock.MatchedBy(func(message string) bool {
+			return strings.Contains(message, "1 oldest backup copies in storage")
+		}),
+	).Once()
+
+	backupService := &BackupService{
+		databases.GetDatabaseService(),
+		storages.GetStorageService(),
+		backupRepository,
+		notifiers.GetNotifierService(),
+		mockNotificationSender,
+		backups_config.GetBackupConfigService(),
+		backups_encryption.GetBackupEncryptionService(),
+		&CreateSuccessBackupUsecase{},
+		cancellation_utils.NewCancellationTracker(),
+		system_instances.GetInstanceService(),
+		logger.GetLogger(),
+		[]BackupRemoveListener{},
+	}
+
+	// both backups take 20 MB, so only the oldest copy has to go
+	localStorage.QuotaMb = 15
+
+	err := backupService.enforceQuotas(
+		backupConfig,
+		[]*storages.Storage{localStorage},
+		nextBackup{},
+	)
+	require.NoError(t, err)
+
+	oldBackup, err = backupRepository.FindByID(oldBackup.ID)
+	require.NoError(t, err)
+	assert.Equal(t, offsiteStorage.ID, oldBackup.StorageID)
+	assert.Equal(t, []uuid.UUID{offsiteStorage.ID}, oldBackup.GetCompletedStorageIDs())
+
+	newBackup, err = backupRepository.FindByID(newBackup.ID)
+	require.NoError(t, err)
+	assert.Equal(t, localStorage.ID, newBackup.StorageID)
+	assert.ElementsMatch(
+		t,
+		[]uuid.UUID{localStorage.ID, offsiteStorage.ID},
+		newBackup.GetCompletedStorageIDs(),
+	)
+
+	mockNotificationSender.AssertExpectations(t)
+}
+
+func createTestBackupWithCopies(
+	t *testing.T,
+	database *databases.Database,
+	createdAt time.Time,
+	backupStorages ...*storages.Storage,
+) *Backup {
+	backup := &Backup{
+		DatabaseID:   database.ID,
+		StorageID:    backupStorages[0].ID,
+		Status:       BackupStatusCompleted,
+		BackupSizeMb: 10,
+		CreatedAt:    createdAt,
+	}
+	require.NoError(t, backupRepository.Save(backup))
+
+	for _, storage := range backupStorages {
+		require.NoError(t, backupRepository.SaveCopy(&BackupCopy{
+			ID:        uuid.New(),
+			BackupID:  backup.ID,
+			StorageID: storage.ID,
+			Status:    BackupCopyStatusCompleted,
+			CreatedAt: createdAt,
+		}))
+	}
+
+	return backup
+}
@@ -2,7 +2,9 @@ package backups
 
 import (
 	"errors"
+	backups_config "postgresus-backend/internal/features/backups/config"
 	"postgresus-backend/internal/storage"
+	"strings"
 
 	"time"
 
@@ -23,11 +25,52 @@ func (r *BackupRepository) Save(backup *Backup) error {
 			Error
 	}
 
-	return db.Save(backup).
+	// hold is changed by UpdateHold only, so saving a backup loaded
+	// before the hold was set does not clear it. Same for the cancel
+	// request, progress of the running backup is saved meanwhile. Tags
+	// are set on create
+	return db.Omit("IsPinned", "HoldUntil", "HoldReason", "CancelRequestedAt", "Tags").
+		Save(backup).
 		Omit("Database", "Storage").
 		Error
 }
 
+func (r *BackupRepository) UpdateHold(backup *Backup) error {
+	return storage.
+		GetDb().
+		Model(&Backup{}).
+		Where("id = ?", backup.ID).
+		Select("IsPinned", "HoldUntil", "HoldReason").
+		Updates(backup).
+		Error
+}
+
+// RequestCancel stores the cancel request, false is
+// returned if the backup is not in progress anymore
+func (r *BackupRepository) RequestCancel(backupID uuid.UUID) (bool, error) {
+	result := storage.
+		GetDb().
+		Model(&Backup{}).
+		Where("id = ? AND status = ?", backupID, BackupStatusInProgress).
+		Update("cancel_requested_at", time.Now().UTC())
+
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *BackupRepository) IsCancelRequested(backupID uuid.UUID) (bool, error) {
+	var count int64
+
+	if err := storage.
+		GetDb().
+		Model(&Backup{}).
+		Where("id = ? AND cancel_requested_at IS NOT NULL", backupID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
 func (r *BackupRepository) FindByDatabaseID(databaseID uuid.UUID) ([]*Backup, error) {
 	var backups []*Backup
 
@@ -35,6 +78,9 @@ func (r *BackupRepository) FindByDatabaseID(databaseID uuid.UUID) ([]*Backup, er
 		GetDb().
 		Preload("Database").
 		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Preload("Entries").
 		Where("database_id = ?", databaseID).
 		Order("created_at DESC").
 		Find(&backups).Error; err != nil {
@@ -44,6 +90,43 @@ func (r *BackupRepository) FindByDatabaseID(databaseID uuid.UUID) ([]*Backup, er
 	return backups, nil
 }
 
+// FindByDatabaseIDAndTags returns backups of the database which have
+// all the tags, all backups of the database when there are no tags
+func (r *BackupRepository) FindByDatabaseIDAndTags(
+	databaseID uuid.UUID,
+	tags []*BackupTag,
+) ([]*Backup, error) {
+	var backups []*Backup
+
+	query := storage.
+		GetDb().
+		Preload("Database").
+		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Preload("Entries").
+		Where("database_id = ?", databaseID)
+
+	for _, tag := range tags {
+		query = query.Where(
+			`EXISTS (
+				SELECT 1 FROM backup_tags
+				WHERE backup_tags.backup_id = backups.id
+					AND backup_tags.key = ?
+					AND backup_tags.value = ?
+			)`,
+			tag.Key,
+			tag.Value,
+		)
+	}
+
+	if err := query.Order("created_at DESC").Find(&backups).Error; err != nil {
+		return nil, err
+	}
+
+	return backups, nil
+}
+
 func (r *BackupRepository) FindByDatabaseIDWithLimit(
 	databaseID uuid.UUID,
 	limit int,
@@ -58,6 +141,9 @@ func (r *BackupRepository) FindByDatabaseIDWithLimit(
 		GetDb().
 		Preload("Database").
 		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Preload("Entries").
 		Where("database_id = ?", databaseID).
 		Order("created_at DESC").
 		Limit(limit).
@@ -68,6 +154,39 @@ func (r *BackupRepository) FindByDatabaseIDWithLimit(
 	return backups, nil
 }
 
+// FindLastCompletedWithSourceSize returns the latest completed backups of
+// the same kind which know their source size, to calculate compression ratio
+func (r *BackupRepository) FindLastCompletedWithSourceSize(
+	databaseID uuid.UUID,
+	backupMethod backups_config.BackupMethod,
+	isWholeServer bool,
+	limit int,
+) ([]*Backup, error) {
+	if limit <= 0 {
+		return nil, errors.New("limit must be greater than 0")
+	}
+
+	var backups []*Backup
+
+	if err := storage.
+		GetDb().
+		Where(
+			"database_id = ? AND status = ? AND backup_method = ? AND is_whole_server = ? AND "+
+				"source_size_bytes > 0",
+			databaseID,
+			BackupStatusCompleted,
+			backupMethod,
+			isWholeServer,
+		).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&backups).Error; err != nil {
+		return nil, err
+	}
+
+	return backups, nil
+}
+
 func (r *BackupRepository) FindByStorageID(storageID uuid.UUID) ([]*Backup, error) {
 	var backups []*Backup
 
@@ -75,6 +194,9 @@ func (r *BackupRepository) FindByStorageID(storageID uuid.UUID) ([]*Backup, erro
 		GetDb().
 		Preload("Database").
 		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Preload("Entries").
 		Where("storage_id = ?", storageID).
 		Order("created_at DESC").
 		Find(&backups).Error; err != nil {
@@ -91,6 +213,9 @@ func (r *BackupRepository) FindLastByDatabaseID(databaseID uuid.UUID) (*Backup,
 		GetDb().
 		Preload("Database").
 		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Preload("Entries").
 		Where("database_id = ?", databaseID).
 		Order("created_at DESC").
 		First(&backup).Error; err != nil {
@@ -111,6 +236,9 @@ func (r *BackupRepository) FindByID(id uuid.UUID) (*Backup, error) {
 		GetDb().
 		Preload("Database").
 		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Preload("Entries").
 		Where("id = ?", id).
 		First(&backup).Error; err != nil {
 		return nil, err
@@ -126,6 +254,9 @@ func (r *BackupRepository) FindByStatus(status BackupStatus) ([]*Backup, error)
 		GetDb().
 		Preload("Database").
 		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Preload("Entries").
 		Where("status = ?", status).
 		Order("created_at DESC").
 		Find(&backups).Error; err != nil {
@@ -145,6 +276,9 @@ func (r *BackupRepository) FindByStorageIdAndStatus(
 		GetDb().
 		Preload("Database").
 		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Preload("Entries").
 		Where("storage_id = ? AND status = ?", storageID, status).
 		Order("created_at DESC").
 		Find(&backups).Error; err != nil {
@@ -164,6 +298,9 @@ func (r *BackupRepository) FindByDatabaseIdAndStatus(
 		GetDb().
 		Preload("Database").
 		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Preload("Entries").
 		Where("database_id = ? AND status = ?", databaseID, status).
 		Order("created_at DESC").
 		Find(&backups).Error; err != nil {
@@ -173,21 +310,203 @@ func (r *BackupRepository) FindByDatabaseIdAndStatus(
 	return backups, nil
 }
 
+// FindLastCompletedPhysicalBefore returns the latest physical backup which was
+// finished (so it is consistent) before the date
+func (r *BackupRepository) FindLastCompletedPhysicalBefore(
+	databaseID uuid.UUID,
+	date time.Time,
+) (*Backup, error) {
+	var backup Backup
+
+	if err := storage.
+		GetDb().
+		Preload("Database").
+		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Preload("Entries").
+		Where(
+			"database_id = ? AND status = ? AND backup_method = ? AND "+
+				"created_at + backup_duration_ms * INTERVAL '1 millisecond' <= ?",
+			databaseID,
+			BackupStatusCompleted,
+			backups_config.BackupMethodPhysical,
+			date,
+		).
+		Order("created_at DESC").
+		First(&backup).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &backup, nil
+}
+
+func (r *BackupRepository) FindOldestCompletedPhysical(databaseID uuid.UUID) (*Backup, error) {
+	var backup Backup
+
+	if err := storage.
+		GetDb().
+		Preload("Database").
+		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Preload("Entries").
+		Where(
+			"database_id = ? AND status = ? AND backup_method = ?",
+			databaseID,
+			BackupStatusCompleted,
+			backups_config.BackupMethodPhysical,
+		).
+		Order("created_at ASC").
+		First(&backup).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &backup, nil
+}
+
+// FindLastCompletedLogical returns the latest backup which can be
+// verified: logical backup of a single database
+func (r *BackupRepository) FindLastCompletedLogical(databaseID uuid.UUID) (*Backup, error) {
+	var backup Backup
+
+	if err := storage.
+		GetDb().
+		Preload("Database").
+		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Where(
+			"database_id = ? AND status = ? AND backup_method = ? AND is_whole_server = ?",
+			databaseID,
+			BackupStatusCompleted,
+			backups_config.BackupMethodLogical,
+			false,
+		).
+		Order("created_at DESC").
+		First(&backup).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &backup, nil
+}
+
+func (r *BackupRepository) FindLastVerifiedByDatabaseID(databaseID uuid.UUID) (*Backup, error) {
+	var backup Backup
+
+	if err := storage.
+		GetDb().
+		Where("database_id = ? AND verified_at IS NOT NULL", databaseID).
+		Order("verified_at DESC").
+		First(&backup).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &backup, nil
+}
+
+func (r *BackupRepository) FindByVerificationStatus(
+	status BackupVerificationStatus,
+) ([]*Backup, error) {
+	var backups []*Backup
+
+	if err := storage.
+		GetDb().
+		Preload("Database").
+		Preload("Storage").
+		Preload("Copies").
+		Preload("Tags").
+		Where("verification_status = ?", status).
+		Find(&backups).Error; err != nil {
+		return nil, err
+	}
+
+	return backups, nil
+}
+
+func (r *BackupRepository) SaveObjects(objects []*BackupObject) error {
+	if len(objects) == 0 {
+		return nil
+	}
+
+	return storage.GetDb().CreateInBatches(objects, 1000).Error
+}
+
+// FindObjectsByBackupID returns objects in the order of the dump. Empty
+// objectType and search are ignored, search matches schema or name
+func (r *BackupRepository) FindObjectsByBackupID(
+	backupID uuid.UUID,
+	objectType string,
+	search string,
+) ([]*BackupObject, error) {
+	var objects []*BackupObject
+
+	query := storage.
+		GetDb().
+		Where("backup_id = ?", backupID)
+
+	if objectType != "" {
+		query = query.Where("object_type = ?", objectType)
+	}
+
+	if search != "" {
+		pattern := "%" + escapeLikePattern(search) + "%"
+		query = query.Where("(schema_name ILIKE ? OR name ILIKE ?)", pattern, pattern)
+	}
+
+	if err := query.
+		Order("dump_id ASC").
+		Find(&objects).Error; err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
 func (r *BackupRepository) DeleteByID(id uuid.UUID) error {
 	return storage.GetDb().Delete(&Backup{}, "id = ?", id).Error
 }
 
-func (r *BackupRepository) FindBackupsBeforeDate(
-	databaseID uuid.UUID,
-	date time.Time,
-) ([]*Backup, error) {
+// FindCompletedByStorageID returns completed backups with a copy in the
+// storage, newest first. Backups made before copies were introduced are
+// in their main storage only
+func (r *BackupRepository) FindCompletedByStorageID(storageID uuid.UUID) ([]*Backup, error) {
 	var backups []*Backup
 
 	if err := storage.
 		GetDb().
 		Preload("Database").
 		Preload("Storage").
-		Where("database_id = ? AND created_at < ?", databaseID, date).
+		Preload("Copies").
+		Preload("Tags").
+		Preload("Entries").
+		Where(`status = ? AND (
+			id IN (SELECT backup_id FROM backup_copies WHERE storage_id = ? AND status = ?)
+			OR (storage_id = ? AND NOT EXISTS (
+				SELECT 1 FROM backup_copies WHERE backup_copies.backup_id = backups.id
+			))
+		)`,
+			BackupStatusCompleted,
+			storageID,
+			BackupCopyStatusCompleted,
+			storageID,
+		).
 		Order("created_at DESC").
 		Find(&backups).Error; err != nil {
 		return nil, err
@@ -195,3 +514,229 @@ func (r *BackupRepository) FindBackupsBeforeDate(
 
 	return backups, nil
 }
+
+// UpdateStorageID points the backup to another storage. It returns false
+// if the backup was removed, so a removed backup is not saved back
+func (r *BackupRepository) UpdateStorageID(backupID, storageID uuid.UUID) (bool, error) {
+	result := storage.
+		GetDb().
+		Model(&Backup{}).
+		Where("id = ?", backupID).
+		Update("storage_id", storageID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *BackupRepository) SaveCopy(backupCopy *BackupCopy) error {
+	return storage.GetDb().Save(backupCopy).Error
+}
+
+func (r *BackupRepository) DeleteCopy(backupID, storageID uuid.UUID) error {
+	return storage.
+		GetDb().
+		Delete(&BackupCopy{}, "backup_id = ? AND storage_id = ?", backupID, storageID).
+		Error
+}
+
+func (r *BackupRepository) SaveStorageMigration(migration *BackupStorageMigration) error {
+	db := storage.GetDb()
+
+	if migration.ID == uuid.Nil {
+		migration.ID = uuid.New()
+		return db.Create(migration).Error
+	}
+
+	return db.Save(migration).Error
+}
+
+// UpdateActiveStorageMigration saves the migration only while it is in
+// progress. It returns false if the migration was finished or cancelled
+func (r *BackupRepository) UpdateActiveStorageMigration(
+	migration *BackupStorageMigration,
+) (bool, error) {
+	result := storage.
+		GetDb().
+		Model(&BackupStorageMigration{}).
+		Where("id = ? AND status = ?", migration.ID, BackupStorageMigrationStatusInProgress).
+		Select("*").
+		Updates(migration)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *BackupRepository) FindStorageMigrationByID(id uuid.UUID) (*BackupStorageMigration, error) {
+	var migration BackupStorageMigration
+
+	if err := storage.
+		GetDb().
+		Where("id = ?", id).
+		First(&migration).Error; err != nil {
+		return nil, err
+	}
+
+	return &migration, nil
+}
+
+// FindLastStorageMigration returns nil if storage of the database was never changed
+func (r *BackupRepository) FindLastStorageMigration(
+	databaseID uuid.UUID,
+) (*BackupStorageMigration, error) {
+	var migration BackupStorageMigration
+
+	if err := storage.
+		GetDb().
+		Where("database_id = ?", databaseID).
+		Order("created_at DESC").
+		First(&migration).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &migration, nil
+}
+
+func (r *BackupRepository) FindStorageMigrationsByStatus(
+	status BackupStorageMigrationStatus,
+) ([]*BackupStorageMigration, error) {
+	var migrations []*BackupStorageMigration
+
+	if err := storage.
+		GetDb().
+		Where("status = ?", status).
+		Order("created_at ASC").
+		Find(&migrations).Error; err != nil {
+		return nil, err
+	}
+
+	return migrations, nil
+}
+
+func (r *BackupRepository) FindStorageMigrationsByDatabaseIDAndStatus(
+	databaseID uuid.UUID,
+	status BackupStorageMigrationStatus,
+) ([]*BackupStorageMigration, error) {
+	var migrations []*BackupStorageMigration
+
+	if err := storage.
+		GetDb().
+		Where("database_id = ? AND status = ?", databaseID, status).
+		Find(&migrations).Error; err != nil {
+		return nil, err
+	}
+
+	return migrations, nil
+}
+
+func (r *BackupRepository) SaveQueueItem(item *BackupQueueItem) error {
+	db := storage.GetDb()
+
+	if item.ID == uuid.Nil {
+		item.ID = uuid.New()
+		return db.Omit("Database").Create(item).Error
+	}
+
+	return db.Omit("Database").Save(item).Error
+}
+
+// FindQueueItems returns running items followed by queued ones in
+// the order they should start
+func (r *BackupRepository) FindQueueItems() ([]*BackupQueueItem, error) {
+	return findQueueItems(storage.GetDb())
+}
+
+// ClaimQueueItems marks items chosen by pick as running by the instance.
+// Workers claim under the advisory lock, so limits are checked with items
+// claimed by other workers. Nothing is claimed while another worker holds
+// the lock, it is tried again on the next dispatch
+func (r *BackupRepository) ClaimQueueItems(
+	instanceID uuid.UUID,
+	pick func(items []*BackupQueueItem) []*BackupQueueItem,
+) ([]*BackupQueueItem, error) {
+	var claimedItems []*BackupQueueItem
+
+	err := storage.GetDb().Transaction(func(tx *gorm.DB) error {
+		var isLocked bool
+		if err := tx.
+			Raw("SELECT pg_try_advisory_xact_lock(?)", backupQueueAdvisoryLockKey).
+			Scan(&isLocked).Error; err != nil {
+			return err
+		}
+
+		if !isLocked {
+			return nil
+		}
+
+		items, err := findQueueItems(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range pick(items) {
+			startedAt := time.Now().UTC()
+			item.Status = BackupQueueItemStatusRunning
+			item.StartedAt = &startedAt
+			item.InstanceID = &instanceID
+
+			if err := tx.Omit("Database").Save(item).Error; err != nil {
+				return err
+			}
+
+			claimedItems = append(claimedItems, item)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return claimedItems, nil
+}
+
+func findQueueItems(db *gorm.DB) ([]*BackupQueueItem, error) {
+	var items []*BackupQueueItem
+
+	if err := db.
+		Preload("Database").
+		Order("status = 'RUNNING' DESC, priority DESC, is_manual DESC, queued_at ASC").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// FindQueueItemByDatabaseID returns nil if backup of the database is not queued
+func (r *BackupRepository) FindQueueItemByDatabaseID(databaseID uuid.UUID) (*BackupQueueItem, error) {
+	var item BackupQueueItem
+
+	if err := storage.
+		GetDb().
+		Where("database_id = ?", databaseID).
+		First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func (r *BackupRepository) DeleteQueueItem(id uuid.UUID) error {
+	return storage.GetDb().Delete(&BackupQueueItem{}, "id = ?", id).Error
+}
+
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,193 @@
+package backups
+
+import (
+	"errors"
+	"fmt"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	users_models "postgresus-backend/internal/features/users/models"
+	"postgresus-backend/internal/util/period"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RetentionDryRunRequest struct {
+	DatabaseID  uuid.UUID     `json:"databaseId"  binding:"required"`
+	StorePeriod period.Period `json:"storePeriod"`
+
+	backups_config.BackupRetention
+}
+
+type RetentionDryRunResponse struct {
+	BackupsToPrune   []*Backup `json:"backupsToPrune"`
+	KeptBackupsCount int       `json:"keptBackupsCount"`
+}
+
+// gfsBucket groups backups by period, the newest completed
+// backup of each of the last count periods is kept
+type gfsBucket struct {
+	count int
+	key   func(t time.Time) string
+}
+
+// DryRunRetentionWithAuth shows which backups would be pruned by the given
+// retention, so the policy can be checked before it is saved
+func (s *BackupService) DryRunRetentionWithAuth(
+	user *users_models.User,
+	request RetentionDryRunRequest,
+) (*RetentionDryRunResponse, error) {
+	if _, err := s.databaseService.GetDatabase(user, request.DatabaseID); err != nil {
+		return nil, err
+	}
+
+	backupConfig := &backups_config.BackupConfig{
+		StorePeriod:     request.StorePeriod,
+		BackupRetention: request.BackupRetention,
+	}
+
+	if err := backupConfig.BackupRetention.Validate(); err != nil {
+		return nil, err
+	}
+
+	if !backupConfig.IsGfs() && !request.StorePeriod.IsValid() {
+		return nil, errors.New("invalid store period: " + string(request.StorePeriod))
+	}
+
+	dbBackups, err := s.backupRepository.FindByDatabaseID(request.DatabaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	backupsToPrune := getBackupsToPrune(dbBackups, backupConfig, time.Now().UTC())
+
+	return &RetentionDryRunResponse{
+		BackupsToPrune:   backupsToPrune,
+		KeptBackupsCount: len(dbBackups) - len(backupsToPrune),
+	}, nil
+}
+
+// getBackupsToPrune returns backups which are not kept by the retention of
+// the config. Backups should be ordered from the newest to the oldest ones.
+// Backups in progress and held backups are always kept
+func getBackupsToPrune(
+	backups []*Backup,
+	backupConfig *backups_config.BackupConfig,
+	now time.Time,
+) []*Backup {
+	var backupsToPrune []*Backup
+
+	if backupConfig.IsGfs() {
+		backupsToPrune = getBackupsToPruneByGfs(backups, &backupConfig.BackupRetention)
+	} else {
+		backupsToPrune = getBackupsToPruneByPeriod(backups, backupConfig.StorePeriod, now)
+	}
+
+	var notHeldBackups []*Backup
+	for _, backup := range backupsToPrune {
+		if !backup.IsHeld(now) {
+			notHeldBackups = append(notHeldBackups, backup)
+		}
+	}
+
+	return notHeldBackups
+}
+
+func getBackupsToPruneByPeriod(
+	backups []*Backup,
+	storePeriod period.Period,
+	now time.Time,
+) []*Backup {
+	if storePeriod == period.PeriodForever {
+		return nil
+	}
+
+	storedSince := storePeriod.SubtractFrom(now)
+
+	var backupsToPrune []*Backup
+	for _, backup := range backups {
+		if backup.Status != BackupStatusInProgress && backup.CreatedAt.Before(storedSince) {
+			backupsToPrune = append(backupsToPrune, backup)
+		}
+	}
+
+	return backupsToPrune
+}
+
+// getBackupsToPruneByGfs keeps completed backups picked by any of the GFS
+// buckets. Failed and cancelled backups have no data, they are kept only
+// until a newer backup completes, so retries still see the last failures
+func getBackupsToPruneByGfs(
+	backups []*Backup,
+	retention *backups_config.BackupRetention,
+) []*Backup {
+	keptBackupIDs := map[uuid.UUID]bool{}
+
+	for _, bucket := range getGfsBuckets(retention) {
+		if bucket.count == 0 {
+			continue
+		}
+
+		seenKeys := map[string]bool{}
+
+		for _, backup := range backups {
+			if len(seenKeys) >= bucket.count {
+				break
+			}
+
+			if backup.Status != BackupStatusCompleted {
+				continue
+			}
+
+			key := bucket.key(backup.CreatedAt.UTC())
+			if seenKeys[key] {
+				continue
+			}
+
+			seenKeys[key] = true
+			keptBackupIDs[backup.ID] = true
+		}
+	}
+
+	var backupsToPrune []*Backup
+	isNewerBackupCompleted := false
+
+	for _, backup := range backups {
+		switch backup.Status {
+		case BackupStatusInProgress:
+			continue
+		case BackupStatusCompleted:
+			isNewerBackupCompleted = true
+
+			if !keptBackupIDs[backup.ID] {
+				backupsToPrune = append(backupsToPrune, backup)
+			}
+		default:
+			if isNewerBackupCompleted {
+				backupsToPrune = append(backupsToPrune, backup)
+			}
+		}
+	}
+
+	return backupsToPrune
+}
+
+func getGfsBuckets(retention *backups_config.BackupRetention) []gfsBucket {
+	return []gfsBucket{
+		{retention.GfsHourlyCount, func(t time.Time) string {
+			return t.Format("2006-01-02T15")
+		}},
+		{retention.GfsDailyCount, func(t time.Time) string {
+			return t.Format("2006-01-02")
+		}},
+		{retention.GfsWeeklyCount, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{retention.GfsMonthlyCount, func(t time.Time) string {
+			return t.Format("2006-01")
+		}},
+		{retention.GfsYearlyCount, func(t time.Time) string {
+			return t.Format("2006")
+		}},
+	}
+}
@@ -0,0 +1,116 @@
+package backups
+
+import (
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"postgresus-backend/internal/util/period"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetBackupsToPrune_WhenGfs_NewestBackupOfEachPeriodKept(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+
+	latest := newRetentionTestBackup(BackupStatusCompleted, "2024-03-15T10:00:00Z")
+	sameDay := newRetentionTestBackup(BackupStatusCompleted, "2024-03-15T02:00:00Z")
+	previousDay := newRetentionTestBackup(BackupStatusCompleted, "2024-03-14T10:00:00Z")
+	olderDay := newRetentionTestBackup(BackupStatusCompleted, "2024-03-13T10:00:00Z")
+	february := newRetentionTestBackup(BackupStatusCompleted, "2024-02-20T10:00:00Z")
+	endOfJanuary := newRetentionTestBackup(BackupStatusCompleted, "2024-01-31T10:00:00Z")
+	startOfJanuary := newRetentionTestBackup(BackupStatusCompleted, "2024-01-05T10:00:00Z")
+
+	backupsToPrune := getBackupsToPrune(
+		[]*Backup{latest, sameDay, previousDay, olderDay, february, endOfJanuary, startOfJanuary},
+		&backups_config.BackupConfig{
+			BackupRetention: backups_config.BackupRetention{
+				RetentionPolicy: backups_config.BackupRetentionPolicyGfs,
+				GfsDailyCount:   2,
+				GfsMonthlyCount: 3,
+			},
+		},
+		now,
+	)
+
+	assert.Equal(t, []*Backup{sameDay, olderDay, startOfJanuary}, backupsToPrune)
+}
+
+func Test_GetBackupsToPrune_WhenGfs_FailedBackupsKeptUntilNewerOneCompleted(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+
+	inProgress := newRetentionTestBackup(BackupStatusInProgress, "2024-03-15T11:00:00Z")
+	latestFailed := newRetentionTestBackup(BackupStatusFailed, "2024-03-15T10:00:00Z")
+	completed := newRetentionTestBackup(BackupStatusCompleted, "2024-03-14T10:00:00Z")
+	olderFailed := newRetentionTestBackup(BackupStatusFailed, "2024-03-13T10:00:00Z")
+	olderCancelled := newRetentionTestBackup(BackupStatusCancelled, "2024-03-12T10:00:00Z")
+
+	backupsToPrune := getBackupsToPrune(
+		[]*Backup{inProgress, latestFailed, completed, olderFailed, olderCancelled},
+		&backups_config.BackupConfig{
+			BackupRetention: backups_config.BackupRetention{
+				RetentionPolicy: backups_config.BackupRetentionPolicyGfs,
+				GfsDailyCount:   7,
+			},
+		},
+		now,
+	)
+
+	assert.Equal(t, []*Backup{olderFailed, olderCancelled}, backupsToPrune)
+}
+
+func Test_GetBackupsToPrune_WhenPeriod_MonthIsCalendarOne(t *testing.T) {
+	now := time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC)
+
+	// older than 30 days, but within the calendar month
+	startOfMarch := newRetentionTestBackup(BackupStatusCompleted, "2024-03-01T11:00:00Z")
+	endOfFebruary := newRetentionTestBackup(BackupStatusCompleted, "2024-02-29T11:00:00Z")
+
+	backupsToPrune := getBackupsToPrune(
+		[]*Backup{startOfMarch, endOfFebruary},
+		&backups_config.BackupConfig{
+			StorePeriod: period.PeriodMonth,
+		},
+		now,
+	)
+
+	assert.Equal(t, []*Backup{endOfFebruary}, backupsToPrune)
+}
+
+func Test_GetBackupsToPrune_WhenBackupIsHeld_BackupIsKept(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	futureDate := now.AddDate(0, 1, 0)
+	pastDate := now.AddDate(0, 0, -1)
+
+	pinned := newRetentionTestBackup(BackupStatusCompleted, "2024-01-10T10:00:00Z")
+	pinned.IsPinned = true
+
+	heldUntilFuture := newRetentionTestBackup(BackupStatusCompleted, "2024-01-09T10:00:00Z")
+	heldUntilFuture.HoldUntil = &futureDate
+
+	holdExpired := newRetentionTestBackup(BackupStatusCompleted, "2024-01-08T10:00:00Z")
+	holdExpired.HoldUntil = &pastDate
+
+	backupsToPrune := getBackupsToPrune(
+		[]*Backup{pinned, heldUntilFuture, holdExpired},
+		&backups_config.BackupConfig{
+			StorePeriod: period.PeriodWeek,
+		},
+		now,
+	)
+
+	assert.Equal(t, []*Backup{holdExpired}, backupsToPrune)
+}
+
+func newRetentionTestBackup(status BackupStatus, createdAt string) *Backup {
+	createdAtTime, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		panic(err)
+	}
+
+	return &Backup{
+		ID:        uuid.New(),
+		Status:    status,
+		CreatedAt: createdAtTime,
+	}
+}
@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	usecases_common "postgresus-backend/internal/features/backups/backups/usecases/common"
 	backups_config "postgresus-backend/internal/features/backups/config"
+	backups_encryption "postgresus-backend/internal/features/backups/encryption"
 	"postgresus-backend/internal/features/databases"
 	"postgresus-backend/internal/features/notifiers"
 	"postgresus-backend/internal/features/storages"
+	system_instances "postgresus-backend/internal/features/system/instances"
 	users_models "postgresus-backend/internal/features/users/models"
+	cancellation_utils "postgresus-backend/internal/util/cancellation"
+	encryption_utils "postgresus-backend/internal/util/encryption"
 	"slices"
 	"time"
 
@@ -23,8 +28,11 @@ type BackupService struct {
 	notifierService     *notifiers.NotifierService
 	notificationSender  NotificationSender
 	backupConfigService *backups_config.BackupConfigService
+	encryptionService   *backups_encryption.BackupEncryptionService
 
 	createBackupUseCase CreateBackupUsecase
+	cancellationTracker *cancellation_utils.CancellationTracker
+	instanceService     *system_instances.InstanceService
 
 	logger *slog.Logger
 
@@ -35,7 +43,21 @@ func (s *BackupService) AddBackupRemoveListener(listener BackupRemoveListener) {
 	s.backupRemoveListeners = append(s.backupRemoveListeners, listener)
 }
 
-func (s *BackupService) OnBeforeBackupsStorageChange(databaseID uuid.UUID) error {
+// OnBeforeBackupsStorageChange moves existing backups to the new storage
+// in background. Backups are removed only when backups are disabled, so
+// the storage they are in can be removed
+func (s *BackupService) OnBeforeBackupsStorageChange(
+	databaseID uuid.UUID,
+	storageID *uuid.UUID,
+) error {
+	if storageID != nil {
+		return s.startStorageMigration(databaseID, *storageID)
+	}
+
+	if err := s.cancelStorageMigrations(databaseID); err != nil {
+		return err
+	}
+
 	err := s.deleteDbBackups(databaseID)
 	if err != nil {
 		return err
@@ -45,6 +67,10 @@ func (s *BackupService) OnBeforeBackupsStorageChange(databaseID uuid.UUID) error
 }
 
 func (s *BackupService) OnBeforeDatabaseRemove(databaseID uuid.UUID) error {
+	if err := s.ensureNoHeldBackups(databaseID); err != nil {
+		return err
+	}
+
 	err := s.deleteDbBackups(databaseID)
 	if err != nil {
 		return err
@@ -56,6 +82,7 @@ func (s *BackupService) OnBeforeDatabaseRemove(databaseID uuid.UUID) error {
 func (s *BackupService) MakeBackupWithAuth(
 	user *users_models.User,
 	databaseID uuid.UUID,
+	labels *BackupLabels,
 ) error {
 	database, err := s.databaseService.GetDatabaseByID(databaseID)
 	if err != nil {
@@ -66,14 +93,20 @@ func (s *BackupService) MakeBackupWithAuth(
 		return errors.New("user does not have access to this database")
 	}
 
-	go s.MakeBackup(databaseID, true)
+	if labels != nil {
+		if err := labels.Validate(); err != nil {
+			return err
+		}
+	}
 
-	return nil
+	return s.enqueueBackup(databaseID, true, true, labels)
 }
 
+// GetBackups returns backups of the database which have all the tags
 func (s *BackupService) GetBackups(
 	user *users_models.User,
 	databaseID uuid.UUID,
+	tags []*BackupTag,
 ) ([]*Backup, error) {
 	database, err := s.databaseService.GetDatabaseByID(databaseID)
 	if err != nil {
@@ -84,7 +117,7 @@ func (s *BackupService) GetBackups(
 		return nil, errors.New("user does not have access to this database")
 	}
 
-	backups, err := s.backupRepository.FindByDatabaseID(databaseID)
+	backups, err := s.backupRepository.FindByDatabaseIDAndTags(databaseID, tags)
 	if err != nil {
 		return nil, err
 	}
@@ -109,10 +142,51 @@ func (s *BackupService) DeleteBackup(
 		return errors.New("backup is in progress")
 	}
 
+	if backup.IsHeld(time.Now().UTC()) {
+		return errors.New("backup is held, clear the hold before removal")
+	}
+
 	return s.deleteBackup(backup)
 }
 
-func (s *BackupService) MakeBackup(databaseID uuid.UUID, isLastTry bool) {
+// CancelBackupWithAuth stops the running backup. Backup run by another
+// instance is stopped by that instance once it sees the stored request.
+// Status is updated by the backup goroutine once the dump is stopped
+func (s *BackupService) CancelBackupWithAuth(
+	user *users_models.User,
+	backupID uuid.UUID,
+) error {
+	backup, err := s.backupRepository.FindByID(backupID)
+	if err != nil {
+		return err
+	}
+
+	if backup.Database.UserID != user.ID {
+		return errors.New("user does not have access to this backup")
+	}
+
+	if backup.Status != BackupStatusInProgress {
+		return errors.New("backup is not in progress")
+	}
+
+	if s.cancellationTracker.Cancel(backup.ID) {
+		return nil
+	}
+
+	isRequested, err := s.backupRepository.RequestCancel(backup.ID)
+	if err != nil {
+		return err
+	}
+
+	if !isRequested {
+		return errors.New("backup is not in progress")
+	}
+
+	return nil
+}
+
+// MakeBackup runs the backup now, labels are nil for scheduled backups
+func (s *BackupService) MakeBackup(databaseID uuid.UUID, isLastTry bool, labels *BackupLabels) {
 	database, err := s.databaseService.GetDatabaseByID(databaseID)
 	if err != nil {
 		s.logger.Error("Failed to get database by ID", "error", err)
@@ -141,17 +215,15 @@ func (s *BackupService) MakeBackup(databaseID uuid.UUID, isLastTry bool) {
 		return
 	}
 
-	if backupConfig.StorageID == nil {
-		s.logger.Error("Backup config storage ID is not defined")
-		return
-	}
-
-	storage, err := s.storageService.GetStorageByID(*backupConfig.StorageID)
+	backupStorages, err := s.getConfigStorages(backupConfig)
 	if err != nil {
-		s.logger.Error("Failed to get storage by ID", "error", err)
+		s.logger.Error("Failed to get backup storages", "error", err)
 		return
 	}
 
+	storage := backupStorages[0]
+	instanceID := s.instanceService.GetInstanceID()
+
 	backup := &Backup{
 		DatabaseID: databaseID,
 		Database:   database,
@@ -159,13 +231,50 @@ func (s *BackupService) MakeBackup(databaseID uuid.UUID, isLastTry bool) {
 		StorageID: storage.ID,
 		Storage:   storage,
 
-		Status: BackupStatusInProgress,
+		Status:     BackupStatusInProgress,
+		InstanceID: &instanceID,
 
 		BackupSizeMb: 0,
 
+		BackupMethod: backupConfig.BackupMethod,
+		DumpFormat:   backupConfig.DumpFormat,
+
+		// physical backup always contains the whole cluster in one file
+		IsWholeServer: database.Postgresql != nil &&
+			database.Postgresql.IsWholeServer &&
+			backupConfig.BackupMethod != backups_config.BackupMethodPhysical,
+
+		BackupFilters: backupConfig.BackupFilters.Copy(),
+
 		CreatedAt: time.Now().UTC(),
 	}
 
+	if labels != nil {
+		backup.Label = labels.Label
+		backup.Notes = labels.Notes
+		backup.Tags = copyBackupTags(labels.Tags)
+	}
+
+	var encryptionKey []byte
+	if backupConfig.Encryption == backups_config.BackupEncryptionAES256GCM {
+		key, dataKey, err := s.encryptionService.GetActiveKey(databaseID)
+		if err != nil {
+			s.onBackupFailed(
+				backupConfig,
+				backup,
+				fmt.Errorf("failed to get backup encryption key: %w", err),
+				time.Now().UTC(),
+			)
+			return
+		}
+
+		encryptionKey = dataKey
+		backup.IsEncrypted = true
+		backup.EncryptionKeyVersion = &key.Version
+	}
+
+	s.estimateBackupSize(backupConfig, database, backup)
+
 	if err := s.backupRepository.Save(backup); err != nil {
 		s.logger.Error("Failed to save backup", "error", err)
 		return
@@ -176,60 +285,78 @@ func (s *BackupService) MakeBackup(databaseID uuid.UUID, isLastTry bool) {
 	backupProgressListener := func(
 		completedMBs float64,
 	) {
-		backup.BackupSizeMb = completedMBs
-		backup.BackupDurationMs = time.Since(start).Milliseconds()
+		backup.updateProgress(completedMBs, time.Since(start), time.Now().UTC())
 
 		if err := s.backupRepository.Save(backup); err != nil {
 			s.logger.Error("Failed to update backup progress", "error", err)
 		}
 	}
 
-	err = s.createBackupUseCase.Execute(
-		backup.ID,
-		backupConfig,
-		database,
-		storage,
-		backupProgressListener,
-	)
-	if err != nil {
-		errMsg := err.Error()
-		backup.FailMessage = &errMsg
-		backup.Status = BackupStatusFailed
-		backup.BackupDurationMs = time.Since(start).Milliseconds()
-		backup.BackupSizeMb = 0
-
-		if updateErr := s.databaseService.SetBackupError(databaseID, errMsg); updateErr != nil {
-			s.logger.Error(
-				"Failed to update database last backup time",
-				"databaseId",
-				databaseID,
-				"error",
-				updateErr,
-			)
-		}
+	ctx, unregisterBackup := s.cancellationTracker.Register(backup.ID)
+	defer unregisterBackup()
 
-		if err := s.backupRepository.Save(backup); err != nil {
-			s.logger.Error("Failed to save backup", "error", err)
-		}
+	go s.cancellationTracker.WatchCancelRequest(ctx, backup.ID, func() (bool, error) {
+		return s.backupRepository.IsCancelRequested(backup.ID)
+	})
 
-		s.SendBackupNotification(
+	var backupMetadata *usecases_common.BackupMetadata
+
+	err = s.enforceQuotas(backupConfig, backupStorages, getNextBackup(backup))
+	if err == nil {
+		err = s.runPreBackupHooks(ctx, backupConfig, database, backup)
+	}
+	if err == nil {
+		backupMetadata, err = s.createBackupUseCase.Execute(
+			ctx,
+			backup.ID,
 			backupConfig,
-			backup,
-			backups_config.NotificationBackupFailed,
-			&errMsg,
+			database,
+			backupStorages,
+			encryptionKey,
+			backupProgressListener,
 		)
 
+		s.runPostBackupHooks(backupConfig, database, backup, getBackupResultStatus(ctx, err))
+	}
+	if err != nil && cancellation_utils.IsCancelledByUser(ctx) {
+		s.onBackupCancelled(backup, backupStorages, start)
+		return
+	}
+
+	if err != nil {
+		s.onBackupFailed(backupConfig, backup, err, start)
 		return
 	}
 
 	backup.Status = BackupStatusCompleted
 	backup.BackupDurationMs = time.Since(start).Milliseconds()
+	backup.finishProgress(true)
+
+	if backupMetadata != nil && backupMetadata.SourceHost != "" {
+		backup.SourceHost = &backupMetadata.SourceHost
+	}
+
+	var failedStorages map[uuid.UUID]string
+	if backupMetadata != nil {
+		failedStorages = backupMetadata.FailedStorages
+	}
+
+	backup.Copies = s.toBackupCopies(backup.ID, backupStorages, failedStorages)
+
+	if backupMetadata != nil && backup.IsWholeServer {
+		backup.Entries = s.toBackupEntries(backup.ID, backupMetadata.Entries)
+	} else if backupMetadata != nil {
+		backup.Sha256 = &backupMetadata.Sha256
+		backup.SizeBytes = &backupMetadata.SizeBytes
+	}
 
 	if err := s.backupRepository.Save(backup); err != nil {
 		s.logger.Error("Failed to save backup", "error", err)
 		return
 	}
 
+	s.indexBackupObjects(database, backup)
+
 	// Update database last backup time
 	now := time.Now().UTC()
 	if updateErr := s.databaseService.SetLastBackupTime(databaseID, now); updateErr != nil {
@@ -254,6 +381,43 @@ func (s *BackupService) MakeBackup(databaseID uuid.UUID, isLastTry bool) {
 	)
 }
 
+// onBackupFailed saves the backup as failed and notifies about it. Backup
+// which failed before start is saved here for the first time
+func (s *BackupService) onBackupFailed(
+	backupConfig *backups_config.BackupConfig,
+	backup *Backup,
+	err error,
+	start time.Time,
+) {
+	errMsg := err.Error()
+	backup.FailMessage = &errMsg
+	backup.Status = BackupStatusFailed
+	backup.BackupDurationMs = time.Since(start).Milliseconds()
+	backup.BackupSizeMb = 0
+	backup.finishProgress(false)
+
+	if updateErr := s.databaseService.SetBackupError(backup.DatabaseID, errMsg); updateErr != nil {
+		s.logger.Error(
+			"Failed to update database last backup time",
+			"databaseId",
+			backup.DatabaseID,
+			"error",
+			updateErr,
+		)
+	}
+
+	if err := s.backupRepository.Save(backup); err != nil {
+		s.logger.Error("Failed to save backup", "error", err)
+	}
+
+	s.SendBackupNotification(
+		backupConfig,
+		backup,
+		backups_config.NotificationBackupFailed,
+		&errMsg,
+	)
+}
+
 func (s *BackupService) SendBackupNotification(
 	backupConfig *backups_config.BackupConfig,
 	backup *Backup,
@@ -266,19 +430,33 @@ func (s *BackupService) SendBackupNotification(
 	}
 
 	for _, notifier := range database.Notifiers {
-		if !slices.Contains(
-			backupConfig.SendNotificationsOn,
-			notificationType,
-		) {
+		// pruned backups are removed without any user action,
+		// so this notification cannot be turned off
+		if notificationType != backups_config.NotificationBackupsPruned &&
+			!slices.Contains(backupConfig.SendNotificationsOn, notificationType) {
 			continue
 		}
 
+		// label tells which of the manual backups it is
+		backupName := "Backup"
+		if backup != nil && backup.Label != nil {
+			backupName = fmt.Sprintf("Backup \"%s\"", *backup.Label)
+		}
+
 		title := ""
 		switch notificationType {
 		case backups_config.NotificationBackupFailed:
-			title = fmt.Sprintf("❌ Backup failed for database \"%s\"", database.Name)
+			title = fmt.Sprintf("❌ %s failed for database \"%s\"", backupName, database.Name)
 		case backups_config.NotificationBackupSuccess:
-			title = fmt.Sprintf("✅ Backup completed for database \"%s\"", database.Name)
+			title = fmt.Sprintf("✅ %s completed for database \"%s\"", backupName, database.Name)
+		case backups_config.NotificationBackupVerificationFailed:
+			title = fmt.Sprintf(
+				"❌ %s verification failed for database \"%s\"",
+				backupName,
+				database.Name,
+			)
+		case backups_config.NotificationBackupsPruned:
+			title = fmt.Sprintf("🗑️ Backups pruned for database \"%s\"", database.Name)
 		}
 
 		message := ""
@@ -319,25 +497,244 @@ func (s *BackupService) GetBackup(backupID uuid.UUID) (*Backup, error) {
 	return s.backupRepository.FindByID(backupID)
 }
 
+// GetLastBackupToVerify returns the latest backup which can be
+// restored into a scratch database or nil if there is no such backup
+func (s *BackupService) GetLastBackupToVerify(databaseID uuid.UUID) (*Backup, error) {
+	return s.backupRepository.FindLastCompletedLogical(databaseID)
+}
+
+// GetLastVerificationTime returns nil if no backup of the database was verified
+func (s *BackupService) GetLastVerificationTime(databaseID uuid.UUID) (*time.Time, error) {
+	backup, err := s.backupRepository.FindLastVerifiedByDatabaseID(databaseID)
+	if err != nil || backup == nil {
+		return nil, err
+	}
+
+	return backup.VerifiedAt, nil
+}
+
+func (s *BackupService) GetBackupsWithVerificationInProgress() ([]*Backup, error) {
+	return s.backupRepository.FindByVerificationStatus(BackupVerificationStatusInProgress)
+}
+
+func (s *BackupService) SaveVerificationResult(
+	backup *Backup,
+	status BackupVerificationStatus,
+	message *string,
+) error {
+	backup.VerificationStatus = &status
+	backup.VerificationMessage = message
+
+	if status != BackupVerificationStatusInProgress {
+		verifiedAt := time.Now().UTC()
+		backup.VerifiedAt = &verifiedAt
+	}
+
+	return s.backupRepository.Save(backup)
+}
+
+// GetLastPhysicalBackupBefore returns base backup to recover the database to
+// the given time or nil if there is no such backup
+func (s *BackupService) GetLastPhysicalBackupBefore(
+	databaseID uuid.UUID,
+	date time.Time,
+) (*Backup, error) {
+	return s.backupRepository.FindLastCompletedPhysicalBefore(databaseID, date)
+}
+
+// GetOldestPhysicalBackup returns the oldest base backup, WAL
+// archived before it cannot be used for recovery
+func (s *BackupService) GetOldestPhysicalBackup(databaseID uuid.UUID) (*Backup, error) {
+	return s.backupRepository.FindOldestCompletedPhysical(databaseID)
+}
+
+// GetBackupFile returns the backup file. For whole server backup entry ID is
+// required and the returned file info is the one of the entry
 func (s *BackupService) GetBackupFile(
 	user *users_models.User,
 	backupID uuid.UUID,
-) (io.ReadCloser, error) {
+	entryID *uuid.UUID,
+) (*BackupFileInfo, io.ReadCloser, error) {
 	backup, err := s.backupRepository.FindByID(backupID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if backup.Database.UserID != user.ID {
-		return nil, errors.New("user does not have access to this backup")
+		return nil, nil, errors.New("user does not have access to this backup")
+	}
+
+	fileInfo, err := s.getBackupFileInfo(backup, entryID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileReader, err := s.openBackupFile(backup, fileInfo.FileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !backup.IsEncrypted {
+		return fileInfo, fileReader, nil
+	}
+
+	decryptedReader, err := s.decryptBackupFile(backup, fileReader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fileInfo, decryptedReader, nil
+}
+
+// estimateBackupSize is best effort, the backup is made
+// without percentage and ETA if the size is unknown
+func (s *BackupService) estimateBackupSize(
+	backupConfig *backups_config.BackupConfig,
+	database *databases.Database,
+	backup *Backup,
+) {
+	sourceSizeBytes, err := s.createBackupUseCase.GetSourceSize(backupConfig, database)
+	if err != nil {
+		s.logger.Warn("Failed to get size of backup source", "databaseId", database.ID, "error", err)
+		return
+	}
+
+	backup.SourceSizeBytes = &sourceSizeBytes
+
+	pastBackups, err := s.backupRepository.FindLastCompletedWithSourceSize(
+		backup.DatabaseID,
+		backup.BackupMethod,
+		backup.IsWholeServer,
+		compressionRatioSampleSize,
+	)
+	if err != nil {
+		s.logger.Warn("Failed to get past backups for size estimate", "error", err)
+		return
+	}
+
+	estimatedSizeMb := estimateBackupSizeMb(sourceSizeBytes, pastBackups, backupConfig)
+	if estimatedSizeMb <= 0 {
+		return
+	}
+
+	percent := 0.0
+	backup.EstimatedSizeMb = &estimatedSizeMb
+	backup.ProgressPercent = &percent
+}
+
+// onBackupCancelled removes partially uploaded file. Whole server backup
+// files are removed by the use case, because only it knows the entries
+func (s *BackupService) onBackupCancelled(
+	backup *Backup,
+	backupStorages []*storages.Storage,
+	start time.Time,
+) {
+	s.logger.Info("Backup cancelled", "backupId", backup.ID)
+
+	if !backup.IsWholeServer {
+		for _, storage := range backupStorages {
+			if err := storage.DeleteFile(backup.ID); err != nil {
+				s.logger.Warn(
+					"Failed to remove cancelled backup file",
+					"backupId",
+					backup.ID,
+					"storageId",
+					storage.ID,
+					"error",
+					err,
+				)
+			}
+		}
+	}
+
+	backup.Status = BackupStatusCancelled
+	backup.BackupDurationMs = time.Since(start).Milliseconds()
+	backup.BackupSizeMb = 0
+	backup.finishProgress(false)
+
+	if err := s.backupRepository.Save(backup); err != nil {
+		s.logger.Error("Failed to save backup", "error", err)
 	}
+}
 
-	storage, err := s.storageService.GetStorageByID(backup.StorageID)
+func (s *BackupService) getBackupFileInfo(
+	backup *Backup,
+	entryID *uuid.UUID,
+) (*BackupFileInfo, error) {
+	if !backup.IsWholeServer {
+		if entryID != nil {
+			return nil, errors.New("backup has no entries, it is not a whole server backup")
+		}
+
+		return backup.GetFileInfo(), nil
+	}
+
+	if entryID == nil {
+		return nil, errors.New("entry ID is required to download whole server backup")
+	}
+
+	for _, entry := range backup.Entries {
+		if entry.ID == *entryID {
+			return entry.GetFileInfo(), nil
+		}
+	}
+
+	return nil, errors.New("backup entry not found")
+}
+
+func (s *BackupService) toBackupEntries(
+	backupID uuid.UUID,
+	entriesMetadata []*usecases_common.BackupEntryMetadata,
+) []*BackupEntry {
+	entries := make([]*BackupEntry, 0, len(entriesMetadata))
+
+	for _, entryMetadata := range entriesMetadata {
+		entry := &BackupEntry{
+			ID:        entryMetadata.ID,
+			BackupID:  backupID,
+			Kind:      BackupEntryKindGlobals,
+			Sha256:    entryMetadata.Sha256,
+			SizeBytes: entryMetadata.SizeBytes,
+			CreatedAt: time.Now().UTC(),
+		}
+
+		if !entryMetadata.IsGlobals {
+			databaseName := entryMetadata.DatabaseName
+			entry.Kind = BackupEntryKindDatabase
+			entry.DatabaseName = &databaseName
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func (s *BackupService) decryptBackupFile(
+	backup *Backup,
+	fileReader io.ReadCloser,
+) (io.ReadCloser, error) {
+	if backup.EncryptionKeyVersion == nil {
+		_ = fileReader.Close()
+		return nil, errors.New("backup is encrypted, but encryption key version is unknown")
+	}
+
+	encryptionKey, err := s.encryptionService.GetKey(
+		backup.DatabaseID,
+		*backup.EncryptionKeyVersion,
+	)
+	if err != nil {
+		_ = fileReader.Close()
+		return nil, err
+	}
+
+	decryptingReader, err := encryption_utils.NewDecryptingReadCloser(fileReader, encryptionKey)
 	if err != nil {
+		_ = fileReader.Close()
 		return nil, err
 	}
 
-	return storage.GetFile(backup.ID)
+	return decryptingReader, nil
 }
 
 func (s *BackupService) deleteBackup(backup *Backup) error {
@@ -347,13 +744,7 @@ func (s *BackupService) deleteBackup(backup *Backup) error {
 		}
 	}
 
-	storage, err := s.storageService.GetStorageByID(backup.StorageID)
-	if err != nil {
-		return err
-	}
-
-	err = storage.DeleteFile(backup.ID)
-	if err != nil {
+	if err := s.deleteBackupFiles(backup); err != nil {
 		return err
 	}
 
@@ -380,7 +771,15 @@ func (s *BackupService) deleteDbBackups(databaseID uuid.UUID) error {
 		return err
 	}
 
+	now := time.Now().UTC()
+
 	for _, dbBackup := range dbBackups {
+		// kept when backups are disabled, removal of the
+		// database is refused while it has held backups
+		if dbBackup.IsHeld(now) {
+			continue
+		}
+
 		err := s.deleteBackup(dbBackup)
 		if err != nil {
 			return err
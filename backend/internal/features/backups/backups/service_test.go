@@ -1,19 +1,29 @@
 package backups
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	usecases_common "postgresus-backend/internal/features/backups/backups/usecases/common"
 	backups_config "postgresus-backend/internal/features/backups/config"
+	backups_encryption "postgresus-backend/internal/features/backups/encryption"
 	"postgresus-backend/internal/features/databases"
 	"postgresus-backend/internal/features/notifiers"
 	"postgresus-backend/internal/features/storages"
+	system_instances "postgresus-backend/internal/features/system/instances"
 	"postgresus-backend/internal/features/users"
+	users_models "postgresus-backend/internal/features/users/models"
+	cancellation_utils "postgresus-backend/internal/util/cancellation"
 	"postgresus-backend/internal/util/logger"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_BackupExecuted_NotificationSent(t *testing.T) {
@@ -36,7 +46,10 @@ func Test_BackupExecuted_NotificationSent(t *testing.T) {
 			notifiers.GetNotifierService(),
 			mockNotificationSender,
 			backups_config.GetBackupConfigService(),
+			backups_encryption.GetBackupEncryptionService(),
 			&CreateFailedBackupUsecase{},
+			cancellation_utils.NewCancellationTracker(),
+			system_instances.GetInstanceService(),
 			logger.GetLogger(),
 			[]BackupRemoveListener{},
 		}
@@ -52,7 +65,7 @@ func Test_BackupExecuted_NotificationSent(t *testing.T) {
 			}),
 		).Once()
 
-		backupService.MakeBackup(database.ID, true)
+		backupService.MakeBackup(database.ID, true, nil)
 
 		// Verify all expectations were met
 		mockNotificationSender.AssertExpectations(t)
@@ -79,12 +92,15 @@ func Test_BackupExecuted_NotificationSent(t *testing.T) {
 			notifiers.GetNotifierService(),
 			mockNotificationSender,
 			backups_config.GetBackupConfigService(),
+			backups_encryption.GetBackupEncryptionService(),
 			&CreateSuccessBackupUsecase{},
+			cancellation_utils.NewCancellationTracker(),
+			system_instances.GetInstanceService(),
 			logger.GetLogger(),
 			[]BackupRemoveListener{},
 		}
 
-		backupService.MakeBackup(database.ID, true)
+		backupService.MakeBackup(database.ID, true, nil)
 
 		// Verify all expectations were met
 		mockNotificationSender.AssertExpectations(t)
@@ -99,7 +115,10 @@ func Test_BackupExecuted_NotificationSent(t *testing.T) {
 			notifiers.GetNotifierService(),
 			mockNotificationSender,
 			backups_config.GetBackupConfigService(),
+			backups_encryption.GetBackupEncryptionService(),
 			&CreateSuccessBackupUsecase{},
+			cancellation_utils.NewCancellationTracker(),
+			system_instances.GetInstanceService(),
 			logger.GetLogger(),
 			[]BackupRemoveListener{},
 		}
@@ -119,7 +138,7 @@ func Test_BackupExecuted_NotificationSent(t *testing.T) {
 			capturedMessage = args.Get(2).(string)
 		}).Once()
 
-		backupService.MakeBackup(database.ID, true)
+		backupService.MakeBackup(database.ID, true, nil)
 
 		// Verify expectations were met
 		mockNotificationSender.AssertExpectations(t)
@@ -133,34 +152,259 @@ func Test_BackupExecuted_NotificationSent(t *testing.T) {
 	})
 }
 
+func Test_PruneBackups_WhenPrunedNotificationIsNotSelected_NotificationSent(t *testing.T) {
+	user := users.GetTestUser()
+	storage := storages.CreateTestStorage(user.UserID)
+	notifier := notifiers.CreateTestNotifier(user.UserID)
+	database := databases.CreateTestDatabase(user.UserID, storage, notifier)
+	backupConfig := backups_config.EnableBackupsForTestDatabase(database.ID, storage)
+
+	defer storages.RemoveTestStorage(storage.ID)
+	defer notifiers.RemoveTestNotifier(notifier)
+	defer databases.RemoveTestDatabase(database)
+
+	assert.NotContains(t, backupConfig.SendNotificationsOn, backups_config.NotificationBackupsPruned)
+
+	backup := &Backup{
+		DatabaseID:   database.ID,
+		StorageID:    storage.ID,
+		Status:       BackupStatusCompleted,
+		BackupSizeMb: 10,
+		CreatedAt:    time.Now().UTC().Add(-24 * time.Hour),
+	}
+	require.NoError(t, backupRepository.Save(backup))
+	defer func() {
+		_ = backupRepository.DeleteByID(backup.ID)
+	}()
+
+	mockNotificationSender := &MockNotificationSender{}
+	mockNotificationSender.On("SendNotification",
+		mock.Anything,
+		mock.MatchedBy(func(title string) bool {
+			return strings.Contains(title, "🗑️ Backups pruned")
+		}),
+		mock.MatchedBy(func(message string) bool {
+			return strings.Contains(message, "quota of storage")
+		}),
+	).Once()
+
+	backupService := &BackupService{
+		databases.GetDatabaseService(),
+		storages.GetStorageService(),
+		backupRepository,
+		notifiers.GetNotifierService(),
+		mockNotificationSender,
+		backups_config.GetBackupConfigService(),
+		backups_encryption.GetBackupEncryptionService(),
+		&CreateSuccessBackupUsecase{},
+		cancellation_utils.NewCancellationTracker(),
+		system_instances.GetInstanceService(),
+		logger.GetLogger(),
+		[]BackupRemoveListener{},
+	}
+
+	backupService.pruneBackups(
+		[]*Backup{backup},
+		fmt.Sprintf("quota of storage \"%s\" is reached", storage.Name),
+	)
+
+	mockNotificationSender.AssertExpectations(t)
+}
+
+func Test_CancelBackup_WhenBackupIsRunning_BackupCancelled(t *testing.T) {
+	user := users.GetTestUser()
+	storage := storages.CreateTestStorage(user.UserID)
+	notifier := notifiers.CreateTestNotifier(user.UserID)
+	database := databases.CreateTestDatabase(user.UserID, storage, notifier)
+	backups_config.EnableBackupsForTestDatabase(database.ID, storage)
+
+	defer storages.RemoveTestStorage(storage.ID)
+	defer notifiers.RemoveTestNotifier(notifier)
+	defer databases.RemoveTestDatabase(database)
+
+	testCases := map[string]bool{
+		"CancelledOnRunningInstance": true,
+		"CancelledOnAnotherInstance": false,
+	}
+
+	for name, isSameInstance := range testCases {
+		t.Run(name, func(t *testing.T) {
+			createBackupUseCase := &CreateBlockingBackupUsecase{started: make(chan struct{})}
+			backupService := &BackupService{
+				databases.GetDatabaseService(),
+				storages.GetStorageService(),
+				backupRepository,
+				notifiers.GetNotifierService(),
+				&MockNotificationSender{},
+				backups_config.GetBackupConfigService(),
+				backups_encryption.GetBackupEncryptionService(),
+				createBackupUseCase,
+				cancellation_utils.NewCancellationTracker(),
+				system_instances.GetInstanceService(),
+				logger.GetLogger(),
+				[]BackupRemoveListener{},
+			}
+
+			// another instance has its own tracker, so the
+			// request is stored and polled by the running one
+			cancellingService := backupService
+			if !isSameInstance {
+				cancellingService = &BackupService{
+					databases.GetDatabaseService(),
+					storages.GetStorageService(),
+					backupRepository,
+					notifiers.GetNotifierService(),
+					&MockNotificationSender{},
+					backups_config.GetBackupConfigService(),
+					backups_encryption.GetBackupEncryptionService(),
+					createBackupUseCase,
+					cancellation_utils.NewCancellationTracker(),
+					system_instances.GetInstanceService(),
+					logger.GetLogger(),
+					[]BackupRemoveListener{},
+				}
+			}
+
+			isFinished := make(chan struct{})
+			go func() {
+				backupService.MakeBackup(database.ID, true, nil)
+				close(isFinished)
+			}()
+
+			select {
+			case <-createBackupUseCase.started:
+			case <-time.After(10 * time.Second):
+				t.Fatal("backup is not started")
+			}
+
+			backup, err := backupRepository.FindLastByDatabaseID(database.ID)
+			require.NoError(t, err)
+			require.NotNil(t, backup)
+			defer func() {
+				_ = backupRepository.DeleteByID(backup.ID)
+			}()
+
+			err = cancellingService.CancelBackupWithAuth(&users_models.User{ID: user.UserID}, backup.ID)
+			require.NoError(t, err)
+
+			select {
+			case <-isFinished:
+			case <-time.After(30 * time.Second):
+				t.Fatal("backup is not cancelled")
+			}
+
+			backup, err = backupRepository.FindByID(backup.ID)
+			require.NoError(t, err)
+			assert.Equal(t, BackupStatusCancelled, backup.Status)
+		})
+	}
+}
+
 type CreateFailedBackupUsecase struct {
 }
 
 func (uc *CreateFailedBackupUsecase) Execute(
+	ctx context.Context,
 	backupID uuid.UUID,
 	backupConfig *backups_config.BackupConfig,
 	database *databases.Database,
-	storage *storages.Storage,
+	backupStorages []*storages.Storage,
+	encryptionKey []byte,
 	backupProgressListener func(
 		completedMBs float64,
 	),
-) error {
+) (*usecases_common.BackupMetadata, error) {
 	backupProgressListener(10) // Assume we completed 10MB
-	return errors.New("backup failed")
+	return nil, errors.New("backup failed")
+}
+
+func (uc *CreateFailedBackupUsecase) GetSourceSize(
+	backupConfig *backups_config.BackupConfig,
+	database *databases.Database,
+) (int64, error) {
+	return 100 * 1024 * 1024, nil
+}
+
+func (uc *CreateFailedBackupUsecase) ListBackupObjects(
+	ctx context.Context,
+	database *databases.Database,
+	dumpFormat backups_config.BackupDumpFormat,
+	dumpReader io.Reader,
+) ([]*usecases_common.BackupObjectMetadata, error) {
+	return nil, nil
 }
 
 type CreateSuccessBackupUsecase struct {
 }
 
 func (uc *CreateSuccessBackupUsecase) Execute(
+	ctx context.Context,
 	backupID uuid.UUID,
 	backupConfig *backups_config.BackupConfig,
 	database *databases.Database,
-	storage *storages.Storage,
+	backupStorages []*storages.Storage,
+	encryptionKey []byte,
 	backupProgressListener func(
 		completedMBs float64,
 	),
-) error {
+) (*usecases_common.BackupMetadata, error) {
 	backupProgressListener(10) // Assume we completed 10MB
-	return nil
+	return &usecases_common.BackupMetadata{
+		Sha256:    "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		SizeBytes: 10 * 1024 * 1024,
+	}, nil
+}
+
+func (uc *CreateSuccessBackupUsecase) GetSourceSize(
+	backupConfig *backups_config.BackupConfig,
+	database *databases.Database,
+) (int64, error) {
+	return 100 * 1024 * 1024, nil
+}
+
+func (uc *CreateSuccessBackupUsecase) ListBackupObjects(
+	ctx context.Context,
+	database *databases.Database,
+	dumpFormat backups_config.BackupDumpFormat,
+	dumpReader io.Reader,
+) ([]*usecases_common.BackupObjectMetadata, error) {
+	return nil, nil
+}
+
+// CreateBlockingBackupUsecase runs until the backup is cancelled
+type CreateBlockingBackupUsecase struct {
+	started chan struct{}
+}
+
+func (uc *CreateBlockingBackupUsecase) Execute(
+	ctx context.Context,
+	backupID uuid.UUID,
+	backupConfig *backups_config.BackupConfig,
+	database *databases.Database,
+	backupStorages []*storages.Storage,
+	encryptionKey []byte,
+	backupProgressListener func(
+		completedMBs float64,
+	),
+) (*usecases_common.BackupMetadata, error) {
+	close(uc.started)
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+func (uc *CreateBlockingBackupUsecase) GetSourceSize(
+	backupConfig *backups_config.BackupConfig,
+	database *databases.Database,
+) (int64, error) {
+	return 100 * 1024 * 1024, nil
+}
+
+func (uc *CreateBlockingBackupUsecase) ListBackupObjects(
+	ctx context.Context,
+	database *databases.Database,
+	dumpFormat backups_config.BackupDumpFormat,
+	dumpReader io.Reader,
+) ([]*usecases_common.BackupObjectMetadata, error) {
+	return nil, nil
 }
@@ -0,0 +1,440 @@
+package backups
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"postgresus-backend/internal/config"
+	"postgresus-backend/internal/features/storages"
+	users_models "postgresus-backend/internal/features/users/models"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GetLastStorageMigrationWithAuth returns progress of the latest storage
+// migration of the database or nil if storage was never changed
+func (s *BackupService) GetLastStorageMigrationWithAuth(
+	user *users_models.User,
+	databaseID uuid.UUID,
+) (*BackupStorageMigration, error) {
+	if _, err := s.databaseService.GetDatabase(user, databaseID); err != nil {
+		return nil, err
+	}
+
+	return s.backupRepository.FindLastStorageMigration(databaseID)
+}
+
+// ResumeStorageMigrationWithAuth retries backups which failed to move,
+// already moved backups are not copied again
+func (s *BackupService) ResumeStorageMigrationWithAuth(
+	user *users_models.User,
+	migrationID uuid.UUID,
+) (*BackupStorageMigration, error) {
+	migration, err := s.backupRepository.FindStorageMigrationByID(migrationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.databaseService.GetDatabase(user, migration.DatabaseID); err != nil {
+		return nil, err
+	}
+
+	if migration.Status != BackupStorageMigrationStatusFailed {
+		return nil, errors.New("only failed storage migration can be resumed")
+	}
+
+	migration.Status = BackupStorageMigrationStatusInProgress
+	migration.FailedBackups = 0
+	migration.FailMessage = nil
+	migration.FinishedAt = nil
+
+	if err := s.backupRepository.SaveStorageMigration(migration); err != nil {
+		return nil, err
+	}
+
+	return migration, nil
+}
+
+// startStorageMigration records the migration, backups are moved by the
+// background service. Newer migration supersedes the unfinished one, it
+// moves backups from all previous storages
+func (s *BackupService) startStorageMigration(databaseID, storageID uuid.UUID) error {
+	dbBackupsInProgress, err := s.backupRepository.FindByDatabaseIdAndStatus(
+		databaseID,
+		BackupStatusInProgress,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(dbBackupsInProgress) > 0 {
+		return errors.New("backup is in progress, storage cannot be changed")
+	}
+
+	if err := s.cancelStorageMigrations(databaseID); err != nil {
+		return err
+	}
+
+	backupsToMigrate, err := s.findBackupsToMigrate(databaseID, storageID)
+	if err != nil {
+		return err
+	}
+
+	if len(backupsToMigrate) == 0 {
+		return nil
+	}
+
+	return s.backupRepository.SaveStorageMigration(&BackupStorageMigration{
+		DatabaseID:   databaseID,
+		StorageID:    storageID,
+		Status:       BackupStorageMigrationStatusInProgress,
+		TotalBackups: len(backupsToMigrate),
+		CreatedAt:    time.Now().UTC(),
+	})
+}
+
+func (s *BackupService) cancelStorageMigrations(databaseID uuid.UUID) error {
+	migrations, err := s.backupRepository.FindStorageMigrationsByDatabaseIDAndStatus(
+		databaseID,
+		BackupStorageMigrationStatusInProgress,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		finishedAt := time.Now().UTC()
+		migration.Status = BackupStorageMigrationStatusCancelled
+		migration.FinishedAt = &finishedAt
+
+		if err := s.backupRepository.SaveStorageMigration(migration); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runStorageMigration moves backups which are not in the target storage yet.
+// It stops on shutdown or when the migration is cancelled, the next run
+// continues with the remaining backups. Progress is saved only while the
+// migration is active, so it does not overwrite the cancellation
+func (s *BackupService) runStorageMigration(migration *BackupStorageMigration) {
+	targetStorage, err := s.storageService.GetStorageByID(migration.StorageID)
+	if err != nil {
+		s.failStorageMigration(migration, err)
+		return
+	}
+
+	backupConfig, err := s.backupConfigService.GetBackupConfigByDbId(migration.DatabaseID)
+	if err != nil {
+		s.failStorageMigration(migration, err)
+		return
+	}
+
+	backupsToMigrate, err := s.findBackupsToMigrate(migration.DatabaseID, migration.StorageID)
+	if err != nil {
+		s.failStorageMigration(migration, err)
+		return
+	}
+
+	migration.TotalBackups = migration.MigratedBackups + len(backupsToMigrate)
+	if !s.saveStorageMigrationProgress(migration) {
+		return
+	}
+
+	// source files stay in storages which are still used by the config
+	usedStorageIDs := backupConfig.GetStorageIDs()
+
+	for _, backup := range backupsToMigrate {
+		if config.IsShouldShutdown() {
+			return
+		}
+
+		if err := s.migrateBackup(backup, targetStorage, usedStorageIDs); err != nil {
+			s.logger.Error(
+				"Failed to move backup to another storage",
+				"backupId",
+				backup.ID,
+				"storageId",
+				targetStorage.ID,
+				"error",
+				err,
+			)
+
+			errMsg := err.Error()
+			migration.FailedBackups++
+			migration.FailMessage = &errMsg
+		} else {
+			migration.MigratedBackups++
+		}
+
+		if !s.saveStorageMigrationProgress(migration) {
+			return
+		}
+	}
+
+	if migration.FailedBackups > 0 {
+		s.finishStorageMigration(migration, BackupStorageMigrationStatusFailed)
+		return
+	}
+
+	s.finishStorageMigration(migration, BackupStorageMigrationStatusCompleted)
+}
+
+// migrateBackup copies files as they are stored, encrypted backups stay
+// encrypted with the same key. Failed and cancelled backups have no
+// complete file, so only the storage reference is moved
+func (s *BackupService) migrateBackup(
+	backup *Backup,
+	targetStorage *storages.Storage,
+	usedStorageIDs []uuid.UUID,
+) error {
+	sourceStorageID := backup.StorageID
+	isCompleted := backup.Status == BackupStatusCompleted
+
+	// backups made before copies were introduced get the record of
+	// the main storage copy, so a source file which stays is tracked
+	if isCompleted && len(backup.Copies) == 0 {
+		sourceCopy := &BackupCopy{
+			ID:        uuid.New(),
+			BackupID:  backup.ID,
+			StorageID: sourceStorageID,
+			Status:    BackupCopyStatusCompleted,
+			CreatedAt: backup.CreatedAt,
+		}
+
+		if err := s.backupRepository.SaveCopy(sourceCopy); err != nil {
+			return err
+		}
+
+		backup.Copies = []*BackupCopy{sourceCopy}
+	}
+
+	isCopyRequired := isCompleted &&
+		!slices.Contains(backup.GetCompletedStorageIDs(), targetStorage.ID)
+
+	if isCopyRequired {
+		for _, fileID := range backup.GetStorageFileIDs() {
+			if err := s.copyBackupFile(backup, fileID, targetStorage); err != nil {
+				s.saveFailedCopy(backup, targetStorage.ID, err)
+				return err
+			}
+		}
+	}
+
+	isUpdated, err := s.backupRepository.UpdateStorageID(backup.ID, targetStorage.ID)
+	if err != nil {
+		return err
+	}
+
+	// removed during the copy, so the copied files are not needed
+	if !isUpdated {
+		s.deleteStorageFiles(backup, targetStorage.ID)
+		return nil
+	}
+
+	if isCopyRequired {
+		// replaces failed copy of the target storage, if any
+		if err := s.backupRepository.DeleteCopy(backup.ID, targetStorage.ID); err != nil {
+			return err
+		}
+
+		if err := s.backupRepository.SaveCopy(&BackupCopy{
+			ID:        uuid.New(),
+			BackupID:  backup.ID,
+			StorageID: targetStorage.ID,
+			Status:    BackupCopyStatusCompleted,
+			CreatedAt: time.Now().UTC(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if slices.Contains(usedStorageIDs, sourceStorageID) {
+		return nil
+	}
+
+	if err := s.backupRepository.DeleteCopy(backup.ID, sourceStorageID); err != nil {
+		return err
+	}
+
+	// the backup already points to the new storage, so a file
+	// left in the old storage does not fail the migration
+	s.deleteStorageFiles(backup, sourceStorageID)
+
+	return nil
+}
+
+func (s *BackupService) copyBackupFile(
+	backup *Backup,
+	fileID uuid.UUID,
+	targetStorage *storages.Storage,
+) error {
+	fileReader, err := s.openBackupFile(backup, fileID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = fileReader.Close()
+	}()
+
+	if err := targetStorage.SaveFile(s.logger, fileID, fileReader); err != nil {
+		return err
+	}
+
+	return s.verifyBackupFile(backup, fileID, targetStorage)
+}
+
+// verifyBackupFile reads the file back from the storage, so the source is
+// not removed when the copy is broken. Checksum and size are of the plain
+// dump, so encrypted file is decrypted first. Backups made before checksums
+// were introduced cannot be verified
+func (s *BackupService) verifyBackupFile(
+	backup *Backup,
+	fileID uuid.UUID,
+	storage *storages.Storage,
+) error {
+	fileInfo := backup.GetFileInfo()
+	if backup.IsWholeServer {
+		var err error
+		if fileInfo, err = s.getBackupFileInfo(backup, &fileID); err != nil {
+			return err
+		}
+	}
+
+	if fileInfo.Sha256 == nil || fileInfo.SizeBytes == nil {
+		return nil
+	}
+
+	fileReader, err := storage.GetFile(fileID)
+	if err != nil {
+		return fmt.Errorf("failed to read copied backup file: %w", err)
+	}
+
+	if backup.IsEncrypted {
+		if fileReader, err = s.decryptBackupFile(backup, fileReader); err != nil {
+			return fmt.Errorf("failed to decrypt copied backup file: %w", err)
+		}
+	}
+	defer func() {
+		_ = fileReader.Close()
+	}()
+
+	hasher := sha256.New()
+	sizeBytes, err := io.Copy(hasher, fileReader)
+	if err != nil {
+		return fmt.Errorf("failed to read copied backup file: %w", err)
+	}
+
+	if sizeBytes != *fileInfo.SizeBytes {
+		return fmt.Errorf(
+			"copied backup file %s has %d bytes, expected %d",
+			fileID,
+			sizeBytes,
+			*fileInfo.SizeBytes,
+		)
+	}
+
+	if hex.EncodeToString(hasher.Sum(nil)) != *fileInfo.Sha256 {
+		return fmt.Errorf("copied backup file %s does not match its checksum", fileID)
+	}
+
+	return nil
+}
+
+// saveFailedCopy records the copy which cannot be used, so its partial
+// file is removed with the backup and the next run copies it again
+func (s *BackupService) saveFailedCopy(backup *Backup, storageID uuid.UUID, copyErr error) {
+	failMessage := copyErr.Error()
+
+	if err := s.backupRepository.DeleteCopy(backup.ID, storageID); err != nil {
+		s.logger.Warn("Failed to remove previous backup copy", "backupId", backup.ID, "error", err)
+		return
+	}
+
+	if err := s.backupRepository.SaveCopy(&BackupCopy{
+		ID:          uuid.New(),
+		BackupID:    backup.ID,
+		StorageID:   storageID,
+		Status:      BackupCopyStatusFailed,
+		FailMessage: &failMessage,
+		CreatedAt:   time.Now().UTC(),
+	}); err != nil {
+		s.logger.Warn("Failed to save failed backup copy", "backupId", backup.ID, "error", err)
+	}
+}
+
+func (s *BackupService) deleteStorageFiles(backup *Backup, storageID uuid.UUID) {
+	storage, err := s.storageService.GetStorageByID(storageID)
+	if err != nil {
+		s.logger.Warn("Failed to get storage to remove backup files", "storageId", storageID, "error", err)
+		return
+	}
+
+	for _, fileID := range backup.GetStorageFileIDs() {
+		if err := storage.DeleteFile(fileID); err != nil {
+			s.logger.Warn(
+				"Failed to remove backup file",
+				"backupId",
+				backup.ID,
+				"storageId",
+				storageID,
+				"error",
+				err,
+			)
+		}
+	}
+}
+
+func (s *BackupService) findBackupsToMigrate(databaseID, storageID uuid.UUID) ([]*Backup, error) {
+	dbBackups, err := s.backupRepository.FindByDatabaseID(databaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var backupsToMigrate []*Backup
+	for _, backup := range dbBackups {
+		if backup.StorageID != storageID && backup.Status != BackupStatusInProgress {
+			backupsToMigrate = append(backupsToMigrate, backup)
+		}
+	}
+
+	return backupsToMigrate, nil
+}
+
+// saveStorageMigrationProgress returns false when the migration was
+// cancelled or cannot be saved, so the run should stop
+func (s *BackupService) saveStorageMigrationProgress(migration *BackupStorageMigration) bool {
+	isActive, err := s.backupRepository.UpdateActiveStorageMigration(migration)
+	if err != nil {
+		s.logger.Error("Failed to save storage migration", "migrationId", migration.ID, "error", err)
+		return false
+	}
+
+	return isActive
+}
+
+func (s *BackupService) failStorageMigration(migration *BackupStorageMigration, err error) {
+	s.logger.Error("Storage migration failed", "migrationId", migration.ID, "error", err)
+
+	errMsg := err.Error()
+	migration.FailMessage = &errMsg
+
+	s.finishStorageMigration(migration, BackupStorageMigrationStatusFailed)
+}
+
+func (s *BackupService) finishStorageMigration(
+	migration *BackupStorageMigration,
+	status BackupStorageMigrationStatus,
+) {
+	finishedAt := time.Now().UTC()
+	migration.Status = status
+	migration.FinishedAt = &finishedAt
+
+	s.saveStorageMigrationProgress(migration)
+}
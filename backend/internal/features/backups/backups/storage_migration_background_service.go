@@ -0,0 +1,64 @@
+package backups
+
+import (
+	"log/slog"
+	"postgresus-backend/internal/config"
+	system_instances "postgresus-backend/internal/features/system/instances"
+	"time"
+)
+
+// BackupStorageMigrationBackgroundService moves backups to the new storage
+// of the database. Migrations in progress are not failed on start, they
+// continue with backups which were not moved before the restart. One
+// instance runs migrations, another one continues them if it dies
+type BackupStorageMigrationBackgroundService struct {
+	backupService    *BackupService
+	backupRepository *BackupRepository
+	migrationLease   *system_instances.Lease
+	logger           *slog.Logger
+}
+
+func (s *BackupStorageMigrationBackgroundService) Run() {
+	for {
+		if config.IsShouldShutdown() {
+			return
+		}
+
+		if isHeld, _ := s.migrationLease.Acquire(); isHeld {
+			if err := s.runStorageMigrations(); err != nil {
+				s.logger.Error("Failed to run backup storage migrations", "error", err)
+			}
+		}
+
+		time.Sleep(1 * time.Minute)
+	}
+}
+
+func (s *BackupStorageMigrationBackgroundService) runStorageMigrations() error {
+	migrations, err := s.backupRepository.FindStorageMigrationsByStatus(
+		BackupStorageMigrationStatusInProgress,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if config.IsShouldShutdown() {
+			return nil
+		}
+
+		s.logger.Info(
+			"Moving backups to another storage",
+			"migrationId",
+			migration.ID,
+			"databaseId",
+			migration.DatabaseID,
+			"storageId",
+			migration.StorageID,
+		)
+
+		s.backupService.runStorageMigration(migration)
+	}
+
+	return nil
+}
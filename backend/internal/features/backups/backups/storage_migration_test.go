@@ -0,0 +1,154 @@
+package backups
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"postgresus-backend/internal/features/databases"
+	"postgresus-backend/internal/features/notifiers"
+	"postgresus-backend/internal/features/storages"
+	"postgresus-backend/internal/features/users"
+	users_models "postgresus-backend/internal/features/users/models"
+	"postgresus-backend/internal/util/logger"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RunStorageMigration_WhenCopyIsVerified_BackupMoved(t *testing.T) {
+	user := users.GetTestUser()
+	sourceStorage := storages.CreateTestStorage(user.UserID)
+	targetStorage := storages.CreateTestStorage(user.UserID)
+	notifier := notifiers.CreateTestNotifier(user.UserID)
+	database := databases.CreateTestDatabase(user.UserID, sourceStorage, notifier)
+	backups_config.EnableBackupsForTestDatabase(database.ID, sourceStorage)
+
+	defer storages.RemoveTestStorage(sourceStorage.ID)
+	defer storages.RemoveTestStorage(targetStorage.ID)
+	defer notifiers.RemoveTestNotifier(notifier)
+	defer databases.RemoveTestDatabase(database)
+
+	backup := createTestBackupWithFile(t, database, sourceStorage, "dump content", "dump content")
+	defer removeTestBackupWithFile(backup, sourceStorage)
+
+	migration := startTestStorageMigration(t, database, targetStorage)
+	GetBackupService().runStorageMigration(migration)
+
+	migration, err := backupRepository.FindStorageMigrationByID(migration.ID)
+	require.NoError(t, err)
+	assert.Equal(t, BackupStorageMigrationStatusCompleted, migration.Status)
+	assert.Equal(t, 1, migration.MigratedBackups)
+
+	backup, err = backupRepository.FindByID(backup.ID)
+	require.NoError(t, err)
+	assert.Equal(t, targetStorage.ID, backup.StorageID)
+	assert.Contains(t, backup.GetCompletedStorageIDs(), targetStorage.ID)
+}
+
+func Test_ResumeStorageMigration_WhenCopyDidNotMatchChecksum_BackupMovedAfterResume(t *testing.T) {
+	user := users.GetTestUser()
+	sourceStorage := storages.CreateTestStorage(user.UserID)
+	targetStorage := storages.CreateTestStorage(user.UserID)
+	notifier := notifiers.CreateTestNotifier(user.UserID)
+	database := databases.CreateTestDatabase(user.UserID, sourceStorage, notifier)
+	backups_config.EnableBackupsForTestDatabase(database.ID, sourceStorage)
+
+	defer storages.RemoveTestStorage(sourceStorage.ID)
+	defer storages.RemoveTestStorage(targetStorage.ID)
+	defer notifiers.RemoveTestNotifier(notifier)
+	defer databases.RemoveTestDatabase(database)
+
+	backup := createTestBackupWithFile(t, database, sourceStorage, "dump content", "corrupted")
+	defer removeTestBackupWithFile(backup, sourceStorage)
+
+	migration := startTestStorageMigration(t, database, targetStorage)
+	GetBackupService().runStorageMigration(migration)
+
+	migration, err := backupRepository.FindStorageMigrationByID(migration.ID)
+	require.NoError(t, err)
+	assert.Equal(t, BackupStorageMigrationStatusFailed, migration.Status)
+	assert.Equal(t, 1, migration.FailedBackups)
+	require.NotNil(t, migration.FailMessage)
+	assert.Contains(t, *migration.FailMessage, "bytes, expected")
+
+	// broken copy is not used, the backup stays in the source storage
+	backup, err = backupRepository.FindByID(backup.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sourceStorage.ID, backup.StorageID)
+	assert.NotContains(t, backup.GetCompletedStorageIDs(), targetStorage.ID)
+
+	err = sourceStorage.SaveFile(logger.GetLogger(), backup.ID, strings.NewReader("dump content"))
+	require.NoError(t, err)
+
+	migration, err = GetBackupService().ResumeStorageMigrationWithAuth(
+		&users_models.User{ID: user.UserID},
+		migration.ID,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, BackupStorageMigrationStatusInProgress, migration.Status)
+
+	GetBackupService().runStorageMigration(migration)
+
+	migration, err = backupRepository.FindStorageMigrationByID(migration.ID)
+	require.NoError(t, err)
+	assert.Equal(t, BackupStorageMigrationStatusCompleted, migration.Status)
+	assert.Equal(t, 1, migration.MigratedBackups)
+	assert.Equal(t, 0, migration.FailedBackups)
+
+	backup, err = backupRepository.FindByID(backup.ID)
+	require.NoError(t, err)
+	assert.Equal(t, targetStorage.ID, backup.StorageID)
+	assert.Contains(t, backup.GetCompletedStorageIDs(), targetStorage.ID)
+}
+
+// createTestBackupWithFile saves the file content, while checksum
+// and size of the backup are taken from the expected content
+func createTestBackupWithFile(
+	t *testing.T,
+	database *databases.Database,
+	storage *storages.Storage,
+	expectedContent string,
+	fileContent string,
+) *Backup {
+	hash := sha256.Sum256([]byte(expectedContent))
+	sha256Hex := hex.EncodeToString(hash[:])
+	sizeBytes := int64(len(expectedContent))
+
+	backup := &Backup{
+		DatabaseID: database.ID,
+		StorageID:  storage.ID,
+		Status:     BackupStatusCompleted,
+		Sha256:     &sha256Hex,
+		SizeBytes:  &sizeBytes,
+		CreatedAt:  time.Now().UTC(),
+	}
+	require.NoError(t, backupRepository.Save(backup))
+
+	err := storage.SaveFile(logger.GetLogger(), backup.ID, strings.NewReader(fileContent))
+	require.NoError(t, err)
+
+	return backup
+}
+
+func removeTestBackupWithFile(backup *Backup, storage *storages.Storage) {
+	_ = storage.DeleteFile(backup.ID)
+	_ = backupRepository.DeleteByID(backup.ID)
+}
+
+func startTestStorageMigration(
+	t *testing.T,
+	database *databases.Database,
+	targetStorage *storages.Storage,
+) *BackupStorageMigration {
+	err := GetBackupService().startStorageMigration(database.ID, targetStorage.ID)
+	require.NoError(t, err)
+
+	migration, err := backupRepository.FindLastStorageMigration(database.ID)
+	require.NoError(t, err)
+	require.NotNil(t, migration)
+
+	return migration
+}
@@ -0,0 +1,48 @@
+package usecases_common
+
+import "github.com/google/uuid"
+
+// BackupMetadata describes the backup file produced by the database
+// dump tool, before optional encryption. The same bytes are returned
+// on download and restored, so checksum is verifiable on both sides
+type BackupMetadata struct {
+	Sha256    string
+	SizeBytes int64
+
+	// SourceHost is "host:port" of the server the backup was taken
+	// from, it differs from the database host when a replica is used
+	SourceHost string
+
+	// FailedStorages are storages which did not save the backup, keyed
+	// by storage ID. Backup fails only when no storage saved it
+	FailedStorages map[uuid.UUID]string
+
+	// Entries are filled for whole server backup instead of the
+	// checksum and size above, each entry is a separate file
+	Entries []*BackupEntryMetadata
+}
+
+type BackupEntryMetadata struct {
+	ID uuid.UUID
+
+	// IsGlobals is set for pg_dumpall --globals-only output,
+	// otherwise the entry is a pg_dump of DatabaseName
+	IsGlobals    bool
+	DatabaseName string
+
+	Sha256    string
+	SizeBytes int64
+}
+
+// BackupObjectMetadata is an entry of the dump table of contents
+type BackupObjectMetadata struct {
+	DumpID int
+	Type   string
+	Schema string
+	Name   string
+	Owner  string
+
+	// SizeBytes is size of the relation in the database when the
+	// backup was made, nil for objects without data of their own
+	SizeBytes *int64
+}
@@ -1,7 +1,10 @@
 package usecases
 
 import (
+	"context"
 	"errors"
+	"io"
+	usecases_common "postgresus-backend/internal/features/backups/backups/usecases/common"
 	usecases_postgresql "postgresus-backend/internal/features/backups/backups/usecases/postgresql"
 	backups_config "postgresus-backend/internal/features/backups/config"
 	"postgresus-backend/internal/features/databases"
@@ -14,25 +17,58 @@ type CreateBackupUsecase struct {
 	CreatePostgresqlBackupUsecase *usecases_postgresql.CreatePostgresqlBackupUsecase
 }
 
-// Execute creates a backup of the database and returns the backup size in MB
+// Execute creates a backup of the database and returns checksum and size of the backup file
 func (uc *CreateBackupUsecase) Execute(
+	ctx context.Context,
 	backupID uuid.UUID,
 	backupConfig *backups_config.BackupConfig,
 	database *databases.Database,
-	storage *storages.Storage,
+	backupStorages []*storages.Storage,
+	encryptionKey []byte,
 	backupProgressListener func(
 		completedMBs float64,
 	),
-) error {
+) (*usecases_common.BackupMetadata, error) {
 	if database.Type == databases.DatabaseTypePostgres {
 		return uc.CreatePostgresqlBackupUsecase.Execute(
+			ctx,
 			backupID,
 			backupConfig,
 			database,
-			storage,
+			backupStorages,
+			encryptionKey,
 			backupProgressListener,
 		)
 	}
 
-	return errors.New("database type not supported")
+	return nil, errors.New("database type not supported")
+}
+
+func (uc *CreateBackupUsecase) GetSourceSize(
+	backupConfig *backups_config.BackupConfig,
+	database *databases.Database,
+) (int64, error) {
+	if database.Type == databases.DatabaseTypePostgres {
+		return uc.CreatePostgresqlBackupUsecase.GetSourceSize(backupConfig, database)
+	}
+
+	return 0, errors.New("database type not supported")
+}
+
+func (uc *CreateBackupUsecase) ListBackupObjects(
+	ctx context.Context,
+	database *databases.Database,
+	dumpFormat backups_config.BackupDumpFormat,
+	dumpReader io.Reader,
+) ([]*usecases_common.BackupObjectMetadata, error) {
+	if database.Type == databases.DatabaseTypePostgres {
+		return uc.CreatePostgresqlBackupUsecase.ListBackupObjects(
+			ctx,
+			database,
+			dumpFormat,
+			dumpReader,
+		)
+	}
+
+	return nil, errors.New("database type not supported")
 }
@@ -9,110 +9,611 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"postgresus-backend/internal/config"
+	usecases_common "postgresus-backend/internal/features/backups/backups/usecases/common"
 	backups_config "postgresus-backend/internal/features/backups/config"
 	"postgresus-backend/internal/features/databases"
 	pgtypes "postgresus-backend/internal/features/databases/databases/postgresql"
 	"postgresus-backend/internal/features/storages"
+	encryption_utils "postgresus-backend/internal/util/encryption"
+	files_utils "postgresus-backend/internal/util/files"
 	"postgresus-backend/internal/util/tools"
 
 	"github.com/google/uuid"
 )
 
+// sizedTocEntryTypes are TOC entries of relations holding data
+var sizedTocEntryTypes = []string{
+	"TABLE",
+	"TABLE DATA",
+	"MATERIALIZED VIEW",
+	"MATERIALIZED VIEW DATA",
+}
+
 type CreatePostgresqlBackupUsecase struct {
 	logger *slog.Logger
 }
 
-// Execute creates a backup of the database
+// Execute creates a backup of the database and returns checksum and size of the backup file
 func (uc *CreatePostgresqlBackupUsecase) Execute(
+	ctx context.Context,
 	backupID uuid.UUID,
 	backupConfig *backups_config.BackupConfig,
 	db *databases.Database,
-	storage *storages.Storage,
+	backupStorages []*storages.Storage,
+	encryptionKey []byte,
 	backupProgressListener func(
 		completedMBs float64,
 	),
-) error {
+) (*usecases_common.BackupMetadata, error) {
+	if !backupConfig.IsBackupsEnabled {
+		return nil, fmt.Errorf("backups are not enabled for this database: \"%s\"", db.Name)
+	}
+
+	pg := db.Postgresql
+
+	if pg == nil {
+		return nil, fmt.Errorf("postgresql database configuration is required for pg_dump backups")
+	}
+
+	if len(backupStorages) == 0 {
+		return nil, fmt.Errorf("at least one storage is required for backups")
+	}
+
+	if backupConfig.BackupMethod == backups_config.BackupMethodPhysical {
+		return uc.executePhysicalBackup(
+			ctx,
+			backupID,
+			backupConfig,
+			db,
+			backupStorages,
+			encryptionKey,
+			backupProgressListener,
+		)
+	}
+
+	// the database could be switched to an older version
+	// after the config was saved
+	if backupConfig.Compression != "" {
+		if err := backupConfig.ValidateCompressionForVersion(pg.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	// pg_dump reads a consistent snapshot on a standby as well, so logical
+	// backups are taken from a healthy replica. Physical backups stay on the
+	// primary, the base backup belongs to the WAL archived from it
+	if len(pg.ReplicaEndpoints) > 0 {
+		sourceDb := *db
+		sourceDb.Postgresql = pg.GetBackupSource(uc.logger)
+
+		db = &sourceDb
+		pg = sourceDb.Postgresql
+	}
+
+	if pg.IsWholeServer {
+		if !backupConfig.BackupFilters.IsEmpty() {
+			return nil, fmt.Errorf("schema and table filters are not supported for whole server backups")
+		}
+
+		return uc.executeWholeServerBackup(
+			ctx,
+			backupConfig,
+			db,
+			backupStorages,
+			encryptionKey,
+			backupProgressListener,
+		)
+	}
+
 	uc.logger.Info(
 		"Creating PostgreSQL backup via pg_dump custom format",
 		"databaseId",
 		db.ID,
-		"storageId",
-		storage.ID,
+		"storageIds",
+		getStorageIDs(backupStorages),
 	)
 
-	if !backupConfig.IsBackupsEnabled {
-		return fmt.Errorf("backups are not enabled for this database: \"%s\"", db.Name)
+	if pg.Database == nil || *pg.Database == "" {
+		return nil, fmt.Errorf("database name is required for pg_dump backups")
 	}
 
+	if err := uc.validateBackupFilters(pg, *pg.Database, &backupConfig.BackupFilters); err != nil {
+		return nil, err
+	}
+
+	args := uc.buildPgDumpArgs(backupConfig, pg, *pg.Database)
+	args = append(args, backupConfig.BackupFilters.ToPgDumpArgs()...)
+
+	return uc.streamToStorage(
+		ctx,
+		backupID,
+		backupConfig,
+		tools.GetPostgresqlExecutable(
+			pg.Version,
+			"pg_dump",
+			config.GetEnv().EnvMode,
+			config.GetEnv().PostgresesInstallDir,
+		),
+		args,
+		backupConfig.DumpFormat == backups_config.BackupDumpFormatDirectory,
+		pg.Password,
+		backupStorages,
+		db,
+		encryptionKey,
+		backupProgressListener,
+	)
+}
+
+// GetSourceSize returns size of the data the backup reads, it is used
+// with compression ratios of past backups to estimate the backup size
+func (uc *CreatePostgresqlBackupUsecase) GetSourceSize(
+	backupConfig *backups_config.BackupConfig,
+	db *databases.Database,
+) (int64, error) {
 	pg := db.Postgresql
 
 	if pg == nil {
-		return fmt.Errorf("postgresql database configuration is required for pg_dump backups")
+		return 0, fmt.Errorf("postgresql database configuration is required")
+	}
+
+	if backupConfig.BackupMethod == backups_config.BackupMethodPhysical {
+		return pg.GetServerSize()
+	}
+
+	// logical backup reads the replica picked as in Execute, so the
+	// primary is not queried when it should not be loaded by backups
+	if len(pg.ReplicaEndpoints) > 0 {
+		pg = pg.GetBackupSource(uc.logger)
+	}
+
+	if pg.IsWholeServer {
+		return pg.GetServerSize()
 	}
 
 	if pg.Database == nil || *pg.Database == "" {
-		return fmt.Errorf("database name is required for pg_dump backups")
+		return 0, fmt.Errorf("database name is required")
+	}
+
+	return pg.GetTableDataSize(*pg.Database)
+}
+
+// ListBackupObjects reads table of contents of the stored dump with
+// pg_restore --list. Custom format dump starts with the table of contents,
+// so reading stops early. Directory dump has it in toc.dat of the tar
+func (uc *CreatePostgresqlBackupUsecase) ListBackupObjects(
+	ctx context.Context,
+	db *databases.Database,
+	dumpFormat backups_config.BackupDumpFormat,
+	dumpReader io.Reader,
+) ([]*usecases_common.BackupObjectMetadata, error) {
+	pg := db.Postgresql
+
+	if pg == nil || pg.Database == nil || *pg.Database == "" {
+		return nil, fmt.Errorf("objects can be listed only for a single database")
+	}
+
+	pgRestoreBin := tools.GetPostgresqlExecutable(
+		pg.Version,
+		tools.PostgresqlExecutablePgRestore,
+		config.GetEnv().EnvMode,
+		config.GetEnv().PostgresesInstallDir,
+	)
+
+	var cmd *exec.Cmd
+
+	if dumpFormat == backups_config.BackupDumpFormatDirectory {
+		dumpDir, err := uc.createTempDumpDirectory()
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = os.RemoveAll(filepath.Dir(dumpDir))
+		}()
+
+		if err := files_utils.ExtractTarFile(dumpReader, "toc.dat", dumpDir); err != nil {
+			return nil, err
+		}
+
+		cmd = exec.CommandContext(ctx, pgRestoreBin, "-Fd", "--list", dumpDir)
+	} else {
+		cmd = exec.CommandContext(ctx, pgRestoreBin, "-Fc", "--list")
+		cmd.Stdin = dumpReader
+	}
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("pg_restore --list failed: %w – stderr: %s", err, stderr.String())
+	}
+
+	// sizes are informational, objects are listed without them on error
+	relationSizes, err := pg.GetRelationSizes(*pg.Database)
+	if err != nil {
+		uc.logger.Warn("Failed to get relation sizes", "databaseId", db.ID, "error", err)
+	}
+
+	var objects []*usecases_common.BackupObjectMetadata
+	for _, entry := range tools.ParseToc(string(output)) {
+		object := &usecases_common.BackupObjectMetadata{
+			DumpID: entry.DumpID,
+			Type:   entry.Type,
+			Schema: entry.Schema,
+			Name:   entry.Name,
+			Owner:  entry.Owner,
+		}
+
+		if slices.Contains(sizedTocEntryTypes, entry.Type) {
+			if sizeBytes, isFound := relationSizes[entry.Schema+"."+entry.Name]; isFound {
+				object.SizeBytes = &sizeBytes
+			}
+		}
+
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// executeWholeServerBackup dumps globals (roles, tablespaces) and then each
+// database of the server one by one. Every dump is a separate file in storage,
+// so a single database can be downloaded or restored without the others
+func (uc *CreatePostgresqlBackupUsecase) executeWholeServerBackup(
+	ctx context.Context,
+	backupConfig *backups_config.BackupConfig,
+	db *databases.Database,
+	backupStorages []*storages.Storage,
+	encryptionKey []byte,
+	backupProgressListener func(completedMBs float64),
+) (*usecases_common.BackupMetadata, error) {
+	pg := db.Postgresql
+
+	databaseNames, err := pg.ListDatabases()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases of the server: %w", err)
+	}
+
+	uc.logger.Info(
+		"Creating PostgreSQL whole server backup",
+		"databaseId",
+		db.ID,
+		"storageIds",
+		getStorageIDs(backupStorages),
+		"databases",
+		databaseNames,
+	)
+
+	metadata := &usecases_common.BackupMetadata{
+		SourceHost:     db.Postgresql.GetEndpoint(),
+		FailedStorages: map[uuid.UUID]string{},
+	}
+
+	// entries of the backup should be in the same storages, so the
+	// storage which failed one entry does not receive the next ones
+	allStorages := backupStorages
+
+	// listener receives size of the current file only,
+	// so sizes of already finished files are added
+	var finishedMBs float64
+	entryProgressListener := func(completedMBs float64) {
+		if backupProgressListener != nil {
+			backupProgressListener(finishedMBs + completedMBs)
+		}
+	}
+
+	backupEntry := func(
+		isGlobals bool,
+		databaseName string,
+		pgBin string,
+		args []string,
+		isDirectoryDump bool,
+	) error {
+		entryID := uuid.New()
+
+		entryMetadata, err := uc.streamToStorage(
+			ctx,
+			entryID,
+			backupConfig,
+			pgBin,
+			args,
+			isDirectoryDump,
+			pg.Password,
+			backupStorages,
+			db,
+			encryptionKey,
+			entryProgressListener,
+		)
+		if err != nil {
+			uc.removeEntryFiles(allStorages, append(metadata.Entries, &usecases_common.BackupEntryMetadata{
+				ID: entryID,
+			}))
+
+			return err
+		}
+
+		for storageID, failMessage := range entryMetadata.FailedStorages {
+			metadata.FailedStorages[storageID] = failMessage
+		}
+
+		backupStorages = slices.DeleteFunc(slices.Clone(backupStorages), func(storage *storages.Storage) bool {
+			_, isFailed := entryMetadata.FailedStorages[storage.ID]
+			return isFailed
+		})
+
+		finishedMBs += float64(entryMetadata.SizeBytes) / (1024 * 1024)
+
+		metadata.Entries = append(metadata.Entries, &usecases_common.BackupEntryMetadata{
+			ID:           entryID,
+			IsGlobals:    isGlobals,
+			DatabaseName: databaseName,
+			Sha256:       entryMetadata.Sha256,
+			SizeBytes:    entryMetadata.SizeBytes,
+		})
+
+		return nil
 	}
 
+	if err := backupEntry(
+		true,
+		"",
+		tools.GetPostgresqlExecutable(
+			pg.Version,
+			tools.PostgresqlExecutablePgDumpall,
+			config.GetEnv().EnvMode,
+			config.GetEnv().PostgresesInstallDir,
+		),
+		buildPgDumpallGlobalsArgs(pg),
+		false,
+	); err != nil {
+		return nil, fmt.Errorf("failed to back up globals: %w", err)
+	}
+
+	pgDumpBin := tools.GetPostgresqlExecutable(
+		pg.Version,
+		tools.PostgresqlExecutablePgDump,
+		config.GetEnv().EnvMode,
+		config.GetEnv().PostgresesInstallDir,
+	)
+
+	for _, databaseName := range databaseNames {
+		if err := backupEntry(
+			false,
+			databaseName,
+			pgDumpBin,
+			uc.buildPgDumpArgs(backupConfig, pg, databaseName),
+			backupConfig.DumpFormat == backups_config.BackupDumpFormatDirectory,
+		); err != nil {
+			return nil, fmt.Errorf("failed to back up database '%s': %w", databaseName, err)
+		}
+	}
+
+	return metadata, nil
+}
+
+// validateBackupFilters checks each pattern matches something in the database.
+// pg_dump silently ignores exclude patterns which match nothing, so a typo
+// would make the dump include a table the user expects to be skipped
+func (uc *CreatePostgresqlBackupUsecase) validateBackupFilters(
+	pg *pgtypes.PostgresqlDatabase,
+	databaseName string,
+	filters *backups_config.BackupFilters,
+) error {
+	if filters.IsEmpty() {
+		return nil
+	}
+
+	catalog, err := pg.LoadCatalog(databaseName)
+	if err != nil {
+		return fmt.Errorf("failed to load database catalog to validate filters: %w", err)
+	}
+
+	for _, pattern := range slices.Concat(filters.IncludedSchemas, filters.ExcludedSchemas) {
+		isFound, err := catalog.HasSchema(pattern)
+		if err != nil {
+			return err
+		}
+
+		if !isFound {
+			return fmt.Errorf("no schema matches pattern \"%s\"", pattern)
+		}
+	}
+
+	tablePatterns := slices.Concat(
+		filters.IncludedTables,
+		filters.ExcludedTables,
+		filters.ExcludedTableData,
+	)
+
+	for _, pattern := range tablePatterns {
+		isFound, err := catalog.HasTable(pattern)
+		if err != nil {
+			return err
+		}
+
+		if !isFound {
+			return fmt.Errorf("no table matches pattern \"%s\"", pattern)
+		}
+	}
+
+	return nil
+}
+
+// removeEntryFiles cleans up files of the failed whole server backup,
+// the backup itself has no file which would be removed with it
+func (uc *CreatePostgresqlBackupUsecase) removeEntryFiles(
+	backupStorages []*storages.Storage,
+	entries []*usecases_common.BackupEntryMetadata,
+) {
+	for _, storage := range backupStorages {
+		for _, entry := range entries {
+			if err := storage.DeleteFile(entry.ID); err != nil {
+				uc.logger.Warn(
+					"Failed to remove backup entry file",
+					"entryId",
+					entry.ID,
+					"storageId",
+					storage.ID,
+					"error",
+					err,
+				)
+			}
+		}
+	}
+}
+
+// buildPgDumpallGlobalsArgs dumps roles and tablespaces only, databases
+// are dumped by pg_dump into entries of their own
+func buildPgDumpallGlobalsArgs(pg *pgtypes.PostgresqlDatabase) []string {
+	return []string{
+		"--globals-only",
+		"--no-password", // Use environment variable for password, prevent prompts
+		"-h", pg.Host,
+		"-p", strconv.Itoa(pg.Port),
+		"-U", pg.Username,
+		"-l", pg.GetMaintenanceDatabase(),
+		"--verbose", // Add verbose output to help with debugging
+	}
+}
+
+func (uc *CreatePostgresqlBackupUsecase) buildPgDumpArgs(
+	backupConfig *backups_config.BackupConfig,
+	pg *pgtypes.PostgresqlDatabase,
+	databaseName string,
+) []string {
 	args := []string{
-		"-Fc",           // custom format with built-in compression
 		"--no-password", // Use environment variable for password, prevent prompts
 		"-h", pg.Host,
 		"-p", strconv.Itoa(pg.Port),
 		"-U", pg.Username,
-		"-d", *pg.Database,
+		"-d", databaseName,
 		"--verbose", // Add verbose output to help with debugging
 	}
 
-	// Use zstd compression level 5 for PostgreSQL 15+ (better compression and speed)
-	// Fall back to gzip compression level 5 for older versions
-	if pg.Version == tools.PostgresqlVersion13 || pg.Version == tools.PostgresqlVersion14 ||
-		pg.Version == tools.PostgresqlVersion15 {
-		args = append(args, "-Z", "5")
-		uc.logger.Info("Using gzip compression level 5 (zstd not available)", "version", pg.Version)
+	// only directory format can be dumped in parallel
+	if backupConfig.DumpFormat == backups_config.BackupDumpFormatDirectory {
+		args = append(args, "-Fd", "-j", strconv.Itoa(max(1, backupConfig.CpuCount)))
 	} else {
-		args = append(args, "--compress=zstd:5")
-		uc.logger.Info("Using zstd compression level 5", "version", pg.Version)
+		args = append(args, "-Fc") // custom format with built-in compression
+	}
+
+	compression := backupConfig.Compression
+	compressionLevel := backupConfig.CompressionLevel
+	if compression == "" {
+		compression = backups_config.GetDefaultCompression(pg.Version)
+		compressionLevel = backups_config.DefaultCompressionLevel
+	}
+
+	// -Z with a number means gzip for all versions, algorithm
+	// names in --compress are understood by pg_dump 16+ only
+	switch compression {
+	case backups_config.BackupCompressionNone:
+		args = append(args, "-Z", "0")
+	case backups_config.BackupCompressionGzip:
+		args = append(args, "-Z", strconv.Itoa(compressionLevel))
+	case backups_config.BackupCompressionLz4:
+		args = append(args, "--compress=lz4:"+strconv.Itoa(compressionLevel))
+	case backups_config.BackupCompressionZstd:
+		args = append(args, "--compress=zstd:"+strconv.Itoa(compressionLevel))
+	}
+
+	uc.logger.Info(
+		"Using compression",
+		"compression",
+		compression,
+		"level",
+		compressionLevel,
+		"version",
+		pg.Version,
+	)
+
+	return args
+}
+
+// executePhysicalBackup creates a backup of the whole cluster via pg_basebackup.
+// Tar output goes to stdout, so it passes the same pipeline as pg_dump output.
+// WAL is fetched at the end of the backup (-X fetch), because pg_basebackup
+// cannot stream WAL in parallel when writing the tar to stdout
+func (uc *CreatePostgresqlBackupUsecase) executePhysicalBackup(
+	ctx context.Context,
+	backupID uuid.UUID,
+	backupConfig *backups_config.BackupConfig,
+	db *databases.Database,
+	backupStorages []*storages.Storage,
+	encryptionKey []byte,
+	backupProgressListener func(completedMBs float64),
+) (*usecases_common.BackupMetadata, error) {
+	uc.logger.Info(
+		"Creating PostgreSQL physical backup via pg_basebackup",
+		"databaseId",
+		db.ID,
+		"storageIds",
+		getStorageIDs(backupStorages),
+	)
+
+	pg := db.Postgresql
+
+	args := []string{
+		"-D", "-", // write tar to stdout
+		"-Ft",
+		"-X", "fetch",
+		"--checkpoint=fast", // do not wait for the next scheduled checkpoint
+		"--no-password",     // Use environment variable for password, prevent prompts
+		"-h", pg.Host,
+		"-p", strconv.Itoa(pg.Port),
+		"-U", pg.Username,
+		"--verbose", // Add verbose output to help with debugging
 	}
 
 	return uc.streamToStorage(
+		ctx,
 		backupID,
 		backupConfig,
 		tools.GetPostgresqlExecutable(
 			pg.Version,
-			"pg_dump",
+			tools.PostgresqlExecutablePgBasebackup,
 			config.GetEnv().EnvMode,
 			config.GetEnv().PostgresesInstallDir,
 		),
 		args,
+		false,
 		pg.Password,
-		storage,
+		backupStorages,
 		db,
+		encryptionKey,
 		backupProgressListener,
 	)
 }
 
-// streamToStorage streams pg_dump output directly to storage
+// streamToStorage streams pg_dump output directly to storages in parallel.
+// When encryption key is passed, the output is encrypted before it leaves
+// Postgresus. Directory dump cannot be written to stdout, so it is written to
+// a temporary directory and streamed to storage as tar when pg_dump is
+// finished. Backup fails only if no storage saved the file
 func (uc *CreatePostgresqlBackupUsecase) streamToStorage(
+	parentCtx context.Context,
 	backupID uuid.UUID,
 	backupConfig *backups_config.BackupConfig,
 	pgBin string,
 	args []string,
+	isDirectoryDump bool,
 	password string,
-	storage *storages.Storage,
+	backupStorages []*storages.Storage,
 	db *databases.Database,
+	encryptionKey []byte,
 	backupProgressListener func(completedMBs float64),
-) error {
+) (*usecases_common.BackupMetadata, error) {
 	uc.logger.Info("Streaming PostgreSQL backup to storage", "pgBin", pgBin, "args", args)
 
 	// if backup not fit into 23 hours, Postgresus
 	// seems not to work for such database size
-	ctx, cancel := context.WithTimeout(context.Background(), 23*time.Hour)
+	ctx, cancel := context.WithTimeout(parentCtx, 23*time.Hour)
 	defer cancel()
 
 	// Monitor for shutdown and cancel context if needed
@@ -136,7 +637,7 @@ func (uc *CreatePostgresqlBackupUsecase) streamToStorage(
 	// Create temporary .pgpass file as a more reliable alternative to PGPASSWORD
 	pgpassFile, err := uc.createTempPgpassFile(db.Postgresql, password)
 	if err != nil {
-		return fmt.Errorf("failed to create temporary .pgpass file: %w", err)
+		return nil, fmt.Errorf("failed to create temporary .pgpass file: %w", err)
 	}
 	defer func() {
 		if pgpassFile != "" {
@@ -146,7 +647,7 @@ func (uc *CreatePostgresqlBackupUsecase) streamToStorage(
 
 	// Verify .pgpass file was created successfully
 	if pgpassFile == "" {
-		return fmt.Errorf("temporary .pgpass file was not created")
+		return nil, fmt.Errorf("temporary .pgpass file was not created")
 	}
 
 	// Verify .pgpass file was created correctly
@@ -157,7 +658,20 @@ func (uc *CreatePostgresqlBackupUsecase) streamToStorage(
 			"mode", info.Mode(),
 		)
 	} else {
-		return fmt.Errorf("failed to verify .pgpass file: %w", err)
+		return nil, fmt.Errorf("failed to verify .pgpass file: %w", err)
+	}
+
+	var dumpDir string
+	if isDirectoryDump {
+		dumpDir, err = uc.createTempDumpDirectory()
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = os.RemoveAll(filepath.Dir(dumpDir))
+		}()
+
+		args = append(args, "-f", dumpDir)
 	}
 
 	cmd := exec.CommandContext(ctx, pgBin, args...)
@@ -210,21 +724,35 @@ func (uc *CreatePostgresqlBackupUsecase) streamToStorage(
 
 	// Verify executable exists and is accessible
 	if _, err := exec.LookPath(pgBin); err != nil {
-		return fmt.Errorf(
+		return nil, fmt.Errorf(
 			"PostgreSQL executable not found or not accessible: %s - %w",
 			pgBin,
 			err,
 		)
 	}
 
-	pgStdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("stdout pipe: %w", err)
+	var pgOutput io.Reader
+	var tarReader *io.PipeReader
+	var tarWriter *io.PipeWriter
+	waitCmd := cmd.Wait
+	waitErrCh := make(chan error, 1)
+
+	if isDirectoryDump {
+		tarReader, tarWriter = io.Pipe()
+		pgOutput = tarReader
+		waitCmd = func() error { return <-waitErrCh }
+	} else {
+		pgStdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("stdout pipe: %w", err)
+		}
+
+		pgOutput = pgStdout
 	}
 
 	pgStderr, err := cmd.StderrPipe()
 	if err != nil {
-		return fmt.Errorf("stderr pipe: %w", err)
+		return nil, fmt.Errorf("stderr pipe: %w", err)
 	}
 
 	// Capture stderr in a separate goroutine to ensure we don't miss any error output
@@ -234,23 +762,37 @@ func (uc *CreatePostgresqlBackupUsecase) streamToStorage(
 		stderrCh <- stderrOutput
 	}()
 
-	// A pipe connecting pg_dump output → storage
+	// A pipe connecting pg_dump output → (encryption) → storage
 	storageReader, storageWriter := io.Pipe()
 
-	// Create a counting writer to track bytes
-	countingWriter := &CountingWriter{writer: storageWriter}
+	var backupWriter io.WriteCloser = storageWriter
+	if encryptionKey != nil {
+		encryptingWriter, err := encryption_utils.NewEncryptingWriter(storageWriter, encryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize encryption: %w", err)
+		}
+
+		backupWriter = encryptingWriter
+	}
+
+	// Create a counting writer to track bytes and checksum of pg_dump output
+	countingWriter := NewCountingWriter(backupWriter)
 
 	// The backup ID becomes the object key / filename in storage
 
-	// Start streaming into storage in its own goroutine
-	saveErrCh := make(chan error, 1)
+	// Start streaming into storages in its own goroutine
+	saveErrsCh := make(chan []error, 1)
 	go func() {
-		saveErrCh <- storage.SaveFile(uc.logger, backupID, storageReader)
+		saveErrsCh <- uc.saveToStorages(backupID, backupStorages, storageReader)
 	}()
 
 	// Start pg_dump
 	if err = cmd.Start(); err != nil {
-		return fmt.Errorf("start %s: %w", filepath.Base(pgBin), err)
+		return nil, fmt.Errorf("start %s: %w", filepath.Base(pgBin), err)
+	}
+
+	if isDirectoryDump {
+		go uc.packDumpDirectory(cmd, dumpDir, tarWriter, waitErrCh)
 	}
 
 	// Copy pg output directly to storage with shutdown checks
@@ -260,7 +802,7 @@ func (uc *CreatePostgresqlBackupUsecase) streamToStorage(
 		bytesWritten, err := uc.copyWithShutdownCheck(
 			ctx,
 			countingWriter,
-			pgStdout,
+			pgOutput,
 			backupProgressListener,
 		)
 		bytesWrittenCh <- bytesWritten
@@ -270,29 +812,37 @@ func (uc *CreatePostgresqlBackupUsecase) streamToStorage(
 	// Wait for the copy to finish first, then the dump process
 	copyErr := <-copyResultCh
 	bytesWritten := <-bytesWrittenCh
-	waitErr := cmd.Wait()
+
+	// unblocks tar packing if the copy stopped before the end
+	if tarReader != nil {
+		_ = tarReader.Close()
+	}
+
+	waitErr := waitCmd()
 
 	// Check for shutdown before finalizing
 	if config.IsShouldShutdown() {
-		if pipeWriter, ok := countingWriter.writer.(*io.PipeWriter); ok {
-			if err := pipeWriter.Close(); err != nil {
-				uc.logger.Error("Failed to close counting writer", "error", err)
-			}
+		if err := storageWriter.Close(); err != nil {
+			uc.logger.Error("Failed to close storage writer", "error", err)
 		}
 
-		<-saveErrCh // Wait for storage to finish
-		return fmt.Errorf("backup cancelled due to shutdown")
+		<-saveErrsCh // Wait for storages to finish
+		return nil, fmt.Errorf("backup cancelled due to shutdown")
 	}
 
-	// Close the pipe writer to signal end of data
-	if pipeWriter, ok := countingWriter.writer.(*io.PipeWriter); ok {
-		if err := pipeWriter.Close(); err != nil {
-			uc.logger.Error("Failed to close counting writer", "error", err)
+	// Flush the last encrypted chunk (if any) and close the pipe to signal end of data
+	if backupWriter != storageWriter && copyErr == nil && waitErr == nil {
+		if err := backupWriter.Close(); err != nil {
+			copyErr = fmt.Errorf("failed to finalize encryption: %w", err)
 		}
 	}
 
-	// Wait until storage ends reading
-	saveErr := <-saveErrCh
+	if err := storageWriter.Close(); err != nil {
+		uc.logger.Error("Failed to close storage writer", "error", err)
+	}
+
+	// Wait until storages end reading
+	failedStorages, saveErr := uc.collectSaveErrors(backupStorages, <-saveErrsCh)
 	stderrOutput := <-stderrCh
 
 	// Send final sizing after backup is completed
@@ -304,7 +854,7 @@ func (uc *CreatePostgresqlBackupUsecase) streamToStorage(
 	switch {
 	case waitErr != nil:
 		if config.IsShouldShutdown() {
-			return fmt.Errorf("backup cancelled due to shutdown")
+			return nil, fmt.Errorf("backup cancelled due to shutdown")
 		}
 
 		// Enhanced error handling for PostgreSQL connection and SSL issues
@@ -400,22 +950,112 @@ func (uc *CreatePostgresqlBackupUsecase) streamToStorage(
 			}
 		}
 
-		return errors.New(errorMsg)
-	case copyErr != nil:
+		return nil, errors.New(errorMsg)
+	// copy fails with ErrAllConsumersFailed when every storage failed,
+	// so errors of the storages are more telling
+	case saveErr != nil:
 		if config.IsShouldShutdown() {
-			return fmt.Errorf("backup cancelled due to shutdown")
+			return nil, fmt.Errorf("backup cancelled due to shutdown")
 		}
 
-		return fmt.Errorf("copy to storage: %w", copyErr)
-	case saveErr != nil:
+		return nil, fmt.Errorf("save to storage: %w", saveErr)
+	case copyErr != nil:
 		if config.IsShouldShutdown() {
-			return fmt.Errorf("backup cancelled due to shutdown")
+			return nil, fmt.Errorf("backup cancelled due to shutdown")
 		}
 
-		return fmt.Errorf("save to storage: %w", saveErr)
+		return nil, fmt.Errorf("copy to storage: %w", copyErr)
 	}
 
-	return nil
+	return &usecases_common.BackupMetadata{
+		Sha256:         countingWriter.GetSha256(),
+		SizeBytes:      countingWriter.GetBytesWritten(),
+		SourceHost:     db.Postgresql.GetEndpoint(),
+		FailedStorages: failedStorages,
+	}, nil
+}
+
+// saveToStorages uploads the same stream to every storage, slow storage
+// slows down the others because the stream is read once
+func (uc *CreatePostgresqlBackupUsecase) saveToStorages(
+	backupID uuid.UUID,
+	backupStorages []*storages.Storage,
+	storageReader *io.PipeReader,
+) []error {
+	consumers := make([]func(reader io.Reader) error, 0, len(backupStorages))
+
+	for _, storage := range backupStorages {
+		consumers = append(consumers, func(reader io.Reader) error {
+			return storage.SaveFile(uc.logger, backupID, reader)
+		})
+	}
+
+	return files_utils.FanOut(storageReader, consumers)
+}
+
+// collectSaveErrors returns error only when all storages failed, otherwise
+// failed storages are returned to be recorded as failed copies
+func (uc *CreatePostgresqlBackupUsecase) collectSaveErrors(
+	backupStorages []*storages.Storage,
+	saveErrs []error,
+) (map[uuid.UUID]string, error) {
+	failedStorages := map[uuid.UUID]string{}
+
+	for i, saveErr := range saveErrs {
+		if saveErr == nil {
+			continue
+		}
+
+		uc.logger.Warn(
+			"Failed to save backup to storage",
+			"storageId",
+			backupStorages[i].ID,
+			"error",
+			saveErr,
+		)
+
+		failedStorages[backupStorages[i].ID] = saveErr.Error()
+	}
+
+	if len(failedStorages) == len(backupStorages) {
+		return nil, errors.Join(saveErrs...)
+	}
+
+	return failedStorages, nil
+}
+
+// packDumpDirectory waits for pg_dump to finish and streams the dump
+// directory as tar. On pg_dump failure the reader side gets the error
+func (uc *CreatePostgresqlBackupUsecase) packDumpDirectory(
+	cmd *exec.Cmd,
+	dumpDir string,
+	tarWriter *io.PipeWriter,
+	waitErrCh chan<- error,
+) {
+	waitErr := cmd.Wait()
+	waitErrCh <- waitErr
+
+	if waitErr != nil {
+		_ = tarWriter.CloseWithError(waitErr)
+		return
+	}
+
+	_ = tarWriter.CloseWithError(files_utils.WriteTar(tarWriter, dumpDir))
+}
+
+// createTempDumpDirectory returns a path for pg_dump -Fd, the path
+// itself is not created because pg_dump refuses existing directories
+func (uc *CreatePostgresqlBackupUsecase) createTempDumpDirectory() (string, error) {
+	if err := files_utils.EnsureDirectories([]string{config.GetEnv().TempFolder}); err != nil {
+		return "", fmt.Errorf("failed to ensure directories: %w", err)
+	}
+
+	tempDir, err := os.MkdirTemp(config.GetEnv().TempFolder, "backup_"+uuid.New().String())
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	return filepath.Join(tempDir, "dump"), nil
 }
 
 // copyWithShutdownCheck copies data from src to dst while checking for shutdown
@@ -522,3 +1162,13 @@ func (uc *CreatePostgresqlBackupUsecase) createTempPgpassFile(
 
 	return pgpassFile, nil
 }
+
+func getStorageIDs(backupStorages []*storages.Storage) []uuid.UUID {
+	storageIDs := make([]uuid.UUID, 0, len(backupStorages))
+
+	for _, storage := range backupStorages {
+		storageIDs = append(storageIDs, storage.ID)
+	}
+
+	return storageIDs
+}
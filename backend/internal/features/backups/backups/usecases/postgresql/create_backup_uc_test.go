@@ -0,0 +1,118 @@
+package usecases_postgresql
+
+import (
+	"slices"
+	"testing"
+
+	backups_config "postgresus-backend/internal/features/backups/config"
+	pgtypes "postgresus-backend/internal/features/databases/databases/postgresql"
+	"postgresus-backend/internal/util/logger"
+	"postgresus-backend/internal/util/tools"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BuildPgDumpallGlobalsArgs_WithMaintenanceDatabase_DumpsGlobalsOnly(t *testing.T) {
+	maintenanceDatabase := "maintenance"
+	pg := &pgtypes.PostgresqlDatabase{
+		Version:       tools.PostgresqlVersion16,
+		Host:          "db.internal",
+		Port:          5433,
+		Username:      "backup",
+		Database:      &maintenanceDatabase,
+		IsWholeServer: true,
+	}
+
+	args := buildPgDumpallGlobalsArgs(pg)
+
+	assert.Contains(t, args, "--globals-only")
+	assertArgValue(t, args, "-l", "maintenance")
+	assertArgValue(t, args, "-h", "db.internal")
+	assertArgValue(t, args, "-p", "5433")
+	assertArgValue(t, args, "-U", "backup")
+
+	// data of databases goes to entries of their own
+	assert.NotContains(t, args, "-d")
+}
+
+func Test_BuildPgDumpArgs_ForDatabaseOfWholeServer_DumpsThisDatabase(t *testing.T) {
+	uc := &CreatePostgresqlBackupUsecase{logger: logger.GetLogger()}
+
+	maintenanceDatabase := "postgres"
+	pg := &pgtypes.PostgresqlDatabase{
+		Version:       tools.PostgresqlVersion16,
+		Host:          "db.internal",
+		Port:          5432,
+		Username:      "backup",
+		Database:      &maintenanceDatabase,
+		IsWholeServer: true,
+	}
+
+	backupConfig := &backups_config.BackupConfig{
+		DumpFormat:       backups_config.BackupDumpFormatCustom,
+		Compression:      backups_config.BackupCompressionGzip,
+		CompressionLevel: 5,
+	}
+
+	for _, databaseName := range []string{"shop", "analytics"} {
+		args := uc.buildPgDumpArgs(backupConfig, pg, databaseName)
+
+		assertArgValue(t, args, "-d", databaseName)
+		assert.Contains(t, args, "-Fc")
+	}
+}
+
+func Test_BuildPgDumpArgs_WithCompression_ArgumentMatchesAlgorithm(t *testing.T) {
+	uc := &CreatePostgresqlBackupUsecase{logger: logger.GetLogger()}
+
+	databaseName := "shop"
+	testCases := []struct {
+		name         string
+		version      tools.PostgresqlVersion
+		compression  backups_config.BackupCompression
+		level        int
+		expectedArgs []string
+	}{
+		{"None", tools.PostgresqlVersion16, backups_config.BackupCompressionNone, 0, []string{"-Z", "0"}},
+		{"Gzip", tools.PostgresqlVersion13, backups_config.BackupCompressionGzip, 7, []string{"-Z", "7"}},
+		{"Lz4", tools.PostgresqlVersion16, backups_config.BackupCompressionLz4, 3, []string{"--compress=lz4:3"}},
+		{"Zstd", tools.PostgresqlVersion17, backups_config.BackupCompressionZstd, 19, []string{"--compress=zstd:19"}},
+		// configs saved before compression was configurable get the default
+		{"DefaultPg15", tools.PostgresqlVersion15, "", 0, []string{"-Z", "5"}},
+		{"DefaultPg16", tools.PostgresqlVersion16, "", 0, []string{"--compress=zstd:5"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pg := &pgtypes.PostgresqlDatabase{
+				Version:  testCase.version,
+				Host:     "db.internal",
+				Port:     5432,
+				Username: "backup",
+				Database: &databaseName,
+			}
+
+			backupConfig := &backups_config.BackupConfig{
+				DumpFormat:       backups_config.BackupDumpFormatCustom,
+				Compression:      testCase.compression,
+				CompressionLevel: testCase.level,
+			}
+
+			args := uc.buildPgDumpArgs(backupConfig, pg, databaseName)
+
+			assert.Equal(t, testCase.expectedArgs, args[len(args)-len(testCase.expectedArgs):])
+		})
+	}
+}
+
+func assertArgValue(t *testing.T, args []string, flag string, expectedValue string) {
+	t.Helper()
+
+	index := slices.Index(args, flag)
+	if !assert.NotEqual(t, -1, index, "flag %s is missing", flag) ||
+		!assert.Less(t, index+1, len(args), "flag %s has no value", flag) {
+		return
+	}
+
+	assert.Equal(t, expectedValue, args[index+1])
+}
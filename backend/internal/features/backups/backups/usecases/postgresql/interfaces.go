@@ -1,15 +1,30 @@
 package usecases_postgresql
 
-import "io"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+)
 
-// CountingWriter wraps an io.Writer and counts the bytes written to it
+// CountingWriter wraps an io.Writer, counts the bytes written
+// to it and computes their SHA-256 checksum
 type CountingWriter struct {
 	writer       io.Writer
+	hasher       hash.Hash
 	bytesWritten int64
 }
 
+func NewCountingWriter(writer io.Writer) *CountingWriter {
+	return &CountingWriter{
+		writer: writer,
+		hasher: sha256.New(),
+	}
+}
+
 func (cw *CountingWriter) Write(p []byte) (n int, err error) {
 	n, err = cw.writer.Write(p)
+	cw.hasher.Write(p[:n])
 	cw.bytesWritten += int64(n)
 	return n, err
 }
@@ -18,3 +33,8 @@ func (cw *CountingWriter) Write(p []byte) (n int, err error) {
 func (cw *CountingWriter) GetBytesWritten() int64 {
 	return cw.bytesWritten
 }
+
+// GetSha256 returns hex encoded SHA-256 of all bytes written so far
+func (cw *CountingWriter) GetSha256() string {
+	return hex.EncodeToString(cw.hasher.Sum(nil))
+}
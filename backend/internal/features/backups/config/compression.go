@@ -0,0 +1,77 @@
+package backups_config
+
+import (
+	"fmt"
+	"postgresus-backend/internal/util/tools"
+	"strconv"
+)
+
+const DefaultCompressionLevel = 5
+
+// GetDefaultCompression returns zstd where pg_dump supports it (PG 16+),
+// otherwise gzip which is supported by all versions
+func GetDefaultCompression(version tools.PostgresqlVersion) BackupCompression {
+	if isCompressionSupportedByVersion(BackupCompressionZstd, version) {
+		return BackupCompressionZstd
+	}
+
+	return BackupCompressionGzip
+}
+
+// ValidateCompressionForVersion checks pg_dump of the given
+// version is able to compress with the configured algorithm
+func (b *BackupConfig) ValidateCompressionForVersion(version tools.PostgresqlVersion) error {
+	if !isCompressionSupportedByVersion(b.Compression, version) {
+		return fmt.Errorf(
+			"%s compression requires PostgreSQL 16 or higher, database version is %s",
+			b.Compression,
+			version,
+		)
+	}
+
+	return nil
+}
+
+func (b *BackupConfig) validateCompression() error {
+	minLevel, maxLevel := 0, 0
+
+	switch b.Compression {
+	case "", BackupCompressionNone:
+		return nil
+	case BackupCompressionGzip:
+		minLevel, maxLevel = 1, 9
+	case BackupCompressionLz4:
+		minLevel, maxLevel = 1, 12
+	case BackupCompressionZstd:
+		minLevel, maxLevel = 1, 22
+	default:
+		return fmt.Errorf("invalid compression: %s", b.Compression)
+	}
+
+	if b.CompressionLevel < minLevel || b.CompressionLevel > maxLevel {
+		return fmt.Errorf(
+			"%s compression level must be between %d and %d",
+			b.Compression,
+			minLevel,
+			maxLevel,
+		)
+	}
+
+	return nil
+}
+
+func isCompressionSupportedByVersion(
+	compression BackupCompression,
+	version tools.PostgresqlVersion,
+) bool {
+	if compression != BackupCompressionLz4 && compression != BackupCompressionZstd {
+		return true
+	}
+
+	versionInt, err := strconv.Atoi(string(version))
+	if err != nil {
+		return false
+	}
+
+	return versionInt >= 16
+}
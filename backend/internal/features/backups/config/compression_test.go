@@ -0,0 +1,74 @@
+package backups_config
+
+import (
+	"postgresus-backend/internal/util/tools"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateCompressionForVersion_WhenAlgorithmNeedsPg16_OlderVersionsRejected(t *testing.T) {
+	testCases := []struct {
+		compression BackupCompression
+		version     tools.PostgresqlVersion
+		isValid     bool
+	}{
+		{BackupCompressionNone, tools.PostgresqlVersion13, true},
+		{BackupCompressionGzip, tools.PostgresqlVersion13, true},
+		{BackupCompressionLz4, tools.PostgresqlVersion15, false},
+		{BackupCompressionZstd, tools.PostgresqlVersion15, false},
+		{BackupCompressionLz4, tools.PostgresqlVersion16, true},
+		{BackupCompressionZstd, tools.PostgresqlVersion18, true},
+		{BackupCompressionZstd, tools.PostgresqlVersion("unknown"), false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(string(testCase.compression)+"_"+string(testCase.version), func(t *testing.T) {
+			backupConfig := &BackupConfig{Compression: testCase.compression}
+
+			err := backupConfig.ValidateCompressionForVersion(testCase.version)
+			if testCase.isValid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func Test_GetDefaultCompression_WhenPg16OrHigher_ZstdUsed(t *testing.T) {
+	assert.Equal(t, BackupCompressionGzip, GetDefaultCompression(tools.PostgresqlVersion15))
+	assert.Equal(t, BackupCompressionZstd, GetDefaultCompression(tools.PostgresqlVersion16))
+	assert.Equal(t, BackupCompressionZstd, GetDefaultCompression(tools.PostgresqlVersion17))
+}
+
+func Test_ValidateCompression_WhenLevelIsOutOfRange_ReturnsError(t *testing.T) {
+	testCases := []struct {
+		compression BackupCompression
+		level       int
+		isValid     bool
+	}{
+		{BackupCompressionNone, 0, true},
+		{BackupCompressionGzip, 9, true},
+		{BackupCompressionGzip, 10, false},
+		{BackupCompressionLz4, 12, true},
+		{BackupCompressionLz4, 0, false},
+		{BackupCompressionZstd, 22, true},
+		{BackupCompressionZstd, 23, false},
+		{BackupCompression("BROTLI"), 5, false},
+	}
+
+	for _, testCase := range testCases {
+		backupConfig := &BackupConfig{
+			Compression:      testCase.compression,
+			CompressionLevel: testCase.level,
+		}
+
+		err := backupConfig.validateCompression()
+		if testCase.isValid {
+			assert.NoError(t, err, "%s level %d", testCase.compression, testCase.level)
+		} else {
+			assert.Error(t, err, "%s level %d", testCase.compression, testCase.level)
+		}
+	}
+}
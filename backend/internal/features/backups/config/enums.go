@@ -5,4 +5,55 @@ type BackupNotificationType string
 const (
 	NotificationBackupFailed  BackupNotificationType = "BACKUP_FAILED"
 	NotificationBackupSuccess BackupNotificationType = "BACKUP_SUCCESS"
+
+	NotificationBackupVerificationFailed BackupNotificationType = "BACKUP_VERIFICATION_FAILED"
+
+	// NotificationBackupsPruned is sent when backups are pruned to fit caps
+	// or quotas, it is sent regardless of SendNotificationsOn
+	NotificationBackupsPruned BackupNotificationType = "BACKUPS_PRUNED"
+)
+
+type BackupEncryption string
+
+const (
+	BackupEncryptionNone      BackupEncryption = "NONE"
+	BackupEncryptionAES256GCM BackupEncryption = "AES_256_GCM"
+)
+
+type BackupMethod string
+
+const (
+	// BackupMethodLogical is pg_dump of a single database
+	BackupMethodLogical BackupMethod = "LOGICAL"
+	// BackupMethodPhysical is pg_basebackup of the whole cluster
+	BackupMethodPhysical BackupMethod = "PHYSICAL"
+)
+
+type BackupCompression string
+
+const (
+	BackupCompressionNone BackupCompression = "NONE"
+	BackupCompressionGzip BackupCompression = "GZIP"
+	BackupCompressionLz4  BackupCompression = "LZ4"
+	BackupCompressionZstd BackupCompression = "ZSTD"
+)
+
+type BackupDumpFormat string
+
+const (
+	// BackupDumpFormatCustom is a single pg_dump -Fc file
+	BackupDumpFormatCustom BackupDumpFormat = "CUSTOM"
+	// BackupDumpFormatDirectory is pg_dump -Fd made with CpuCount
+	// parallel jobs and stored as tar of the dump directory
+	BackupDumpFormatDirectory BackupDumpFormat = "DIRECTORY"
+)
+
+type BackupRetentionPolicy string
+
+const (
+	// BackupRetentionPolicyPeriod keeps backups younger than the store period
+	BackupRetentionPolicyPeriod BackupRetentionPolicy = "PERIOD"
+	// BackupRetentionPolicyGfs keeps the newest backup of each of the last
+	// N hours, days, weeks, months and years (grandfather-father-son)
+	BackupRetentionPolicyGfs BackupRetentionPolicy = "GFS"
 )
@@ -0,0 +1,122 @@
+package backups_config
+
+import (
+	"errors"
+	"strings"
+)
+
+// BackupFilters limits logical backup to a part of the database. Values are
+// pg_dump patterns, e.g. "public", "audit.*" or "public.log_*". Lists are
+// stored as comma separated strings, the same way as notification types
+type BackupFilters struct {
+	IncludedSchemas   []string `json:"includedSchemas"   gorm:"-"`
+	ExcludedSchemas   []string `json:"excludedSchemas"   gorm:"-"`
+	IncludedTables    []string `json:"includedTables"    gorm:"-"`
+	ExcludedTables    []string `json:"excludedTables"    gorm:"-"`
+	ExcludedTableData []string `json:"excludedTableData" gorm:"-"`
+
+	IncludedSchemasString   string `json:"-" gorm:"column:included_schemas;type:text;not null"`
+	ExcludedSchemasString   string `json:"-" gorm:"column:excluded_schemas;type:text;not null"`
+	IncludedTablesString    string `json:"-" gorm:"column:included_tables;type:text;not null"`
+	ExcludedTablesString    string `json:"-" gorm:"column:excluded_tables;type:text;not null"`
+	ExcludedTableDataString string `json:"-" gorm:"column:excluded_table_data;type:text;not null"`
+}
+
+func (f *BackupFilters) IsEmpty() bool {
+	return len(f.IncludedSchemas) == 0 &&
+		len(f.ExcludedSchemas) == 0 &&
+		len(f.IncludedTables) == 0 &&
+		len(f.ExcludedTables) == 0 &&
+		len(f.ExcludedTableData) == 0
+}
+
+func (f *BackupFilters) Validate() error {
+	lists := [][]string{
+		f.IncludedSchemas,
+		f.ExcludedSchemas,
+		f.IncludedTables,
+		f.ExcludedTables,
+		f.ExcludedTableData,
+	}
+
+	for _, patterns := range lists {
+		for _, pattern := range patterns {
+			if strings.TrimSpace(pattern) == "" {
+				return errors.New("schema and table patterns cannot be empty")
+			}
+
+			if strings.Contains(pattern, ",") {
+				return errors.New("schema and table patterns cannot contain commas: " + pattern)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ToPgDumpArgs maps filters to pg_dump arguments
+func (f *BackupFilters) ToPgDumpArgs() []string {
+	var args []string
+
+	for _, pattern := range f.IncludedSchemas {
+		args = append(args, "--schema="+pattern)
+	}
+
+	for _, pattern := range f.ExcludedSchemas {
+		args = append(args, "--exclude-schema="+pattern)
+	}
+
+	for _, pattern := range f.IncludedTables {
+		args = append(args, "--table="+pattern)
+	}
+
+	for _, pattern := range f.ExcludedTables {
+		args = append(args, "--exclude-table="+pattern)
+	}
+
+	for _, pattern := range f.ExcludedTableData {
+		args = append(args, "--exclude-table-data="+pattern)
+	}
+
+	return args
+}
+
+func (f *BackupFilters) Copy() BackupFilters {
+	return BackupFilters{
+		IncludedSchemas:   copyPatterns(f.IncludedSchemas),
+		ExcludedSchemas:   copyPatterns(f.ExcludedSchemas),
+		IncludedTables:    copyPatterns(f.IncludedTables),
+		ExcludedTables:    copyPatterns(f.ExcludedTables),
+		ExcludedTableData: copyPatterns(f.ExcludedTableData),
+	}
+}
+
+// EncodeLists should be called before save of the owning model
+func (f *BackupFilters) EncodeLists() {
+	f.IncludedSchemasString = strings.Join(f.IncludedSchemas, ",")
+	f.ExcludedSchemasString = strings.Join(f.ExcludedSchemas, ",")
+	f.IncludedTablesString = strings.Join(f.IncludedTables, ",")
+	f.ExcludedTablesString = strings.Join(f.ExcludedTables, ",")
+	f.ExcludedTableDataString = strings.Join(f.ExcludedTableData, ",")
+}
+
+// DecodeLists should be called after find of the owning model
+func (f *BackupFilters) DecodeLists() {
+	f.IncludedSchemas = splitPatterns(f.IncludedSchemasString)
+	f.ExcludedSchemas = splitPatterns(f.ExcludedSchemasString)
+	f.IncludedTables = splitPatterns(f.IncludedTablesString)
+	f.ExcludedTables = splitPatterns(f.ExcludedTablesString)
+	f.ExcludedTableData = splitPatterns(f.ExcludedTableDataString)
+}
+
+func splitPatterns(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+
+	return strings.Split(value, ",")
+}
+
+func copyPatterns(patterns []string) []string {
+	return append([]string{}, patterns...)
+}
@@ -0,0 +1,69 @@
+package backups_config
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultHookTimeoutSeconds = 60
+	MaxHookTimeoutSeconds     = 60 * 60
+)
+
+// BackupHooks are run around each backup. SQL is executed against the
+// source database, commands are run by sh on the Postgresus host. Pre
+// hooks run SQL first, post hooks too; empty hook is skipped
+type BackupHooks struct {
+	PreBackupSql      string `json:"preBackupSql"      gorm:"column:pre_backup_sql;type:text;not null"`
+	PreBackupCommand  string `json:"preBackupCommand"  gorm:"column:pre_backup_command;type:text;not null"`
+	PostBackupSql     string `json:"postBackupSql"     gorm:"column:post_backup_sql;type:text;not null"`
+	PostBackupCommand string `json:"postBackupCommand" gorm:"column:post_backup_command;type:text;not null"`
+
+	// HookTimeoutSeconds limits each SQL script and command separately,
+	// 0 uses the default of 60 seconds
+	HookTimeoutSeconds int `json:"hookTimeoutSeconds" gorm:"column:hook_timeout_seconds;type:int;not null"`
+}
+
+func (h *BackupHooks) HasPreBackupHooks() bool {
+	return strings.TrimSpace(h.PreBackupSql) != "" ||
+		strings.TrimSpace(h.PreBackupCommand) != ""
+}
+
+func (h *BackupHooks) HasPostBackupHooks() bool {
+	return strings.TrimSpace(h.PostBackupSql) != "" ||
+		strings.TrimSpace(h.PostBackupCommand) != ""
+}
+
+// HasSameCommands tells whether the commands are unchanged, so a user who
+// cannot set commands may still save the rest of the config
+func (h *BackupHooks) HasSameCommands(other *BackupHooks) bool {
+	return strings.TrimSpace(h.PreBackupCommand) == strings.TrimSpace(other.PreBackupCommand) &&
+		strings.TrimSpace(h.PostBackupCommand) == strings.TrimSpace(other.PostBackupCommand)
+}
+
+func (h *BackupHooks) GetTimeout() time.Duration {
+	if h.HookTimeoutSeconds <= 0 {
+		return DefaultHookTimeoutSeconds * time.Second
+	}
+
+	return time.Duration(h.HookTimeoutSeconds) * time.Second
+}
+
+func (h *BackupHooks) Validate() error {
+	if h.HookTimeoutSeconds < 0 || h.HookTimeoutSeconds > MaxHookTimeoutSeconds {
+		return errors.New("hook timeout must be from 1 second to 1 hour, or 0 to use the default")
+	}
+
+	return nil
+}
+
+func (h *BackupHooks) Copy() BackupHooks {
+	return BackupHooks{
+		PreBackupSql:       h.PreBackupSql,
+		PreBackupCommand:   h.PreBackupCommand,
+		PostBackupSql:      h.PostBackupSql,
+		PostBackupCommand:  h.PostBackupCommand,
+		HookTimeoutSeconds: h.HookTimeoutSeconds,
+	}
+}
@@ -3,5 +3,7 @@ package backups_config
 import "github.com/google/uuid"
 
 type BackupConfigStorageChangeListener interface {
-	OnBeforeBackupsStorageChange(dbID uuid.UUID) error
+	// OnBeforeBackupsStorageChange is called when backups of the database
+	// are moved to another storage, storageID is nil when backups are disabled
+	OnBeforeBackupsStorageChange(dbID uuid.UUID, storageID *uuid.UUID) error
 }
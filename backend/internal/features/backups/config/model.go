@@ -16,14 +16,23 @@ type BackupConfig struct {
 
 	IsBackupsEnabled bool `json:"isBackupsEnabled" gorm:"column:is_backups_enabled;type:boolean;not null"`
 
+	// StorePeriod is used by the period retention policy
 	StorePeriod period.Period `json:"storePeriod" gorm:"column:store_period;type:text;not null"`
 
+	BackupRetention `gorm:"embedded"`
+
 	BackupIntervalID uuid.UUID           `json:"backupIntervalId"         gorm:"column:backup_interval_id;type:uuid;not null"`
 	BackupInterval   *intervals.Interval `json:"backupInterval,omitempty" gorm:"foreignKey:BackupIntervalID"`
 
 	Storage   *storages.Storage `json:"storage"   gorm:"foreignKey:StorageID"`
 	StorageID *uuid.UUID        `json:"storageId" gorm:"column:storage_id;type:uuid;"`
 
+	// ExtraStorageIDs receive the same backup in parallel with the main
+	// storage, e.g. local disk, S3 and NAS to follow the 3-2-1 rule. IDs
+	// are stored as comma separated string
+	ExtraStorageIDs       []uuid.UUID `json:"extraStorageIds" gorm:"-"`
+	ExtraStorageIDsString string      `json:"-"               gorm:"column:extra_storage_ids;type:text;not null"`
+
 	SendNotificationsOn       []BackupNotificationType `json:"sendNotificationsOn" gorm:"-"`
 	SendNotificationsOnString string                   `json:"-"                   gorm:"column:send_notifications_on;type:text;not null"`
 
@@ -31,6 +40,38 @@ type BackupConfig struct {
 	MaxFailedTriesCount int  `json:"maxFailedTriesCount" gorm:"column:max_failed_tries_count;type:int;not null"`
 
 	CpuCount int `json:"cpuCount" gorm:"type:int;not null"`
+
+	// Priority of the backup in the queue, backups with higher priority
+	// start first when there are more due backups than free slots
+	BackupPriority int `json:"backupPriority" gorm:"column:backup_priority;type:int;not null"`
+
+	Encryption BackupEncryption `json:"encryption" gorm:"column:encryption;type:text;not null"`
+
+	BackupMethod BackupMethod `json:"backupMethod" gorm:"column:backup_method;type:text;not null"`
+
+	DumpFormat BackupDumpFormat `json:"dumpFormat" gorm:"column:dump_format;type:text;not null"`
+
+	// Compression of logical backups. Level is ignored for none. When
+	// empty on save, the default for the database version is used
+	Compression      BackupCompression `json:"compression"      gorm:"column:compression;type:text;not null"`
+	CompressionLevel int               `json:"compressionLevel" gorm:"column:compression_level;type:int;not null"`
+
+	// WAL is streamed continuously to the storage to allow point-in-time
+	// recovery. Physical backups are used as base backups for recovery
+	IsWalArchivingEnabled bool `json:"isWalArchivingEnabled" gorm:"column:is_wal_archiving_enabled;type:boolean;not null"`
+
+	BackupFilters `gorm:"embedded"`
+
+	BackupHooks `gorm:"embedded"`
+
+	// Completed backups are restored by schedule into a scratch database on
+	// the verification server, which is another database added by the user.
+	// VerificationSql is an optional query returning a single boolean
+	IsVerificationEnabled  bool                `json:"isVerificationEnabled"          gorm:"column:is_verification_enabled;type:boolean;not null"`
+	VerificationDatabaseID *uuid.UUID          `json:"verificationDatabaseId"         gorm:"column:verification_database_id;type:uuid"`
+	VerificationIntervalID *uuid.UUID          `json:"verificationIntervalId"         gorm:"column:verification_interval_id;type:uuid"`
+	VerificationInterval   *intervals.Interval `json:"verificationInterval,omitempty" gorm:"foreignKey:VerificationIntervalID"`
+	VerificationSql        string              `json:"verificationSql"                gorm:"column:verification_sql;type:text;not null"`
 }
 
 func (h *BackupConfig) TableName() string {
@@ -51,6 +92,29 @@ func (b *BackupConfig) BeforeSave(tx *gorm.DB) error {
 		b.SendNotificationsOnString = ""
 	}
 
+	if b.Encryption == "" {
+		b.Encryption = BackupEncryptionNone
+	}
+
+	if b.BackupMethod == "" {
+		b.BackupMethod = BackupMethodLogical
+	}
+
+	if b.DumpFormat == "" {
+		b.DumpFormat = BackupDumpFormatCustom
+	}
+
+	if b.RetentionPolicy == "" {
+		b.RetentionPolicy = BackupRetentionPolicyPeriod
+	}
+
+	b.BackupFilters.EncodeLists()
+	b.encodeExtraStorageIDs()
+
+	if b.HookTimeoutSeconds == 0 {
+		b.HookTimeoutSeconds = DefaultHookTimeoutSeconds
+	}
+
 	return nil
 }
 
@@ -67,7 +131,9 @@ func (b *BackupConfig) AfterFind(tx *gorm.DB) error {
 		b.SendNotificationsOn = []BackupNotificationType{}
 	}
 
-	return nil
+	b.BackupFilters.DecodeLists()
+
+	return b.decodeExtraStorageIDs()
 }
 
 func (b *BackupConfig) Validate() error {
@@ -76,7 +142,17 @@ func (b *BackupConfig) Validate() error {
 		return errors.New("backup interval is required")
 	}
 
-	if b.StorePeriod == "" {
+	if b.BackupInterval != nil {
+		if err := b.BackupInterval.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := b.BackupRetention.Validate(); err != nil {
+		return err
+	}
+
+	if !b.BackupRetention.IsGfs() && b.StorePeriod == "" {
 		return errors.New("store period is required")
 	}
 
@@ -88,6 +164,72 @@ func (b *BackupConfig) Validate() error {
 		return errors.New("max failed tries count must be greater than 0")
 	}
 
+	if b.Encryption != "" &&
+		b.Encryption != BackupEncryptionNone &&
+		b.Encryption != BackupEncryptionAES256GCM {
+		return errors.New("invalid encryption: " + string(b.Encryption))
+	}
+
+	if err := b.validateCompression(); err != nil {
+		return err
+	}
+
+	if b.BackupMethod != "" &&
+		b.BackupMethod != BackupMethodLogical &&
+		b.BackupMethod != BackupMethodPhysical {
+		return errors.New("invalid backup method: " + string(b.BackupMethod))
+	}
+
+	if b.DumpFormat != "" &&
+		b.DumpFormat != BackupDumpFormatCustom &&
+		b.DumpFormat != BackupDumpFormatDirectory {
+		return errors.New("invalid dump format: " + string(b.DumpFormat))
+	}
+
+	if b.DumpFormat == BackupDumpFormatDirectory && b.BackupMethod == BackupMethodPhysical {
+		return errors.New("directory dump format requires logical backup method")
+	}
+
+	if b.IsWalArchivingEnabled && b.BackupMethod != BackupMethodPhysical {
+		return errors.New("WAL archiving requires physical backup method")
+	}
+
+	if err := b.BackupFilters.Validate(); err != nil {
+		return err
+	}
+
+	if !b.BackupFilters.IsEmpty() && b.BackupMethod == BackupMethodPhysical {
+		return errors.New("schema and table filters are supported only by logical backups")
+	}
+
+	if err := b.BackupHooks.Validate(); err != nil {
+		return err
+	}
+
+	if err := b.validateExtraStorages(); err != nil {
+		return err
+	}
+
+	if b.IsVerificationEnabled {
+		if b.VerificationDatabaseID == nil {
+			return errors.New("verification database is required")
+		}
+
+		if b.VerificationIntervalID == nil && b.VerificationInterval == nil {
+			return errors.New("verification interval is required")
+		}
+
+		if b.VerificationInterval != nil {
+			if err := b.VerificationInterval.Validate(); err != nil {
+				return err
+			}
+		}
+
+		if b.BackupMethod == BackupMethodPhysical {
+			return errors.New("verification is supported only for logical backups")
+		}
+	}
+
 	return nil
 }
 
@@ -96,12 +238,37 @@ func (b *BackupConfig) Copy(newDatabaseID uuid.UUID) *BackupConfig {
 		DatabaseID:          newDatabaseID,
 		IsBackupsEnabled:    b.IsBackupsEnabled,
 		StorePeriod:         b.StorePeriod,
+		BackupRetention:     b.BackupRetention,
 		BackupIntervalID:    uuid.Nil,
 		BackupInterval:      b.BackupInterval.Copy(),
 		StorageID:           b.StorageID,
+		ExtraStorageIDs:     append([]uuid.UUID{}, b.ExtraStorageIDs...),
 		SendNotificationsOn: b.SendNotificationsOn,
 		IsRetryIfFailed:     b.IsRetryIfFailed,
 		MaxFailedTriesCount: b.MaxFailedTriesCount,
 		CpuCount:            b.CpuCount,
+		BackupPriority:      b.BackupPriority,
+		Encryption:          b.Encryption,
+		BackupMethod:        b.BackupMethod,
+		DumpFormat:          b.DumpFormat,
+		Compression:         b.Compression,
+		CompressionLevel:    b.CompressionLevel,
+		// not copied, because each archiving database holds a replication
+		// slot on the server and a forgotten slot retains WAL forever
+		IsWalArchivingEnabled:  false,
+		BackupFilters:          b.BackupFilters.Copy(),
+		BackupHooks:            b.BackupHooks.Copy(),
+		IsVerificationEnabled:  b.IsVerificationEnabled,
+		VerificationDatabaseID: b.VerificationDatabaseID,
+		VerificationInterval:   copyInterval(b.VerificationInterval),
+		VerificationSql:        b.VerificationSql,
 	}
 }
+
+func copyInterval(interval *intervals.Interval) *intervals.Interval {
+	if interval == nil {
+		return nil
+	}
+
+	return interval.Copy()
+}
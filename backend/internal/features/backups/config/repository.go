@@ -33,6 +33,20 @@ func (r *BackupConfigRepository) Save(
 			}
 		}
 
+		if backupConfig.VerificationInterval != nil {
+			if backupConfig.VerificationInterval.ID == uuid.Nil {
+				if err := tx.Create(backupConfig.VerificationInterval).Error; err != nil {
+					return err
+				}
+			} else {
+				if err := tx.Save(backupConfig.VerificationInterval).Error; err != nil {
+					return err
+				}
+			}
+
+			backupConfig.VerificationIntervalID = &backupConfig.VerificationInterval.ID
+		}
+
 		// Set storage ID
 		if backupConfig.Storage != nil && backupConfig.Storage.ID != uuid.Nil {
 			backupConfig.StorageID = &backupConfig.Storage.ID
@@ -40,7 +54,7 @@ func (r *BackupConfigRepository) Save(
 
 		// Use Save which handles both create and update based on primary key
 		if err := tx.Save(backupConfig).
-			Omit("BackupInterval", "Storage").
+			Omit("BackupInterval", "VerificationInterval", "Storage").
 			Error; err != nil {
 			return err
 		}
@@ -61,6 +75,7 @@ func (r *BackupConfigRepository) FindByDatabaseID(databaseID uuid.UUID) (*Backup
 	if err := storage.
 		GetDb().
 		Preload("BackupInterval").
+		Preload("VerificationInterval").
 		Preload("Storage").
 		Where("database_id = ?", databaseID).
 		First(&backupConfig).Error; err != nil {
@@ -80,6 +95,7 @@ func (r *BackupConfigRepository) GetWithEnabledBackups() ([]*BackupConfig, error
 	if err := storage.
 		GetDb().
 		Preload("BackupInterval").
+		Preload("VerificationInterval").
 		Preload("Storage").
 		Where("is_backups_enabled = ?", true).
 		Find(&backupConfigs).Error; err != nil {
@@ -89,13 +105,33 @@ func (r *BackupConfigRepository) GetWithEnabledBackups() ([]*BackupConfig, error
 	return backupConfigs, nil
 }
 
+func (r *BackupConfigRepository) GetWithEnabledVerification() ([]*BackupConfig, error) {
+	var backupConfigs []*BackupConfig
+
+	if err := storage.
+		GetDb().
+		Preload("BackupInterval").
+		Preload("VerificationInterval").
+		Preload("Storage").
+		Where("is_backups_enabled = ? AND is_verification_enabled = ?", true, true).
+		Find(&backupConfigs).Error; err != nil {
+		return nil, err
+	}
+
+	return backupConfigs, nil
+}
+
 func (r *BackupConfigRepository) IsStorageUsing(storageID uuid.UUID) (bool, error) {
 	var count int64
 
 	if err := storage.
 		GetDb().
 		Table("backup_configs").
-		Where("storage_id = ?", storageID).
+		Where(
+			"storage_id = ? OR extra_storage_ids LIKE ?",
+			storageID,
+			"%"+storageID.String()+"%",
+		).
 		Count(&count).Error; err != nil {
 		return false, err
 	}
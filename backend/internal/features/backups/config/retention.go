@@ -0,0 +1,69 @@
+package backups_config
+
+import "errors"
+
+// BackupRetention decides which backups are pruned. GFS counts are the
+// number of periods with a kept backup, e.g. 84 monthly keeps the newest
+// backup of each of the last 84 months with backups. Counts of the same
+// backup add up, so a backup can be kept as both daily and monthly
+type BackupRetention struct {
+	RetentionPolicy BackupRetentionPolicy `json:"retentionPolicy" gorm:"column:retention_policy;type:text;not null"`
+
+	GfsHourlyCount  int `json:"gfsHourlyCount"  gorm:"column:gfs_hourly_count;type:int;not null"`
+	GfsDailyCount   int `json:"gfsDailyCount"   gorm:"column:gfs_daily_count;type:int;not null"`
+	GfsWeeklyCount  int `json:"gfsWeeklyCount"  gorm:"column:gfs_weekly_count;type:int;not null"`
+	GfsMonthlyCount int `json:"gfsMonthlyCount" gorm:"column:gfs_monthly_count;type:int;not null"`
+	GfsYearlyCount  int `json:"gfsYearlyCount"  gorm:"column:gfs_yearly_count;type:int;not null"`
+
+	// Caps of the database backups applied with any policy, the oldest
+	// backups are pruned to fit them. Zero means no cap
+	MaxBackupsCount  int   `json:"maxBackupsCount"  gorm:"column:max_backups_count;type:int;not null"`
+	MaxBackupsSizeMb int64 `json:"maxBackupsSizeMb" gorm:"column:max_backups_size_mb;type:bigint;not null"`
+}
+
+func (r *BackupRetention) Validate() error {
+	if r.MaxBackupsCount < 0 || r.MaxBackupsSizeMb < 0 {
+		return errors.New("backups count and size caps cannot be negative")
+	}
+
+	switch r.RetentionPolicy {
+	case "", BackupRetentionPolicyPeriod:
+		return nil
+	case BackupRetentionPolicyGfs:
+	default:
+		return errors.New("invalid retention policy: " + string(r.RetentionPolicy))
+	}
+
+	counts := []int{
+		r.GfsHourlyCount,
+		r.GfsDailyCount,
+		r.GfsWeeklyCount,
+		r.GfsMonthlyCount,
+		r.GfsYearlyCount,
+	}
+
+	isAnyBackupKept := false
+	for _, count := range counts {
+		if count < 0 {
+			return errors.New("GFS counts cannot be negative")
+		}
+
+		if count > 0 {
+			isAnyBackupKept = true
+		}
+	}
+
+	if !isAnyBackupKept {
+		return errors.New("GFS retention should keep at least one backup")
+	}
+
+	return nil
+}
+
+func (r *BackupRetention) IsGfs() bool {
+	return r.RetentionPolicy == BackupRetentionPolicyGfs
+}
+
+func (r *BackupRetention) HasCaps() bool {
+	return r.MaxBackupsCount > 0 || r.MaxBackupsSizeMb > 0
+}
@@ -1,11 +1,14 @@
 package backups_config
 
 import (
+	"errors"
 	"postgresus-backend/internal/features/databases"
 	"postgresus-backend/internal/features/intervals"
 	"postgresus-backend/internal/features/storages"
+	user_enums "postgresus-backend/internal/features/users/enums"
 	users_models "postgresus-backend/internal/features/users/models"
 	"postgresus-backend/internal/util/period"
+	"postgresus-backend/internal/util/tools"
 
 	"github.com/google/uuid"
 )
@@ -32,11 +35,35 @@ func (s *BackupConfigService) SaveBackupConfigWithAuth(
 		return nil, err
 	}
 
-	_, err := s.databaseService.GetDatabase(user, backupConfig.DatabaseID)
+	database, err := s.databaseService.GetDatabase(user, backupConfig.DatabaseID)
 	if err != nil {
 		return nil, err
 	}
 
+	// filters are patterns of a single database, they
+	// are ambiguous for the whole server backup
+	if !backupConfig.BackupFilters.IsEmpty() &&
+		database.Postgresql != nil &&
+		database.Postgresql.IsWholeServer {
+		return nil, errors.New("schema and table filters are not supported for whole server backups")
+	}
+
+	if backupConfig.IsVerificationEnabled {
+		if err := s.validateVerificationDatabase(user, database, backupConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, storageID := range backupConfig.ExtraStorageIDs {
+		if _, err := s.storageService.GetStorage(user, storageID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.validateCommandHooks(user, backupConfig); err != nil {
+		return nil, err
+	}
+
 	return s.SaveBackupConfig(backupConfig)
 }
 
@@ -47,6 +74,10 @@ func (s *BackupConfigService) SaveBackupConfig(
 		return nil, err
 	}
 
+	if err := s.applyCompressionForDatabase(backupConfig); err != nil {
+		return nil, err
+	}
+
 	// Check if there's an existing backup config for this database
 	existingConfig, err := s.GetBackupConfigByDbId(backupConfig.DatabaseID)
 	if err != nil {
@@ -60,6 +91,7 @@ func (s *BackupConfigService) SaveBackupConfig(
 			!storageIDsEqual(existingConfig.StorageID, &backupConfig.Storage.ID) {
 			if err := s.dbStorageChangeListener.OnBeforeBackupsStorageChange(
 				backupConfig.DatabaseID,
+				&backupConfig.Storage.ID,
 			); err != nil {
 				return nil, err
 			}
@@ -69,6 +101,7 @@ func (s *BackupConfigService) SaveBackupConfig(
 	if !backupConfig.IsBackupsEnabled && existingConfig.StorageID != nil {
 		if err := s.dbStorageChangeListener.OnBeforeBackupsStorageChange(
 			backupConfig.DatabaseID,
+			nil,
 		); err != nil {
 			return nil, err
 		}
@@ -77,6 +110,7 @@ func (s *BackupConfigService) SaveBackupConfig(
 		// storage removal for unused storages
 		backupConfig.Storage = nil
 		backupConfig.StorageID = nil
+		backupConfig.ExtraStorageIDs = []uuid.UUID{}
 	}
 
 	return s.backupConfigRepository.Save(backupConfig)
@@ -130,6 +164,10 @@ func (s *BackupConfigService) GetBackupConfigsWithEnabledBackups() ([]*BackupCon
 	return s.backupConfigRepository.GetWithEnabledBackups()
 }
 
+func (s *BackupConfigService) GetBackupConfigsWithEnabledVerification() ([]*BackupConfig, error) {
+	return s.backupConfigRepository.GetWithEnabledVerification()
+}
+
 func (s *BackupConfigService) OnDatabaseCopied(originalDatabaseID, newDatabaseID uuid.UUID) {
 	originalConfig, err := s.GetBackupConfigByDbId(originalDatabaseID)
 	if err != nil {
@@ -144,12 +182,83 @@ func (s *BackupConfigService) OnDatabaseCopied(originalDatabaseID, newDatabaseID
 	}
 }
 
+// validateCommandHooks allows only admins to set command hooks, they are
+// run by sh on the Postgresus host. Other users may keep the commands an
+// admin has set, but cannot change them
+func (s *BackupConfigService) validateCommandHooks(
+	user *users_models.User,
+	backupConfig *BackupConfig,
+) error {
+	if user.Role == user_enums.UserRoleAdmin {
+		return nil
+	}
+
+	existingConfig, err := s.GetBackupConfigByDbId(backupConfig.DatabaseID)
+	if err != nil {
+		return err
+	}
+
+	existingHooks := &BackupHooks{}
+	if existingConfig != nil {
+		existingHooks = &existingConfig.BackupHooks
+	}
+
+	if !backupConfig.BackupHooks.HasSameCommands(existingHooks) {
+		return errors.New("only admin can set command hooks")
+	}
+
+	return nil
+}
+
+// validateVerificationDatabase checks the user may use the verification
+// server and the backup can be restored there
+func (s *BackupConfigService) validateVerificationDatabase(
+	user *users_models.User,
+	database *databases.Database,
+	backupConfig *BackupConfig,
+) error {
+	if database.Postgresql != nil && database.Postgresql.IsWholeServer {
+		return errors.New("verification is not supported for whole server backups")
+	}
+
+	verificationDatabase, err := s.databaseService.GetDatabase(
+		user,
+		*backupConfig.VerificationDatabaseID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if verificationDatabase.Postgresql == nil || database.Postgresql == nil {
+		return errors.New("verification is supported only for PostgreSQL databases")
+	}
+
+	if tools.IsBackupDbVersionHigherThanRestoreDbVersion(
+		database.Postgresql.Version,
+		verificationDatabase.Postgresql.Version,
+	) {
+		return errors.New("verification server version must be the same as the database version or higher")
+	}
+
+	return nil
+}
+
 func (s *BackupConfigService) initializeDefaultConfig(
 	databaseID uuid.UUID,
 ) error {
+	database, err := s.databaseService.GetDatabaseByID(databaseID)
+	if err != nil {
+		return err
+	}
+
+	compression := BackupCompressionGzip
+	if database.Postgresql != nil {
+		compression = GetDefaultCompression(database.Postgresql.Version)
+	}
+
 	timeOfDay := "04:00"
 
-	_, err := s.backupConfigRepository.Save(&BackupConfig{
+	_, err = s.backupConfigRepository.Save(&BackupConfig{
 		DatabaseID:       databaseID,
 		IsBackupsEnabled: false,
 		StorePeriod:      period.PeriodWeek,
@@ -164,11 +273,36 @@ func (s *BackupConfigService) initializeDefaultConfig(
 		CpuCount:            1,
 		IsRetryIfFailed:     true,
 		MaxFailedTriesCount: 3,
+		Encryption:          BackupEncryptionNone,
+		BackupMethod:        BackupMethodLogical,
+		DumpFormat:          BackupDumpFormatCustom,
+		Compression:         compression,
+		CompressionLevel:    DefaultCompressionLevel,
 	})
 
 	return err
 }
 
+// applyCompressionForDatabase fills compression for configs saved by clients
+// which do not know about it and checks the algorithm against the version
+func (s *BackupConfigService) applyCompressionForDatabase(backupConfig *BackupConfig) error {
+	database, err := s.databaseService.GetDatabaseByID(backupConfig.DatabaseID)
+	if err != nil {
+		return err
+	}
+
+	if database.Postgresql == nil {
+		return nil
+	}
+
+	if backupConfig.Compression == "" {
+		backupConfig.Compression = GetDefaultCompression(database.Postgresql.Version)
+		backupConfig.CompressionLevel = DefaultCompressionLevel
+	}
+
+	return backupConfig.ValidateCompressionForVersion(database.Postgresql.Version)
+}
+
 func storageIDsEqual(id1, id2 *uuid.UUID) bool {
 	if id1 == nil && id2 == nil {
 		return true
@@ -0,0 +1,78 @@
+package backups_config
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GetStorageIDs returns the main storage followed by extra ones,
+// each of them receives a copy of the backup
+func (b *BackupConfig) GetStorageIDs() []uuid.UUID {
+	var storageIDs []uuid.UUID
+
+	if b.StorageID != nil {
+		storageIDs = append(storageIDs, *b.StorageID)
+	}
+
+	return append(storageIDs, b.ExtraStorageIDs...)
+}
+
+func (b *BackupConfig) validateExtraStorages() error {
+	if len(b.ExtraStorageIDs) == 0 {
+		return nil
+	}
+
+	// controller passes the main storage as object without ID
+	mainStorageID := b.StorageID
+	if b.Storage != nil {
+		mainStorageID = &b.Storage.ID
+	}
+
+	if mainStorageID == nil {
+		return errors.New("main storage is required to use extra storages")
+	}
+
+	for i, storageID := range b.ExtraStorageIDs {
+		if storageID == *mainStorageID {
+			return errors.New("extra storage cannot be the same as the main storage")
+		}
+
+		if slices.Contains(b.ExtraStorageIDs[:i], storageID) {
+			return errors.New("extra storages cannot contain duplicates")
+		}
+	}
+
+	return nil
+}
+
+func (b *BackupConfig) encodeExtraStorageIDs() {
+	storageIDs := make([]string, len(b.ExtraStorageIDs))
+
+	for i, storageID := range b.ExtraStorageIDs {
+		storageIDs[i] = storageID.String()
+	}
+
+	b.ExtraStorageIDsString = strings.Join(storageIDs, ",")
+}
+
+func (b *BackupConfig) decodeExtraStorageIDs() error {
+	b.ExtraStorageIDs = []uuid.UUID{}
+
+	if b.ExtraStorageIDsString == "" {
+		return nil
+	}
+
+	for _, storageIDText := range strings.Split(b.ExtraStorageIDsString, ",") {
+		storageID, err := uuid.Parse(storageIDText)
+		if err != nil {
+			return err
+		}
+
+		b.ExtraStorageIDs = append(b.ExtraStorageIDs, storageID)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package backups_encryption
+
+import (
+	"net/http"
+	"postgresus-backend/internal/features/users"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type BackupEncryptionController struct {
+	backupEncryptionService *BackupEncryptionService
+	userService             *users.UserService
+}
+
+func (c *BackupEncryptionController) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/backup-encryption-keys/database/:id/rotate", c.RotateKey)
+}
+
+// RotateKey
+// @Summary Rotate backup encryption key
+// @Description Create a new encryption key version for the database. Next backups use the new key, older backups keep their key version
+// @Tags backup-encryption-keys
+// @Produce json
+// @Param id path string true "Database ID"
+// @Success 200 {object} BackupEncryptionKey
+// @Failure 400
+// @Failure 401
+// @Router /backup-encryption-keys/database/{id}/rotate [post]
+func (c *BackupEncryptionController) RotateKey(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid database ID"})
+		return
+	}
+
+	authorizationHeader := ctx.GetHeader("Authorization")
+	if authorizationHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+		return
+	}
+
+	user, err := c.userService.GetUserFromToken(authorizationHeader)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	key, err := c.backupEncryptionService.RotateKeyWithAuth(user, id)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, key)
+}
@@ -0,0 +1,30 @@
+package backups_encryption
+
+import (
+	"postgresus-backend/internal/config"
+	"postgresus-backend/internal/features/databases"
+	"postgresus-backend/internal/features/users"
+)
+
+var backupEncryptionKeyRepository = &BackupEncryptionKeyRepository{}
+var masterKeyStorage = &MasterKeyStorage{
+	keyPath:      config.GetEnv().EncryptionKeyPath,
+	envMasterKey: config.GetEnv().EncryptionMasterKey,
+}
+var backupEncryptionService = &BackupEncryptionService{
+	backupEncryptionKeyRepository,
+	masterKeyStorage,
+	databases.GetDatabaseService(),
+}
+var backupEncryptionController = &BackupEncryptionController{
+	backupEncryptionService,
+	users.GetUserService(),
+}
+
+func GetBackupEncryptionService() *BackupEncryptionService {
+	return backupEncryptionService
+}
+
+func GetBackupEncryptionController() *BackupEncryptionController {
+	return backupEncryptionController
+}
@@ -0,0 +1,76 @@
+package backups_encryption
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	encryption_utils "postgresus-backend/internal/util/encryption"
+)
+
+// MasterKeyStorage keeps the master key outside of the metadata DB. Key
+// from the env is shared by all instances, otherwise the key is kept in a
+// file which is generated on first use
+type MasterKeyStorage struct {
+	keyPath string
+	// hex key from ENCRYPTION_MASTER_KEY, it is required when instances
+	// are split, so the file is never generated per host
+	envMasterKey string
+
+	mutex     sync.Mutex
+	masterKey []byte
+}
+
+func (s *MasterKeyStorage) GetMasterKey() ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.masterKey != nil {
+		return s.masterKey, nil
+	}
+
+	if s.envMasterKey != "" {
+		masterKey, err := hex.DecodeString(s.envMasterKey)
+		if err != nil || len(masterKey) != encryption_utils.KeySize {
+			return nil, errors.New("ENCRYPTION_MASTER_KEY is malformed")
+		}
+
+		s.masterKey = masterKey
+		return s.masterKey, nil
+	}
+
+	content, err := os.ReadFile(s.keyPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read master key: %w", err)
+	}
+
+	if err == nil {
+		masterKey, err := hex.DecodeString(strings.TrimSpace(string(content)))
+		if err != nil || len(masterKey) != encryption_utils.KeySize {
+			return nil, fmt.Errorf("master key file %s is malformed", s.keyPath)
+		}
+
+		s.masterKey = masterKey
+		return s.masterKey, nil
+	}
+
+	masterKey, err := encryption_utils.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.keyPath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create master key directory: %w", err)
+	}
+
+	if err := os.WriteFile(s.keyPath, []byte(hex.EncodeToString(masterKey)), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write master key: %w", err)
+	}
+
+	s.masterKey = masterKey
+	return s.masterKey, nil
+}
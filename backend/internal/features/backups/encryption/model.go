@@ -0,0 +1,22 @@
+package backups_encryption
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BackupEncryptionKey is a per-database data key. The key itself is
+// stored wrapped with the master key, so the metadata DB alone
+// is not enough to decrypt backups
+type BackupEncryptionKey struct {
+	ID           uuid.UUID `json:"id"         gorm:"column:id;primaryKey;type:uuid;default:gen_random_uuid()"`
+	DatabaseID   uuid.UUID `json:"databaseId" gorm:"column:database_id;type:uuid;not null"`
+	Version      int       `json:"version"    gorm:"column:version;type:int;not null"`
+	EncryptedKey string    `json:"-"          gorm:"column:encrypted_key;type:text;not null"`
+	CreatedAt    time.Time `json:"createdAt"  gorm:"column:created_at"`
+}
+
+func (k *BackupEncryptionKey) TableName() string {
+	return "backup_encryption_keys"
+}
@@ -0,0 +1,55 @@
+package backups_encryption
+
+import (
+	"errors"
+	"postgresus-backend/internal/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type BackupEncryptionKeyRepository struct{}
+
+func (r *BackupEncryptionKeyRepository) Create(key *BackupEncryptionKey) error {
+	if key.ID == uuid.Nil {
+		key.ID = uuid.New()
+	}
+
+	return storage.GetDb().Create(key).Error
+}
+
+func (r *BackupEncryptionKeyRepository) FindLatestByDatabaseID(
+	databaseID uuid.UUID,
+) (*BackupEncryptionKey, error) {
+	var key BackupEncryptionKey
+
+	if err := storage.
+		GetDb().
+		Where("database_id = ?", databaseID).
+		Order("version DESC").
+		First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *BackupEncryptionKeyRepository) FindByDatabaseIDAndVersion(
+	databaseID uuid.UUID,
+	version int,
+) (*BackupEncryptionKey, error) {
+	var key BackupEncryptionKey
+
+	if err := storage.
+		GetDb().
+		Where("database_id = ? AND version = ?", databaseID, version).
+		First(&key).Error; err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,114 @@
+package backups_encryption
+
+import (
+	"fmt"
+	"postgresus-backend/internal/features/databases"
+	users_models "postgresus-backend/internal/features/users/models"
+	encryption_utils "postgresus-backend/internal/util/encryption"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BackupEncryptionService struct {
+	backupEncryptionKeyRepository *BackupEncryptionKeyRepository
+	masterKeyStorage              *MasterKeyStorage
+	databaseService               *databases.DatabaseService
+}
+
+// GetActiveKey returns the latest key version of the database,
+// the first version is created on demand
+func (s *BackupEncryptionService) GetActiveKey(
+	databaseID uuid.UUID,
+) (*BackupEncryptionKey, []byte, error) {
+	key, err := s.backupEncryptionKeyRepository.FindLatestByDatabaseID(databaseID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if key == nil {
+		return s.createKey(databaseID, 1)
+	}
+
+	dataKey, err := s.unwrapKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, dataKey, nil
+}
+
+func (s *BackupEncryptionService) GetKey(databaseID uuid.UUID, version int) ([]byte, error) {
+	key, err := s.backupEncryptionKeyRepository.FindByDatabaseIDAndVersion(databaseID, version)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key version %d is not found: %w", version, err)
+	}
+
+	return s.unwrapKey(key)
+}
+
+// RotateKeyWithAuth creates a new key version used for all next backups.
+// Previous versions are kept to decrypt older backups
+func (s *BackupEncryptionService) RotateKeyWithAuth(
+	user *users_models.User,
+	databaseID uuid.UUID,
+) (*BackupEncryptionKey, error) {
+	if _, err := s.databaseService.GetDatabase(user, databaseID); err != nil {
+		return nil, err
+	}
+
+	latestKey, err := s.backupEncryptionKeyRepository.FindLatestByDatabaseID(databaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	nextVersion := 1
+	if latestKey != nil {
+		nextVersion = latestKey.Version + 1
+	}
+
+	key, _, err := s.createKey(databaseID, nextVersion)
+	return key, err
+}
+
+func (s *BackupEncryptionService) createKey(
+	databaseID uuid.UUID,
+	version int,
+) (*BackupEncryptionKey, []byte, error) {
+	masterKey, err := s.masterKeyStorage.GetMasterKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dataKey, err := encryption_utils.GenerateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wrappedKey, err := encryption_utils.WrapKey(masterKey, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := &BackupEncryptionKey{
+		DatabaseID:   databaseID,
+		Version:      version,
+		EncryptedKey: wrappedKey,
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	if err := s.backupEncryptionKeyRepository.Create(key); err != nil {
+		return nil, nil, err
+	}
+
+	return key, dataKey, nil
+}
+
+func (s *BackupEncryptionService) unwrapKey(key *BackupEncryptionKey) ([]byte, error) {
+	masterKey, err := s.masterKeyStorage.GetMasterKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return encryption_utils.UnwrapKey(masterKey, key.EncryptedKey)
+}
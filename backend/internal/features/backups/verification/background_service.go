@@ -0,0 +1,128 @@
+package backups_verification
+
+import (
+	"log/slog"
+	"postgresus-backend/internal/config"
+	"postgresus-backend/internal/features/backups/backups"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	system_instances "postgresus-backend/internal/features/system/instances"
+	"time"
+)
+
+type BackupVerificationBackgroundService struct {
+	backupVerificationService *BackupVerificationService
+	backupService             *backups.BackupService
+	backupConfigService       *backups_config.BackupConfigService
+	verificationLease         *system_instances.Lease
+	logger                    *slog.Logger
+}
+
+func (s *BackupVerificationBackgroundService) Run() {
+	for {
+		if config.IsShouldShutdown() {
+			return
+		}
+
+		isHeld, isJustAcquired := s.verificationLease.Acquire()
+
+		// verifications are run by the lease holder only, so the ones
+		// in progress were left by the previous holder or process
+		if isJustAcquired {
+			if err := s.failVerificationsInProgress(); err != nil {
+				s.logger.Error("Failed to fail backup verifications in progress", "error", err)
+			}
+		}
+
+		if isHeld {
+			if err := s.runPendingVerifications(); err != nil {
+				s.logger.Error("Failed to run pending backup verifications", "error", err)
+			}
+		}
+
+		time.Sleep(1 * time.Minute)
+	}
+}
+
+func (s *BackupVerificationBackgroundService) failVerificationsInProgress() error {
+	backupsInProgress, err := s.backupService.GetBackupsWithVerificationInProgress()
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range backupsInProgress {
+		failMessage := "Verification failed because its instance stopped"
+
+		if err := s.backupService.SaveVerificationResult(
+			backup,
+			backups.BackupVerificationStatusFailed,
+			&failMessage,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runPendingVerifications verifies the latest backup of each database when
+// its verification interval is due. Verifications are run one by one, so
+// the verification server does not restore several backups at once
+func (s *BackupVerificationBackgroundService) runPendingVerifications() error {
+	backupConfigs, err := s.backupConfigService.GetBackupConfigsWithEnabledVerification()
+	if err != nil {
+		return err
+	}
+
+	for _, backupConfig := range backupConfigs {
+		if config.IsShouldShutdown() {
+			return nil
+		}
+
+		if backupConfig.VerificationInterval == nil {
+			continue
+		}
+
+		lastVerificationTime, err := s.backupService.GetLastVerificationTime(
+			backupConfig.DatabaseID,
+		)
+		if err != nil {
+			s.logger.Error(
+				"Failed to get last verification time",
+				"databaseId",
+				backupConfig.DatabaseID,
+				"error",
+				err,
+			)
+			continue
+		}
+
+		if !backupConfig.VerificationInterval.ShouldTriggerBackup(
+			time.Now().UTC(),
+			lastVerificationTime,
+		) {
+			continue
+		}
+
+		backup, err := s.backupService.GetLastBackupToVerify(backupConfig.DatabaseID)
+		if err != nil {
+			s.logger.Error(
+				"Failed to get backup to verify",
+				"databaseId",
+				backupConfig.DatabaseID,
+				"error",
+				err,
+			)
+			continue
+		}
+
+		// the latest backup is already verified, the next one will be
+		// verified as soon as it is completed
+		if backup == nil || backup.VerificationStatus != nil {
+			continue
+		}
+
+		s.backupVerificationService.VerifyBackup(backupConfig, backup)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package backups_verification
+
+import (
+	"fmt"
+	pgtypes "postgresus-backend/internal/features/databases/databases/postgresql"
+	"postgresus-backend/internal/util/tools"
+	"slices"
+	"strings"
+)
+
+// relationTocTypes are TOC entries which become relations in pg_class,
+// they can be found in the catalog of the restored database
+var relationTocTypes = []string{
+	"TABLE",
+	"VIEW",
+	"MATERIALIZED VIEW",
+	"SEQUENCE",
+	"FOREIGN TABLE",
+}
+
+// checkRestoredRelations compares relations listed by pg_restore --list with
+// the restored database. pg_restore may skip objects on errors it does not
+// treat as fatal, so exit code alone is not enough. Count of tables is returned
+func checkRestoredRelations(catalog *pgtypes.Catalog, tocEntries []*tools.TocEntry) (int, error) {
+	tablesCount := 0
+	relationsCount := 0
+
+	var missingRelations []string
+
+	for _, entry := range tocEntries {
+		if !slices.Contains(relationTocTypes, entry.Type) {
+			continue
+		}
+
+		relationsCount++
+
+		if entry.Type == "TABLE" {
+			tablesCount++
+		}
+
+		if !hasRelation(catalog, entry.Schema, entry.Name) {
+			missingRelations = append(missingRelations, entry.Schema+"."+entry.Name)
+		}
+	}
+
+	if len(missingRelations) > 0 {
+		return tablesCount, fmt.Errorf(
+			"%d of %d relations of the backup are missing after restore: %s",
+			len(missingRelations),
+			relationsCount,
+			strings.Join(missingRelations, ", "),
+		)
+	}
+
+	return tablesCount, nil
+}
+
+func hasRelation(catalog *pgtypes.Catalog, schema string, name string) bool {
+	for _, table := range catalog.Tables {
+		if table.Schema == schema && table.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,56 @@
+package backups_verification
+
+import (
+	pgtypes "postgresus-backend/internal/features/databases/databases/postgresql"
+	"postgresus-backend/internal/util/tools"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CheckRestoredRelations_WhenAllRelationsRestored_ReturnsTablesCount(t *testing.T) {
+	catalog := &pgtypes.Catalog{
+		Tables: []pgtypes.CatalogTable{
+			{Schema: "public", Name: "users"},
+			{Schema: "public", Name: "orders"},
+			{Schema: "public", Name: "users_id_seq"},
+			{Schema: "reports", Name: "daily_sales"},
+		},
+	}
+
+	tocEntries := []*tools.TocEntry{
+		{Type: "SCHEMA", Name: "reports"},
+		{Type: "TABLE", Schema: "public", Name: "users"},
+		{Type: "TABLE", Schema: "public", Name: "orders"},
+		{Type: "SEQUENCE", Schema: "public", Name: "users_id_seq"},
+		{Type: "MATERIALIZED VIEW", Schema: "reports", Name: "daily_sales"},
+		{Type: "TABLE DATA", Schema: "public", Name: "users"},
+		{Type: "CONSTRAINT", Schema: "public", Name: "users users_pkey"},
+	}
+
+	tablesCount, err := checkRestoredRelations(catalog, tocEntries)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, tablesCount)
+}
+
+func Test_CheckRestoredRelations_WhenRelationMissing_ReturnsError(t *testing.T) {
+	catalog := &pgtypes.Catalog{
+		Tables: []pgtypes.CatalogTable{
+			{Schema: "public", Name: "users"},
+		},
+	}
+
+	tocEntries := []*tools.TocEntry{
+		{Type: "TABLE", Schema: "public", Name: "users"},
+		{Type: "TABLE", Schema: "public", Name: "orders"},
+		{Type: "VIEW", Schema: "audit", Name: "recent_orders"},
+	}
+
+	_, err := checkRestoredRelations(catalog, tocEntries)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 of 3 relations")
+	assert.Contains(t, err.Error(), "public.orders, audit.recent_orders")
+}
@@ -0,0 +1,38 @@
+package backups_verification
+
+import (
+	"postgresus-backend/internal/features/backups/backups"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"postgresus-backend/internal/features/databases"
+	usecases_postgresql "postgresus-backend/internal/features/restores/usecases/postgresql"
+	system_instances "postgresus-backend/internal/features/system/instances"
+	cancellation_utils "postgresus-backend/internal/util/cancellation"
+	"postgresus-backend/internal/util/logger"
+)
+
+var backupVerificationService = &BackupVerificationService{
+	backups.GetBackupService(),
+	databases.GetDatabaseService(),
+	usecases_postgresql.GetRestorePostgresqlBackupUsecase(),
+	cancellation_utils.NewCancellationTracker(),
+	logger.GetLogger(),
+}
+var backupVerificationBackgroundService = &BackupVerificationBackgroundService{
+	backupVerificationService,
+	backups.GetBackupService(),
+	backups_config.GetBackupConfigService(),
+	system_instances.GetInstanceService().NewLease("backup-verification"),
+	logger.GetLogger(),
+}
+
+func SetupDependencies() {
+	backups.GetBackupService().AddBackupRemoveListener(backupVerificationService)
+}
+
+func GetBackupVerificationService() *BackupVerificationService {
+	return backupVerificationService
+}
+
+func GetBackupVerificationBackgroundService() *BackupVerificationBackgroundService {
+	return backupVerificationBackgroundService
+}
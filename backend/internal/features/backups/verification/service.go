@@ -0,0 +1,232 @@
+package backups_verification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"postgresus-backend/internal/features/backups/backups"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"postgresus-backend/internal/features/databases"
+	pgtypes "postgresus-backend/internal/features/databases/databases/postgresql"
+	"postgresus-backend/internal/features/restores/enums"
+	"postgresus-backend/internal/features/restores/models"
+	usecases_postgresql "postgresus-backend/internal/features/restores/usecases/postgresql"
+	cancellation_utils "postgresus-backend/internal/util/cancellation"
+	"postgresus-backend/internal/util/tools"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const assertionTimeout = 5 * time.Minute
+
+type BackupVerificationService struct {
+	backupService                  *backups.BackupService
+	databaseService                *databases.DatabaseService
+	restorePostgresqlBackupUsecase *usecases_postgresql.RestorePostgresqlBackupUsecase
+	cancellationTracker            *cancellation_utils.CancellationTracker
+	logger                         *slog.Logger
+}
+
+// OnBeforeBackupRemove stops verification of the backup, so the restore
+// does not read files which are being removed
+func (s *BackupVerificationService) OnBeforeBackupRemove(backup *backups.Backup) error {
+	if s.cancellationTracker.Cancel(backup.ID) {
+		s.logger.Info("Backup verification cancelled, backup is removed", "backupId", backup.ID)
+	}
+
+	return nil
+}
+
+// VerifyBackup restores the backup into a scratch database on the
+// verification server and checks the result. The scratch database
+// is dropped afterwards, whatever the result is
+func (s *BackupVerificationService) VerifyBackup(
+	backupConfig *backups_config.BackupConfig,
+	backup *backups.Backup,
+) {
+	s.logger.Info("Verifying backup", "backupId", backup.ID, "databaseId", backup.DatabaseID)
+
+	if err := s.backupService.SaveVerificationResult(
+		backup,
+		backups.BackupVerificationStatusInProgress,
+		nil,
+	); err != nil {
+		s.logger.Error("Failed to save backup verification status", "error", err)
+		return
+	}
+
+	ctx, unregisterVerification := s.cancellationTracker.Register(backup.ID)
+	defer unregisterVerification()
+
+	message, err := s.verifyBackup(ctx, backupConfig, backup)
+
+	// verification is cancelled when the backup is removed,
+	// so there is nothing to save the result to
+	if err != nil && cancellation_utils.IsCancelledByUser(ctx) {
+		return
+	}
+
+	if err != nil {
+		errMsg := err.Error()
+
+		s.logger.Error("Backup verification failed", "backupId", backup.ID, "error", err)
+
+		if err := s.backupService.SaveVerificationResult(
+			backup,
+			backups.BackupVerificationStatusFailed,
+			&errMsg,
+		); err != nil {
+			s.logger.Error("Failed to save backup verification status", "error", err)
+		}
+
+		s.backupService.SendBackupNotification(
+			backupConfig,
+			backup,
+			backups_config.NotificationBackupVerificationFailed,
+			&errMsg,
+		)
+
+		return
+	}
+
+	if err := s.backupService.SaveVerificationResult(
+		backup,
+		backups.BackupVerificationStatusVerified,
+		&message,
+	); err != nil {
+		s.logger.Error("Failed to save backup verification status", "error", err)
+	}
+
+	s.logger.Info("Backup verified", "backupId", backup.ID, "result", message)
+}
+
+func (s *BackupVerificationService) verifyBackup(
+	ctx context.Context,
+	backupConfig *backups_config.BackupConfig,
+	backup *backups.Backup,
+) (string, error) {
+	if backupConfig.VerificationDatabaseID == nil {
+		return "", errors.New("verification database is not configured")
+	}
+
+	// version of the database is needed to list objects of the backup
+	database, err := s.databaseService.GetDatabaseByID(backup.DatabaseID)
+	if err != nil {
+		return "", err
+	}
+
+	backup.Database = database
+
+	verificationDatabase, err := s.databaseService.GetDatabaseByID(
+		*backupConfig.VerificationDatabaseID,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to get verification database: %w", err)
+	}
+
+	if database.Postgresql == nil || verificationDatabase.Postgresql == nil {
+		return "", errors.New("verification is supported only for PostgreSQL databases")
+	}
+
+	if tools.IsBackupDbVersionHigherThanRestoreDbVersion(
+		database.Postgresql.Version,
+		verificationDatabase.Postgresql.Version,
+	) {
+		return "", errors.New("verification server version is lower than the database version")
+	}
+
+	backupStorages, err := s.backupService.GetBackupStorages(backup)
+	if err != nil {
+		return "", err
+	}
+
+	tocEntries, err := s.restorePostgresqlBackupUsecase.ListBackupObjects(ctx, backup, backupStorages)
+	if err != nil {
+		return "", fmt.Errorf("failed to list objects of the backup: %w", err)
+	}
+
+	// verification database is used as the maintenance database of the server
+	serverPg := verificationDatabase.Postgresql
+	scratchDatabaseName := getScratchDatabaseName(backup.ID)
+
+	if err := serverPg.CreateScratchDatabase(scratchDatabaseName); err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := serverPg.DropDatabaseIfExists(scratchDatabaseName); err != nil {
+			s.logger.Error(
+				"Failed to drop scratch database",
+				"database",
+				scratchDatabaseName,
+				"error",
+				err,
+			)
+		}
+	}()
+
+	scratchPg := &pgtypes.PostgresqlDatabase{
+		Version:  serverPg.Version,
+		Host:     serverPg.Host,
+		Port:     serverPg.Port,
+		Username: serverPg.Username,
+		Password: serverPg.Password,
+		Database: &scratchDatabaseName,
+		IsHttps:  serverPg.IsHttps,
+	}
+
+	// roles of the source server may be missing on the verification one
+	restore := models.Restore{
+		ID:             uuid.New(),
+		Status:         enums.RestoreStatusInProgress,
+		BackupID:       backup.ID,
+		Backup:         backup,
+		Postgresql:     scratchPg,
+		IsNoPrivileges: true,
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	if err := s.restorePostgresqlBackupUsecase.Execute(
+		ctx,
+		backupConfig,
+		restore,
+		backup,
+		backupStorages,
+	); err != nil {
+		return "", fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	catalog, err := scratchPg.LoadCatalog(scratchDatabaseName)
+	if err != nil {
+		return "", err
+	}
+
+	tablesCount, err := checkRestoredRelations(catalog, tocEntries)
+	if err != nil {
+		return "", err
+	}
+
+	message := fmt.Sprintf(
+		"Backup restored into a scratch database, all %d tables of the backup are present",
+		tablesCount,
+	)
+
+	if strings.TrimSpace(backupConfig.VerificationSql) != "" {
+		assertionCtx, cancel := context.WithTimeout(ctx, assertionTimeout)
+		defer cancel()
+
+		if err := scratchPg.ExecuteAssertion(assertionCtx, backupConfig.VerificationSql); err != nil {
+			return "", err
+		}
+
+		message += ", SQL assertion passed"
+	}
+
+	return message, nil
+}
+
+func getScratchDatabaseName(backupID uuid.UUID) string {
+	return "postgresus_verify_" + strings.ReplaceAll(backupID.String(), "-", "")
+}
@@ -0,0 +1,32 @@
+package backups_verification
+
+import (
+	"postgresus-backend/internal/features/backups/backups"
+	cancellation_utils "postgresus-backend/internal/util/cancellation"
+	"postgresus-backend/internal/util/logger"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OnBeforeBackupRemove_WhenVerificationIsRunning_VerificationCancelled(t *testing.T) {
+	verificationService := &BackupVerificationService{
+		cancellationTracker: cancellation_utils.NewCancellationTracker(),
+		logger:              logger.GetLogger(),
+	}
+
+	backup := &backups.Backup{ID: uuid.New()}
+	otherBackup := &backups.Backup{ID: uuid.New()}
+
+	ctx, unregisterVerification := verificationService.cancellationTracker.Register(backup.ID)
+	defer unregisterVerification()
+
+	require.NoError(t, verificationService.OnBeforeBackupRemove(otherBackup))
+	assert.NoError(t, ctx.Err())
+
+	require.NoError(t, verificationService.OnBeforeBackupRemove(backup))
+	assert.Error(t, ctx.Err())
+	assert.True(t, cancellation_utils.IsCancelledByUser(ctx))
+}
@@ -0,0 +1,272 @@
+package backups_wal
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"postgresus-backend/internal/config"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"postgresus-backend/internal/features/databases"
+	system_instances "postgresus-backend/internal/features/system/instances"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const segmentsCleanupInterval = 10 * time.Minute
+
+type WalArchivingBackgroundService struct {
+	walArchivingService *WalArchivingService
+	backupConfigService *backups_config.BackupConfigService
+	databaseService     *databases.DatabaseService
+
+	// receivers stream from replication slots, so only one
+	// instance runs them at a time
+	archivingLease *system_instances.Lease
+
+	receivers       map[uuid.UUID]*walReceiver
+	lastCleanupTime time.Time
+	logger          *slog.Logger
+
+	// closed when Run returns, so the instance keeps the
+	// lease until the received segments are uploaded
+	stopped chan struct{}
+}
+
+func (s *WalArchivingBackgroundService) Run() {
+	defer close(s.stopped)
+	defer s.archiveLeftSegments()
+	defer s.stopAllReceivers()
+
+	for {
+		if config.IsShouldShutdown() {
+			return
+		}
+
+		// receivers of the new holder continue from the same slots, which
+		// are confirmed past segments received here. So they are uploaded
+		// by this instance, failed uploads are retried on the next run
+		isHeld, isJustAcquired := s.archivingLease.Acquire()
+		if !isHeld {
+			s.stopAllReceivers()
+			s.archiveLeftSegments()
+			time.Sleep(10 * time.Second)
+			continue
+		}
+
+		// partial segments left by the previous run are behind the slots
+		// when another instance held the lease meanwhile, so receivers
+		// start from clean directories
+		if isJustAcquired {
+			s.archiveLeftSegments()
+		}
+
+		backupConfigs, err := s.getWalArchivingConfigs()
+		if err != nil {
+			s.logger.Error("Failed to get WAL archiving configs", "error", err)
+		} else {
+			s.syncReceivers(backupConfigs)
+			s.archiveCompletedSegments(backupConfigs)
+			s.archiveLeftSegments()
+
+			if time.Since(s.lastCleanupTime) > segmentsCleanupInterval {
+				s.cleanOldSegments(backupConfigs)
+				s.lastCleanupTime = time.Now().UTC()
+			}
+		}
+
+		time.Sleep(10 * time.Second)
+	}
+}
+
+func (s *WalArchivingBackgroundService) getWalArchivingConfigs() (
+	map[uuid.UUID]*backups_config.BackupConfig,
+	error,
+) {
+	enabledBackupConfigs, err := s.backupConfigService.GetBackupConfigsWithEnabledBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	backupConfigs := make(map[uuid.UUID]*backups_config.BackupConfig)
+	for _, backupConfig := range enabledBackupConfigs {
+		if backupConfig.IsWalArchivingEnabled &&
+			backupConfig.BackupMethod == backups_config.BackupMethodPhysical {
+			backupConfigs[backupConfig.DatabaseID] = backupConfig
+		}
+	}
+
+	return backupConfigs, nil
+}
+
+// WaitForStop waits until the receivers are stopped and their segments
+// are uploaded on shutdown, so the lease is released after the upload
+func (s *WalArchivingBackgroundService) WaitForStop(timeout time.Duration) {
+	select {
+	case <-s.stopped:
+	case <-time.After(timeout):
+		s.logger.Warn("WAL archiving is not stopped in time, segments are uploaded on next start")
+	}
+}
+
+// syncReceivers starts receivers for databases with enabled archiving
+// and stops (dropping the replication slot) for disabled ones. Received
+// segments of the stopped receivers are uploaded by archiveLeftSegments
+func (s *WalArchivingBackgroundService) syncReceivers(
+	backupConfigs map[uuid.UUID]*backups_config.BackupConfig,
+) {
+	for databaseID, receiver := range s.receivers {
+		if _, isEnabled := backupConfigs[databaseID]; isEnabled {
+			continue
+		}
+
+		s.logger.Info("Stopping WAL archiving", "databaseId", databaseID)
+		receiver.stop()
+		delete(s.receivers, databaseID)
+
+		if err := dropReplicationSlot(receiver.database); err != nil {
+			s.logger.Error(
+				"Failed to drop replication slot",
+				"databaseId",
+				databaseID,
+				"error",
+				err,
+			)
+		}
+	}
+
+	for databaseID := range backupConfigs {
+		if _, isRunning := s.receivers[databaseID]; isRunning {
+			continue
+		}
+
+		database, err := s.databaseService.GetDatabaseByID(databaseID)
+		if err != nil {
+			s.logger.Error("Failed to get database for WAL archiving", "error", err)
+			continue
+		}
+
+		s.logger.Info("Starting WAL archiving", "databaseId", databaseID)
+		s.receivers[databaseID] = startWalReceiver(
+			database,
+			getWalDir(databaseID),
+			s.logger,
+		)
+	}
+}
+
+func (s *WalArchivingBackgroundService) archiveCompletedSegments(
+	backupConfigs map[uuid.UUID]*backups_config.BackupConfig,
+) {
+	for databaseID, receiver := range s.receivers {
+		if err := s.walArchivingService.ArchiveCompletedSegments(
+			receiver.database,
+			backupConfigs[databaseID],
+			receiver.walDir,
+		); err != nil {
+			s.logger.Error(
+				"Failed to archive WAL segments",
+				"databaseId",
+				databaseID,
+				"error",
+				err,
+			)
+		}
+	}
+}
+
+func (s *WalArchivingBackgroundService) cleanOldSegments(
+	backupConfigs map[uuid.UUID]*backups_config.BackupConfig,
+) {
+	for _, backupConfig := range backupConfigs {
+		if err := s.walArchivingService.CleanOldSegments(backupConfig); err != nil {
+			s.logger.Error(
+				"Failed to clean old WAL segments",
+				"databaseId",
+				backupConfig.DatabaseID,
+				"error",
+				err,
+			)
+		}
+	}
+}
+
+// archiveLeftSegments uploads segments of WAL directories without a running
+// receiver, i.e. of receivers stopped on lost lease, shutdown or disabled
+// archiving. The directory is removed once all its segments are uploaded
+func (s *WalArchivingBackgroundService) archiveLeftSegments() {
+	entries, err := os.ReadDir(config.GetEnv().WalFolder)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			s.logger.Error("Failed to read WAL folder", "error", err)
+		}
+
+		return
+	}
+
+	for _, entry := range entries {
+		databaseID, err := uuid.Parse(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+
+		if _, isRunning := s.receivers[databaseID]; isRunning {
+			continue
+		}
+
+		if err := s.archiveLeftDatabaseSegments(databaseID); err != nil {
+			s.logger.Error(
+				"Failed to archive left WAL segments",
+				"databaseId",
+				databaseID,
+				"error",
+				err,
+			)
+		}
+	}
+}
+
+func (s *WalArchivingBackgroundService) archiveLeftDatabaseSegments(databaseID uuid.UUID) error {
+	walDir := getWalDir(databaseID)
+
+	database, err := s.databaseService.GetDatabaseByID(databaseID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// segments of the removed database are removed with it
+		return os.RemoveAll(walDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	backupConfig, err := s.backupConfigService.GetBackupConfigByDbId(databaseID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.walArchivingService.ArchiveCompletedSegments(
+		database,
+		backupConfig,
+		walDir,
+	); err != nil {
+		return err
+	}
+
+	// partial segment is received again from its start by the next
+	// receiver, it continues from the slot and not from this directory
+	return os.RemoveAll(walDir)
+}
+
+// stopAllReceivers stops processes on shutdown or lost lease. Slots
+// are kept, so archiving continues from the same point on start
+func (s *WalArchivingBackgroundService) stopAllReceivers() {
+	for databaseID, receiver := range s.receivers {
+		receiver.stop()
+		delete(s.receivers, databaseID)
+	}
+}
+
+func getWalDir(databaseID uuid.UUID) string {
+	return filepath.Join(config.GetEnv().WalFolder, databaseID.String())
+}
@@ -0,0 +1,56 @@
+package backups_wal
+
+import (
+	"net/http"
+	"postgresus-backend/internal/features/users"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WalArchivingController struct {
+	walArchivingService *WalArchivingService
+	userService         *users.UserService
+}
+
+func (c *WalArchivingController) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/wal-archiving/database/:id/recovery-window", c.GetRecoveryWindow)
+}
+
+// GetRecoveryWindow
+// @Summary Get point-in-time recovery window
+// @Description Get the range of time the database can be recovered to using physical backups and archived WAL
+// @Tags wal-archiving
+// @Produce json
+// @Param id path string true "Database ID"
+// @Success 200 {object} RecoveryWindow
+// @Failure 400
+// @Failure 401
+// @Router /wal-archiving/database/{id}/recovery-window [get]
+func (c *WalArchivingController) GetRecoveryWindow(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid database ID"})
+		return
+	}
+
+	authorizationHeader := ctx.GetHeader("Authorization")
+	if authorizationHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+		return
+	}
+
+	user, err := c.userService.GetUserFromToken(authorizationHeader)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	recoveryWindow, err := c.walArchivingService.GetRecoveryWindowWithAuth(user, id)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, recoveryWindow)
+}
@@ -0,0 +1,53 @@
+package backups_wal
+
+import (
+	"postgresus-backend/internal/features/backups/backups"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	backups_encryption "postgresus-backend/internal/features/backups/encryption"
+	"postgresus-backend/internal/features/databases"
+	"postgresus-backend/internal/features/storages"
+	system_instances "postgresus-backend/internal/features/system/instances"
+	"postgresus-backend/internal/features/users"
+	"postgresus-backend/internal/util/logger"
+
+	"github.com/google/uuid"
+)
+
+var walSegmentRepository = &WalSegmentRepository{}
+var walArchivingService = &WalArchivingService{
+	walSegmentRepository,
+	backups.GetBackupService(),
+	databases.GetDatabaseService(),
+	storages.GetStorageService(),
+	backups_encryption.GetBackupEncryptionService(),
+	logger.GetLogger(),
+}
+var walArchivingBackgroundService = &WalArchivingBackgroundService{
+	walArchivingService: walArchivingService,
+	backupConfigService: backups_config.GetBackupConfigService(),
+	databaseService:     databases.GetDatabaseService(),
+	archivingLease:      system_instances.GetInstanceService().NewLease("wal-archiving"),
+	receivers:           map[uuid.UUID]*walReceiver{},
+	logger:              logger.GetLogger(),
+	stopped:             make(chan struct{}),
+}
+var walArchivingController = &WalArchivingController{
+	walArchivingService,
+	users.GetUserService(),
+}
+
+func SetupDependencies() {
+	databases.GetDatabaseService().AddDbRemoveListener(walArchivingService)
+}
+
+func GetWalArchivingService() *WalArchivingService {
+	return walArchivingService
+}
+
+func GetWalArchivingBackgroundService() *WalArchivingBackgroundService {
+	return walArchivingBackgroundService
+}
+
+func GetWalArchivingController() *WalArchivingController {
+	return walArchivingController
+}
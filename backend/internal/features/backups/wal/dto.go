@@ -0,0 +1,10 @@
+package backups_wal
+
+import "time"
+
+// RecoveryWindow is the range of time the database can be recovered to.
+// Both bounds are nil when point-in-time recovery is not possible yet
+type RecoveryWindow struct {
+	EarliestTime *time.Time `json:"earliestTime"`
+	LatestTime   *time.Time `json:"latestTime"`
+}
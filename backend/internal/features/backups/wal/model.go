@@ -0,0 +1,36 @@
+package backups_wal
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WalSegment is a WAL segment or a timeline history file received
+// by pg_receivewal and uploaded to the storage. ID is used as the
+// file name in the storage
+type WalSegment struct {
+	ID         uuid.UUID `json:"id"         gorm:"column:id;type:uuid;primaryKey"`
+	DatabaseID uuid.UUID `json:"databaseId" gorm:"column:database_id;type:uuid;not null"`
+	StorageID  uuid.UUID `json:"storageId"  gorm:"column:storage_id;type:uuid;not null"`
+
+	FileName  string `json:"fileName"  gorm:"column:file_name;type:text;not null"`
+	SizeBytes int64  `json:"sizeBytes" gorm:"column:size_bytes;type:bigint;not null"`
+	Sha256    string `json:"sha256"    gorm:"column:sha256;type:text;not null"`
+
+	IsEncrypted          bool `json:"isEncrypted"          gorm:"column:is_encrypted;not null"`
+	EncryptionKeyVersion *int `json:"encryptionKeyVersion" gorm:"column:encryption_key_version"`
+
+	// ArchivedAt is when the segment was completed by pg_receivewal. The
+	// segment has no WAL written after this time, so recovery to a target
+	// time needs all segments up to the first one archived after the target
+	ArchivedAt time.Time `json:"archivedAt" gorm:"column:archived_at;not null"`
+}
+
+func (s *WalSegment) TableName() string {
+	return "wal_segments"
+}
+
+func (s *WalSegment) IsHistoryFile() bool {
+	return historyFileRegex.MatchString(s.FileName)
+}
@@ -0,0 +1,224 @@
+package backups_wal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"postgresus-backend/internal/config"
+	"postgresus-backend/internal/features/databases"
+	pgtypes "postgresus-backend/internal/features/databases/databases/postgresql"
+	"postgresus-backend/internal/util/tools"
+
+	"github.com/google/uuid"
+)
+
+const receiverRestartDelay = 30 * time.Second
+
+// walReceiver keeps pg_receivewal running for a single database. The process
+// is restarted on any failure (network, server restart) until the receiver is
+// stopped. Thanks to the replication slot, the server keeps WAL while the
+// receiver is down, so nothing is lost between restarts
+type walReceiver struct {
+	database *databases.Database
+	walDir   string
+	logger   *slog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func startWalReceiver(
+	database *databases.Database,
+	walDir string,
+	logger *slog.Logger,
+) *walReceiver {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	receiver := &walReceiver{
+		database: database,
+		walDir:   walDir,
+		logger:   logger,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	go receiver.run(ctx)
+
+	return receiver
+}
+
+func (r *walReceiver) stop() {
+	r.cancel()
+	<-r.done
+}
+
+func (r *walReceiver) run(ctx context.Context) {
+	defer close(r.done)
+
+	for {
+		err := r.receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		r.logger.Error(
+			"pg_receivewal stopped, restarting",
+			"databaseId",
+			r.database.ID,
+			"error",
+			err,
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(receiverRestartDelay):
+		}
+	}
+}
+
+func (r *walReceiver) receive(ctx context.Context) error {
+	if err := os.MkdirAll(r.walDir, 0700); err != nil {
+		return fmt.Errorf("failed to create WAL directory: %w", err)
+	}
+
+	slotName := getReplicationSlotName(r.database.ID)
+
+	if err := runPgReceivewal(
+		ctx,
+		r.database.Postgresql,
+		"--create-slot",
+		"--if-not-exists",
+		"-S", slotName,
+	); err != nil {
+		return fmt.Errorf("failed to create replication slot: %w", err)
+	}
+
+	r.logger.Info("Starting pg_receivewal", "databaseId", r.database.ID, "slot", slotName)
+
+	// --no-loop makes pg_receivewal exit on connection loss, so the
+	// failure is logged and retried by us. No --verbose, because stderr
+	// is kept in memory for the whole life of the process
+	return runPgReceivewal(
+		ctx,
+		r.database.Postgresql,
+		"-D", r.walDir,
+		"-S", slotName,
+		"--no-loop",
+	)
+}
+
+// dropReplicationSlot removes the slot, otherwise the server
+// keeps WAL for the slot forever after archiving is disabled
+func dropReplicationSlot(database *databases.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	return runPgReceivewal(
+		ctx,
+		database.Postgresql,
+		"--drop-slot",
+		"-S", getReplicationSlotName(database.ID),
+	)
+}
+
+func getReplicationSlotName(databaseID uuid.UUID) string {
+	// slot names allow only lower case letters, numbers and underscores
+	return "postgresus_" + strings.ReplaceAll(databaseID.String(), "-", "")
+}
+
+func runPgReceivewal(
+	ctx context.Context,
+	pg *pgtypes.PostgresqlDatabase,
+	args ...string,
+) error {
+	if pg == nil {
+		return fmt.Errorf("postgresql database configuration is required for WAL archiving")
+	}
+
+	pgpassFile, err := createTempPgpassFile(pg)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary .pgpass file: %w", err)
+	}
+	defer func() {
+		if pgpassFile != "" {
+			_ = os.RemoveAll(filepath.Dir(pgpassFile))
+		}
+	}()
+
+	connectionArgs := []string{
+		"--no-password",
+		"-h", pg.Host,
+		"-p", strconv.Itoa(pg.Port),
+		"-U", pg.Username,
+	}
+
+	cmd := exec.CommandContext(
+		ctx,
+		tools.GetPostgresqlExecutable(
+			pg.Version,
+			tools.PostgresqlExecutablePgReceivewal,
+			config.GetEnv().EnvMode,
+			config.GetEnv().PostgresesInstallDir,
+		),
+		append(connectionArgs, args...)...,
+	)
+
+	cmd.Env = os.Environ()
+	cmd.Env = append(cmd.Env, "PGCONNECT_TIMEOUT=30")
+
+	if pgpassFile != "" {
+		cmd.Env = append(cmd.Env, "PGPASSFILE="+pgpassFile)
+	}
+
+	if pg.IsHttps {
+		cmd.Env = append(cmd.Env, "PGSSLMODE=require")
+	} else {
+		cmd.Env = append(cmd.Env, "PGSSLMODE=prefer")
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w – stderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
+func createTempPgpassFile(pg *pgtypes.PostgresqlDatabase) (string, error) {
+	if pg.Password == "" {
+		return "", nil
+	}
+
+	// replication connections match the "replication" database
+	// in .pgpass, so the database is a wildcard
+	pgpassContent := fmt.Sprintf("%s:%d:*:%s:%s",
+		pg.Host,
+		pg.Port,
+		pg.Username,
+		pg.Password,
+	)
+
+	tempDir, err := os.MkdirTemp("", "pgpass")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	pgpassFile := filepath.Join(tempDir, ".pgpass")
+	if err := os.WriteFile(pgpassFile, []byte(pgpassContent), 0600); err != nil {
+		_ = os.RemoveAll(tempDir)
+		return "", fmt.Errorf("failed to write temporary .pgpass file: %w", err)
+	}
+
+	return pgpassFile, nil
+}
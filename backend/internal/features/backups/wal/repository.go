@@ -0,0 +1,106 @@
+package backups_wal
+
+import (
+	"errors"
+	"postgresus-backend/internal/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WalSegmentRepository struct{}
+
+func (r *WalSegmentRepository) Save(segment *WalSegment) error {
+	if segment.ID == uuid.Nil {
+		segment.ID = uuid.New()
+	}
+
+	return storage.GetDb().Save(segment).Error
+}
+
+func (r *WalSegmentRepository) FindByDatabaseID(databaseID uuid.UUID) ([]*WalSegment, error) {
+	var segments []*WalSegment
+
+	if err := storage.
+		GetDb().
+		Where("database_id = ?", databaseID).
+		Order("archived_at ASC, file_name ASC").
+		Find(&segments).Error; err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+func (r *WalSegmentRepository) FindArchivedAfter(
+	databaseID uuid.UUID,
+	date time.Time,
+) ([]*WalSegment, error) {
+	var segments []*WalSegment
+
+	if err := storage.
+		GetDb().
+		Where("database_id = ? AND archived_at >= ?", databaseID, date).
+		Order("archived_at ASC, file_name ASC").
+		Find(&segments).Error; err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+func (r *WalSegmentRepository) FindArchivedBefore(
+	databaseID uuid.UUID,
+	date time.Time,
+) ([]*WalSegment, error) {
+	var segments []*WalSegment
+
+	if err := storage.
+		GetDb().
+		Where("database_id = ? AND archived_at < ?", databaseID, date).
+		Order("archived_at ASC, file_name ASC").
+		Find(&segments).Error; err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+func (r *WalSegmentRepository) FindNotInStorage(
+	databaseID uuid.UUID,
+	storageID uuid.UUID,
+) ([]*WalSegment, error) {
+	var segments []*WalSegment
+
+	if err := storage.
+		GetDb().
+		Where("database_id = ? AND storage_id <> ?", databaseID, storageID).
+		Find(&segments).Error; err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+func (r *WalSegmentRepository) FindLastByDatabaseID(databaseID uuid.UUID) (*WalSegment, error) {
+	var segment WalSegment
+
+	if err := storage.
+		GetDb().
+		Where("database_id = ?", databaseID).
+		Order("archived_at DESC").
+		First(&segment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &segment, nil
+}
+
+func (r *WalSegmentRepository) DeleteByID(id uuid.UUID) error {
+	return storage.GetDb().Delete(&WalSegment{}, "id = ?", id).Error
+}
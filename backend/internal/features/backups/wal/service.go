@@ -0,0 +1,537 @@
+package backups_wal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"postgresus-backend/internal/features/backups/backups"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	backups_encryption "postgresus-backend/internal/features/backups/encryption"
+	"postgresus-backend/internal/features/databases"
+	"postgresus-backend/internal/features/storages"
+	users_models "postgresus-backend/internal/features/users/models"
+	encryption_utils "postgresus-backend/internal/util/encryption"
+
+	"github.com/google/uuid"
+)
+
+// recoveryWalDirName is a directory inside the restored data directory
+// where WAL segments are downloaded for restore_command
+const recoveryWalDirName = "postgresus_wal"
+
+// downloadingFileSuffix marks a segment which is not downloaded and
+// verified yet, restore_command never asks for such a name
+const downloadingFileSuffix = ".download"
+
+var (
+	segmentFileRegex = regexp.MustCompile(`^[0-9A-F]{24}$`)
+	historyFileRegex = regexp.MustCompile(`^[0-9A-F]{8}\.history$`)
+)
+
+type WalArchivingService struct {
+	walSegmentRepository *WalSegmentRepository
+	backupService        *backups.BackupService
+	databaseService      *databases.DatabaseService
+	storageService       *storages.StorageService
+	encryptionService    *backups_encryption.BackupEncryptionService
+	logger               *slog.Logger
+}
+
+func (s *WalArchivingService) GetRecoveryWindowWithAuth(
+	user *users_models.User,
+	databaseID uuid.UUID,
+) (*RecoveryWindow, error) {
+	if _, err := s.databaseService.GetDatabase(user, databaseID); err != nil {
+		return nil, err
+	}
+
+	return s.GetRecoveryWindow(databaseID)
+}
+
+// GetRecoveryWindow returns the range between the end of the oldest base
+// backup and the last archived WAL segment
+func (s *WalArchivingService) GetRecoveryWindow(databaseID uuid.UUID) (*RecoveryWindow, error) {
+	oldestBackup, err := s.backupService.GetOldestPhysicalBackup(databaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	lastSegment, err := s.walSegmentRepository.FindLastByDatabaseID(databaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	if oldestBackup == nil || lastSegment == nil {
+		return &RecoveryWindow{}, nil
+	}
+
+	earliestTime := oldestBackup.GetFinishedAt()
+	if lastSegment.ArchivedAt.Before(earliestTime) {
+		return &RecoveryWindow{}, nil
+	}
+
+	return &RecoveryWindow{
+		EarliestTime: &earliestTime,
+		LatestTime:   &lastSegment.ArchivedAt,
+	}, nil
+}
+
+// ValidateRecoveryTarget checks the base backup can be rolled forward to the target
+func (s *WalArchivingService) ValidateRecoveryTarget(
+	backup *backups.Backup,
+	targetTime time.Time,
+) error {
+	if backup.BackupMethod != backups_config.BackupMethodPhysical {
+		return errors.New("point-in-time recovery requires a physical backup")
+	}
+
+	if targetTime.Before(backup.GetFinishedAt()) {
+		return fmt.Errorf(
+			"recovery target is earlier than the end of the base backup (%s)",
+			backup.GetFinishedAt().Format(time.RFC3339),
+		)
+	}
+
+	lastSegment, err := s.walSegmentRepository.FindLastByDatabaseID(backup.DatabaseID)
+	if err != nil {
+		return err
+	}
+
+	if lastSegment == nil {
+		return errors.New("no WAL is archived for the database")
+	}
+
+	if lastSegment.ArchivedAt.Before(targetTime) {
+		return fmt.Errorf(
+			"WAL is archived only up to %s",
+			lastSegment.ArchivedAt.Format(time.RFC3339),
+		)
+	}
+
+	return nil
+}
+
+// PrepareRecovery downloads WAL segments needed to roll the base backup forward
+// to the target time into the data directory and configures the cluster to
+// recover to this time on the next start
+func (s *WalArchivingService) PrepareRecovery(
+	backup *backups.Backup,
+	targetTime time.Time,
+	dataDir string,
+) error {
+	segments, err := s.walSegmentRepository.FindArchivedAfter(backup.DatabaseID, backup.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	walDir := filepath.Join(dataDir, recoveryWalDirName)
+	if err := os.MkdirAll(walDir, 0700); err != nil {
+		return fmt.Errorf("failed to create WAL directory: %w", err)
+	}
+
+	for _, segment := range segments {
+		if err := s.downloadSegment(segment, walDir); err != nil {
+			return err
+		}
+
+		// the first segment archived after the target covers
+		// it, WAL after this segment is not needed
+		if !segment.IsHistoryFile() && segment.ArchivedAt.After(targetTime) {
+			break
+		}
+	}
+
+	// history files are needed even if they are older than the base backup
+	historySegments, err := s.walSegmentRepository.FindArchivedBefore(
+		backup.DatabaseID,
+		backup.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range historySegments {
+		if !segment.IsHistoryFile() {
+			continue
+		}
+
+		if err := s.downloadSegment(segment, walDir); err != nil {
+			return err
+		}
+	}
+
+	return writeRecoveryConfig(dataDir, walDir, targetTime)
+}
+
+// ArchiveCompletedSegments uploads segments completed by pg_receivewal
+// to the storage and removes them from the local directory
+func (s *WalArchivingService) ArchiveCompletedSegments(
+	database *databases.Database,
+	backupConfig *backups_config.BackupConfig,
+	walDir string,
+) error {
+	if backupConfig.StorageID == nil {
+		return errors.New("backup config storage ID is not defined")
+	}
+
+	entries, err := os.ReadDir(walDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to read WAL directory: %w", err)
+	}
+
+	fileNames := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// partial segments are still being written by pg_receivewal
+		if entry.IsDir() ||
+			(!segmentFileRegex.MatchString(entry.Name()) &&
+				!historyFileRegex.MatchString(entry.Name())) {
+			continue
+		}
+
+		fileNames = append(fileNames, entry.Name())
+	}
+
+	if len(fileNames) == 0 {
+		return nil
+	}
+
+	slices.Sort(fileNames)
+
+	storage, err := s.storageService.GetStorageByID(*backupConfig.StorageID)
+	if err != nil {
+		return err
+	}
+
+	for _, fileName := range fileNames {
+		filePath := filepath.Join(walDir, fileName)
+
+		if err := s.uploadSegment(database, backupConfig, storage, filePath); err != nil {
+			return fmt.Errorf("failed to archive WAL segment %s: %w", fileName, err)
+		}
+
+		if err := os.Remove(filePath); err != nil {
+			return fmt.Errorf("failed to remove archived WAL segment %s: %w", fileName, err)
+		}
+	}
+
+	return nil
+}
+
+// CleanOldSegments removes segments which cannot be used for recovery anymore:
+// archived before the oldest base backup or left in the previous storage
+func (s *WalArchivingService) CleanOldSegments(backupConfig *backups_config.BackupConfig) error {
+	segmentsToRemove := make([]*WalSegment, 0)
+
+	if backupConfig.StorageID != nil {
+		segments, err := s.walSegmentRepository.FindNotInStorage(
+			backupConfig.DatabaseID,
+			*backupConfig.StorageID,
+		)
+		if err != nil {
+			return err
+		}
+
+		segmentsToRemove = append(segmentsToRemove, segments...)
+	}
+
+	oldestBackup, err := s.backupService.GetOldestPhysicalBackup(backupConfig.DatabaseID)
+	if err != nil {
+		return err
+	}
+
+	if oldestBackup != nil {
+		segments, err := s.walSegmentRepository.FindArchivedBefore(
+			backupConfig.DatabaseID,
+			oldestBackup.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, segment := range segments {
+			if !segment.IsHistoryFile() {
+				segmentsToRemove = append(segmentsToRemove, segment)
+			}
+		}
+	}
+
+	for _, segment := range segmentsToRemove {
+		if err := s.deleteSegment(segment); err != nil {
+			s.logger.Error("Failed to delete WAL segment", "segmentId", segment.ID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *WalArchivingService) OnBeforeDatabaseRemove(databaseID uuid.UUID) error {
+	segments, err := s.walSegmentRepository.FindByDatabaseID(databaseID)
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range segments {
+		if err := s.deleteSegment(segment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *WalArchivingService) uploadSegment(
+	database *databases.Database,
+	backupConfig *backups_config.BackupConfig,
+	storage *storages.Storage,
+	filePath string,
+) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	segment := &WalSegment{
+		ID:         uuid.New(),
+		DatabaseID: database.ID,
+		StorageID:  storage.ID,
+		FileName:   filepath.Base(filePath),
+		SizeBytes:  info.Size(),
+		ArchivedAt: info.ModTime().UTC(),
+	}
+
+	var encryptionKey []byte
+	if backupConfig.Encryption == backups_config.BackupEncryptionAES256GCM {
+		key, dataKey, err := s.encryptionService.GetActiveKey(database.ID)
+		if err != nil {
+			return err
+		}
+
+		encryptionKey = dataKey
+		segment.IsEncrypted = true
+		segment.EncryptionKeyVersion = &key.Version
+	}
+
+	hasher := sha256.New()
+	storageReader, storageWriter := io.Pipe()
+
+	writeErrCh := make(chan error, 1)
+	go func() {
+		err := writeSegment(storageWriter, io.TeeReader(file, hasher), encryptionKey)
+		_ = storageWriter.CloseWithError(err)
+		writeErrCh <- err
+	}()
+
+	saveErr := storage.SaveFile(s.logger, segment.ID, storageReader)
+	_ = storageReader.CloseWithError(saveErr)
+
+	if writeErr := <-writeErrCh; writeErr != nil && saveErr == nil {
+		saveErr = writeErr
+	}
+
+	if saveErr != nil {
+		return saveErr
+	}
+
+	segment.Sha256 = hex.EncodeToString(hasher.Sum(nil))
+
+	return s.walSegmentRepository.Save(segment)
+}
+
+func (s *WalArchivingService) downloadSegment(segment *WalSegment, walDir string) error {
+	filePath := filepath.Join(walDir, segment.FileName)
+
+	// the same segment may be archived twice if the app was stopped
+	// between upload and local file removal, so the file may be
+	// downloaded already. File left by a failed restore is replaced
+	if isSegmentFileValid(filePath, segment.Sha256) {
+		return nil
+	}
+
+	storage, err := s.storageService.GetStorageByID(segment.StorageID)
+	if err != nil {
+		return err
+	}
+
+	reader, err := storage.GetFile(segment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get WAL segment %s: %w", segment.FileName, err)
+	}
+
+	if segment.IsEncrypted {
+		reader, err = s.decryptSegment(segment, reader)
+		if err != nil {
+			return err
+		}
+	}
+	defer func() { _ = reader.Close() }()
+
+	return saveSegmentFile(reader, filePath, segment.Sha256)
+}
+
+func (s *WalArchivingService) decryptSegment(
+	segment *WalSegment,
+	reader io.ReadCloser,
+) (io.ReadCloser, error) {
+	if segment.EncryptionKeyVersion == nil {
+		_ = reader.Close()
+		return nil, errors.New("WAL segment is encrypted, but encryption key version is unknown")
+	}
+
+	encryptionKey, err := s.encryptionService.GetKey(
+		segment.DatabaseID,
+		*segment.EncryptionKeyVersion,
+	)
+	if err != nil {
+		_ = reader.Close()
+		return nil, err
+	}
+
+	decryptingReader, err := encryption_utils.NewDecryptingReadCloser(reader, encryptionKey)
+	if err != nil {
+		_ = reader.Close()
+		return nil, err
+	}
+
+	return decryptingReader, nil
+}
+
+func (s *WalArchivingService) deleteSegment(segment *WalSegment) error {
+	storage, err := s.storageService.GetStorageByID(segment.StorageID)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.DeleteFile(segment.ID); err != nil {
+		s.logger.Error("Failed to delete WAL segment file", "segmentId", segment.ID, "error", err)
+	}
+
+	return s.walSegmentRepository.DeleteByID(segment.ID)
+}
+
+func writeSegment(writer io.Writer, reader io.Reader, encryptionKey []byte) error {
+	if encryptionKey == nil {
+		_, err := io.Copy(writer, reader)
+		return err
+	}
+
+	encryptingWriter, err := encryption_utils.NewEncryptingWriter(writer, encryptionKey)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(encryptingWriter, reader); err != nil {
+		return err
+	}
+
+	return encryptingWriter.Close()
+}
+
+// saveSegmentFile writes the segment under a temp name and renames it only
+// when the checksum matches, so restore_command never copies a partial
+// or corrupted segment
+func saveSegmentFile(reader io.Reader, filePath string, expectedSha256 string) error {
+	tempFilePath := filePath + downloadingFileSuffix
+
+	if err := writeSegmentFile(reader, tempFilePath, expectedSha256); err != nil {
+		_ = os.Remove(tempFilePath)
+		return fmt.Errorf("failed to download WAL segment %s: %w", filepath.Base(filePath), err)
+	}
+
+	return os.Rename(tempFilePath, filePath)
+}
+
+func writeSegmentFile(reader io.Reader, filePath string, expectedSha256 string) error {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hasher), reader); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if hex.EncodeToString(hasher.Sum(nil)) != expectedSha256 {
+		return errors.New("segment is corrupted: checksum mismatch")
+	}
+
+	return nil
+}
+
+func isSegmentFileValid(filePath string, expectedSha256 string) bool {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = file.Close() }()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return false
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)) == expectedSha256
+}
+
+// writeRecoveryConfig makes PostgreSQL replay downloaded WAL up to the
+// target time on start and then promote to a normal primary
+func writeRecoveryConfig(dataDir string, walDir string, targetTime time.Time) error {
+	restoreCommand := fmt.Sprintf(
+		`cp "%s/%%f" "%%p"`,
+		filepath.ToSlash(walDir),
+	)
+
+	recoveryConfig := fmt.Sprintf(
+		"\n# Added by Postgresus point-in-time recovery\n"+
+			"restore_command = '%s'\n"+
+			"recovery_target_time = '%s'\n"+
+			"recovery_target_action = 'promote'\n",
+		strings.ReplaceAll(restoreCommand, "'", "''"),
+		targetTime.UTC().Format("2006-01-02 15:04:05.999999-07"),
+	)
+
+	autoConfFile, err := os.OpenFile(
+		filepath.Join(dataDir, "postgresql.auto.conf"),
+		os.O_CREATE|os.O_APPEND|os.O_WRONLY,
+		0600,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to open postgresql.auto.conf: %w", err)
+	}
+
+	if _, err := autoConfFile.WriteString(recoveryConfig); err != nil {
+		_ = autoConfFile.Close()
+		return fmt.Errorf("failed to write recovery config: %w", err)
+	}
+
+	if err := autoConfFile.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dataDir, "recovery.signal"), []byte{}, 0600)
+}
@@ -0,0 +1,127 @@
+package backups_wal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"postgresus-backend/internal/features/storages"
+	"postgresus-backend/internal/features/users"
+	"postgresus-backend/internal/util/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSegmentFileName = "000000010000000000000003"
+
+func Test_DownloadSegment_WhenChecksumMatches_SegmentSaved(t *testing.T) {
+	storage, segment := createTestSegment(t, "wal content", "wal content")
+	defer storages.RemoveTestStorage(storage.ID)
+	defer func() { _ = storage.DeleteFile(segment.ID) }()
+
+	walDir := t.TempDir()
+
+	err := walArchivingService.downloadSegment(segment, walDir)
+	require.NoError(t, err)
+
+	assertWalDirFiles(t, walDir, map[string]string{testSegmentFileName: "wal content"})
+}
+
+func Test_DownloadSegment_WhenChecksumDoesNotMatch_SegmentRemoved(t *testing.T) {
+	storage, segment := createTestSegment(t, "wal content", "corrupted")
+	defer storages.RemoveTestStorage(storage.ID)
+	defer func() { _ = storage.DeleteFile(segment.ID) }()
+
+	walDir := t.TempDir()
+
+	err := walArchivingService.downloadSegment(segment, walDir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+
+	assertWalDirFiles(t, walDir, map[string]string{})
+}
+
+func Test_DownloadSegment_WhenExistingFileIsCorrupted_SegmentReplaced(t *testing.T) {
+	storage, segment := createTestSegment(t, "wal content", "wal content")
+	defer storages.RemoveTestStorage(storage.ID)
+	defer func() { _ = storage.DeleteFile(segment.ID) }()
+
+	walDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(walDir, testSegmentFileName), []byte("wal con"), 0600)
+	require.NoError(t, err)
+
+	err = walArchivingService.downloadSegment(segment, walDir)
+	require.NoError(t, err)
+
+	assertWalDirFiles(t, walDir, map[string]string{testSegmentFileName: "wal content"})
+}
+
+func Test_WriteRecoveryConfig_WithTargetTime_RecoveryConfigured(t *testing.T) {
+	dataDir := t.TempDir()
+	walDir := filepath.Join(dataDir, recoveryWalDirName)
+	targetTime := time.Date(2025, 12, 1, 10, 30, 15, 0, time.FixedZone("CET", 3600))
+
+	err := os.WriteFile(filepath.Join(dataDir, "postgresql.auto.conf"), []byte("port = 5432\n"), 0600)
+	require.NoError(t, err)
+
+	err = writeRecoveryConfig(dataDir, walDir, targetTime)
+	require.NoError(t, err)
+
+	autoConf, err := os.ReadFile(filepath.Join(dataDir, "postgresql.auto.conf"))
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(string(autoConf), "port = 5432\n"))
+	assert.Contains(t, string(autoConf), "restore_command = 'cp \""+filepath.ToSlash(walDir)+"/%f\" \"%p\"'")
+	assert.Contains(t, string(autoConf), "recovery_target_time = '2025-12-01 09:30:15+00'")
+	assert.Contains(t, string(autoConf), "recovery_target_action = 'promote'")
+
+	assert.FileExists(t, filepath.Join(dataDir, "recovery.signal"))
+}
+
+// createTestSegment saves the file content, while checksum
+// of the segment is taken from the expected content
+func createTestSegment(
+	t *testing.T,
+	expectedContent string,
+	fileContent string,
+) (*storages.Storage, *WalSegment) {
+	user := users.GetTestUser()
+	storage := storages.CreateTestStorage(user.UserID)
+
+	hash := sha256.Sum256([]byte(expectedContent))
+
+	segment := &WalSegment{
+		ID:         uuid.New(),
+		StorageID:  storage.ID,
+		FileName:   testSegmentFileName,
+		SizeBytes:  int64(len(expectedContent)),
+		Sha256:     hex.EncodeToString(hash[:]),
+		ArchivedAt: time.Now().UTC(),
+	}
+
+	err := storage.SaveFile(logger.GetLogger(), segment.ID, strings.NewReader(fileContent))
+	require.NoError(t, err)
+
+	return storage, segment
+}
+
+func assertWalDirFiles(t *testing.T, walDir string, expectedFiles map[string]string) {
+	entries, err := os.ReadDir(walDir)
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(walDir, entry.Name()))
+		require.NoError(t, err)
+
+		files[entry.Name()] = string(content)
+	}
+
+	assert.Equal(t, expectedFiles, files)
+}
@@ -0,0 +1,196 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Catalog is a list of schemas and tables of a database, it is
+// used to check pg_dump patterns before the dump is started
+type Catalog struct {
+	Schemas []string
+	Tables  []CatalogTable
+}
+
+type CatalogTable struct {
+	Schema string
+	Name   string
+
+	// IsVisible is true when the table is found via search_path,
+	// only such tables are matched by unqualified patterns
+	IsVisible bool
+}
+
+func (p *PostgresqlDatabase) LoadCatalog(databaseName string) (*Catalog, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, buildConnectionStringForDB(p, databaseName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database '%s': %w", databaseName, err)
+	}
+	defer func() {
+		_ = conn.Close(ctx)
+	}()
+
+	catalog := &Catalog{}
+
+	schemaRows, err := conn.Query(
+		ctx,
+		`SELECT nspname FROM pg_namespace
+		 WHERE nspname NOT LIKE 'pg\_toast%' AND nspname NOT LIKE 'pg\_temp\_%'`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schemas: %w", err)
+	}
+	defer schemaRows.Close()
+
+	for schemaRows.Next() {
+		var schema string
+
+		if err := schemaRows.Scan(&schema); err != nil {
+			return nil, fmt.Errorf("failed to scan schema: %w", err)
+		}
+
+		catalog.Schemas = append(catalog.Schemas, schema)
+	}
+
+	if err := schemaRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over schema rows: %w", err)
+	}
+
+	// the same relation kinds pg_dump matches with --table
+	tableRows, err := conn.Query(
+		ctx,
+		`SELECT n.nspname, c.relname, pg_table_is_visible(c.oid)
+		 FROM pg_class c
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f', 'S')`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	defer tableRows.Close()
+
+	for tableRows.Next() {
+		var table CatalogTable
+
+		if err := tableRows.Scan(&table.Schema, &table.Name, &table.IsVisible); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+
+		catalog.Tables = append(catalog.Tables, table)
+	}
+
+	if err := tableRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over table rows: %w", err)
+	}
+
+	return catalog, nil
+}
+
+// HasSchema reports whether any schema matches the pg_dump pattern
+func (c *Catalog) HasSchema(pattern string) (bool, error) {
+	parts, err := parsePattern(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	if len(parts) != 1 {
+		return false, fmt.Errorf("improper schema pattern (too many dotted names): %s", pattern)
+	}
+
+	for _, schema := range c.Schemas {
+		if parts[0].MatchString(schema) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// HasTable reports whether any table matches the pg_dump pattern
+func (c *Catalog) HasTable(pattern string) (bool, error) {
+	parts, err := parsePattern(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	if len(parts) > 2 {
+		return false, fmt.Errorf("improper table pattern (too many dotted names): %s", pattern)
+	}
+
+	for _, table := range c.Tables {
+		if len(parts) == 1 {
+			if table.IsVisible && parts[0].MatchString(table.Name) {
+				return true, nil
+			}
+
+			continue
+		}
+
+		if parts[0].MatchString(table.Schema) && parts[1].MatchString(table.Name) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// parsePattern converts pattern to a regexp per dotted part, following psql
+// rules: unquoted text is lower cased, * and ? are wildcards, text inside
+// double quotes is taken literally and "" inside quotes is a quote
+func parsePattern(pattern string) ([]*regexp.Regexp, error) {
+	var parts []string
+	var current strings.Builder
+
+	isInQuotes := false
+	runes := []rune(pattern)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == '"' && isInQuotes && i+1 < len(runes) && runes[i+1] == '"':
+			current.WriteString(regexp.QuoteMeta(`"`))
+			i++
+		case r == '"':
+			isInQuotes = !isInQuotes
+		case isInQuotes:
+			current.WriteString(regexp.QuoteMeta(string(r)))
+		case r == '.':
+			parts = append(parts, current.String())
+			current.Reset()
+		case r == '*':
+			current.WriteString(".*")
+		case r == '?':
+			current.WriteString(".")
+		default:
+			current.WriteString(regexp.QuoteMeta(string(unicode.ToLower(r))))
+		}
+	}
+
+	if isInQuotes {
+		return nil, fmt.Errorf("unterminated quoted name in pattern: %s", pattern)
+	}
+
+	parts = append(parts, current.String())
+
+	compiledParts := make([]*regexp.Regexp, 0, len(parts))
+	for _, part := range parts {
+		compiledPart, err := regexp.Compile("^(?:" + part + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+
+		compiledParts = append(compiledParts, compiledPart)
+	}
+
+	return compiledParts, nil
+}
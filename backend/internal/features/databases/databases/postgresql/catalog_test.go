@@ -0,0 +1,70 @@
+package postgresql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CatalogHasSchema_MatchesPgDumpPatterns(t *testing.T) {
+	catalog := &Catalog{Schemas: []string{"public", "audit", "Sales"}}
+
+	cases := []struct {
+		pattern  string
+		expected bool
+	}{
+		{"public", true},
+		{"PUBLIC", true},
+		{"aud*", true},
+		{"audi?", true},
+		{"sales", false},
+		{`"Sales"`, true},
+		{"missing", false},
+	}
+
+	for _, c := range cases {
+		isFound, err := catalog.HasSchema(c.pattern)
+		require.NoError(t, err, c.pattern)
+		assert.Equal(t, c.expected, isFound, c.pattern)
+	}
+
+	_, err := catalog.HasSchema("public.users")
+	assert.Error(t, err)
+}
+
+func Test_CatalogHasTable_MatchesPgDumpPatterns(t *testing.T) {
+	catalog := &Catalog{
+		Tables: []CatalogTable{
+			{Schema: "public", Name: "users", IsVisible: true},
+			{Schema: "audit", Name: "log_2024", IsVisible: false},
+			{Schema: "public", Name: "Orders.Archive", IsVisible: true},
+		},
+	}
+
+	cases := []struct {
+		pattern  string
+		expected bool
+	}{
+		{"users", true},
+		{"public.users", true},
+		{"*.users", true},
+		{"audit.log_*", true},
+		{"log_2024", false}, // not in search_path
+		{"audit.log_????", true},
+		{`public."Orders.Archive"`, true},
+		{"public.missing", false},
+	}
+
+	for _, c := range cases {
+		isFound, err := catalog.HasTable(c.pattern)
+		require.NoError(t, err, c.pattern)
+		assert.Equal(t, c.expected, isFound, c.pattern)
+	}
+
+	_, err := catalog.HasTable("public.orders.archive")
+	assert.Error(t, err)
+
+	_, err = catalog.HasTable(`public."users`)
+	assert.Error(t, err)
+}
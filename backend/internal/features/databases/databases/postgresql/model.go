@@ -8,12 +8,16 @@ import (
 	"postgresus-backend/internal/util/tools"
 	"regexp"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
 )
 
+const defaultMaintenanceDatabase = "postgres"
+
 type PostgresqlDatabase struct {
 	ID uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 
@@ -29,12 +33,46 @@ type PostgresqlDatabase struct {
 	Password string  `json:"password" gorm:"type:text;not null"`
 	Database *string `json:"database" gorm:"type:text"`
 	IsHttps  bool    `json:"isHttps"  gorm:"type:boolean;default:false"`
+
+	// IsWholeServer backs up every database of the server plus globals
+	// (roles, tablespaces). Database is then optional and only used as
+	// the maintenance database to connect to
+	IsWholeServer bool `json:"isWholeServer" gorm:"column:is_whole_server;type:boolean;default:false"`
+
+	// ReplicaEndpoints are "host:port" of standby servers. Logical backups
+	// are taken from the first healthy replica to take load off the primary,
+	// credentials and database name are the same as for the primary
+	ReplicaEndpoints       []string `json:"replicaEndpoints" gorm:"-"`
+	ReplicaEndpointsString string   `json:"-"                gorm:"column:replica_endpoints;type:text;not null"`
+
+	// MaxReplicationLagSeconds is the lag above which replica is skipped
+	MaxReplicationLagSeconds int `json:"maxReplicationLagSeconds" gorm:"column:max_replication_lag_seconds;type:int;not null"`
 }
 
 func (p *PostgresqlDatabase) TableName() string {
 	return "postgresql_databases"
 }
 
+func (p *PostgresqlDatabase) BeforeSave(tx *gorm.DB) error {
+	p.ReplicaEndpointsString = strings.Join(p.ReplicaEndpoints, ",")
+
+	if p.MaxReplicationLagSeconds == 0 {
+		p.MaxReplicationLagSeconds = DefaultMaxReplicationLagSeconds
+	}
+
+	return nil
+}
+
+func (p *PostgresqlDatabase) AfterFind(tx *gorm.DB) error {
+	if p.ReplicaEndpointsString == "" {
+		p.ReplicaEndpoints = []string{}
+	} else {
+		p.ReplicaEndpoints = strings.Split(p.ReplicaEndpointsString, ",")
+	}
+
+	return nil
+}
+
 func (p *PostgresqlDatabase) Validate() error {
 	if p.Version == "" {
 		return errors.New("version is required")
@@ -56,6 +94,16 @@ func (p *PostgresqlDatabase) Validate() error {
 		return errors.New("password is required")
 	}
 
+	for _, endpoint := range p.ReplicaEndpoints {
+		if _, _, err := parseReplicaEndpoint(endpoint); err != nil {
+			return err
+		}
+	}
+
+	if p.MaxReplicationLagSeconds < 0 {
+		return errors.New("max replication lag cannot be negative")
+	}
+
 	return nil
 }
 
@@ -63,9 +111,107 @@ func (p *PostgresqlDatabase) TestConnection(logger *slog.Logger) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
+	if p.IsWholeServer {
+		return testWholeServerConnection(logger, ctx, p)
+	}
+
 	return testSingleDatabaseConnection(logger, ctx, p)
 }
 
+// GetMaintenanceDatabase returns database to connect to when
+// the operation is not bound to a specific database
+func (p *PostgresqlDatabase) GetMaintenanceDatabase() string {
+	if p.Database == nil || *p.Database == "" {
+		return defaultMaintenanceDatabase
+	}
+
+	return *p.Database
+}
+
+// ListDatabases returns names of all databases of the server which
+// can be dumped. Templates and databases not accepting connections
+// are skipped, the same way as pg_dumpall does
+func (p *PostgresqlDatabase) ListDatabases() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, buildConnectionStringForDB(p, p.GetMaintenanceDatabase()))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to connect to database '%s': %w",
+			p.GetMaintenanceDatabase(),
+			err,
+		)
+	}
+	defer func() {
+		_ = conn.Close(ctx)
+	}()
+
+	rows, err := conn.Query(
+		ctx,
+		"SELECT datname FROM pg_database WHERE datallowconn AND NOT datistemplate ORDER BY datname",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query databases: %w", err)
+	}
+	defer rows.Close()
+
+	var databaseNames []string
+	for rows.Next() {
+		var datname string
+
+		if err := rows.Scan(&datname); err != nil {
+			return nil, fmt.Errorf("failed to scan database name: %w", err)
+		}
+
+		databaseNames = append(databaseNames, datname)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over database rows: %w", err)
+	}
+
+	return databaseNames, nil
+}
+
+// CreateDatabaseIfNotExists is used on whole server restore, pg_restore
+// cannot create the database itself when restoring into it
+func (p *PostgresqlDatabase) CreateDatabaseIfNotExists(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, buildConnectionStringForDB(p, p.GetMaintenanceDatabase()))
+	if err != nil {
+		return fmt.Errorf(
+			"failed to connect to database '%s': %w",
+			p.GetMaintenanceDatabase(),
+			err,
+		)
+	}
+	defer func() {
+		_ = conn.Close(ctx)
+	}()
+
+	var isExists bool
+	if err := conn.QueryRow(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)",
+		name,
+	).Scan(&isExists); err != nil {
+		return fmt.Errorf("failed to check database '%s': %w", name, err)
+	}
+
+	if isExists {
+		return nil
+	}
+
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to create database '%s': %w", name, err)
+	}
+
+	return nil
+}
+
 // testSingleDatabaseConnection tests connection to a specific database for pg_dump
 func testSingleDatabaseConnection(
 	logger *slog.Logger,
@@ -112,6 +258,44 @@ func testSingleDatabaseConnection(
 	return nil
 }
 
+// testWholeServerConnection tests connection to the maintenance database
+// and checks the user can read the list of databases for pg_dumpall
+func testWholeServerConnection(
+	logger *slog.Logger,
+	ctx context.Context,
+	postgresDb *PostgresqlDatabase,
+) error {
+	maintenanceDb := postgresDb.GetMaintenanceDatabase()
+
+	conn, err := pgx.Connect(ctx, buildConnectionStringForDB(postgresDb, maintenanceDb))
+	if err != nil {
+		return fmt.Errorf("failed to connect to database '%s': %w", maintenanceDb, err)
+	}
+	defer func() {
+		if closeErr := conn.Close(ctx); closeErr != nil {
+			logger.Error("Failed to close connection", "error", closeErr)
+		}
+	}()
+
+	if err := verifyDatabaseVersion(ctx, conn, postgresDb.Version); err != nil {
+		return err
+	}
+
+	var databasesCount int
+	if err := conn.QueryRow(
+		ctx,
+		"SELECT count(*) FROM pg_database WHERE datallowconn AND NOT datistemplate",
+	).Scan(&databasesCount); err != nil {
+		return fmt.Errorf("cannot list databases of the server: %w", err)
+	}
+
+	if databasesCount == 0 {
+		return errors.New("server has no databases to back up")
+	}
+
+	return nil
+}
+
 // verifyDatabaseVersion checks if the actual database version matches the specified version
 func verifyDatabaseVersion(
 	ctx context.Context,
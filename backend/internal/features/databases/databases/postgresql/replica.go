@@ -0,0 +1,145 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	DefaultMaxReplicationLagSeconds = 300
+
+	replicaCheckTimeout = 10 * time.Second
+)
+
+// GetEndpoint returns "host:port" of the server
+func (p *PostgresqlDatabase) GetEndpoint() string {
+	return net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+}
+
+// GetBackupSource returns the first healthy replica to back up from.
+// The primary itself is returned when there are no replicas or none of
+// them is in recovery with the lag below MaxReplicationLagSeconds
+func (p *PostgresqlDatabase) GetBackupSource(logger *slog.Logger) *PostgresqlDatabase {
+	for _, endpoint := range p.ReplicaEndpoints {
+		replica, err := p.checkReplica(endpoint)
+		if err != nil {
+			logger.Warn(
+				"Replica is not healthy, skipping it",
+				"replica",
+				endpoint,
+				"error",
+				err,
+			)
+			continue
+		}
+
+		return replica
+	}
+
+	if len(p.ReplicaEndpoints) > 0 {
+		logger.Warn("No healthy replica found, falling back to primary", "primary", p.GetEndpoint())
+	}
+
+	return p
+}
+
+func (p *PostgresqlDatabase) checkReplica(endpoint string) (*PostgresqlDatabase, error) {
+	host, port, err := parseReplicaEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	replica := *p
+	replica.Host = host
+	replica.Port = port
+	replica.ReplicaEndpoints = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), replicaCheckTimeout)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, buildConnectionStringForDB(&replica, replica.GetMaintenanceDatabase()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to replica: %w", err)
+	}
+	defer func() {
+		_ = conn.Close(ctx)
+	}()
+
+	// replay timestamp stays old while the primary has no writes, so
+	// replica which replayed everything it received has no lag
+	var isInRecovery bool
+	var lagSeconds *float64
+
+	if err := conn.QueryRow(
+		ctx,
+		`SELECT
+			pg_is_in_recovery(),
+			CASE
+				WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+				ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+			END::float8`,
+	).Scan(&isInRecovery, &lagSeconds); err != nil {
+		return nil, fmt.Errorf("failed to get replica state: %w", err)
+	}
+
+	if err := validateReplicaState(isInRecovery, lagSeconds, p.getMaxReplicationLagSeconds()); err != nil {
+		return nil, err
+	}
+
+	return &replica, nil
+}
+
+func (p *PostgresqlDatabase) getMaxReplicationLagSeconds() int {
+	if p.MaxReplicationLagSeconds == 0 {
+		return DefaultMaxReplicationLagSeconds
+	}
+
+	return p.MaxReplicationLagSeconds
+}
+
+// validateReplicaState rejects promoted replicas, they are not kept in
+// sync with the primary anymore, and replicas with unknown or high lag
+func validateReplicaState(isInRecovery bool, lagSeconds *float64, maxLagSeconds int) error {
+	if !isInRecovery {
+		return errors.New("server is not in recovery, it is not a standby")
+	}
+
+	if lagSeconds == nil {
+		return errors.New("replication lag is unknown, replica has not replayed any transaction")
+	}
+
+	if *lagSeconds > float64(maxLagSeconds) {
+		return fmt.Errorf(
+			"replication lag %.0f seconds exceeds %d seconds",
+			*lagSeconds,
+			maxLagSeconds,
+		)
+	}
+
+	return nil
+}
+
+func parseReplicaEndpoint(endpoint string) (string, int, error) {
+	host, portString, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid replica endpoint \"%s\", expected host:port", endpoint)
+	}
+
+	port, err := strconv.Atoi(portString)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port of replica endpoint \"%s\"", endpoint)
+	}
+
+	if host == "" {
+		return "", 0, fmt.Errorf("host of replica endpoint \"%s\" is required", endpoint)
+	}
+
+	return host, port, nil
+}
@@ -0,0 +1,46 @@
+package postgresql
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ValidateReplicaState_AcceptsStandbyWithLagBelowThreshold(t *testing.T) {
+	lagSeconds := 12.5
+
+	assert.NoError(t, validateReplicaState(true, &lagSeconds, 60))
+}
+
+func Test_ValidateReplicaState_RejectsPromotedLaggingOrUnknownReplica(t *testing.T) {
+	lagSeconds := 120.0
+
+	assert.ErrorContains(t, validateReplicaState(false, &lagSeconds, 300), "not in recovery")
+	assert.ErrorContains(t, validateReplicaState(true, &lagSeconds, 60), "exceeds 60 seconds")
+	assert.ErrorContains(t, validateReplicaState(true, nil, 60), "unknown")
+}
+
+func Test_ParseReplicaEndpoint_ParsesHostAndPort(t *testing.T) {
+	host, port, err := parseReplicaEndpoint("replica-1.example.com:5433")
+	require.NoError(t, err)
+	assert.Equal(t, "replica-1.example.com", host)
+	assert.Equal(t, 5433, port)
+
+	host, port, err = parseReplicaEndpoint("[::1]:5432")
+	require.NoError(t, err)
+	assert.Equal(t, "::1", host)
+	assert.Equal(t, 5432, port)
+
+	for _, endpoint := range []string{"replica-1", "replica-1:abc", "replica-1:0", ":5432"} {
+		_, _, err := parseReplicaEndpoint(endpoint)
+		assert.Error(t, err, endpoint)
+	}
+}
+
+func Test_GetBackupSource_WhenNoReplicas_ReturnsPrimary(t *testing.T) {
+	primary := &PostgresqlDatabase{Host: "primary", Port: 5432}
+
+	assert.Same(t, primary, primary.GetBackupSource(slog.Default()))
+}
@@ -0,0 +1,71 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateScratchDatabase creates an empty database to restore a backup into
+// for verification. It is made from template0, so objects added to template1
+// on the server do not conflict with the restored ones. Database left after
+// a previous run is recreated
+func (p *PostgresqlDatabase) CreateScratchDatabase(name string) error {
+	if err := p.DropDatabaseIfExists(name); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, buildConnectionStringForDB(p, p.GetMaintenanceDatabase()))
+	if err != nil {
+		return fmt.Errorf(
+			"failed to connect to database '%s': %w",
+			p.GetMaintenanceDatabase(),
+			err,
+		)
+	}
+	defer func() {
+		_ = conn.Close(ctx)
+	}()
+
+	if _, err := conn.Exec(
+		ctx,
+		"CREATE DATABASE "+pgx.Identifier{name}.Sanitize()+" TEMPLATE template0",
+	); err != nil {
+		return fmt.Errorf("failed to create database '%s': %w", name, err)
+	}
+
+	return nil
+}
+
+func (p *PostgresqlDatabase) DropDatabaseIfExists(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, buildConnectionStringForDB(p, p.GetMaintenanceDatabase()))
+	if err != nil {
+		return fmt.Errorf(
+			"failed to connect to database '%s': %w",
+			p.GetMaintenanceDatabase(),
+			err,
+		)
+	}
+	defer func() {
+		_ = conn.Close(ctx)
+	}()
+
+	// WITH (FORCE) is available since PostgreSQL 13, the oldest supported
+	// version. It closes connections left by a killed pg_restore
+	if _, err := conn.Exec(
+		ctx,
+		"DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()+" WITH (FORCE)",
+	); err != nil {
+		return fmt.Errorf("failed to drop database '%s': %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ExecuteScript runs SQL against the backed up database (the maintenance
+// database for whole server). Script may contain several statements, output
+// consists of command tags and notices raised by the script
+func (p *PostgresqlDatabase) ExecuteScript(ctx context.Context, script string) (string, error) {
+	databaseName := p.GetMaintenanceDatabase()
+	if !p.IsWholeServer && p.Database != nil && *p.Database != "" {
+		databaseName = *p.Database
+	}
+
+	connConfig, err := pgx.ParseConfig(buildConnectionStringForDB(p, databaseName))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse connection config: %w", err)
+	}
+
+	var output strings.Builder
+	connConfig.OnNotice = func(_ *pgconn.PgConn, notice *pgconn.Notice) {
+		output.WriteString(notice.Severity + ": " + notice.Message + "\n")
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to database '%s': %w", databaseName, err)
+	}
+	defer func() {
+		_ = conn.Close(context.Background())
+	}()
+
+	// simple protocol allows several statements in one script
+	results, err := conn.PgConn().Exec(ctx, script).ReadAll()
+	for _, result := range results {
+		if result.Err == nil {
+			output.WriteString(result.CommandTag.String() + "\n")
+		}
+	}
+
+	return output.String(), err
+}
+
+// ExecuteAssertion runs a query returning a single boolean which should
+// be true, e.g. "SELECT count(*) > 0 FROM users"
+func (p *PostgresqlDatabase) ExecuteAssertion(ctx context.Context, query string) error {
+	databaseName := p.GetMaintenanceDatabase()
+
+	conn, err := pgx.Connect(ctx, buildConnectionStringForDB(p, databaseName))
+	if err != nil {
+		return fmt.Errorf("failed to connect to database '%s': %w", databaseName, err)
+	}
+	defer func() {
+		_ = conn.Close(context.Background())
+	}()
+
+	var isPassed bool
+	if err := conn.QueryRow(ctx, query).Scan(&isPassed); err != nil {
+		return fmt.Errorf("failed to execute assertion: %w", err)
+	}
+
+	if !isPassed {
+		return fmt.Errorf("assertion returned false: %s", query)
+	}
+
+	return nil
+}
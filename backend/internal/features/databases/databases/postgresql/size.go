@@ -0,0 +1,115 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetServerSize returns size of all databases of the server. It is
+// what pg_basebackup copies and what whole server backup dumps
+func (p *PostgresqlDatabase) GetServerSize() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, buildConnectionStringForDB(p, p.GetMaintenanceDatabase()))
+	if err != nil {
+		return 0, fmt.Errorf(
+			"failed to connect to database '%s': %w",
+			p.GetMaintenanceDatabase(),
+			err,
+		)
+	}
+	defer func() {
+		_ = conn.Close(ctx)
+	}()
+
+	var sizeBytes int64
+	if err := conn.QueryRow(
+		ctx,
+		"SELECT COALESCE(SUM(pg_database_size(datname)), 0)::bigint FROM pg_database WHERE datallowconn",
+	).Scan(&sizeBytes); err != nil {
+		return 0, fmt.Errorf("failed to query server size: %w", err)
+	}
+
+	return sizeBytes, nil
+}
+
+// GetTableDataSize returns size of tables (with TOAST) of the database.
+// Indexes are not counted, because pg_dump writes only their definitions
+func (p *PostgresqlDatabase) GetTableDataSize(databaseName string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, buildConnectionStringForDB(p, databaseName))
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to database '%s': %w", databaseName, err)
+	}
+	defer func() {
+		_ = conn.Close(ctx)
+	}()
+
+	var sizeBytes int64
+	if err := conn.QueryRow(
+		ctx,
+		`SELECT COALESCE(SUM(pg_table_size(c.oid)), 0)::bigint
+		 FROM pg_class c
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 WHERE c.relkind IN ('r', 'm')
+		   AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		   AND n.nspname NOT LIKE 'pg\_toast%'`,
+	).Scan(&sizeBytes); err != nil {
+		return 0, fmt.Errorf("failed to query table sizes: %w", err)
+	}
+
+	return sizeBytes, nil
+}
+
+// GetRelationSizes returns size of tables (with TOAST) and materialized
+// views of the database keyed by "schema.name"
+func (p *PostgresqlDatabase) GetRelationSizes(databaseName string) (map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, buildConnectionStringForDB(p, databaseName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database '%s': %w", databaseName, err)
+	}
+	defer func() {
+		_ = conn.Close(ctx)
+	}()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT n.nspname, c.relname, pg_table_size(c.oid)::bigint
+		 FROM pg_class c
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 WHERE c.relkind IN ('r', 'm')
+		   AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		   AND n.nspname NOT LIKE 'pg\_toast%'`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query relation sizes: %w", err)
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]int64)
+	for rows.Next() {
+		var schema, name string
+		var sizeBytes int64
+
+		if err := rows.Scan(&schema, &name, &sizeBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan relation size: %w", err)
+		}
+
+		sizes[schema+"."+name] = sizeBytes
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over relation rows: %w", err)
+	}
+
+	return sizes, nil
+}
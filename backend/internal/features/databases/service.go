@@ -265,6 +265,14 @@ func (s *DatabaseService) CopyDatabase(
 				Password:   existingDatabase.Postgresql.Password,
 				Database:   existingDatabase.Postgresql.Database,
 				IsHttps:    existingDatabase.Postgresql.IsHttps,
+
+				IsWholeServer: existingDatabase.Postgresql.IsWholeServer,
+
+				ReplicaEndpoints: append(
+					[]string{},
+					existingDatabase.Postgresql.ReplicaEndpoints...,
+				),
+				MaxReplicationLagSeconds: existingDatabase.Postgresql.MaxReplicationLagSeconds,
 			}
 		}
 	}
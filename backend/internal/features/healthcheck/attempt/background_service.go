@@ -4,12 +4,14 @@ import (
 	"log/slog"
 	"postgresus-backend/internal/config"
 	healthcheck_config "postgresus-backend/internal/features/healthcheck/config"
+	system_instances "postgresus-backend/internal/features/system/instances"
 	"time"
 )
 
 type HealthcheckAttemptBackgroundService struct {
 	healthcheckConfigService *healthcheck_config.HealthcheckConfigService
 	checkPgHealthUseCase     *CheckPgHealthUseCase
+	checkLease               *system_instances.Lease
 	logger                   *slog.Logger
 }
 
@@ -28,7 +30,13 @@ func (s *HealthcheckAttemptBackgroundService) Run() {
 	}
 }
 
+// checkDatabases is run by the lease holder only, so databases
+// are not checked and notified about by each instance
 func (s *HealthcheckAttemptBackgroundService) checkDatabases() {
+	if isHeld, _ := s.checkLease.Acquire(); !isHeld {
+		return
+	}
+
 	now := time.Now().UTC()
 
 	healthcheckConfigs, err := s.healthcheckConfigService.GetDatabasesWithEnabledHealthcheck()
@@ -4,6 +4,7 @@ import (
 	"postgresus-backend/internal/features/databases"
 	healthcheck_config "postgresus-backend/internal/features/healthcheck/config"
 	"postgresus-backend/internal/features/notifiers"
+	system_instances "postgresus-backend/internal/features/system/instances"
 	"postgresus-backend/internal/features/users"
 	"postgresus-backend/internal/util/logger"
 )
@@ -23,6 +24,7 @@ var checkPgHealthUseCase = &CheckPgHealthUseCase{
 var healthcheckAttemptBackgroundService = &HealthcheckAttemptBackgroundService{
 	healthcheck_config.GetHealthcheckConfigService(),
 	checkPgHealthUseCase,
+	system_instances.GetInstanceService().NewLease("healthcheck-attempts"),
 	logger.GetLogger(),
 }
 var healthcheckAttemptController = &HealthcheckAttemptController{
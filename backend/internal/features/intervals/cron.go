@@ -0,0 +1,321 @@
+package intervals
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule which does not fire within this period never fires, it
+// covers February 29 of the next leap year
+const cronSearchYears = 8
+
+var cronMonthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var cronWeekdayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// CronSchedule is a standard 5-field cron expression: minute, hour, day of
+// month, month and day of week. Besides lists, ranges, steps and names it
+// supports "L" in day of month for the last day of the month and "nL" in day
+// of week for the last weekday n of the month, e.g. "0 3 * * 0L" fires at
+// 03:00 on the last Sunday. As in Vixie cron, when both day of month and day
+// of week are restricted the day matches either of them
+type CronSchedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+
+	isLastDayOfMonth bool
+	lastWeekdays     uint64
+
+	isDaysRestricted     bool
+	isWeekdaysRestricted bool
+}
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+
+	isLastDayAllowed     bool
+	isLastWeekdayAllowed bool
+}
+
+func ParseCronExpression(expression string) (*CronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf(
+			"cron expression \"%s\" should have 5 fields: minute, hour, day of month, month and day of week",
+			expression,
+		)
+	}
+
+	schedule := &CronSchedule{
+		isDaysRestricted:     !strings.HasPrefix(fields[2], "*"),
+		isWeekdaysRestricted: !strings.HasPrefix(fields[4], "*"),
+	}
+
+	var err error
+
+	if schedule.minutes, _, err = parseCronField(
+		fields[0],
+		cronField{name: "minute", min: 0, max: 59},
+	); err != nil {
+		return nil, err
+	}
+
+	if schedule.hours, _, err = parseCronField(
+		fields[1],
+		cronField{name: "hour", min: 0, max: 23},
+	); err != nil {
+		return nil, err
+	}
+
+	var lastDays uint64
+	if schedule.days, lastDays, err = parseCronField(
+		fields[2],
+		cronField{name: "day of month", min: 1, max: 31, isLastDayAllowed: true},
+	); err != nil {
+		return nil, err
+	}
+	schedule.isLastDayOfMonth = lastDays != 0
+
+	if schedule.months, _, err = parseCronField(
+		fields[3],
+		cronField{name: "month", min: 1, max: 12, names: cronMonthNames},
+	); err != nil {
+		return nil, err
+	}
+
+	// 7 is Sunday as well
+	if schedule.weekdays, schedule.lastWeekdays, err = parseCronField(
+		fields[4],
+		cronField{
+			name:                 "day of week",
+			min:                  0,
+			max:                  7,
+			names:                cronWeekdayNames,
+			isLastWeekdayAllowed: true,
+		},
+	); err != nil {
+		return nil, err
+	}
+	schedule.weekdays = foldSunday(schedule.weekdays)
+	schedule.lastWeekdays = foldSunday(schedule.lastWeekdays)
+
+	return schedule, nil
+}
+
+// Next returns the first time strictly after the given one which matches
+// the schedule, in location of the given time. The schedule is matched on
+// the wall clock, so a slot skipped by DST fires after the gap and a slot
+// repeated by DST fires once, see toLocalTime. Zero time is returned when
+// the schedule never fires, e.g. on February 30
+func (s *CronSchedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+
+	// wall clock is iterated in UTC, which has no DST
+	wall := time.Date(
+		after.Year(), after.Month(), after.Day(),
+		after.Hour(), after.Minute(), 0, 0, time.UTC,
+	).Add(time.Minute)
+
+	limit := wall.AddDate(cronSearchYears, 0, 0)
+
+	for wall.Before(limit) {
+		switch {
+		case !hasBit(s.months, int(wall.Month())):
+			wall = time.Date(wall.Year(), wall.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.matchesDay(wall):
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !hasBit(s.hours, wall.Hour()):
+			wall = wall.Truncate(time.Hour).Add(time.Hour)
+		case !hasBit(s.minutes, wall.Minute()):
+			wall = wall.Add(time.Minute)
+		default:
+			t := toLocalTime(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), loc)
+
+			// wall clock after the DST overlap maps to the earlier
+			// occurrence, which is before the given time
+			if t.After(after) {
+				return t
+			}
+
+			wall = wall.Add(time.Minute)
+		}
+	}
+
+	return time.Time{}
+}
+
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	weekday := int(t.Weekday())
+
+	isDayMatched := hasBit(s.days, t.Day()) ||
+		(s.isLastDayOfMonth && t.Day() == daysInMonth)
+
+	isWeekdayMatched := hasBit(s.weekdays, weekday) ||
+		(hasBit(s.lastWeekdays, weekday) && t.Day()+7 > daysInMonth)
+
+	if s.isDaysRestricted && s.isWeekdaysRestricted {
+		return isDayMatched || isWeekdayMatched
+	}
+
+	return isDayMatched && isWeekdayMatched
+}
+
+// parseCronField returns bits of matched values and bits of "L" values.
+// "L" alone in day of month is the last day, "nL" in day of week is
+// the last weekday n of the month
+func parseCronField(value string, field cronField) (uint64, uint64, error) {
+	var valueBits, lastBits uint64
+
+	for _, part := range strings.Split(value, ",") {
+		isLastAllowed := field.isLastDayAllowed || field.isLastWeekdayAllowed
+		if isLastAllowed && strings.HasSuffix(strings.ToUpper(part), "L") {
+			lastValue, err := parseCronLast(part, field)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			lastBits |= 1 << lastValue
+			continue
+		}
+
+		partBits, err := parseCronRange(part, field)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		valueBits |= partBits
+	}
+
+	return valueBits, lastBits, nil
+}
+
+func parseCronLast(part string, field cronField) (int, error) {
+	prefix := part[:len(part)-1]
+
+	// day of month has a single last day
+	if field.isLastDayAllowed {
+		if prefix != "" {
+			return 0, fmt.Errorf("invalid %s \"%s\", use \"L\" for the last day", field.name, part)
+		}
+
+		return field.max, nil
+	}
+
+	if prefix == "" {
+		return 0, fmt.Errorf("invalid %s \"%s\", use \"nL\" for the last weekday n", field.name, part)
+	}
+
+	return parseCronValue(prefix, field)
+}
+
+func parseCronRange(part string, field cronField) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+	step := 1
+	if hasStep {
+		parsedStep, err := strconv.Atoi(stepPart)
+		if err != nil || parsedStep < 1 {
+			return 0, fmt.Errorf("invalid step \"%s\" of %s", stepPart, field.name)
+		}
+
+		step = parsedStep
+	}
+
+	var start, end int
+
+	switch {
+	case rangePart == "*":
+		start, end = field.min, field.max
+	case strings.Contains(rangePart, "-"):
+		startPart, endPart, _ := strings.Cut(rangePart, "-")
+
+		var err error
+		if start, err = parseCronValue(startPart, field); err != nil {
+			return 0, err
+		}
+
+		if end, err = parseCronValue(endPart, field); err != nil {
+			return 0, err
+		}
+
+		if start > end {
+			return 0, fmt.Errorf("invalid range \"%s\" of %s", rangePart, field.name)
+		}
+	default:
+		var err error
+		if start, err = parseCronValue(rangePart, field); err != nil {
+			return 0, err
+		}
+
+		// "5/15" is from 5 to the max value
+		end = start
+		if hasStep {
+			end = field.max
+		}
+	}
+
+	var rangeBits uint64
+	for value := start; value <= end; value += step {
+		rangeBits |= 1 << value
+	}
+
+	return rangeBits, nil
+}
+
+func parseCronValue(value string, field cronField) (int, error) {
+	if namedValue, isNamed := field.names[strings.ToUpper(value)]; isNamed {
+		return namedValue, nil
+	}
+
+	parsedValue, err := strconv.Atoi(value)
+	if err != nil || parsedValue < field.min || parsedValue > field.max {
+		return 0, fmt.Errorf(
+			"invalid %s \"%s\", it should be from %d to %d",
+			field.name,
+			value,
+			field.min,
+			field.max,
+		)
+	}
+
+	return parsedValue, nil
+}
+
+func foldSunday(weekdayBits uint64) uint64 {
+	if hasBit(weekdayBits, 7) {
+		weekdayBits = (weekdayBits | 1) &^ (1 << 7)
+	}
+
+	return weekdayBits
+}
+
+func hasBit(valueBits uint64, value int) bool {
+	return valueBits&(1<<value) != 0
+}
+
+func validateCronExpression(expression string) error {
+	schedule, err := ParseCronExpression(expression)
+	if err != nil {
+		return err
+	}
+
+	if schedule.Next(time.Now().UTC()).IsZero() {
+		return fmt.Errorf("cron expression \"%s\" never fires", expression)
+	}
+
+	return nil
+}
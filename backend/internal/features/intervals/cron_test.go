@@ -0,0 +1,120 @@
+package intervals
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronSchedule_Next(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expression string
+		after      time.Time
+		expected   time.Time
+	}{
+		{
+			name:       "Every 15 minutes: Next quarter of the hour",
+			expression: "*/15 * * * *",
+			after:      time.Date(2024, 1, 15, 10, 7, 30, 0, time.UTC),
+			expected:   time.Date(2024, 1, 15, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name:       "Exactly at slot: Next slot, not the same one",
+			expression: "*/15 * * * *",
+			after:      time.Date(2024, 1, 15, 10, 15, 0, 0, time.UTC),
+			expected:   time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "Business hours after 17:45 on Friday: Monday 09:00",
+			expression: "*/15 9-17 * * MON-FRI",
+			after:      time.Date(2024, 1, 19, 17, 45, 0, 0, time.UTC),
+			expected:   time.Date(2024, 1, 22, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Last day of month in leap year: February 29",
+			expression: "30 23 L * *",
+			after:      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2024, 2, 29, 23, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "Last Sunday of month: January 28",
+			expression: "0 3 * * 0L",
+			after:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2024, 1, 28, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Last Friday with name: February 23",
+			expression: "0 18 * * FRIL",
+			after:      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2024, 2, 23, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Sunday as 7: Next Sunday",
+			expression: "0 0 * * 7",
+			after:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Both days restricted: Either of them matches",
+			expression: "0 0 13 * FRI",
+			after:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Month names and list: Next quarter start",
+			expression: "0 6 1 JAN,APR,JUL,OCT *",
+			after:      time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			expected:   time.Date(2024, 4, 1, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Step from value: Minute 5, 25 or 45",
+			expression: "5/20 * * * *",
+			after:      time.Date(2024, 1, 15, 10, 26, 0, 0, time.UTC),
+			expected:   time.Date(2024, 1, 15, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			name:       "February 30: Never fires",
+			expression: "0 0 30 2 *",
+			after:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected:   time.Time{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := ParseCronExpression(tc.expression)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, schedule.Next(tc.after))
+		})
+	}
+}
+
+func TestCronSchedule_ParseInvalidExpression(t *testing.T) {
+	expressions := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * 5L * *",
+		"* * * * L",
+		"* * * FOO *",
+		"* L * * *",
+	}
+
+	for _, expression := range expressions {
+		t.Run(expression, func(t *testing.T) {
+			_, err := ParseCronExpression(expression)
+			assert.Error(t, err)
+		})
+	}
+}
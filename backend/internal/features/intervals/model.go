@@ -17,6 +17,12 @@ type Interval struct {
 	Weekday *int `json:"weekday,omitempty"    gorm:"type:int"`
 	// only for MONTHLY
 	DayOfMonth *int `json:"dayOfMonth,omitempty" gorm:"type:int"`
+	// only for CRON, e.g. "*/15 9-17 * * MON-FRI"
+	CronExpression *string `json:"cronExpression,omitempty" gorm:"type:text"`
+
+	// IANA timezone of the time of day and the cron expression, e.g.
+	// "Europe/Berlin", so "04:00" stays 04:00 across DST. UTC when empty
+	Timezone *string `json:"timezone,omitempty" gorm:"type:text"`
 }
 
 func (i *Interval) BeforeSave(tx *gorm.DB) error {
@@ -40,30 +46,107 @@ func (i *Interval) Validate() error {
 		return errors.New("day of month is required for monthly intervals")
 	}
 
+	if i.Timezone != nil && *i.Timezone != "" {
+		if err := validateTimezone(*i.Timezone); err != nil {
+			return err
+		}
+	}
+
+	if i.Interval == IntervalCron {
+		if i.CronExpression == nil {
+			return errors.New("cron expression is required for cron intervals")
+		}
+
+		if err := validateCronExpression(*i.CronExpression); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// ShouldTriggerBackup checks if a backup should be triggered based on the interval and last backup time
+// ShouldTriggerBackup checks if a backup should be triggered based on the interval and last backup time.
+// Days, weeks and months are calendar ones of the interval timezone
 func (i *Interval) ShouldTriggerBackup(now time.Time, lastBackupTime *time.Time) bool {
 	// If no backup has been made yet, trigger immediately
 	if lastBackupTime == nil {
 		return true
 	}
 
+	loc := i.GetLocation()
+	now = now.In(loc)
+	lastBackup := lastBackupTime.In(loc)
+
 	switch i.Interval {
 	case IntervalHourly:
-		return now.Sub(*lastBackupTime) >= time.Hour
+		return now.Sub(lastBackup) >= time.Hour
 	case IntervalDaily:
-		return i.shouldTriggerDaily(now, *lastBackupTime)
+		return i.shouldTriggerDaily(now, lastBackup)
 	case IntervalWeekly:
-		return i.shouldTriggerWeekly(now, *lastBackupTime)
+		return i.shouldTriggerWeekly(now, lastBackup)
 	case IntervalMonthly:
-		return i.shouldTriggerMonthly(now, *lastBackupTime)
+		return i.shouldTriggerMonthly(now, lastBackup)
+	case IntervalCron:
+		return i.shouldTriggerCron(now, lastBackup)
 	default:
 		return false
 	}
 }
 
+// GetNextTriggerTime returns when the backup should be triggered next in
+// UTC, it is now when the backup is due. Zero time is returned when the
+// interval never fires, e.g. when the time of day is malformed
+func (i *Interval) GetNextTriggerTime(now time.Time, lastBackupTime *time.Time) time.Time {
+	if i.ShouldTriggerBackup(now, lastBackupTime) {
+		return now.UTC()
+	}
+
+	loc := i.GetLocation()
+	now = now.In(loc)
+	lastBackup := lastBackupTime.In(loc)
+
+	var next time.Time
+
+	switch i.Interval {
+	case IntervalHourly:
+		next = lastBackup.Add(time.Hour)
+	case IntervalDaily:
+		next = i.getNextDailyTriggerTime(now)
+	case IntervalWeekly:
+		next = i.getNextWeeklyTriggerTime(now, lastBackup)
+	case IntervalMonthly:
+		next = i.getNextMonthlyTriggerTime(now)
+	case IntervalCron:
+		schedule, err := i.getCronSchedule()
+		if err != nil {
+			return time.Time{}
+		}
+
+		next = schedule.Next(now)
+	}
+
+	if next.IsZero() {
+		return next
+	}
+
+	return next.UTC()
+}
+
+// GetLocation returns the timezone the interval is evaluated in. Timezone
+// is validated on save, UTC is used if it cannot be loaded anymore
+func (i *Interval) GetLocation() *time.Location {
+	if i.Timezone == nil || *i.Timezone == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(*i.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
 func (i *Interval) Copy() *Interval {
 	return &Interval{
 		ID:         uuid.Nil,
@@ -71,6 +154,9 @@ func (i *Interval) Copy() *Interval {
 		TimeOfDay:  i.TimeOfDay,
 		Weekday:    i.Weekday,
 		DayOfMonth: i.DayOfMonth,
+
+		CronExpression: i.CronExpression,
+		Timezone:       i.Timezone,
 	}
 }
 
@@ -80,21 +166,16 @@ func (i *Interval) shouldTriggerDaily(now, lastBackup time.Time) bool {
 		return !isSameDay(lastBackup, now)
 	}
 
-	t, err := time.Parse("15:04", *i.TimeOfDay)
-	if err != nil {
+	// Today's scheduled slot (todayTgt)
+	todayTgt, ok := withTimeOfDay(now, *i.TimeOfDay)
+	if !ok {
 		return false // malformed ⇒ play safe
 	}
 
-	// Today's scheduled slot (todayTgt)
-	todayTgt := time.Date(
-		now.Year(), now.Month(), now.Day(),
-		t.Hour(), t.Minute(), 0, 0, now.Location(),
-	)
-
 	// The last scheduled slot that should already have happened
 	var lastScheduled time.Time
 	if now.Before(todayTgt) {
-		lastScheduled = todayTgt.AddDate(0, 0, -1)
+		lastScheduled, _ = withTimeOfDay(addLocalDays(now, -1), *i.TimeOfDay)
 	} else {
 		lastScheduled = todayTgt
 	}
@@ -120,21 +201,11 @@ func (i *Interval) shouldTriggerWeekly(now, lastBackup time.Time) bool {
 			daysFromMonday = int(targetWd) - 1
 		}
 
-		targetThisWeek := startOfWeek.AddDate(0, 0, daysFromMonday)
+		targetThisWeek := addLocalDays(startOfWeek, daysFromMonday)
 
 		if i.TimeOfDay != nil {
-			t, err := time.Parse("15:04", *i.TimeOfDay)
-			if err == nil {
-				targetThisWeek = time.Date(
-					targetThisWeek.Year(),
-					targetThisWeek.Month(),
-					targetThisWeek.Day(),
-					t.Hour(),
-					t.Minute(),
-					0,
-					0,
-					targetThisWeek.Location(),
-				)
+			if t, ok := withTimeOfDay(targetThisWeek, *i.TimeOfDay); ok {
+				targetThisWeek = t
 			}
 		}
 
@@ -147,8 +218,8 @@ func (i *Interval) shouldTriggerWeekly(now, lastBackup time.Time) bool {
 		return false
 	}
 
-	// no Weekday: generic 7-day interval
-	return now.Sub(lastBackup) >= 7*24*time.Hour
+	// no Weekday: generic 7-day interval, days are calendar ones
+	return !now.Before(addLocalDays(lastBackup, 7))
 }
 
 // monthly trigger: on specified day/calendar month, otherwise next calendar month
@@ -157,21 +228,11 @@ func (i *Interval) shouldTriggerMonthly(now, lastBackup time.Time) bool {
 		day := *i.DayOfMonth
 
 		// Calculate the target datetime for this month
-		targetThisMonth := time.Date(now.Year(), now.Month(), day, 0, 0, 0, 0, now.Location())
+		targetThisMonth := toLocalTime(now.Year(), now.Month(), day, 0, 0, now.Location())
 
 		if i.TimeOfDay != nil {
-			t, err := time.Parse("15:04", *i.TimeOfDay)
-			if err == nil {
-				targetThisMonth = time.Date(
-					targetThisMonth.Year(),
-					targetThisMonth.Month(),
-					targetThisMonth.Day(),
-					t.Hour(),
-					t.Minute(),
-					0,
-					0,
-					targetThisMonth.Location(),
-				)
+			if t, ok := withTimeOfDay(targetThisMonth, *i.TimeOfDay); ok {
+				targetThisMonth = t
 			}
 		}
 
@@ -187,6 +248,125 @@ func (i *Interval) shouldTriggerMonthly(now, lastBackup time.Time) bool {
 	return lastBackup.Before(getStartOfMonth(now))
 }
 
+// cron trigger: fire when a slot of the schedule passed since the last
+// backup, missed slots are caught up with a single backup
+func (i *Interval) shouldTriggerCron(now, lastBackup time.Time) bool {
+	schedule, err := i.getCronSchedule()
+	if err != nil {
+		return false // malformed ⇒ play safe
+	}
+
+	next := schedule.Next(lastBackup)
+	return !next.IsZero() && !next.After(now)
+}
+
+func (i *Interval) getCronSchedule() (*CronSchedule, error) {
+	if i.CronExpression == nil {
+		return nil, errors.New("cron expression is not set")
+	}
+
+	return ParseCronExpression(*i.CronExpression)
+}
+
+// next daily slot is tomorrow when today's one is taken already
+func (i *Interval) getNextDailyTriggerTime(now time.Time) time.Time {
+	tomorrow := toLocalTime(now.Year(), now.Month(), now.Day()+1, 0, 0, now.Location())
+
+	if i.TimeOfDay == nil {
+		return tomorrow
+	}
+
+	todayTgt, ok := withTimeOfDay(now, *i.TimeOfDay)
+	if !ok {
+		return time.Time{}
+	}
+
+	if now.Before(todayTgt) {
+		return todayTgt
+	}
+
+	tomorrowTgt, _ := withTimeOfDay(tomorrow, *i.TimeOfDay)
+	return tomorrowTgt
+}
+
+func (i *Interval) getNextWeeklyTriggerTime(now, lastBackup time.Time) time.Time {
+	if i.Weekday == nil {
+		return addLocalDays(lastBackup, 7)
+	}
+
+	// same Monday based week as in shouldTriggerWeekly
+	daysFromMonday := (int(time.Weekday(*i.Weekday)) + 6) % 7
+	targetThisWeek := addLocalDays(getStartOfWeek(now), daysFromMonday)
+
+	if i.TimeOfDay != nil {
+		if t, ok := withTimeOfDay(targetThisWeek, *i.TimeOfDay); ok {
+			targetThisWeek = t
+		}
+	}
+
+	if now.Before(targetThisWeek) {
+		return targetThisWeek
+	}
+
+	nextWeekTarget := addLocalDays(getStartOfWeek(now), daysFromMonday+7)
+	if i.TimeOfDay != nil {
+		if t, ok := withTimeOfDay(nextWeekTarget, *i.TimeOfDay); ok {
+			nextWeekTarget = t
+		}
+	}
+
+	return nextWeekTarget
+}
+
+func (i *Interval) getNextMonthlyTriggerTime(now time.Time) time.Time {
+	nextMonthStart := toLocalTime(now.Year(), now.Month()+1, 1, 0, 0, now.Location())
+
+	if i.DayOfMonth == nil {
+		return nextMonthStart
+	}
+
+	getTarget := func(monthStart time.Time) time.Time {
+		// day past the end of the month overflows to the next one, as in shouldTriggerMonthly
+		target := toLocalTime(
+			monthStart.Year(), monthStart.Month(), *i.DayOfMonth,
+			0, 0, monthStart.Location(),
+		)
+
+		if i.TimeOfDay != nil {
+			if t, ok := withTimeOfDay(target, *i.TimeOfDay); ok {
+				target = t
+			}
+		}
+
+		return target
+	}
+
+	targetThisMonth := getTarget(getStartOfMonth(now))
+	if now.Before(targetThisMonth) {
+		return targetThisMonth
+	}
+
+	return getTarget(nextMonthStart)
+}
+
+// withTimeOfDay returns the day at "15:04" time of day, see
+// toLocalTime for the time of day skipped or repeated by DST
+func withTimeOfDay(day time.Time, timeOfDay string) (time.Time, bool) {
+	t, err := time.Parse("15:04", timeOfDay)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return toLocalTime(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), day.Location()), true
+}
+
+// addLocalDays moves the time by calendar days keeping its wall clock
+// time, so a day across a DST change is 23 or 25 hours long
+func addLocalDays(t time.Time, days int) time.Time {
+	return toLocalTime(t.Year(), t.Month(), t.Day()+days, t.Hour(), t.Minute(), t.Location()).
+		Add(time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond()))
+}
+
 func isSameDay(a, b time.Time) bool {
 	y1, m1, d1 := a.Date()
 	y2, m2, d2 := b.Date()
@@ -198,9 +378,9 @@ func getStartOfWeek(t time.Time) time.Time {
 	if wd == 0 {
 		wd = 7
 	}
-	return time.Date(t.Year(), t.Month(), t.Day()-wd+1, 0, 0, 0, 0, t.Location())
+	return toLocalTime(t.Year(), t.Month(), t.Day()-wd+1, 0, 0, t.Location())
 }
 
 func getStartOfMonth(t time.Time) time.Time {
-	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return toLocalTime(t.Year(), t.Month(), 1, 0, 0, t.Location())
 }
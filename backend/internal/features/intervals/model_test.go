@@ -526,4 +526,402 @@ func TestInterval_Validate(t *testing.T) {
 		err := interval.Validate()
 		assert.NoError(t, err)
 	})
+
+	t.Run("Cron interval requires cron expression", func(t *testing.T) {
+		interval := &Interval{
+			ID:       uuid.New(),
+			Interval: IntervalCron,
+		}
+		err := interval.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cron expression is required")
+	})
+
+	t.Run("Cron interval with invalid expression", func(t *testing.T) {
+		cronExpression := "*/15 9-17 * *"
+		interval := &Interval{
+			ID:             uuid.New(),
+			Interval:       IntervalCron,
+			CronExpression: &cronExpression,
+		}
+		err := interval.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "should have 5 fields")
+	})
+
+	t.Run("Cron interval which never fires", func(t *testing.T) {
+		cronExpression := "0 0 30 2 *"
+		interval := &Interval{
+			ID:             uuid.New(),
+			Interval:       IntervalCron,
+			CronExpression: &cronExpression,
+		}
+		err := interval.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "never fires")
+	})
+
+	t.Run("Invalid timezone", func(t *testing.T) {
+		timezone := "Europe/Atlantis"
+		interval := &Interval{
+			ID:       uuid.New(),
+			Interval: IntervalHourly,
+			Timezone: &timezone,
+		}
+		err := interval.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid timezone")
+	})
+
+	t.Run("Valid cron interval", func(t *testing.T) {
+		cronExpression := "*/15 9-17 * * MON-FRI"
+		interval := &Interval{
+			ID:             uuid.New(),
+			Interval:       IntervalCron,
+			CronExpression: &cronExpression,
+		}
+		err := interval.Validate()
+		assert.NoError(t, err)
+	})
+}
+
+func TestInterval_ShouldTriggerBackup_Cron(t *testing.T) {
+	cronExpression := "*/15 9-17 * * MON-FRI"
+	interval := &Interval{
+		ID:             uuid.New(),
+		Interval:       IntervalCron,
+		CronExpression: &cronExpression,
+	}
+
+	// Monday, January 15, 2024
+	baseDate := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("No previous backup: Trigger backup immediately", func(t *testing.T) {
+		should := interval.ShouldTriggerBackup(baseDate.Add(3*time.Hour), nil)
+		assert.True(t, should)
+	})
+
+	t.Run("Slot 10:15 passed since backup at 10:00: Trigger backup", func(t *testing.T) {
+		lastBackup := baseDate.Add(10 * time.Hour)
+		now := baseDate.Add(10*time.Hour + 15*time.Minute)
+		should := interval.ShouldTriggerBackup(now, &lastBackup)
+		assert.True(t, should)
+	})
+
+	t.Run("Backup at 10:00, now 10:14: Do not trigger backup", func(t *testing.T) {
+		lastBackup := baseDate.Add(10 * time.Hour)
+		now := baseDate.Add(10*time.Hour + 14*time.Minute)
+		should := interval.ShouldTriggerBackup(now, &lastBackup)
+		assert.False(t, should)
+	})
+
+	t.Run("Backup at 17:45, now 20:00: Do not trigger outside business hours", func(t *testing.T) {
+		lastBackup := baseDate.Add(17*time.Hour + 45*time.Minute)
+		now := baseDate.Add(20 * time.Hour)
+		should := interval.ShouldTriggerBackup(now, &lastBackup)
+		assert.False(t, should)
+	})
+
+	t.Run("Backup on Friday, now Saturday noon: Do not trigger backup", func(t *testing.T) {
+		lastBackup := time.Date(2024, 1, 19, 17, 45, 0, 0, time.UTC)
+		now := time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC)
+		should := interval.ShouldTriggerBackup(now, &lastBackup)
+		assert.False(t, should)
+	})
+
+	t.Run("Slots missed over weekend: Trigger backup on Monday", func(t *testing.T) {
+		lastBackup := time.Date(2024, 1, 19, 17, 45, 0, 0, time.UTC)
+		now := time.Date(2024, 1, 22, 11, 3, 0, 0, time.UTC)
+		should := interval.ShouldTriggerBackup(now, &lastBackup)
+		assert.True(t, should)
+	})
+
+	t.Run("Malformed expression: Do not trigger backup", func(t *testing.T) {
+		malformedExpression := "every 15 minutes"
+		malformedInterval := &Interval{
+			ID:             uuid.New(),
+			Interval:       IntervalCron,
+			CronExpression: &malformedExpression,
+		}
+		lastBackup := baseDate
+		should := malformedInterval.ShouldTriggerBackup(baseDate.Add(24*time.Hour), &lastBackup)
+		assert.False(t, should)
+	})
+}
+
+func TestInterval_GetNextTriggerTime(t *testing.T) {
+	// Monday, January 15, 2024 10:05
+	now := time.Date(2024, 1, 15, 10, 5, 0, 0, time.UTC)
+
+	t.Run("Backup is due: Next trigger is now", func(t *testing.T) {
+		interval := &Interval{ID: uuid.New(), Interval: IntervalHourly}
+		assert.Equal(t, now, interval.GetNextTriggerTime(now, nil))
+	})
+
+	t.Run("Hourly: Hour after the last backup", func(t *testing.T) {
+		interval := &Interval{ID: uuid.New(), Interval: IntervalHourly}
+		lastBackup := now.Add(-20 * time.Minute)
+		assert.Equal(t, lastBackup.Add(time.Hour), interval.GetNextTriggerTime(now, &lastBackup))
+	})
+
+	t.Run("Daily before today's slot: Today's slot", func(t *testing.T) {
+		timeOfDay := "18:30"
+		interval := &Interval{ID: uuid.New(), Interval: IntervalDaily, TimeOfDay: &timeOfDay}
+		lastBackup := time.Date(2024, 1, 14, 18, 30, 0, 0, time.UTC)
+		assert.Equal(
+			t,
+			time.Date(2024, 1, 15, 18, 30, 0, 0, time.UTC),
+			interval.GetNextTriggerTime(now, &lastBackup),
+		)
+	})
+
+	t.Run("Daily after today's backup: Tomorrow's slot", func(t *testing.T) {
+		timeOfDay := "09:00"
+		interval := &Interval{ID: uuid.New(), Interval: IntervalDaily, TimeOfDay: &timeOfDay}
+		lastBackup := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+		assert.Equal(
+			t,
+			time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC),
+			interval.GetNextTriggerTime(now, &lastBackup),
+		)
+	})
+
+	t.Run("Weekly on Sunday: Sunday of this week", func(t *testing.T) {
+		timeOfDay := "03:00"
+		weekday := int(time.Sunday)
+		interval := &Interval{
+			ID:        uuid.New(),
+			Interval:  IntervalWeekly,
+			TimeOfDay: &timeOfDay,
+			Weekday:   &weekday,
+		}
+		lastBackup := time.Date(2024, 1, 14, 3, 0, 0, 0, time.UTC)
+		assert.Equal(
+			t,
+			time.Date(2024, 1, 21, 3, 0, 0, 0, time.UTC),
+			interval.GetNextTriggerTime(now, &lastBackup),
+		)
+	})
+
+	t.Run("Monthly after this month's backup: Next month's slot", func(t *testing.T) {
+		timeOfDay := "02:00"
+		dayOfMonth := 1
+		interval := &Interval{
+			ID:         uuid.New(),
+			Interval:   IntervalMonthly,
+			TimeOfDay:  &timeOfDay,
+			DayOfMonth: &dayOfMonth,
+		}
+		lastBackup := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+		assert.Equal(
+			t,
+			time.Date(2024, 2, 1, 2, 0, 0, 0, time.UTC),
+			interval.GetNextTriggerTime(now, &lastBackup),
+		)
+	})
+
+	t.Run("Cron on the last Sunday: Last Sunday of the month", func(t *testing.T) {
+		cronExpression := "0 3 * * 0L"
+		interval := &Interval{
+			ID:             uuid.New(),
+			Interval:       IntervalCron,
+			CronExpression: &cronExpression,
+		}
+		lastBackup := time.Date(2023, 12, 31, 3, 0, 0, 0, time.UTC)
+		assert.Equal(
+			t,
+			time.Date(2024, 1, 28, 3, 0, 0, 0, time.UTC),
+			interval.GetNextTriggerTime(now, &lastBackup),
+		)
+	})
+}
+
+func TestInterval_ShouldTriggerBackup_Timezone(t *testing.T) {
+	timezone := "Europe/Berlin"
+
+	t.Run("Daily 04:00 in Berlin: Slot follows DST change", func(t *testing.T) {
+		timeOfDay := "04:00"
+		interval := &Interval{
+			ID:        uuid.New(),
+			Interval:  IntervalDaily,
+			TimeOfDay: &timeOfDay,
+			Timezone:  &timezone,
+		}
+
+		// 04:00 CET is 03:00 UTC, 04:00 CEST after March 31 is 02:00 UTC
+		lastBackup := time.Date(2024, 3, 30, 3, 0, 0, 0, time.UTC)
+
+		should := interval.ShouldTriggerBackup(time.Date(2024, 3, 31, 1, 59, 0, 0, time.UTC), &lastBackup)
+		assert.False(t, should)
+
+		should = interval.ShouldTriggerBackup(time.Date(2024, 3, 31, 2, 0, 0, 0, time.UTC), &lastBackup)
+		assert.True(t, should)
+	})
+
+	t.Run("Daily 02:30 on DST gap day: Trigger at 03:30 CEST", func(t *testing.T) {
+		timeOfDay := "02:30"
+		interval := &Interval{
+			ID:        uuid.New(),
+			Interval:  IntervalDaily,
+			TimeOfDay: &timeOfDay,
+			Timezone:  &timezone,
+		}
+
+		lastBackup := time.Date(2024, 3, 30, 1, 30, 0, 0, time.UTC)
+
+		// 03:29 CEST
+		should := interval.ShouldTriggerBackup(time.Date(2024, 3, 31, 1, 29, 0, 0, time.UTC), &lastBackup)
+		assert.False(t, should)
+
+		// 03:30 CEST
+		should = interval.ShouldTriggerBackup(time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC), &lastBackup)
+		assert.True(t, should)
+	})
+
+	t.Run("Daily 02:30 on DST overlap day: Trigger once at first occurrence", func(t *testing.T) {
+		timeOfDay := "02:30"
+		interval := &Interval{
+			ID:        uuid.New(),
+			Interval:  IntervalDaily,
+			TimeOfDay: &timeOfDay,
+			Timezone:  &timezone,
+		}
+
+		lastBackup := time.Date(2024, 10, 26, 0, 30, 0, 0, time.UTC)
+
+		// 02:30 CEST, the first occurrence
+		firstOccurrence := time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC)
+		should := interval.ShouldTriggerBackup(firstOccurrence, &lastBackup)
+		assert.True(t, should)
+
+		// 02:30 CET, the second occurrence after the backup at the first one
+		secondOccurrence := time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC)
+		should = interval.ShouldTriggerBackup(secondOccurrence, &firstOccurrence)
+		assert.False(t, should)
+	})
+
+	t.Run("Daily without time of day: Day starts at local midnight", func(t *testing.T) {
+		interval := &Interval{
+			ID:       uuid.New(),
+			Interval: IntervalDaily,
+			Timezone: &timezone,
+		}
+
+		// 23:30 and 00:30 of different Berlin days, the same UTC day
+		lastBackup := time.Date(2024, 1, 15, 22, 30, 0, 0, time.UTC)
+		now := time.Date(2024, 1, 15, 23, 30, 0, 0, time.UTC)
+		should := interval.ShouldTriggerBackup(now, &lastBackup)
+		assert.True(t, should)
+	})
+
+	t.Run("Weekly on Monday 04:00 in Berlin: Week starts at local midnight", func(t *testing.T) {
+		timeOfDay := "04:00"
+		weekday := int(time.Monday)
+		interval := &Interval{
+			ID:        uuid.New(),
+			Interval:  IntervalWeekly,
+			TimeOfDay: &timeOfDay,
+			Weekday:   &weekday,
+			Timezone:  &timezone,
+		}
+
+		// Monday, April 1, 04:00 CEST is 02:00 UTC
+		lastBackup := time.Date(2024, 3, 25, 3, 0, 0, 0, time.UTC)
+
+		should := interval.ShouldTriggerBackup(time.Date(2024, 4, 1, 1, 59, 0, 0, time.UTC), &lastBackup)
+		assert.False(t, should)
+
+		should = interval.ShouldTriggerBackup(time.Date(2024, 4, 1, 2, 0, 0, 0, time.UTC), &lastBackup)
+		assert.True(t, should)
+	})
+
+	t.Run("Weekly without weekday across DST: 7 calendar days", func(t *testing.T) {
+		interval := &Interval{
+			ID:       uuid.New(),
+			Interval: IntervalWeekly,
+			Timezone: &timezone,
+		}
+
+		// 12:00 CET, the week after is 12:00 CEST, 167 hours later
+		lastBackup := time.Date(2024, 3, 27, 11, 0, 0, 0, time.UTC)
+
+		should := interval.ShouldTriggerBackup(time.Date(2024, 4, 3, 10, 0, 0, 0, time.UTC), &lastBackup)
+		assert.True(t, should)
+	})
+
+	t.Run("Monthly on 1st at 00:30 in New York: Month of the local calendar", func(t *testing.T) {
+		newYork := "America/New_York"
+		timeOfDay := "00:30"
+		dayOfMonth := 1
+		interval := &Interval{
+			ID:         uuid.New(),
+			Interval:   IntervalMonthly,
+			TimeOfDay:  &timeOfDay,
+			DayOfMonth: &dayOfMonth,
+			Timezone:   &newYork,
+		}
+
+		// at 03:00 UTC it is still January 31 in New York,
+		// February 1 00:30 EST is 05:30 UTC
+		lastBackup := time.Date(2024, 1, 1, 5, 30, 0, 0, time.UTC)
+
+		should := interval.ShouldTriggerBackup(time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC), &lastBackup)
+		assert.False(t, should)
+
+		should = interval.ShouldTriggerBackup(time.Date(2024, 2, 1, 5, 30, 0, 0, time.UTC), &lastBackup)
+		assert.True(t, should)
+	})
+
+	t.Run("Cron at 02:30 on DST gap and overlap days in Berlin", func(t *testing.T) {
+		cronExpression := "30 2 * * *"
+		interval := &Interval{
+			ID:             uuid.New(),
+			Interval:       IntervalCron,
+			CronExpression: &cronExpression,
+			Timezone:       &timezone,
+		}
+
+		lastBackup := time.Date(2024, 3, 30, 1, 30, 0, 0, time.UTC)
+		assert.Equal(
+			t,
+			time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC),
+			interval.GetNextTriggerTime(time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), &lastBackup),
+		)
+
+		firstOccurrence := time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC)
+		secondOccurrence := time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC)
+		should := interval.ShouldTriggerBackup(secondOccurrence, &firstOccurrence)
+		assert.False(t, should)
+		assert.Equal(
+			t,
+			time.Date(2024, 10, 28, 1, 30, 0, 0, time.UTC),
+			interval.GetNextTriggerTime(secondOccurrence, &firstOccurrence),
+		)
+	})
+
+	t.Run("Cron every 30 minutes across DST overlap: Repeated slots fire once", func(t *testing.T) {
+		cronExpression := "*/30 * * * *"
+		interval := &Interval{
+			ID:             uuid.New(),
+			Interval:       IntervalCron,
+			CronExpression: &cronExpression,
+			Timezone:       &timezone,
+		}
+
+		// backup at 02:00 CEST, then 02:30 CEST. Repeated 02:00
+		// and 02:30 CET are skipped, so the next one is 03:00 CET
+		lastBackup := time.Date(2024, 10, 27, 0, 0, 0, 0, time.UTC)
+		var triggers []time.Time
+		for range 3 {
+			next := interval.GetNextTriggerTime(lastBackup.Add(time.Second), &lastBackup)
+			triggers = append(triggers, next)
+			lastBackup = next
+		}
+
+		assert.Equal(t, []time.Time{
+			time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC),
+			time.Date(2024, 10, 27, 2, 0, 0, 0, time.UTC),
+			time.Date(2024, 10, 27, 2, 30, 0, 0, time.UTC),
+		}, triggers)
+	})
 }
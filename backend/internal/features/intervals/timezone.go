@@ -0,0 +1,68 @@
+package intervals
+
+import (
+	"fmt"
+	"time"
+
+	// the image has no system tz database, so IANA zones are embedded
+	_ "time/tzdata"
+)
+
+// transitions of a zone are more than this apart, so offsets at
+// both sides of the wall clock time are the only candidates
+const offsetProbeDistance = 24 * time.Hour
+
+func validateTimezone(timezone string) error {
+	// Local depends on the server, so it is not a timezone of the interval
+	if timezone == "Local" {
+		return fmt.Errorf("invalid timezone \"%s\", use an IANA name like \"Europe/Berlin\"", timezone)
+	}
+
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("invalid timezone \"%s\", use an IANA name like \"Europe/Berlin\"", timezone)
+	}
+
+	return nil
+}
+
+// toLocalTime returns the instant of the wall clock time in the location,
+// time.Date does not guarantee which one it picks around DST transitions.
+// Wall time skipped by a DST gap is moved forward by the gap, so "02:30"
+// becomes "03:30" on that day. Wall time repeated by a DST overlap is its
+// first occurrence, so the slot fires once. Overflowing values are
+// normalized as in time.Date, e.g. February 31 is March 2 or 3
+func toLocalTime(year int, month time.Month, day, hour, minute int, loc *time.Location) time.Time {
+	wall := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+
+	_, offsetBefore := wall.Add(-offsetProbeDistance).In(loc).Zone()
+	_, offsetAfter := wall.Add(offsetProbeDistance).In(loc).Zone()
+
+	beforeCandidate := wall.Add(-time.Duration(offsetBefore) * time.Second).In(loc)
+	afterCandidate := wall.Add(-time.Duration(offsetAfter) * time.Second).In(loc)
+
+	isBeforeValid := isSameWallClock(beforeCandidate, wall)
+	isAfterValid := isSameWallClock(afterCandidate, wall)
+
+	switch {
+	case isBeforeValid && isAfterValid:
+		if afterCandidate.Before(beforeCandidate) {
+			return afterCandidate
+		}
+
+		return beforeCandidate
+	case isAfterValid:
+		return afterCandidate
+	default:
+		// in a gap the offset before the transition moves the wall clock
+		// forward by the gap, it is also the only candidate with no gap
+		return beforeCandidate
+	}
+}
+
+func isSameWallClock(t, wall time.Time) bool {
+	return t.Year() == wall.Year() &&
+		t.Month() == wall.Month() &&
+		t.Day() == wall.Day() &&
+		t.Hour() == wall.Hour() &&
+		t.Minute() == wall.Minute()
+}
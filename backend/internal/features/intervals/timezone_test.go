@@ -0,0 +1,83 @@
+package intervals
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToLocalTime_DstTransitions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		timezone string
+		wall     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "New York regular day: Same wall clock",
+			timezone: "America/New_York",
+			wall:     time.Date(2024, 1, 15, 4, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "New York gap: 02:30 moves to 03:30 EDT",
+			timezone: "America/New_York",
+			wall:     time.Date(2024, 3, 10, 2, 30, 0, 0, time.UTC),
+			expected: time.Date(2024, 3, 10, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "New York overlap: 01:30 is the first occurrence in EDT",
+			timezone: "America/New_York",
+			wall:     time.Date(2024, 11, 3, 1, 30, 0, 0, time.UTC),
+			expected: time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "Berlin gap: 02:30 moves to 03:30 CEST",
+			timezone: "Europe/Berlin",
+			wall:     time.Date(2024, 3, 31, 2, 30, 0, 0, time.UTC),
+			expected: time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "Berlin overlap: 02:30 is the first occurrence in CEST",
+			timezone: "Europe/Berlin",
+			wall:     time.Date(2024, 10, 27, 2, 30, 0, 0, time.UTC),
+			expected: time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "Sydney gap: 02:30 moves to 03:30 AEDT",
+			timezone: "Australia/Sydney",
+			wall:     time.Date(2024, 10, 6, 2, 30, 0, 0, time.UTC),
+			expected: time.Date(2024, 10, 5, 16, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "Sydney overlap: 02:30 is the first occurrence in AEDT",
+			timezone: "Australia/Sydney",
+			wall:     time.Date(2024, 4, 7, 2, 30, 0, 0, time.UTC),
+			expected: time.Date(2024, 4, 6, 15, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			loc, err := time.LoadLocation(tc.timezone)
+			require.NoError(t, err)
+
+			localTime := toLocalTime(
+				tc.wall.Year(), tc.wall.Month(), tc.wall.Day(),
+				tc.wall.Hour(), tc.wall.Minute(), loc,
+			)
+
+			assert.Equal(t, tc.expected, localTime.UTC())
+		})
+	}
+}
+
+func TestValidateTimezone(t *testing.T) {
+	assert.NoError(t, validateTimezone("Europe/Berlin"))
+	assert.NoError(t, validateTimezone("UTC"))
+	assert.Error(t, validateTimezone("Local"))
+	assert.Error(t, validateTimezone("Mars/Olympus_Mons"))
+	assert.Error(t, validateTimezone("+02:00"))
+}
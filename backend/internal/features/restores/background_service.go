@@ -2,29 +2,54 @@ package restores
 
 import (
 	"log/slog"
+	"postgresus-backend/internal/config"
 	"postgresus-backend/internal/features/restores/enums"
+	system_instances "postgresus-backend/internal/features/system/instances"
+	"time"
 )
 
 type RestoreBackgroundService struct {
 	restoreRepository *RestoreRepository
+	instanceService   *system_instances.InstanceService
 	logger            *slog.Logger
 }
 
 func (s *RestoreBackgroundService) Run() {
-	if err := s.failRestoresInProgress(); err != nil {
-		s.logger.Error("Failed to fail restores in progress", "error", err)
-		panic(err)
+	for {
+		if config.IsShouldShutdown() {
+			return
+		}
+
+		if err := s.failOrphanedRestores(); err != nil {
+			s.logger.Error("Failed to fail orphaned restores", "error", err)
+		}
+
+		time.Sleep(1 * time.Minute)
 	}
 }
 
-func (s *RestoreBackgroundService) failRestoresInProgress() error {
+// failOrphanedRestores fails restores in progress whose instance stopped
+// sending heartbeats. Restores are run by web instances, so they are
+// checked by liveness of the instance instead of on start
+func (s *RestoreBackgroundService) failOrphanedRestores() error {
 	restoresInProgress, err := s.restoreRepository.FindByStatus(enums.RestoreStatusInProgress)
 	if err != nil {
 		return err
 	}
 
+	// read after the restores, so instance which started
+	// one of them is already registered
+	aliveInstances, err := s.instanceService.GetAliveInstances()
+	if err != nil {
+		return err
+	}
+
 	for _, restore := range restoresInProgress {
-		failMessage := "Restore failed due to application restart"
+		if aliveInstances.Contains(restore.InstanceID) {
+			continue
+		}
+
+		failMessage := "Restore failed because its instance stopped"
 		restore.Status = enums.RestoreStatusFailed
 		restore.FailMessage = &failMessage
 
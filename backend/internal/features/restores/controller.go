@@ -16,6 +16,11 @@ type RestoreController struct {
 func (c *RestoreController) RegisterRoutes(router *gin.RouterGroup) {
 	router.GET("/restores/:backupId", c.GetRestores)
 	router.POST("/restores/:backupId/restore", c.RestoreBackup)
+	router.POST("/restores/point-in-time", c.RestoreToPointInTime)
+
+	// gin requires the same wildcard name on the same path segment,
+	// so restore ID is passed as :backupId here
+	router.POST("/restores/:backupId/cancel", c.CancelRestore)
 }
 
 // GetRestores
@@ -97,3 +102,81 @@ func (c *RestoreController) RestoreBackup(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "restore started successfully"})
 }
+
+// RestoreToPointInTime
+// @Summary Restore a database to a point in time
+// @Description Unpack the latest physical backup finished before the target time into the target data directory and configure recovery of archived WAL up to the target time. Recovery is performed by PostgreSQL on the first start of the cluster
+// @Tags restores
+// @Accept json
+// @Produce json
+// @Param request body PointInTimeRestoreRequest true "Point-in-time restore request"
+// @Success 200 {object} map[string]string
+// @Failure 400
+// @Failure 401
+// @Router /restores/point-in-time [post]
+func (c *RestoreController) RestoreToPointInTime(ctx *gin.Context) {
+	var requestDTO PointInTimeRestoreRequest
+	if err := ctx.ShouldBindJSON(&requestDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	authorizationHeader := ctx.GetHeader("Authorization")
+	if authorizationHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+		return
+	}
+
+	user, err := c.userService.GetUserFromToken(authorizationHeader)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	backup, err := c.restoreService.RestoreToPointInTimeWithAuth(user, requestDTO)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":  "restore started successfully",
+		"backupId": backup.ID.String(),
+	})
+}
+
+// CancelRestore
+// @Summary Cancel a restore
+// @Description Stop the in-progress restore
+// @Tags restores
+// @Param id path string true "Restore ID"
+// @Success 204
+// @Failure 400
+// @Failure 401
+// @Router /restores/{id}/cancel [post]
+func (c *RestoreController) CancelRestore(ctx *gin.Context) {
+	restoreID, err := uuid.Parse(ctx.Param("backupId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid restore ID"})
+		return
+	}
+
+	authorizationHeader := ctx.GetHeader("Authorization")
+	if authorizationHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+		return
+	}
+
+	user, err := c.userService.GetUserFromToken(authorizationHeader)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	if err := c.restoreService.CancelRestoreWithAuth(user, restoreID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -3,10 +3,13 @@ package restores
 import (
 	"postgresus-backend/internal/features/backups/backups"
 	backups_config "postgresus-backend/internal/features/backups/config"
+	backups_wal "postgresus-backend/internal/features/backups/wal"
 	"postgresus-backend/internal/features/databases"
 	"postgresus-backend/internal/features/restores/usecases"
 	"postgresus-backend/internal/features/storages"
+	system_instances "postgresus-backend/internal/features/system/instances"
 	"postgresus-backend/internal/features/users"
+	cancellation_utils "postgresus-backend/internal/util/cancellation"
 	"postgresus-backend/internal/util/logger"
 )
 
@@ -17,7 +20,10 @@ var restoreService = &RestoreService{
 	storages.GetStorageService(),
 	backups_config.GetBackupConfigService(),
 	usecases.GetRestoreBackupUsecase(),
+	cancellation_utils.NewCancellationTracker(),
 	databases.GetDatabaseService(),
+	backups_wal.GetWalArchivingService(),
+	system_instances.GetInstanceService(),
 	logger.GetLogger(),
 }
 var restoreController = &RestoreController{
@@ -27,6 +33,7 @@ var restoreController = &RestoreController{
 
 var restoreBackgroundService = &RestoreBackgroundService{
 	restoreRepository,
+	system_instances.GetInstanceService(),
 	logger.GetLogger(),
 }
 
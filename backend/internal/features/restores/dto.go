@@ -2,8 +2,36 @@ package restores
 
 import (
 	"postgresus-backend/internal/features/databases/databases/postgresql"
+	"postgresus-backend/internal/features/restores/models"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type RestoreBackupRequest struct {
 	PostgresqlDatabase *postgresql.PostgresqlDatabase `json:"postgresqlDatabase"`
+
+	// TargetDataDirectory is required for physical backups. It should be
+	// an empty directory on the Postgresus host to unpack the cluster to,
+	// below postgresus-data/restores
+	TargetDataDirectory *string `json:"targetDataDirectory"`
+
+	// RecoveryTargetTime rolls physical backup forward to the
+	// given time using archived WAL. Optional
+	RecoveryTargetTime *time.Time `json:"recoveryTargetTime"`
+
+	// EntryDatabaseName restores only this database of the whole server
+	// backup into PostgresqlDatabase.Database. Without it, the whole server
+	// is restored and PostgresqlDatabase.Database is the maintenance database
+	EntryDatabaseName *string `json:"entryDatabaseName"`
+
+	// Schemas, tables and data or schema only flag of the filters restore
+	// a part of logical backup into the live database
+	models.RestoreFilters
+}
+
+type PointInTimeRestoreRequest struct {
+	DatabaseID          uuid.UUID `json:"databaseId"          binding:"required"`
+	TargetTime          time.Time `json:"targetTime"          binding:"required"`
+	TargetDataDirectory string    `json:"targetDataDirectory" binding:"required"`
 }
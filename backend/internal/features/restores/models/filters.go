@@ -0,0 +1,150 @@
+package models
+
+import (
+	"errors"
+	"postgresus-backend/internal/util/tools"
+	"slices"
+	"strings"
+)
+
+// tableTocEntryTypes are entries restored for a table chosen by the filter.
+// Constraints, triggers, defaults, rules and policies are named after the
+// table, e.g. "users users_pkey", so they are matched by the name prefix
+var tableTocEntryTypes = []string{
+	"TABLE",
+	"TABLE DATA",
+	"CONSTRAINT",
+	"FK CONSTRAINT",
+	"CHECK CONSTRAINT",
+	"TRIGGER",
+	"DEFAULT",
+	"RULE",
+	"POLICY",
+	"ROW SECURITY",
+}
+
+// RestoreFilters limit restore of logical backup to some objects, so one
+// object can be restored into a live database without touching the rest.
+// Tables are "schema.table" or "table" of any schema, names are exact and
+// not patterns. Lists are stored as comma separated strings, the same way
+// as backup filters
+type RestoreFilters struct {
+	Schemas []string `json:"schemas" gorm:"-"`
+	Tables  []string `json:"tables"  gorm:"-"`
+
+	// IsDataOnly appends rows to existing tables, they are not
+	// dropped and recreated. IsSchemaOnly restores definitions only
+	IsDataOnly   bool `json:"isDataOnly"   gorm:"column:is_data_only;default:false"`
+	IsSchemaOnly bool `json:"isSchemaOnly" gorm:"column:is_schema_only;default:false"`
+
+	SchemasString string `json:"-" gorm:"column:filter_schemas;type:text;not null"`
+	TablesString  string `json:"-" gorm:"column:filter_tables;type:text;not null"`
+}
+
+// IsEmpty is true when the whole dump is restored
+func (f *RestoreFilters) IsEmpty() bool {
+	return len(f.Schemas) == 0 && len(f.Tables) == 0 && !f.IsDataOnly && !f.IsSchemaOnly
+}
+
+func (f *RestoreFilters) Validate() error {
+	if f.IsDataOnly && f.IsSchemaOnly {
+		return errors.New("data only and schema only restores cannot be combined")
+	}
+
+	for _, name := range append(append([]string{}, f.Schemas...), f.Tables...) {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("schema and table names cannot be empty")
+		}
+
+		if strings.ContainsAny(name, ",\n\r") {
+			return errors.New("schema and table names cannot contain commas or line breaks: " + name)
+		}
+	}
+
+	for _, table := range f.Tables {
+		if strings.Count(table, ".") > 1 {
+			return errors.New("table should be \"table\" or \"schema.table\": " + table)
+		}
+	}
+
+	return nil
+}
+
+// IsListRequired tells pg_restore -L list file is needed. pg_restore -t
+// does not accept schema-qualified names, so tables are picked by list.
+// Schemas alone are passed as -n
+func (f *RestoreFilters) IsListRequired() bool {
+	return len(f.Tables) > 0
+}
+
+// ToPgRestoreArgs maps filters to pg_restore arguments, except the list
+// file. --clean cannot be combined with --data-only, so it is up to the
+// caller to skip it
+func (f *RestoreFilters) ToPgRestoreArgs() []string {
+	var args []string
+
+	if !f.IsListRequired() {
+		for _, schema := range f.Schemas {
+			args = append(args, "--schema="+schema)
+		}
+	}
+
+	if f.IsDataOnly {
+		args = append(args, "--data-only")
+	}
+
+	if f.IsSchemaOnly {
+		args = append(args, "--schema-only")
+	}
+
+	return args
+}
+
+// IsTocEntrySelected is used to build pg_restore -L list. Entry is selected
+// when it belongs to one of the schemas or to one of the tables
+func (f *RestoreFilters) IsTocEntrySelected(entry *tools.TocEntry) bool {
+	if entry.Schema != "" && slices.Contains(f.Schemas, entry.Schema) {
+		return true
+	}
+
+	if !slices.Contains(tableTocEntryTypes, entry.Type) {
+		return false
+	}
+
+	for _, table := range f.Tables {
+		schema, name, isQualified := strings.Cut(table, ".")
+		if !isQualified {
+			schema, name = "", table
+		}
+
+		if schema != "" && schema != entry.Schema {
+			continue
+		}
+
+		if entry.Name == name || strings.HasPrefix(entry.Name, name+" ") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// EncodeLists should be called before save of the owning model
+func (f *RestoreFilters) EncodeLists() {
+	f.SchemasString = strings.Join(f.Schemas, ",")
+	f.TablesString = strings.Join(f.Tables, ",")
+}
+
+// DecodeLists should be called after find of the owning model
+func (f *RestoreFilters) DecodeLists() {
+	f.Schemas = splitNames(f.SchemasString)
+	f.Tables = splitNames(f.TablesString)
+}
+
+func splitNames(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+
+	return strings.Split(value, ",")
+}
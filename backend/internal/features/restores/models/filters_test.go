@@ -0,0 +1,154 @@
+package models
+
+import (
+	"postgresus-backend/internal/util/tools"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RestoreFilters_IsTocEntrySelected_MatchesTablesAndSchemas(t *testing.T) {
+	filters := &RestoreFilters{
+		Schemas: []string{"audit"},
+		Tables:  []string{"public.users", "orders"},
+	}
+
+	cases := []struct {
+		entry    tools.TocEntry
+		expected bool
+	}{
+		{tools.TocEntry{Type: "TABLE", Schema: "public", Name: "users"}, true},
+		{tools.TocEntry{Type: "TABLE DATA", Schema: "public", Name: "users"}, true},
+		{tools.TocEntry{Type: "CONSTRAINT", Schema: "public", Name: "users users_pkey"}, true},
+		{tools.TocEntry{Type: "TABLE", Schema: "billing", Name: "users"}, false},
+		{tools.TocEntry{Type: "TABLE", Schema: "public", Name: "users_archive"}, false},
+		{tools.TocEntry{Type: "TABLE", Schema: "billing", Name: "orders"}, true},
+		// indexes are named after themselves, they are picked by dependencies
+		{tools.TocEntry{Type: "INDEX", Schema: "public", Name: "users_email_idx"}, false},
+		{tools.TocEntry{Type: "FUNCTION", Schema: "audit", Name: "log_change()"}, true},
+		{tools.TocEntry{Type: "SCHEMA", Schema: "", Name: "audit"}, false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, filters.IsTocEntrySelected(&c.entry), c.entry)
+	}
+}
+
+// shopDumpToc is pg_restore --list --verbose output of a dump of
+//
+//	CREATE TABLE users (id serial PRIMARY KEY, email text NOT NULL);
+//	CREATE INDEX users_email_idx ON users (email);
+//	COMMENT ON TABLE users IS 'app users';
+//	GRANT SELECT ON users TO reporting;
+//	CREATE TABLE orders (id serial PRIMARY KEY, user_id int REFERENCES users (id));
+//	CREATE VIEW active_users AS SELECT * FROM users;
+const shopDumpToc = `;
+; Archive created at 2025-11-22 10:00:00 UTC
+;     dbname: shop
+;     TOC Entries: 21
+;     Compression: gzip
+;     Dump Version: 1.15-0
+;     Format: CUSTOM
+;     Integer: 4 bytes
+;     Offset: 8 bytes
+;     Dumped from database version: 16.4
+;     Dumped by pg_dump version: 16.4
+;
+;
+; Selected TOC Entries:
+;
+218; 1259 16399 TABLE public orders postgres
+219; 1259 16398 SEQUENCE public orders_id_seq postgres
+;	depends on: 218
+3378; 0 0 SEQUENCE OWNED BY public orders_id_seq postgres
+;	depends on: 219
+216; 1259 16386 TABLE public users postgres
+3380; 0 0 COMMENT public TABLE users postgres
+;	depends on: 216
+217; 1259 16385 SEQUENCE public users_id_seq postgres
+;	depends on: 216
+3381; 0 0 SEQUENCE OWNED BY public users_id_seq postgres
+;	depends on: 217
+220; 1259 16412 VIEW public active_users postgres
+;	depends on: 216
+3213; 2604 16402 DEFAULT public orders id postgres
+;	depends on: 219 218
+3212; 2604 16389 DEFAULT public users id postgres
+;	depends on: 217 216
+3370; 0 16399 TABLE DATA public orders postgres
+;	depends on: 218
+3368; 0 16386 TABLE DATA public users postgres
+;	depends on: 216
+3383; 0 0 SEQUENCE SET public orders_id_seq postgres
+;	depends on: 219
+3384; 0 0 SEQUENCE SET public users_id_seq postgres
+;	depends on: 217
+3218; 2606 16404 CONSTRAINT public orders orders_pkey postgres
+;	depends on: 218
+3215; 2606 16393 CONSTRAINT public users users_pkey postgres
+;	depends on: 216
+3216; 1259 16410 INDEX public users_email_idx postgres
+;	depends on: 216
+3219; 2606 16405 FK CONSTRAINT public orders orders_user_id_fkey postgres
+;	depends on: 218 3215
+3382; 0 0 ACL public TABLE users postgres
+;	depends on: 216
+`
+
+func Test_RestoreFilters_WithTableFilter_ListHasTableWithDependentObjects(t *testing.T) {
+	filters := &RestoreFilters{Tables: []string{"public.users"}}
+
+	list, selectedCount := tools.FilterTocList(shopDumpToc, filters.IsTocEntrySelected)
+
+	selectedNames := []string{}
+	for _, entry := range tools.ParseToc(list) {
+		selectedNames = append(selectedNames, entry.Type+" "+entry.Name)
+	}
+
+	// view and orders depend on users, but they are other relations
+	assert.Equal(t, 10, selectedCount)
+	assert.Equal(t, []string{
+		"TABLE users",
+		"COMMENT TABLE users",
+		"SEQUENCE users_id_seq",
+		"SEQUENCE OWNED BY users_id_seq",
+		"DEFAULT users id",
+		"TABLE DATA users",
+		"SEQUENCE SET users_id_seq",
+		"CONSTRAINT users users_pkey",
+		"INDEX users_email_idx",
+		"ACL TABLE users",
+	}, selectedNames)
+}
+
+func Test_RestoreFilters_WithBothTables_ListHasForeignKey(t *testing.T) {
+	filters := &RestoreFilters{Tables: []string{"users", "orders"}}
+
+	list, _ := tools.FilterTocList(shopDumpToc, filters.IsTocEntrySelected)
+
+	assert.Contains(t, list, "FK CONSTRAINT public orders orders_user_id_fkey")
+	assert.Contains(t, list, "SEQUENCE SET public orders_id_seq")
+	assert.NotContains(t, list, "VIEW public active_users")
+}
+
+func Test_RestoreFilters_ToPgRestoreArgs_MapsSchemasAndDataMode(t *testing.T) {
+	filters := &RestoreFilters{Schemas: []string{"public", "audit"}, IsDataOnly: true}
+	assert.Equal(
+		t,
+		[]string{"--schema=public", "--schema=audit", "--data-only"},
+		filters.ToPgRestoreArgs(),
+	)
+
+	// schemas are part of the list file when tables are chosen
+	filters = &RestoreFilters{Schemas: []string{"audit"}, Tables: []string{"users"}, IsSchemaOnly: true}
+	assert.Equal(t, []string{"--schema-only"}, filters.ToPgRestoreArgs())
+	assert.True(t, filters.IsListRequired())
+}
+
+func Test_RestoreFilters_Validate_RejectsInvalidFilters(t *testing.T) {
+	assert.Error(t, (&RestoreFilters{IsDataOnly: true, IsSchemaOnly: true}).Validate())
+	assert.Error(t, (&RestoreFilters{Tables: []string{"db.public.users"}}).Validate())
+	assert.Error(t, (&RestoreFilters{Schemas: []string{"a,b"}}).Validate())
+	assert.Error(t, (&RestoreFilters{Tables: []string{" "}}).Validate())
+	assert.NoError(t, (&RestoreFilters{Tables: []string{"public.users"}, IsDataOnly: true}).Validate())
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Restore struct {
@@ -18,8 +19,46 @@ type Restore struct {
 
 	Postgresql *postgresql.PostgresqlDatabase `json:"postgresql,omitempty" gorm:"foreignKey:RestoreID"`
 
+	// TargetDataDirectory is where physical backup is unpacked to
+	TargetDataDirectory *string `json:"targetDataDirectory,omitempty" gorm:"column:target_data_directory"`
+
+	// RecoveryTargetTime is set for point-in-time recovery, the physical
+	// backup is rolled forward to this time using archived WAL
+	RecoveryTargetTime *time.Time `json:"recoveryTargetTime,omitempty" gorm:"column:recovery_target_time"`
+
+	// EntryDatabaseName picks a single database of the whole server backup
+	// to restore into the target database. When empty, globals and all
+	// databases are restored under their original names
+	EntryDatabaseName *string `json:"entryDatabaseName,omitempty" gorm:"column:entry_database_name"`
+
+	// IsNoPrivileges skips GRANT and REVOKE, the target server may not
+	// have roles of the source one (e.g. scratch database of verification)
+	IsNoPrivileges bool `json:"isNoPrivileges" gorm:"column:is_no_privileges;default:false"`
+
+	// Filters pick objects of logical backup to restore, the
+	// whole dump is restored when they are empty
+	RestoreFilters `gorm:"embedded"`
+
 	FailMessage *string `json:"failMessage" gorm:"column:fail_message"`
 
+	// InstanceID of the process which runs the restore, restore in
+	// progress is failed when heartbeat of the instance expires
+	InstanceID *uuid.UUID `json:"instanceId" gorm:"column:instance_id;type:uuid"`
+
+	// CancelRequestedAt is set when cancel is requested on an instance which
+	// does not run the restore, the running instance polls it and stops
+	CancelRequestedAt *time.Time `json:"cancelRequestedAt" gorm:"column:cancel_requested_at"`
+
 	RestoreDurationMs int64     `json:"restoreDurationMs" gorm:"column:restore_duration_ms;default:0"`
 	CreatedAt         time.Time `json:"createdAt"         gorm:"column:created_at;default:now()"`
 }
+
+func (r *Restore) BeforeSave(tx *gorm.DB) error {
+	r.RestoreFilters.EncodeLists()
+	return nil
+}
+
+func (r *Restore) AfterFind(tx *gorm.DB) error {
+	r.RestoreFilters.DecodeLists()
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"postgresus-backend/internal/features/restores/enums"
 	"postgresus-backend/internal/features/restores/models"
 	"postgresus-backend/internal/storage"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -21,11 +22,39 @@ func (r *RestoreRepository) Save(restore *models.Restore) error {
 			Error
 	}
 
-	return db.Save(restore).
+	// cancel request is set by RequestCancel only
+	return db.Omit("CancelRequestedAt").
+		Save(restore).
 		Omit("Backup").
 		Error
 }
 
+// RequestCancel stores the cancel request, false is
+// returned if the restore is not in progress anymore
+func (r *RestoreRepository) RequestCancel(restoreID uuid.UUID) (bool, error) {
+	result := storage.
+		GetDb().
+		Model(&models.Restore{}).
+		Where("id = ? AND status = ?", restoreID, enums.RestoreStatusInProgress).
+		Update("cancel_requested_at", time.Now().UTC())
+
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *RestoreRepository) IsCancelRequested(restoreID uuid.UUID) (bool, error) {
+	var count int64
+
+	if err := storage.
+		GetDb().
+		Model(&models.Restore{}).
+		Where("id = ? AND cancel_requested_at IS NOT NULL", restoreID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
 func (r *RestoreRepository) FindByBackupID(backupID uuid.UUID) ([]*models.Restore, error) {
 	var restores []*models.Restore
 
@@ -4,14 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"postgresus-backend/internal/config"
 	"postgresus-backend/internal/features/backups/backups"
 	backups_config "postgresus-backend/internal/features/backups/config"
+	backups_wal "postgresus-backend/internal/features/backups/wal"
 	"postgresus-backend/internal/features/databases"
 	"postgresus-backend/internal/features/restores/enums"
 	"postgresus-backend/internal/features/restores/models"
 	"postgresus-backend/internal/features/restores/usecases"
 	"postgresus-backend/internal/features/storages"
+	system_instances "postgresus-backend/internal/features/system/instances"
 	users_models "postgresus-backend/internal/features/users/models"
+	cancellation_utils "postgresus-backend/internal/util/cancellation"
+	files_utils "postgresus-backend/internal/util/files"
 	"postgresus-backend/internal/util/tools"
 	"time"
 
@@ -24,7 +29,10 @@ type RestoreService struct {
 	storageService       *storages.StorageService
 	backupConfigService  *backups_config.BackupConfigService
 	restoreBackupUsecase *usecases.RestoreBackupUsecase
+	cancellationTracker  *cancellation_utils.CancellationTracker
 	databaseService      *databases.DatabaseService
+	walArchivingService  *backups_wal.WalArchivingService
+	instanceService      *system_instances.InstanceService
 	logger               *slog.Logger
 }
 
@@ -84,19 +92,50 @@ func (s *RestoreService) RestoreBackupWithAuth(
 		return err
 	}
 
-	fmt.Printf(
-		"restore from %s to %s\n",
-		backupDatabase.Postgresql.Version,
-		requestDTO.PostgresqlDatabase.Version,
-	)
+	if backup.BackupMethod == backups_config.BackupMethodPhysical {
+		if requestDTO.TargetDataDirectory == nil || *requestDTO.TargetDataDirectory == "" {
+			return errors.New("target data directory is required to restore physical backup")
+		}
 
-	if tools.IsBackupDbVersionHigherThanRestoreDbVersion(
-		backupDatabase.Postgresql.Version,
-		requestDTO.PostgresqlDatabase.Version,
-	) {
-		return errors.New(`backup database version is higher than restore database version. ` +
-			`Should be restored to the same version as the backup database or higher. ` +
-			`For example, you can restore PG 15 backup to PG 15, 16 or higher. But cannot restore to 14 and lower`)
+		if _, err := files_utils.ResolveDirInside(
+			config.GetEnv().RestoreFolder,
+			*requestDTO.TargetDataDirectory,
+		); err != nil {
+			return fmt.Errorf("invalid target data directory: %w", err)
+		}
+
+		if !requestDTO.RestoreFilters.IsEmpty() {
+			return errors.New("schema and table filters are not supported for physical backups")
+		}
+
+		if requestDTO.RecoveryTargetTime != nil {
+			if err := s.walArchivingService.ValidateRecoveryTarget(
+				backup,
+				*requestDTO.RecoveryTargetTime,
+			); err != nil {
+				return err
+			}
+		}
+	} else {
+		if requestDTO.RecoveryTargetTime != nil {
+			return errors.New("point-in-time recovery requires a physical backup")
+		}
+
+		if requestDTO.PostgresqlDatabase == nil {
+			return errors.New("postgresql database is required")
+		}
+
+		if err := s.validateRestoreDbVersion(backupDatabase, requestDTO); err != nil {
+			return err
+		}
+
+		if err := s.validateWholeServerRestore(backup, requestDTO); err != nil {
+			return err
+		}
+
+		if err := s.validateRestoreFilters(backup, requestDTO); err != nil {
+			return err
+		}
 	}
 
 	go func() {
@@ -108,6 +147,79 @@ func (s *RestoreService) RestoreBackupWithAuth(
 	return nil
 }
 
+// RestoreToPointInTimeWithAuth picks the latest physical backup finished
+// before the target time and rolls it forward using archived WAL
+func (s *RestoreService) RestoreToPointInTimeWithAuth(
+	user *users_models.User,
+	requestDTO PointInTimeRestoreRequest,
+) (*backups.Backup, error) {
+	if _, err := s.databaseService.GetDatabase(user, requestDTO.DatabaseID); err != nil {
+		return nil, err
+	}
+
+	targetTime := requestDTO.TargetTime.UTC()
+
+	backup, err := s.backupService.GetLastPhysicalBackupBefore(requestDTO.DatabaseID, targetTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if backup == nil {
+		return nil, errors.New("there is no physical backup finished before the target time")
+	}
+
+	if err := s.RestoreBackupWithAuth(user, backup.ID, RestoreBackupRequest{
+		TargetDataDirectory: &requestDTO.TargetDataDirectory,
+		RecoveryTargetTime:  &targetTime,
+	}); err != nil {
+		return nil, err
+	}
+
+	return backup, nil
+}
+
+// CancelRestoreWithAuth stops the running restore. Restore run by another
+// instance is stopped by that instance once it sees the stored request.
+// Status is updated by the restore goroutine once pg_restore is stopped
+func (s *RestoreService) CancelRestoreWithAuth(
+	user *users_models.User,
+	restoreID uuid.UUID,
+) error {
+	restore, err := s.restoreRepository.FindByID(restoreID)
+	if err != nil {
+		return err
+	}
+
+	// restore preloads backup without its database
+	backup, err := s.backupService.GetBackup(restore.BackupID)
+	if err != nil {
+		return err
+	}
+
+	if backup.Database.UserID != user.ID {
+		return errors.New("user does not have access to this restore")
+	}
+
+	if restore.Status != enums.RestoreStatusInProgress {
+		return errors.New("restore is not in progress")
+	}
+
+	if s.cancellationTracker.Cancel(restore.ID) {
+		return nil
+	}
+
+	isRequested, err := s.restoreRepository.RequestCancel(restore.ID)
+	if err != nil {
+		return err
+	}
+
+	if !isRequested {
+		return errors.New("restore is not in progress")
+	}
+
+	return nil
+}
+
 func (s *RestoreService) RestoreBackup(
 	backup *backups.Backup,
 	requestDTO RestoreBackupRequest,
@@ -116,15 +228,24 @@ func (s *RestoreService) RestoreBackup(
 		return errors.New("backup is not completed")
 	}
 
-	if backup.Database.Type == databases.DatabaseTypePostgres {
+	isPhysicalBackup := backup.BackupMethod == backups_config.BackupMethodPhysical
+
+	if backup.Database.Type == databases.DatabaseTypePostgres && !isPhysicalBackup {
 		if requestDTO.PostgresqlDatabase == nil {
 			return errors.New("postgresql database is required")
 		}
 	}
 
+	if isPhysicalBackup && requestDTO.TargetDataDirectory == nil {
+		return errors.New("target data directory is required to restore physical backup")
+	}
+
+	instanceID := s.instanceService.GetInstanceID()
+
 	restore := models.Restore{
-		ID:     uuid.New(),
-		Status: enums.RestoreStatusInProgress,
+		ID:         uuid.New(),
+		Status:     enums.RestoreStatusInProgress,
+		InstanceID: &instanceID,
 
 		BackupID: backup.ID,
 		Backup:   backup,
@@ -132,6 +253,11 @@ func (s *RestoreService) RestoreBackup(
 		CreatedAt:         time.Now().UTC(),
 		RestoreDurationMs: 0,
 
+		TargetDataDirectory: requestDTO.TargetDataDirectory,
+		RecoveryTargetTime:  requestDTO.RecoveryTargetTime,
+		EntryDatabaseName:   requestDTO.EntryDatabaseName,
+		RestoreFilters:      requestDTO.RestoreFilters,
+
 		FailMessage: nil,
 	}
 
@@ -151,7 +277,7 @@ func (s *RestoreService) RestoreBackup(
 		}
 	}
 
-	storage, err := s.storageService.GetStorageByID(backup.StorageID)
+	backupStorages, err := s.backupService.GetBackupStorages(backup)
 	if err != nil {
 		return err
 	}
@@ -165,12 +291,27 @@ func (s *RestoreService) RestoreBackup(
 
 	start := time.Now().UTC()
 
+	ctx, unregisterRestore := s.cancellationTracker.Register(restore.ID)
+	defer unregisterRestore()
+
+	go s.cancellationTracker.WatchCancelRequest(ctx, restore.ID, func() (bool, error) {
+		return s.restoreRepository.IsCancelRequested(restore.ID)
+	})
+
 	err = s.restoreBackupUsecase.Execute(
+		ctx,
 		backupConfig,
 		restore,
 		backup,
-		storage,
+		backupStorages,
 	)
+	if err != nil && cancellation_utils.IsCancelledByUser(ctx) {
+		restore.Status = enums.RestoreStatusCancelled
+		restore.RestoreDurationMs = time.Since(start).Milliseconds()
+
+		return s.restoreRepository.Save(&restore)
+	}
+
 	if err != nil {
 		errMsg := err.Error()
 		restore.FailMessage = &errMsg
@@ -193,3 +334,68 @@ func (s *RestoreService) RestoreBackup(
 
 	return nil
 }
+
+func (s *RestoreService) validateRestoreDbVersion(
+	backupDatabase *databases.Database,
+	requestDTO RestoreBackupRequest,
+) error {
+	fmt.Printf(
+		"restore from %s to %s\n",
+		backupDatabase.Postgresql.Version,
+		requestDTO.PostgresqlDatabase.Version,
+	)
+
+	if tools.IsBackupDbVersionHigherThanRestoreDbVersion(
+		backupDatabase.Postgresql.Version,
+		requestDTO.PostgresqlDatabase.Version,
+	) {
+		return errors.New(`backup database version is higher than restore database version. ` +
+			`Should be restored to the same version as the backup database or higher. ` +
+			`For example, you can restore PG 15 backup to PG 15, 16 or higher. But cannot restore to 14 and lower`)
+	}
+
+	return nil
+}
+
+func (s *RestoreService) validateWholeServerRestore(
+	backup *backups.Backup,
+	requestDTO RestoreBackupRequest,
+) error {
+	if !backup.IsWholeServer {
+		if requestDTO.EntryDatabaseName != nil {
+			return errors.New("database can be chosen only for whole server backup")
+		}
+
+		return nil
+	}
+
+	if requestDTO.EntryDatabaseName == nil {
+		return nil
+	}
+
+	if backup.GetDatabaseEntry(*requestDTO.EntryDatabaseName) == nil {
+		return fmt.Errorf("database '%s' is not found in the backup", *requestDTO.EntryDatabaseName)
+	}
+
+	if requestDTO.PostgresqlDatabase.Database == nil ||
+		*requestDTO.PostgresqlDatabase.Database == "" {
+		return errors.New("target database name is required to restore a single database")
+	}
+
+	return nil
+}
+
+func (s *RestoreService) validateRestoreFilters(
+	backup *backups.Backup,
+	requestDTO RestoreBackupRequest,
+) error {
+	if requestDTO.RestoreFilters.IsEmpty() {
+		return nil
+	}
+
+	if backup.IsWholeServer {
+		return errors.New("schema and table filters are not supported for whole server backups")
+	}
+
+	return requestDTO.RestoreFilters.Validate()
+}
@@ -0,0 +1,34 @@
+package usecases_postgresql
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"postgresus-backend/internal/config"
+)
+
+// checksumReader wraps an io.Reader, counts and hashes the bytes read
+// from it and stops reading on cancel or shutdown
+type checksumReader struct {
+	ctx       context.Context
+	reader    io.Reader
+	hasher    hash.Hash
+	bytesRead int64
+}
+
+func (cr *checksumReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, fmt.Errorf("read cancelled: %w", err)
+	}
+
+	if config.IsShouldShutdown() {
+		return 0, fmt.Errorf("read cancelled due to shutdown")
+	}
+
+	n, err := cr.reader.Read(p)
+	cr.hasher.Write(p[:n])
+	cr.bytesRead += int64(n)
+
+	return n, err
+}
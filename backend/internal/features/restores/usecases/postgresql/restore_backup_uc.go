@@ -1,7 +1,11 @@
 package usecases_postgresql
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -9,6 +13,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -16,10 +21,13 @@ import (
 	"postgresus-backend/internal/config"
 	"postgresus-backend/internal/features/backups/backups"
 	backups_config "postgresus-backend/internal/features/backups/config"
+	backups_encryption "postgresus-backend/internal/features/backups/encryption"
+	backups_wal "postgresus-backend/internal/features/backups/wal"
 	"postgresus-backend/internal/features/databases"
 	pgtypes "postgresus-backend/internal/features/databases/databases/postgresql"
 	"postgresus-backend/internal/features/restores/models"
 	"postgresus-backend/internal/features/storages"
+	encryption_utils "postgresus-backend/internal/util/encryption"
 	files_utils "postgresus-backend/internal/util/files"
 	"postgresus-backend/internal/util/tools"
 
@@ -27,19 +35,30 @@ import (
 )
 
 type RestorePostgresqlBackupUsecase struct {
-	logger *slog.Logger
+	logger              *slog.Logger
+	encryptionService   *backups_encryption.BackupEncryptionService
+	walArchivingService *backups_wal.WalArchivingService
 }
 
 func (uc *RestorePostgresqlBackupUsecase) Execute(
+	ctx context.Context,
 	backupConfig *backups_config.BackupConfig,
 	restore models.Restore,
 	backup *backups.Backup,
-	storage *storages.Storage,
+	backupStorages []*storages.Storage,
 ) error {
 	if backup.Database.Type != databases.DatabaseTypePostgres {
 		return errors.New("database type not supported")
 	}
 
+	if backup.BackupMethod == backups_config.BackupMethodPhysical {
+		return uc.restorePhysicalBackup(ctx, restore, backup, backupStorages)
+	}
+
+	if backup.IsWholeServer {
+		return uc.restoreWholeServerBackup(ctx, backupConfig, restore, backup, backupStorages)
+	}
+
 	uc.logger.Info(
 		"Restoring PostgreSQL backup via pg_restore",
 		"restoreId",
@@ -57,47 +76,434 @@ func (uc *RestorePostgresqlBackupUsecase) Execute(
 		return fmt.Errorf("target database name is required for pg_restore")
 	}
 
+	args := uc.buildPgRestoreArgs(backupConfig, backup, pg, *pg.Database, true)
+	if restore.IsNoPrivileges {
+		args = append(args, "--no-privileges")
+	}
+
+	// pg_restore rejects --clean with --data-only, rows are appended instead
+	if restore.RestoreFilters.IsDataOnly {
+		args = slices.DeleteFunc(args, func(arg string) bool {
+			return arg == "--clean" || arg == "--if-exists"
+		})
+	}
+
+	args = append(args, restore.RestoreFilters.ToPgRestoreArgs()...)
+
+	var listFilter func(entry *tools.TocEntry) bool
+	if restore.RestoreFilters.IsListRequired() {
+		listFilter = restore.RestoreFilters.IsTocEntrySelected
+	}
+
+	return uc.restoreFromStorage(
+		ctx,
+		uc.getPgRestoreBin(pg),
+		args,
+		pg.Password,
+		backup,
+		backup.GetFileInfo(),
+		backup.DumpFormat == backups_config.BackupDumpFormatDirectory,
+		backupStorages,
+		pg,
+		listFilter,
+	)
+}
+
+// ListBackupObjects returns TOC of the logical backup via pg_restore --list.
+// Whole server backups have a TOC per database, so they are not supported
+func (uc *RestorePostgresqlBackupUsecase) ListBackupObjects(
+	ctx context.Context,
+	backup *backups.Backup,
+	backupStorages []*storages.Storage,
+) ([]*tools.TocEntry, error) {
+	if backup.BackupMethod == backups_config.BackupMethodPhysical || backup.IsWholeServer {
+		return nil, errors.New("objects can be listed only for logical backup of a single database")
+	}
+
+	if backup.Database == nil || backup.Database.Postgresql == nil {
+		return nil, errors.New("postgresql database of the backup is not found")
+	}
+
+	tempBackupFile, cleanupFunc, err := uc.downloadBackupToTempFile(
+		ctx,
+		backup,
+		backup.GetFileInfo(),
+		backupStorages,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download backup to temporary file: %w", err)
+	}
+	defer cleanupFunc()
+
+	isDirectoryDump := backup.DumpFormat == backups_config.BackupDumpFormatDirectory
+	if isDirectoryDump {
+		tempBackupFile, err = uc.extractDumpDirectory(tempBackupFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	output, err := uc.listToc(
+		ctx,
+		tools.GetPostgresqlExecutable(
+			backup.Database.Postgresql.Version,
+			tools.PostgresqlExecutablePgRestore,
+			config.GetEnv().EnvMode,
+			config.GetEnv().PostgresesInstallDir,
+		),
+		tempBackupFile,
+		isDirectoryDump,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return tools.ParseToc(output), nil
+}
+
+// restoreWholeServerBackup restores a single database of the backup into the
+// target database or, if no database is chosen, globals and all databases.
+// Databases are restored under their original names and created if missing
+func (uc *RestorePostgresqlBackupUsecase) restoreWholeServerBackup(
+	ctx context.Context,
+	backupConfig *backups_config.BackupConfig,
+	restore models.Restore,
+	backup *backups.Backup,
+	backupStorages []*storages.Storage,
+) error {
+	pg := restore.Postgresql
+	if pg == nil {
+		return fmt.Errorf("postgresql configuration is required for restore")
+	}
+
+	if restore.EntryDatabaseName != nil {
+		entry := backup.GetDatabaseEntry(*restore.EntryDatabaseName)
+		if entry == nil {
+			return fmt.Errorf("database '%s' is not found in the backup", *restore.EntryDatabaseName)
+		}
+
+		if pg.Database == nil || *pg.Database == "" {
+			return fmt.Errorf("target database name is required for pg_restore")
+		}
+
+		uc.logger.Info(
+			"Restoring single database of whole server backup",
+			"restoreId",
+			restore.ID,
+			"backupId",
+			backup.ID,
+			"database",
+			*restore.EntryDatabaseName,
+		)
+
+		args := uc.buildPgRestoreArgs(backupConfig, backup, pg, *pg.Database, true)
+		if restore.IsNoPrivileges {
+			args = append(args, "--no-privileges")
+		}
+
+		return uc.restoreFromStorage(
+			ctx,
+			uc.getPgRestoreBin(pg),
+			args,
+			pg.Password,
+			backup,
+			entry.GetFileInfo(),
+			backup.DumpFormat == backups_config.BackupDumpFormatDirectory,
+			backupStorages,
+			pg,
+			nil,
+		)
+	}
+
+	uc.logger.Info(
+		"Restoring whole server backup",
+		"restoreId",
+		restore.ID,
+		"backupId",
+		backup.ID,
+	)
+
+	// roles should exist before databases are restored, otherwise
+	// ownership and grants of the restored objects are lost
+	if globalsEntry := backup.GetGlobalsEntry(); globalsEntry != nil {
+		psqlArgs := []string{
+			"--no-password", // Use environment variable for password, prevent prompts
+			"-h", pg.Host,
+			"-p", strconv.Itoa(pg.Port),
+			"-U", pg.Username,
+			"-d", pg.GetMaintenanceDatabase(),
+			"-f", // backup file is appended as the last argument
+		}
+
+		if err := uc.restoreFromStorage(
+			ctx,
+			tools.GetPostgresqlExecutable(
+				pg.Version,
+				tools.PostgresqlExecutablePsql,
+				config.GetEnv().EnvMode,
+				config.GetEnv().PostgresesInstallDir,
+			),
+			psqlArgs,
+			pg.Password,
+			backup,
+			globalsEntry.GetFileInfo(),
+			false,
+			backupStorages,
+			pg,
+			nil,
+		); err != nil {
+			return fmt.Errorf("failed to restore globals: %w", err)
+		}
+	}
+
+	for _, entry := range backup.Entries {
+		if entry.Kind != backups.BackupEntryKindDatabase || entry.DatabaseName == nil {
+			continue
+		}
+
+		databaseName := *entry.DatabaseName
+
+		if err := pg.CreateDatabaseIfNotExists(databaseName); err != nil {
+			return err
+		}
+
+		// owners are kept, because roles were restored with globals
+		args := uc.buildPgRestoreArgs(backupConfig, backup, pg, databaseName, false)
+		if restore.IsNoPrivileges {
+			args = append(args, "--no-privileges")
+		}
+
+		if err := uc.restoreFromStorage(
+			ctx,
+			uc.getPgRestoreBin(pg),
+			args,
+			pg.Password,
+			backup,
+			entry.GetFileInfo(),
+			backup.DumpFormat == backups_config.BackupDumpFormatDirectory,
+			backupStorages,
+			pg,
+			nil,
+		); err != nil {
+			return fmt.Errorf("failed to restore database '%s': %w", databaseName, err)
+		}
+	}
+
+	return nil
+}
+
+func (uc *RestorePostgresqlBackupUsecase) buildPgRestoreArgs(
+	backupConfig *backups_config.BackupConfig,
+	backup *backups.Backup,
+	pg *pgtypes.PostgresqlDatabase,
+	databaseName string,
+	isNoOwner bool,
+) []string {
 	// Use parallel jobs based on CPU count (same as backup)
 	// Cap between 1 and 8 to avoid overwhelming the server
 	parallelJobs := max(1, min(backupConfig.CpuCount, 8))
 
+	// expect the same format as backup
+	dumpFormatArg := "-Fc"
+	if backup.DumpFormat == backups_config.BackupDumpFormatDirectory {
+		dumpFormatArg = "-Fd"
+	}
+
 	args := []string{
-		"-Fc",                            // expect custom format (same as backup)
+		dumpFormatArg,
 		"-j", strconv.Itoa(parallelJobs), // parallel jobs based on CPU count
 		"--no-password", // Use environment variable for password, prevent prompts
 		"-h", pg.Host,
 		"-p", strconv.Itoa(pg.Port),
 		"-U", pg.Username,
-		"-d", *pg.Database,
+		"-d", databaseName,
 		"--verbose",   // Add verbose output to help with debugging
 		"--clean",     // Clean (drop) database objects before recreating them
 		"--if-exists", // Use IF EXISTS when dropping objects
-		"--no-owner",
 	}
 
-	return uc.restoreFromStorage(
-		tools.GetPostgresqlExecutable(
-			pg.Version,
-			"pg_restore",
-			config.GetEnv().EnvMode,
-			config.GetEnv().PostgresesInstallDir,
-		),
-		args,
-		pg.Password,
-		backup,
-		storage,
-		pg,
+	if isNoOwner {
+		args = append(args, "--no-owner")
+	}
+
+	return args
+}
+
+func (uc *RestorePostgresqlBackupUsecase) getPgRestoreBin(pg *pgtypes.PostgresqlDatabase) string {
+	return tools.GetPostgresqlExecutable(
+		pg.Version,
+		"pg_restore",
+		config.GetEnv().EnvMode,
+		config.GetEnv().PostgresesInstallDir,
+	)
+}
+
+// restorePhysicalBackup unpacks pg_basebackup tar into the target data directory.
+// Checksum is verified after unpacking, on mismatch the directory is cleaned up
+// so a corrupted cluster cannot be started by mistake
+func (uc *RestorePostgresqlBackupUsecase) restorePhysicalBackup(
+	parentCtx context.Context,
+	restore models.Restore,
+	backup *backups.Backup,
+	backupStorages []*storages.Storage,
+) error {
+	if restore.TargetDataDirectory == nil || *restore.TargetDataDirectory == "" {
+		return errors.New("target data directory is required to restore physical backup")
+	}
+
+	// the directory is cleaned up on failure, so it cannot be any
+	// directory of the Postgresus host
+	targetDir, err := files_utils.ResolveDirInside(
+		config.GetEnv().RestoreFolder,
+		*restore.TargetDataDirectory,
+	)
+	if err != nil {
+		return fmt.Errorf("invalid target data directory: %w", err)
+	}
+
+	if err := uc.ensureEmptyDataDirectory(targetDir); err != nil {
+		return err
+	}
+
+	uc.logger.Info(
+		"Restoring PostgreSQL physical backup into data directory",
+		"restoreId",
+		restore.ID,
+		"backupId",
+		backup.ID,
+		"targetDir",
+		targetDir,
 	)
+
+	ctx, cancel := context.WithTimeout(parentCtx, 23*time.Hour)
+	defer cancel()
+
+	// the file is unpacked while it is read, so there is no
+	// fallback to another copy once unpacking has started
+	backupReader, err := uc.openBackupFile(backup.ID, backupStorages)
+	if err != nil {
+		return fmt.Errorf("failed to get backup file from storage: %w", err)
+	}
+
+	if backup.IsEncrypted {
+		backupReader, err = uc.decryptBackupReader(backup, backupReader)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt backup: %w", err)
+		}
+	}
+
+	defer func() {
+		if err := backupReader.Close(); err != nil {
+			uc.logger.Error("Failed to close backup reader", "error", err)
+		}
+	}()
+
+	checksumReader := &checksumReader{
+		ctx:    ctx,
+		reader: backupReader,
+		hasher: sha256.New(),
+	}
+
+	if err := uc.extractDataDirectory(checksumReader, targetDir); err != nil {
+		_ = files_utils.CleanFolder(targetDir)
+		return err
+	}
+
+	if err := uc.verifyBackupChecksum(
+		backup.GetFileInfo(),
+		hex.EncodeToString(checksumReader.hasher.Sum(nil)),
+		checksumReader.bytesRead,
+	); err != nil {
+		_ = files_utils.CleanFolder(targetDir)
+		return err
+	}
+
+	if restore.RecoveryTargetTime != nil {
+		if err := uc.walArchivingService.PrepareRecovery(
+			backup,
+			*restore.RecoveryTargetTime,
+			targetDir,
+		); err != nil {
+			_ = files_utils.CleanFolder(targetDir)
+			return fmt.Errorf("failed to prepare point-in-time recovery: %w", err)
+		}
+	}
+
+	uc.logger.Info("Physical backup unpacked", "targetDir", targetDir)
+	return nil
+}
+
+// extractDataDirectory unpacks tar (optionally gzipped) and reads the stream
+// till the end, so checksum covers the whole file including tar padding
+func (uc *RestorePostgresqlBackupUsecase) extractDataDirectory(
+	reader io.Reader,
+	targetDir string,
+) error {
+	bufferedReader := bufio.NewReader(reader)
+
+	var tarReader io.Reader = bufferedReader
+	if header, err := bufferedReader.Peek(2); err == nil && header[0] == 0x1f && header[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(bufferedReader)
+		if err != nil {
+			return fmt.Errorf("failed to read gzipped backup: %w", err)
+		}
+		defer func() { _ = gzipReader.Close() }()
+
+		tarReader = gzipReader
+	}
+
+	if err := files_utils.ExtractTar(tarReader, targetDir); err != nil {
+		return fmt.Errorf("failed to unpack backup: %w", err)
+	}
+
+	if _, err := io.Copy(io.Discard, bufferedReader); err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	return nil
+}
+
+// ensureEmptyDataDirectory refuses to unpack over an existing cluster
+func (uc *RestorePostgresqlBackupUsecase) ensureEmptyDataDirectory(targetDir string) error {
+	info, err := os.Stat(targetDir)
+	if os.IsNotExist(err) {
+		// PostgreSQL refuses to start if data directory is accessible by others
+		return os.MkdirAll(targetDir, 0700)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to check target data directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("target data directory is not a directory: %s", targetDir)
+	}
+
+	entries, err := os.ReadDir(targetDir)
+	if err != nil {
+		return fmt.Errorf("failed to read target data directory: %w", err)
+	}
+
+	if len(entries) > 0 {
+		return fmt.Errorf("target data directory is not empty: %s", targetDir)
+	}
+
+	return nil
 }
 
-// restoreFromStorage restores backup data from storage using pg_restore
+// restoreFromStorage restores backup data from storage using pg_restore.
+// When listFilter is passed, only selected TOC entries are restored via -L
 func (uc *RestorePostgresqlBackupUsecase) restoreFromStorage(
+	parentCtx context.Context,
 	pgBin string,
 	args []string,
 	password string,
 	backup *backups.Backup,
-	storage *storages.Storage,
+	fileInfo *backups.BackupFileInfo,
+	isDirectoryDump bool,
+	backupStorages []*storages.Storage,
 	pgConfig *pgtypes.PostgresqlDatabase,
+	listFilter func(entry *tools.TocEntry) bool,
 ) error {
 	uc.logger.Info(
 		"Restoring PostgreSQL backup from storage via temporary file",
@@ -107,7 +513,7 @@ func (uc *RestorePostgresqlBackupUsecase) restoreFromStorage(
 		args,
 	)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Minute)
+	ctx, cancel := context.WithTimeout(parentCtx, 60*time.Minute)
 	defer cancel()
 
 	// Monitor for shutdown and cancel context if needed
@@ -155,23 +561,127 @@ func (uc *RestorePostgresqlBackupUsecase) restoreFromStorage(
 	}
 
 	// Download backup to temporary file
-	tempBackupFile, cleanupFunc, err := uc.downloadBackupToTempFile(ctx, backup, storage)
+	tempBackupFile, cleanupFunc, err := uc.downloadBackupToTempFile(ctx, backup, fileInfo, backupStorages)
 	if err != nil {
 		return fmt.Errorf("failed to download backup to temporary file: %w", err)
 	}
 	defer cleanupFunc()
 
+	if isDirectoryDump {
+		tempBackupFile, err = uc.extractDumpDirectory(tempBackupFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if listFilter != nil {
+		listFile, err := uc.createRestoreListFile(
+			ctx,
+			pgBin,
+			tempBackupFile,
+			isDirectoryDump,
+			listFilter,
+		)
+		if err != nil {
+			return err
+		}
+
+		args = append(args, "-L", listFile)
+	}
+
 	// Add the temporary backup file as the last argument to pg_restore
 	args = append(args, tempBackupFile)
 
 	return uc.executePgRestore(ctx, pgBin, args, pgpassFile, pgConfig, backup)
 }
 
-// downloadBackupToTempFile downloads backup data from storage to a temporary file
+// createRestoreListFile writes pg_restore -L list of selected entries next
+// to the downloaded backup, so it is removed together with the backup
+func (uc *RestorePostgresqlBackupUsecase) createRestoreListFile(
+	ctx context.Context,
+	pgBin string,
+	dumpPath string,
+	isDirectoryDump bool,
+	listFilter func(entry *tools.TocEntry) bool,
+) (string, error) {
+	output, err := uc.listToc(ctx, pgBin, dumpPath, isDirectoryDump)
+	if err != nil {
+		return "", err
+	}
+
+	list, selectedCount := tools.FilterTocList(output, listFilter)
+	if selectedCount == 0 {
+		return "", errors.New("no objects of the backup match the schema and table filters")
+	}
+
+	listFile := filepath.Join(filepath.Dir(dumpPath), "restore.list")
+	if err := os.WriteFile(listFile, []byte(list), 0600); err != nil {
+		return "", fmt.Errorf("failed to write restore list file: %w", err)
+	}
+
+	uc.logger.Info("Restoring selected objects of the backup", "count", selectedCount)
+
+	return listFile, nil
+}
+
+func (uc *RestorePostgresqlBackupUsecase) listToc(
+	ctx context.Context,
+	pgBin string,
+	dumpPath string,
+	isDirectoryDump bool,
+) (string, error) {
+	dumpFormatArg := "-Fc"
+	if isDirectoryDump {
+		dumpFormatArg = "-Fd"
+	}
+
+	// --verbose adds dependencies of the entries, they
+	// pick indexes and sequences of the chosen tables
+	cmd := exec.CommandContext(ctx, pgBin, dumpFormatArg, "--list", "--verbose", dumpPath)
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("pg_restore --list failed: %w – stderr: %s", err, stderr.String())
+	}
+
+	return string(output), nil
+}
+
+// extractDumpDirectory unpacks tar of directory dump next to the downloaded
+// file, the file is removed right away to not keep the dump on disk twice
+func (uc *RestorePostgresqlBackupUsecase) extractDumpDirectory(tarFile string) (string, error) {
+	dumpDir := filepath.Join(filepath.Dir(tarFile), "dump")
+
+	file, err := os.Open(tarFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to open downloaded backup: %w", err)
+	}
+
+	err = files_utils.ExtractTar(file, dumpDir)
+	_ = file.Close()
+
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack directory dump: %w", err)
+	}
+
+	if err := os.Remove(tarFile); err != nil {
+		uc.logger.Warn("Failed to remove downloaded backup", "file", tarFile, "error", err)
+	}
+
+	return dumpDir, nil
+}
+
+// downloadBackupToTempFile downloads backup data from storage to a temporary
+// file. Storages are tried in order, so a missing or corrupted copy falls
+// back to the copy in the next storage
 func (uc *RestorePostgresqlBackupUsecase) downloadBackupToTempFile(
 	ctx context.Context,
 	backup *backups.Backup,
-	storage *storages.Storage,
+	fileInfo *backups.BackupFileInfo,
+	backupStorages []*storages.Storage,
 ) (string, func(), error) {
 	err := files_utils.EnsureDirectories([]string{
 		config.GetEnv().TempFolder,
@@ -192,19 +702,74 @@ func (uc *RestorePostgresqlBackupUsecase) downloadBackupToTempFile(
 
 	tempBackupFile := filepath.Join(tempDir, "backup.dump")
 
+	var downloadErrs []error
+	for _, storage := range backupStorages {
+		err := uc.downloadFromStorage(ctx, backup, fileInfo, storage, tempBackupFile)
+		if err == nil {
+			uc.logger.Info("Backup file written to temporary location", "tempFile", tempBackupFile)
+			return tempBackupFile, cleanupFunc, nil
+		}
+
+		if ctx.Err() != nil || config.IsShouldShutdown() {
+			cleanupFunc()
+			return "", nil, err
+		}
+
+		uc.logger.Warn(
+			"Failed to download backup copy, trying the next storage",
+			"backupId",
+			backup.ID,
+			"storageId",
+			storage.ID,
+			"error",
+			err,
+		)
+
+		downloadErrs = append(downloadErrs, err)
+	}
+
+	cleanupFunc()
+
+	if len(downloadErrs) == 0 {
+		return "", nil, errors.New("backup has no storage to download it from")
+	}
+
+	return "", nil, errors.Join(downloadErrs...)
+}
+
+// downloadFromStorage writes the file of a single storage to the
+// temporary file, the file is overwritten on each attempt
+func (uc *RestorePostgresqlBackupUsecase) downloadFromStorage(
+	ctx context.Context,
+	backup *backups.Backup,
+	fileInfo *backups.BackupFileInfo,
+	storage *storages.Storage,
+	tempBackupFile string,
+) error {
 	// Get backup data from storage
 	uc.logger.Info(
 		"Downloading backup file from storage to temporary file",
 		"backupId",
 		backup.ID,
+		"fileId",
+		fileInfo.FileID,
+		"storageId",
+		storage.ID,
 		"tempFile",
 		tempBackupFile,
 	)
-	backupReader, err := storage.GetFile(backup.ID)
+	backupReader, err := storage.GetFile(fileInfo.FileID)
 	if err != nil {
-		cleanupFunc()
-		return "", nil, fmt.Errorf("failed to get backup file from storage: %w", err)
+		return fmt.Errorf("failed to get backup file from storage: %w", err)
 	}
+
+	if backup.IsEncrypted {
+		backupReader, err = uc.decryptBackupReader(backup, backupReader)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt backup: %w", err)
+		}
+	}
+
 	defer func() {
 		if err := backupReader.Close(); err != nil {
 			uc.logger.Error("Failed to close backup reader", "error", err)
@@ -214,8 +779,7 @@ func (uc *RestorePostgresqlBackupUsecase) downloadBackupToTempFile(
 	// Create temporary backup file
 	tempFile, err := os.Create(tempBackupFile)
 	if err != nil {
-		cleanupFunc()
-		return "", nil, fmt.Errorf("failed to create temporary backup file: %w", err)
+		return fmt.Errorf("failed to create temporary backup file: %w", err)
 	}
 	defer func() {
 		if err := tempFile.Close(); err != nil {
@@ -223,18 +787,115 @@ func (uc *RestorePostgresqlBackupUsecase) downloadBackupToTempFile(
 		}
 	}()
 
-	// Copy backup data to temporary file with shutdown checks
-	_, err = uc.copyWithShutdownCheck(ctx, tempFile, backupReader)
+	// Copy backup data to temporary file with shutdown checks,
+	// checksum is calculated on the fly to avoid second read of the file
+	hasher := sha256.New()
+	bytesWritten, err := uc.copyWithShutdownCheck(
+		ctx,
+		io.MultiWriter(tempFile, hasher),
+		backupReader,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write backup to temporary file: %w", err)
+	}
+
+	return uc.verifyBackupChecksum(
+		fileInfo,
+		hex.EncodeToString(hasher.Sum(nil)),
+		bytesWritten,
+	)
+}
+
+// openBackupFile opens the file from the first storage which can read it
+func (uc *RestorePostgresqlBackupUsecase) openBackupFile(
+	fileID uuid.UUID,
+	backupStorages []*storages.Storage,
+) (io.ReadCloser, error) {
+	var openErrs []error
+
+	for _, storage := range backupStorages {
+		backupReader, err := storage.GetFile(fileID)
+		if err == nil {
+			return backupReader, nil
+		}
+
+		uc.logger.Warn(
+			"Failed to open backup file, trying the next storage",
+			"fileId",
+			fileID,
+			"storageId",
+			storage.ID,
+			"error",
+			err,
+		)
+
+		openErrs = append(openErrs, err)
+	}
+
+	if len(openErrs) == 0 {
+		return nil, errors.New("backup has no storage to read it from")
+	}
+
+	return nil, errors.Join(openErrs...)
+}
+
+// verifyBackupChecksum refuses corrupted or truncated backup files, so pg_restore
+// never runs over them. Backups made before checksums were introduced are skipped
+func (uc *RestorePostgresqlBackupUsecase) verifyBackupChecksum(
+	fileInfo *backups.BackupFileInfo,
+	sha256Hex string,
+	sizeBytes int64,
+) error {
+	if fileInfo.SizeBytes != nil && *fileInfo.SizeBytes != sizeBytes {
+		return fmt.Errorf(
+			"backup file is truncated or corrupted: expected %d bytes, downloaded %d bytes",
+			*fileInfo.SizeBytes,
+			sizeBytes,
+		)
+	}
+
+	if fileInfo.Sha256 != nil && *fileInfo.Sha256 != sha256Hex {
+		return fmt.Errorf(
+			"backup file is corrupted: expected SHA-256 %s, got %s",
+			*fileInfo.Sha256,
+			sha256Hex,
+		)
+	}
+
+	if fileInfo.Sha256 == nil {
+		uc.logger.Warn("Backup has no checksum, skipping verification", "fileId", fileInfo.FileID)
+	}
+
+	return nil
+}
+
+// decryptBackupReader wraps storage reader with decryption using
+// the key version the backup was made with
+func (uc *RestorePostgresqlBackupUsecase) decryptBackupReader(
+	backup *backups.Backup,
+	backupReader io.ReadCloser,
+) (io.ReadCloser, error) {
+	if backup.EncryptionKeyVersion == nil {
+		_ = backupReader.Close()
+		return nil, errors.New("backup is encrypted, but encryption key version is unknown")
+	}
+
+	encryptionKey, err := uc.encryptionService.GetKey(
+		backup.DatabaseID,
+		*backup.EncryptionKeyVersion,
+	)
 	if err != nil {
-		cleanupFunc()
-		return "", nil, fmt.Errorf("failed to write backup to temporary file: %w", err)
+		_ = backupReader.Close()
+		return nil, err
 	}
 
-	// Close the temp file to ensure all data is written - this is handled by defer
-	// Removing explicit close to avoid double-close error
+	decryptingReader, err := encryption_utils.NewDecryptingReadCloser(backupReader, encryptionKey)
+	if err != nil {
+		_ = backupReader.Close()
+		return nil, err
+	}
 
-	uc.logger.Info("Backup file written to temporary location", "tempFile", tempBackupFile)
-	return tempBackupFile, cleanupFunc, nil
+	return decryptingReader, nil
 }
 
 // executePgRestore executes the pg_restore command with proper environment setup
@@ -1,6 +1,7 @@
 package usecases
 
 import (
+	"context"
 	"errors"
 	"postgresus-backend/internal/features/backups/backups"
 	backups_config "postgresus-backend/internal/features/backups/config"
@@ -15,17 +16,19 @@ type RestoreBackupUsecase struct {
 }
 
 func (uc *RestoreBackupUsecase) Execute(
+	ctx context.Context,
 	backupConfig *backups_config.BackupConfig,
 	restore models.Restore,
 	backup *backups.Backup,
-	storage *storages.Storage,
+	backupStorages []*storages.Storage,
 ) error {
 	if restore.Backup.Database.Type == databases.DatabaseTypePostgres {
 		return uc.restorePostgresqlBackupUsecase.Execute(
+			ctx,
 			backupConfig,
 			restore,
 			backup,
-			storage,
+			backupStorages,
 		)
 	}
 
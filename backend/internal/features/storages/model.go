@@ -19,6 +19,10 @@ type Storage struct {
 	Name          string      `json:"name"          gorm:"column:name;not null;type:text"`
 	LastSaveError *string     `json:"lastSaveError" gorm:"column:last_save_error;type:text"`
 
+	// QuotaMb caps size of backups in the storage, the oldest backups are
+	// pruned before a new one starts. Zero means no quota
+	QuotaMb int64 `json:"quotaMb" gorm:"column:quota_mb;type:bigint;not null"`
+
 	// specific storage
 	LocalStorage       *local_storage.LocalStorage              `json:"localStorage"       gorm:"foreignKey:StorageID"`
 	S3Storage          *s3_storage.S3Storage                    `json:"s3Storage"          gorm:"foreignKey:StorageID"`
@@ -56,6 +60,10 @@ func (s *Storage) Validate() error {
 		return errors.New("storage name is required")
 	}
 
+	if s.QuotaMb < 0 {
+		return errors.New("storage quota cannot be negative")
+	}
+
 	return s.getSpecificStorage().Validate()
 }
 
@@ -2,6 +2,7 @@ package system_healthcheck
 
 import (
 	"errors"
+	"postgresus-backend/internal/config"
 	"postgresus-backend/internal/features/backups/backups"
 	"postgresus-backend/internal/features/disk"
 	"postgresus-backend/internal/storage"
@@ -29,7 +30,9 @@ func (s *HealthcheckService) IsHealthy() error {
 		return errors.New("cannot connect to the database")
 	}
 
-	if !s.backupBackgroundService.IsBackupsWorkerRunning() {
+	// web instance does not run backups, workers are checked on their own
+	if config.GetEnv().IsBackgroundEnabled() &&
+		!s.backupBackgroundService.IsBackupsWorkerRunning() {
 		return errors.New("backups are not running for more than 5 minutes")
 	}
 
@@ -0,0 +1,31 @@
+package system_instances
+
+import (
+	"log/slog"
+	"postgresus-backend/internal/config"
+	"time"
+)
+
+type InstanceHeartbeatBackgroundService struct {
+	instanceService    *InstanceService
+	instanceRepository *InstanceRepository
+	logger             *slog.Logger
+}
+
+func (s *InstanceHeartbeatBackgroundService) Run() {
+	for {
+		if config.IsShouldShutdown() {
+			return
+		}
+
+		time.Sleep(heartbeatInterval)
+
+		if err := s.instanceRepository.SaveHeartbeat(s.instanceService.instance); err != nil {
+			s.logger.Error("Failed to save instance heartbeat", "error", err)
+		}
+
+		if err := s.instanceRepository.DeleteStale(staleInstanceTTL); err != nil {
+			s.logger.Error("Failed to remove stale instances", "error", err)
+		}
+	}
+}
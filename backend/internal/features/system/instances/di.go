@@ -0,0 +1,45 @@
+package system_instances
+
+import (
+	"os"
+	"postgresus-backend/internal/config"
+	"postgresus-backend/internal/util/logger"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var instanceRepository = &InstanceRepository{}
+var instanceService = &InstanceService{
+	instanceRepository,
+	newInstance(),
+	logger.GetLogger(),
+}
+var instanceHeartbeatBackgroundService = &InstanceHeartbeatBackgroundService{
+	instanceService,
+	instanceRepository,
+	logger.GetLogger(),
+}
+
+func GetInstanceService() *InstanceService {
+	return instanceService
+}
+
+func GetInstanceHeartbeatBackgroundService() *InstanceHeartbeatBackgroundService {
+	return instanceHeartbeatBackgroundService
+}
+
+func newInstance() *Instance {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return &Instance{
+		ID:        uuid.New(),
+		Hostname:  hostname,
+		IsWeb:     config.GetEnv().IsWebEnabled(),
+		IsWorker:  config.GetEnv().IsBackgroundEnabled(),
+		StartedAt: time.Now().UTC(),
+	}
+}
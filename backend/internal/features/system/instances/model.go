@@ -0,0 +1,34 @@
+package system_instances
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Instance is a running Postgresus process. It is alive while its
+// heartbeat is fresher than the TTL, jobs of dead instance are failed
+type Instance struct {
+	ID          uuid.UUID `json:"id"          gorm:"column:id;type:uuid;primaryKey"`
+	Hostname    string    `json:"hostname"    gorm:"column:hostname;type:text;not null"`
+	IsWeb       bool      `json:"isWeb"       gorm:"column:is_web;not null"`
+	IsWorker    bool      `json:"isWorker"    gorm:"column:is_worker;not null"`
+	StartedAt   time.Time `json:"startedAt"   gorm:"column:started_at"`
+	HeartbeatAt time.Time `json:"heartbeatAt" gorm:"column:heartbeat_at"`
+}
+
+func (i *Instance) TableName() string {
+	return "instances"
+}
+
+// JobLease gives the job to one instance at a time. It is held until
+// the holder stops sending heartbeats, then any instance can take it
+type JobLease struct {
+	Name       string    `json:"name"       gorm:"column:name;type:text;primaryKey"`
+	InstanceID uuid.UUID `json:"instanceId" gorm:"column:instance_id;type:uuid;not null"`
+	AcquiredAt time.Time `json:"acquiredAt" gorm:"column:acquired_at"`
+}
+
+func (l *JobLease) TableName() string {
+	return "job_leases"
+}
@@ -0,0 +1,85 @@
+package system_instances
+
+import (
+	"postgresus-backend/internal/storage"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InstanceRepository struct{}
+
+// SaveHeartbeat updates heartbeat of the instance. Instance is inserted
+// again if it was removed as stale, e.g. after a long pause. Database
+// time is used, so clocks of the hosts do not have to be in sync
+func (r *InstanceRepository) SaveHeartbeat(instance *Instance) error {
+	return storage.GetDb().Exec(`
+		INSERT INTO instances (id, hostname, is_web, is_worker, started_at, heartbeat_at)
+		VALUES (?, ?, ?, ?, ?, now())
+		ON CONFLICT (id) DO UPDATE SET heartbeat_at = now()`,
+		instance.ID,
+		instance.Hostname,
+		instance.IsWeb,
+		instance.IsWorker,
+		instance.StartedAt,
+	).Error
+}
+
+func (r *InstanceRepository) FindAliveIDs(ttl time.Duration) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+
+	if err := storage.
+		GetDb().
+		Model(&Instance{}).
+		Where("heartbeat_at > now() - make_interval(secs => ?)", ttl.Seconds()).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// DeleteByID removes the instance and its leases
+func (r *InstanceRepository) DeleteByID(id uuid.UUID) error {
+	return storage.GetDb().Delete(&Instance{}, "id = ?", id).Error
+}
+
+func (r *InstanceRepository) DeleteStale(staleAfter time.Duration) error {
+	return storage.
+		GetDb().
+		Delete(&Instance{}, "heartbeat_at < now() - make_interval(secs => ?)", staleAfter.Seconds()).
+		Error
+}
+
+// TryAcquireLease returns true when the instance holds the lease. The lease
+// is taken over only if heartbeat of its holder is older than the TTL
+func (r *InstanceRepository) TryAcquireLease(
+	name string,
+	instanceID uuid.UUID,
+	ttl time.Duration,
+) (bool, error) {
+	result := storage.GetDb().Exec(`
+		INSERT INTO job_leases (name, instance_id, acquired_at)
+		VALUES (?, ?, now())
+		ON CONFLICT (name) DO UPDATE
+		SET instance_id = EXCLUDED.instance_id,
+		    acquired_at = CASE
+		        WHEN job_leases.instance_id = EXCLUDED.instance_id THEN job_leases.acquired_at
+		        ELSE EXCLUDED.acquired_at
+		    END
+		WHERE job_leases.instance_id = EXCLUDED.instance_id
+		   OR NOT EXISTS (
+		        SELECT 1 FROM instances
+		        WHERE instances.id = job_leases.instance_id
+		          AND instances.heartbeat_at > now() - make_interval(secs => ?)
+		   )`,
+		name,
+		instanceID,
+		ttl.Seconds(),
+	)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
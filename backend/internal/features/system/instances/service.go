@@ -0,0 +1,109 @@
+package system_instances
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	heartbeatInterval = 10 * time.Second
+
+	// instance without heartbeat for the TTL is dead, its leases are
+	// taken over and its running jobs are failed
+	instanceTTL = 1 * time.Minute
+
+	// dead instances are kept for a while to be seen in the table
+	staleInstanceTTL = 24 * time.Hour
+)
+
+type InstanceService struct {
+	instanceRepository *InstanceRepository
+	instance           *Instance
+	logger             *slog.Logger
+}
+
+// AliveInstances is a snapshot of instances with fresh heartbeats
+type AliveInstances map[uuid.UUID]bool
+
+// Contains returns false for nil ID, jobs started before instances
+// were tracked have no instance and are treated as orphaned
+func (a AliveInstances) Contains(instanceID *uuid.UUID) bool {
+	return instanceID != nil && a[*instanceID]
+}
+
+func (s *InstanceService) GetInstanceID() uuid.UUID {
+	return s.instance.ID
+}
+
+// Register saves the first heartbeat, so jobs of the instance
+// are not failed before the heartbeat loop is started
+func (s *InstanceService) Register() error {
+	return s.instanceRepository.SaveHeartbeat(s.instance)
+}
+
+// Unregister removes the instance on shutdown, so its leases are taken
+// over and its jobs are failed without waiting for the TTL
+func (s *InstanceService) Unregister() error {
+	return s.instanceRepository.DeleteByID(s.instance.ID)
+}
+
+func (s *InstanceService) GetAliveInstances() (AliveInstances, error) {
+	ids, err := s.instanceRepository.FindAliveIDs(instanceTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	aliveInstances := AliveInstances{}
+	for _, id := range ids {
+		aliveInstances[id] = true
+	}
+
+	return aliveInstances, nil
+}
+
+func (s *InstanceService) NewLease(name string) *Lease {
+	return &Lease{
+		name:            name,
+		instanceService: s,
+	}
+}
+
+// Lease lets one instance run a job at a time, e.g. the backup scheduler.
+// Its holder keeps it while sending heartbeats, so HA pair runs the job
+// on one instance and the other takes it over when the first one dies
+type Lease struct {
+	name            string
+	instanceService *InstanceService
+	isHeld          bool
+}
+
+// Acquire is called before each run of the job. It returns whether the
+// lease is held and whether it was just taken, e.g. to fail jobs left
+// by the previous holder. Lease is not held when the database fails
+func (l *Lease) Acquire() (isHeld bool, isJustAcquired bool) {
+	isAcquired, err := l.instanceService.instanceRepository.TryAcquireLease(
+		l.name,
+		l.instanceService.instance.ID,
+		instanceTTL,
+	)
+	if err != nil {
+		l.instanceService.logger.Error("Failed to acquire job lease", "lease", l.name, "error", err)
+		isAcquired = false
+	}
+
+	isJustAcquired = isAcquired && !l.isHeld
+
+	if isJustAcquired {
+		l.instanceService.logger.Info("Job lease acquired", "lease", l.name)
+	}
+
+	if !isAcquired && l.isHeld {
+		l.instanceService.logger.Info("Job lease lost", "lease", l.name)
+	}
+
+	l.isHeld = isAcquired
+
+	return isAcquired, isJustAcquired
+}
@@ -0,0 +1,79 @@
+package system_instances
+
+import (
+	"postgresus-backend/internal/storage"
+	"postgresus-backend/internal/util/logger"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Lease_WhenHolderHeartbeatExpires_LeaseIsTakenOver(t *testing.T) {
+	firstService := newTestInstanceService()
+	secondService := newTestInstanceService()
+	defer removeTestInstance(firstService)
+	defer removeTestInstance(secondService)
+
+	leaseName := "test-lease-" + uuid.NewString()
+	firstLease := firstService.NewLease(leaseName)
+	secondLease := secondService.NewLease(leaseName)
+
+	isHeld, isJustAcquired := firstLease.Acquire()
+	assert.True(t, isHeld)
+	assert.True(t, isJustAcquired)
+
+	isHeld, isJustAcquired = firstLease.Acquire()
+	assert.True(t, isHeld)
+	assert.False(t, isJustAcquired)
+
+	isHeld, _ = secondLease.Acquire()
+	assert.False(t, isHeld)
+
+	// first instance stops sending heartbeats
+	require.NoError(t, storage.GetDb().Exec(
+		"UPDATE instances SET heartbeat_at = now() - interval '2 minutes' WHERE id = ?",
+		firstService.GetInstanceID(),
+	).Error)
+
+	isHeld, isJustAcquired = secondLease.Acquire()
+	assert.True(t, isHeld)
+	assert.True(t, isJustAcquired)
+
+	isHeld, _ = firstLease.Acquire()
+	assert.False(t, isHeld)
+
+	aliveInstances, err := firstService.GetAliveInstances()
+	require.NoError(t, err)
+
+	firstInstanceID := firstService.GetInstanceID()
+	secondInstanceID := secondService.GetInstanceID()
+	assert.False(t, aliveInstances.Contains(&firstInstanceID))
+	assert.True(t, aliveInstances.Contains(&secondInstanceID))
+	assert.False(t, aliveInstances.Contains(nil))
+}
+
+func newTestInstanceService() *InstanceService {
+	service := &InstanceService{
+		instanceRepository,
+		&Instance{
+			ID:        uuid.New(),
+			Hostname:  "test",
+			IsWorker:  true,
+			StartedAt: time.Now().UTC(),
+		},
+		logger.GetLogger(),
+	}
+
+	if err := service.Register(); err != nil {
+		panic(err)
+	}
+
+	return service
+}
+
+func removeTestInstance(service *InstanceService) {
+	_ = service.Unregister()
+}
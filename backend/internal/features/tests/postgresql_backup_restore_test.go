@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -137,14 +138,17 @@ func testBackupRestoreForVersion(t *testing.T, pgVersion string, port string) {
 
 	// Make backup
 	progressTracker := func(completedMBs float64) {}
-	err = usecases_postgresql_backup.GetCreatePostgresqlBackupUsecase().Execute(
+	backupMetadata, err := usecases_postgresql_backup.GetCreatePostgresqlBackupUsecase().Execute(
+		context.Background(),
 		backupID,
 		backupConfig,
 		backupDb,
-		storage,
+		[]*storages.Storage{storage},
+		nil,
 		progressTracker,
 	)
 	assert.NoError(t, err)
+	assert.NotNil(t, backupMetadata)
 
 	// Create new database
 	newDBName := "restoreddb"
@@ -167,6 +171,8 @@ func testBackupRestoreForVersion(t *testing.T, pgVersion string, port string) {
 		DatabaseID: backupDb.ID,
 		StorageID:  storage.ID,
 		Status:     backups.BackupStatusCompleted,
+		Sha256:     &backupMetadata.Sha256,
+		SizeBytes:  &backupMetadata.SizeBytes,
 		CreatedAt:  time.Now().UTC(),
 		Storage:    storage,
 		Database:   backupDb,
@@ -189,7 +195,7 @@ func testBackupRestoreForVersion(t *testing.T, pgVersion string, port string) {
 
 	// Restore the backup
 	restoreBackupUC := usecases_postgresql_restore.GetRestorePostgresqlBackupUsecase()
-	err = restoreBackupUC.Execute(backupConfig, restore, completedBackup, storage)
+	err = restoreBackupUC.Execute(context.Background(), backupConfig, restore, completedBackup, []*storages.Storage{storage})
 	assert.NoError(t, err)
 
 	// Verify restored table exists
@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"postgresus-backend/internal/config"
+	"postgresus-backend/internal/features/backups/backups"
+	usecases_postgresql_backup "postgresus-backend/internal/features/backups/backups/usecases/postgresql"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"postgresus-backend/internal/features/databases"
+	pgtypes "postgresus-backend/internal/features/databases/databases/postgresql"
+	"postgresus-backend/internal/features/intervals"
+	"postgresus-backend/internal/features/restores/models"
+	usecases_postgresql_restore "postgresus-backend/internal/features/restores/usecases/postgresql"
+	"postgresus-backend/internal/features/storages"
+	local_storage "postgresus-backend/internal/features/storages/models/local"
+	"postgresus-backend/internal/util/period"
+	"postgresus-backend/internal/util/tools"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PhysicalBackupAndRestorePostgresql_DataDirectoryRestored(t *testing.T) {
+	env := config.GetEnv()
+	cases := []struct {
+		name    string
+		version string
+		port    string
+	}{
+		{"PostgreSQL 13", "13", env.TestPostgres13Port},
+		{"PostgreSQL 14", "14", env.TestPostgres14Port},
+		{"PostgreSQL 15", "15", env.TestPostgres15Port},
+		{"PostgreSQL 16", "16", env.TestPostgres16Port},
+		{"PostgreSQL 17", "17", env.TestPostgres17Port},
+		{"PostgreSQL 18", "18", env.TestPostgres18Port},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			testPhysicalBackupRestoreForVersion(t, tc.version, tc.port)
+		})
+	}
+}
+
+func testPhysicalBackupRestoreForVersion(t *testing.T, pgVersion string, port string) {
+	container, err := connectToPostgresContainer(pgVersion, port)
+	require.NoError(t, err)
+	defer func() {
+		if container.DB != nil {
+			container.DB.Close()
+		}
+	}()
+
+	backupDb, backupConfig, storage := createPhysicalBackupTestData(container, pgVersion)
+
+	backupID := uuid.New()
+	backupMetadata, err := usecases_postgresql_backup.GetCreatePostgresqlBackupUsecase().Execute(
+		context.Background(),
+		backupID,
+		backupConfig,
+		backupDb,
+		[]*storages.Storage{storage},
+		nil,
+		func(completedMBs float64) {},
+	)
+	require.NoError(t, err)
+	require.NotNil(t, backupMetadata)
+	defer func() {
+		_ = os.Remove(filepath.Join(config.GetEnv().DataFolder, backupID.String()))
+	}()
+
+	completedBackup := &backups.Backup{
+		ID:           backupID,
+		DatabaseID:   backupDb.ID,
+		StorageID:    storage.ID,
+		Status:       backups.BackupStatusCompleted,
+		BackupMethod: backups_config.BackupMethodPhysical,
+		Sha256:       &backupMetadata.Sha256,
+		SizeBytes:    &backupMetadata.SizeBytes,
+		CreatedAt:    time.Now().UTC(),
+		Storage:      storage,
+		Database:     backupDb,
+	}
+
+	targetDir := filepath.Join(config.GetEnv().RestoreFolder, uuid.New().String())
+	defer func() {
+		_ = os.RemoveAll(targetDir)
+	}()
+
+	restore := models.Restore{
+		ID:                  uuid.New(),
+		Backup:              completedBackup,
+		TargetDataDirectory: &targetDir,
+	}
+
+	err = usecases_postgresql_restore.GetRestorePostgresqlBackupUsecase().Execute(
+		context.Background(),
+		backupConfig,
+		restore,
+		completedBackup,
+		[]*storages.Storage{storage},
+	)
+	require.NoError(t, err)
+
+	pgVersionFile, err := os.ReadFile(filepath.Join(targetDir, "PG_VERSION"))
+	require.NoError(t, err)
+	assert.Equal(t, pgVersion, strings.TrimSpace(string(pgVersionFile)))
+
+	assert.FileExists(t, filepath.Join(targetDir, "backup_label"))
+	assert.DirExists(t, filepath.Join(targetDir, "base"))
+
+	// the directory is cleaned up on failure, so it is limited to the restores folder
+	outsideDir := filepath.Join(t.TempDir(), "cluster")
+	restore.TargetDataDirectory = &outsideDir
+
+	err = usecases_postgresql_restore.GetRestorePostgresqlBackupUsecase().Execute(
+		context.Background(),
+		backupConfig,
+		restore,
+		completedBackup,
+		[]*storages.Storage{storage},
+	)
+	assert.ErrorContains(t, err, "invalid target data directory")
+	assert.NoDirExists(t, outsideDir)
+}
+
+func createPhysicalBackupTestData(
+	container *PostgresContainer,
+	pgVersion string,
+) (*databases.Database, *backups_config.BackupConfig, *storages.Storage) {
+	backupDb := &databases.Database{
+		ID:   uuid.New(),
+		Type: databases.DatabaseTypePostgres,
+		Name: "Test Database",
+		Postgresql: &pgtypes.PostgresqlDatabase{
+			Version:  tools.GetPostgresqlVersionEnum(pgVersion),
+			Host:     container.Host,
+			Port:     container.Port,
+			Username: container.Username,
+			Password: container.Password,
+			Database: &container.Database,
+			IsHttps:  false,
+		},
+	}
+
+	storageID := uuid.New()
+	backupConfig := &backups_config.BackupConfig{
+		DatabaseID:       backupDb.ID,
+		IsBackupsEnabled: true,
+		StorePeriod:      period.PeriodDay,
+		BackupInterval:   &intervals.Interval{Interval: intervals.IntervalDaily},
+		StorageID:        &storageID,
+		BackupMethod:     backups_config.BackupMethodPhysical,
+		CpuCount:         1,
+	}
+
+	storage := &storages.Storage{
+		UserID:       uuid.New(),
+		Type:         storages.StorageTypeLocal,
+		Name:         "Test Storage",
+		LocalStorage: &local_storage.LocalStorage{},
+	}
+
+	return backupDb, backupConfig, storage
+}
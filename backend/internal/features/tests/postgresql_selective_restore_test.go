@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"postgresus-backend/internal/config"
+	"postgresus-backend/internal/features/backups/backups"
+	usecases_postgresql_backup "postgresus-backend/internal/features/backups/backups/usecases/postgresql"
+	backups_config "postgresus-backend/internal/features/backups/config"
+	"postgresus-backend/internal/features/databases"
+	pgtypes "postgresus-backend/internal/features/databases/databases/postgresql"
+	"postgresus-backend/internal/features/intervals"
+	"postgresus-backend/internal/features/restores/models"
+	usecases_postgresql_restore "postgresus-backend/internal/features/restores/usecases/postgresql"
+	"postgresus-backend/internal/features/storages"
+	local_storage "postgresus-backend/internal/features/storages/models/local"
+	"postgresus-backend/internal/util/period"
+	"postgresus-backend/internal/util/tools"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const createShopTablesQuery = `
+DROP VIEW IF EXISTS active_users;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS users;
+
+CREATE TABLE users (id SERIAL PRIMARY KEY, email TEXT NOT NULL);
+CREATE INDEX users_email_idx ON users (email);
+COMMENT ON TABLE users IS 'app users';
+
+CREATE TABLE orders (id SERIAL PRIMARY KEY, user_id INTEGER REFERENCES users (id));
+CREATE VIEW active_users AS SELECT * FROM users;
+
+INSERT INTO users (email) VALUES ('a@example.com'), ('b@example.com');
+INSERT INTO orders (user_id) VALUES (1);
+`
+
+func Test_SelectiveRestorePostgresql_TableRestoredWithIndexesAndSequences(t *testing.T) {
+	env := config.GetEnv()
+	cases := []struct {
+		name    string
+		version string
+		port    string
+	}{
+		{"PostgreSQL 13", "13", env.TestPostgres13Port},
+		{"PostgreSQL 14", "14", env.TestPostgres14Port},
+		{"PostgreSQL 15", "15", env.TestPostgres15Port},
+		{"PostgreSQL 16", "16", env.TestPostgres16Port},
+		{"PostgreSQL 17", "17", env.TestPostgres17Port},
+		{"PostgreSQL 18", "18", env.TestPostgres18Port},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			testSelectiveRestoreForVersion(t, tc.version, tc.port)
+		})
+	}
+}
+
+func testSelectiveRestoreForVersion(t *testing.T, pgVersion string, port string) {
+	container, err := connectToPostgresContainer(pgVersion, port)
+	require.NoError(t, err)
+	defer func() {
+		if container.DB != nil {
+			container.DB.Close()
+		}
+	}()
+
+	// tables of the test are separate from the ones of the full restore test
+	sourceDBName := "selectivesourcedb"
+	targetDBName := "selectivetargetdb"
+
+	for _, dbName := range []string{sourceDBName, targetDBName} {
+		_, err = container.DB.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s;", dbName))
+		require.NoError(t, err)
+
+		_, err = container.DB.Exec(fmt.Sprintf("CREATE DATABASE %s;", dbName))
+		require.NoError(t, err)
+	}
+
+	sourceDB := connectToTestDatabase(t, container, sourceDBName)
+	defer sourceDB.Close()
+
+	_, err = sourceDB.Exec(createShopTablesQuery)
+	require.NoError(t, err)
+
+	pgVersionEnum := tools.GetPostgresqlVersionEnum(pgVersion)
+
+	backupDb := &databases.Database{
+		ID:   uuid.New(),
+		Type: databases.DatabaseTypePostgres,
+		Name: "Test Database",
+		Postgresql: &pgtypes.PostgresqlDatabase{
+			Version:  pgVersionEnum,
+			Host:     container.Host,
+			Port:     container.Port,
+			Username: container.Username,
+			Password: container.Password,
+			Database: &sourceDBName,
+			IsHttps:  false,
+		},
+	}
+
+	storageID := uuid.New()
+	backupConfig := &backups_config.BackupConfig{
+		DatabaseID:       backupDb.ID,
+		IsBackupsEnabled: true,
+		StorePeriod:      period.PeriodDay,
+		BackupInterval:   &intervals.Interval{Interval: intervals.IntervalDaily},
+		StorageID:        &storageID,
+		CpuCount:         1,
+	}
+
+	storage := &storages.Storage{
+		UserID:       uuid.New(),
+		Type:         storages.StorageTypeLocal,
+		Name:         "Test Storage",
+		LocalStorage: &local_storage.LocalStorage{},
+	}
+
+	backupID := uuid.New()
+	backupMetadata, err := usecases_postgresql_backup.GetCreatePostgresqlBackupUsecase().Execute(
+		context.Background(),
+		backupID,
+		backupConfig,
+		backupDb,
+		[]*storages.Storage{storage},
+		nil,
+		func(completedMBs float64) {},
+	)
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Remove(filepath.Join(config.GetEnv().DataFolder, backupID.String()))
+	}()
+
+	completedBackup := &backups.Backup{
+		ID:         backupID,
+		DatabaseID: backupDb.ID,
+		StorageID:  storage.ID,
+		Status:     backups.BackupStatusCompleted,
+		Sha256:     &backupMetadata.Sha256,
+		SizeBytes:  &backupMetadata.SizeBytes,
+		CreatedAt:  time.Now().UTC(),
+		Storage:    storage,
+		Database:   backupDb,
+	}
+
+	restore := models.Restore{
+		ID:     uuid.New(),
+		Backup: completedBackup,
+		Postgresql: &pgtypes.PostgresqlDatabase{
+			Version:  pgVersionEnum,
+			Host:     container.Host,
+			Port:     container.Port,
+			Username: container.Username,
+			Password: container.Password,
+			Database: &targetDBName,
+			IsHttps:  false,
+		},
+		RestoreFilters: models.RestoreFilters{Tables: []string{"public.users"}},
+	}
+
+	err = usecases_postgresql_restore.GetRestorePostgresqlBackupUsecase().Execute(
+		context.Background(),
+		backupConfig,
+		restore,
+		completedBackup,
+		[]*storages.Storage{storage},
+	)
+	require.NoError(t, err)
+
+	targetDB := connectToTestDatabase(t, container, targetDBName)
+	defer targetDB.Close()
+
+	var isIndexRestored bool
+	err = targetDB.Get(
+		&isIndexRestored,
+		"SELECT EXISTS (SELECT FROM pg_indexes WHERE indexname = 'users_email_idx')",
+	)
+	require.NoError(t, err)
+	assert.True(t, isIndexRestored, "index of the table should be restored")
+
+	var tableComment string
+	err = targetDB.Get(&tableComment, "SELECT obj_description('public.users'::regclass)")
+	require.NoError(t, err)
+	assert.Equal(t, "app users", tableComment)
+
+	// sequence is restored with its value, so new rows do not clash
+	var nextID int
+	err = targetDB.Get(&nextID, "INSERT INTO users (email) VALUES ('c@example.com') RETURNING id")
+	require.NoError(t, err)
+	assert.Equal(t, 3, nextID)
+
+	var isOrdersRestored bool
+	err = targetDB.Get(
+		&isOrdersRestored,
+		"SELECT EXISTS (SELECT FROM information_schema.tables WHERE table_name = 'orders')",
+	)
+	require.NoError(t, err)
+	assert.False(t, isOrdersRestored, "other tables should not be restored")
+}
+
+func connectToTestDatabase(t *testing.T, container *PostgresContainer, dbName string) *sqlx.DB {
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		container.Host, container.Port, container.Username, container.Password, dbName)
+
+	db, err := sqlx.Connect("postgres", dsn)
+	require.NoError(t, err)
+
+	return db
+}
@@ -0,0 +1,106 @@
+package cancellation_utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// cancel request stored by another instance is seen within this interval
+const cancelRequestPollInterval = 5 * time.Second
+
+var ErrCancelledByUser = errors.New("cancelled by user")
+
+// CancellationTracker keeps cancel functions of running jobs, so a job
+// started in a detached goroutine can be stopped by its ID
+type CancellationTracker struct {
+	mu      sync.Mutex
+	cancels map[uuid.UUID]context.CancelCauseFunc
+}
+
+func NewCancellationTracker() *CancellationTracker {
+	return &CancellationTracker{
+		cancels: make(map[uuid.UUID]context.CancelCauseFunc),
+	}
+}
+
+// Register returns context of the job and a function
+// which should be called when the job is finished
+func (t *CancellationTracker) Register(jobID uuid.UUID) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	t.mu.Lock()
+	t.cancels[jobID] = cancel
+	t.mu.Unlock()
+
+	return ctx, func() {
+		t.mu.Lock()
+		delete(t.cancels, jobID)
+		t.mu.Unlock()
+
+		cancel(nil)
+	}
+}
+
+// Cancel stops the job, false is returned if
+// the job is not running in this process
+func (t *CancellationTracker) Cancel(jobID uuid.UUID) bool {
+	t.mu.Lock()
+	cancel, isFound := t.cancels[jobID]
+	t.mu.Unlock()
+
+	if !isFound {
+		return false
+	}
+
+	cancel(ErrCancelledByUser)
+	return true
+}
+
+// WatchCancelRequest cancels the job once isCancelRequested returns true,
+// so the job is stopped when the request is received by an instance which
+// does not run it. The request is checked right away, since the job is
+// stored before it is registered and may be cancelled in between. It
+// returns when the job context is done
+func (t *CancellationTracker) WatchCancelRequest(
+	ctx context.Context,
+	jobID uuid.UUID,
+	isCancelRequested func() (bool, error),
+) {
+	t.watchCancelRequest(ctx, jobID, isCancelRequested, cancelRequestPollInterval)
+}
+
+func (t *CancellationTracker) watchCancelRequest(
+	ctx context.Context,
+	jobID uuid.UUID,
+	isCancelRequested func() (bool, error),
+	pollInterval time.Duration,
+) {
+	// failed check is retried on the next tick
+	if isRequested, err := isCancelRequested(); err == nil && isRequested {
+		t.Cancel(jobID)
+		return
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if isRequested, err := isCancelRequested(); err == nil && isRequested {
+				t.Cancel(jobID)
+				return
+			}
+		}
+	}
+}
+
+func IsCancelledByUser(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrCancelledByUser)
+}
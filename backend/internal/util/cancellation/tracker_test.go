@@ -0,0 +1,78 @@
+package cancellation_utils
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WatchCancelRequest_WhenRequestIsStored_JobCancelled(t *testing.T) {
+	tracker := NewCancellationTracker()
+	jobID := uuid.New()
+
+	ctx, unregister := tracker.Register(jobID)
+	defer unregister()
+
+	var checkCount atomic.Int32
+	isCancelRequested := func() (bool, error) {
+		return checkCount.Add(1) >= 3, nil
+	}
+
+	go tracker.watchCancelRequest(ctx, jobID, isCancelRequested, time.Millisecond)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("job is not cancelled")
+	}
+
+	assert.True(t, IsCancelledByUser(ctx))
+}
+
+func Test_WatchCancelRequest_WhenRequestedBeforeRegister_JobCancelledRightAway(t *testing.T) {
+	tracker := NewCancellationTracker()
+	jobID := uuid.New()
+
+	ctx, unregister := tracker.Register(jobID)
+	defer unregister()
+
+	go tracker.watchCancelRequest(ctx, jobID, func() (bool, error) {
+		return true, nil
+	}, time.Hour)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("job is not cancelled")
+	}
+
+	assert.True(t, IsCancelledByUser(ctx))
+}
+
+func Test_WatchCancelRequest_WhenJobFinished_WatchStopped(t *testing.T) {
+	tracker := NewCancellationTracker()
+	jobID := uuid.New()
+
+	ctx, unregister := tracker.Register(jobID)
+
+	isStopped := make(chan struct{})
+	go func() {
+		tracker.watchCancelRequest(ctx, jobID, func() (bool, error) {
+			return false, nil
+		}, time.Millisecond)
+		close(isStopped)
+	}()
+
+	unregister()
+
+	select {
+	case <-isStopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch is not stopped")
+	}
+
+	assert.False(t, IsCancelledByUser(ctx))
+}
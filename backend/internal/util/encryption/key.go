@@ -0,0 +1,54 @@
+package encryption_utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// WrapKey encrypts a data key with the master key, so
+// it can be stored next to the data it protects
+func WrapKey(masterKey []byte, dataKey []byte) (string, error) {
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, dataKey, nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func UnwrapKey(masterKey []byte, wrappedKey string) ([]byte, error) {
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(wrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode wrapped key: %w", err)
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+
+	dataKey, err := aead.Open(
+		nil,
+		sealed[:aead.NonceSize()],
+		sealed[aead.NonceSize():],
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key, master key may be wrong: %w", err)
+	}
+
+	return dataKey, nil
+}
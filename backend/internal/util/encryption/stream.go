@@ -0,0 +1,259 @@
+package encryption_utils
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted stream layout:
+//
+//	magic (8 bytes) | nonce prefix (7 bytes) | chunk 1 | chunk 2 | ... | final chunk
+//
+// Every chunk is sealed with AES-256-GCM using nonce = prefix | counter (4 bytes) | final flag (1 byte).
+// The final flag prevents an attacker (or a truncated upload) from silently cutting the stream
+// at a chunk boundary: a stream that does not end with a chunk sealed as final is rejected.
+const (
+	KeySize = 32
+
+	streamMagic     = "PGSENC01"
+	noncePrefixSize = 7
+	plainChunkSize  = 64 * 1024
+	sealedChunkSize = plainChunkSize + 16
+)
+
+var ErrTruncatedStream = errors.New("encrypted stream is truncated")
+
+type encryptingWriter struct {
+	writer      io.Writer
+	aead        cipher.AEAD
+	noncePrefix []byte
+	counter     uint32
+	buffer      []byte
+	isClosed    bool
+}
+
+type decryptingReader struct {
+	reader      *bufio.Reader
+	aead        cipher.AEAD
+	noncePrefix []byte
+	counter     uint32
+	sealed      []byte
+	plain       []byte
+	isFinished  bool
+}
+
+type decryptingReadCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+// NewEncryptingWriter returns a writer that encrypts everything written to it
+// and writes the ciphertext to w. Close must be called to flush the final chunk,
+// it does not close w.
+func NewEncryptingWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	noncePrefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(noncePrefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	if _, err := w.Write([]byte(streamMagic)); err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(noncePrefix); err != nil {
+		return nil, err
+	}
+
+	return &encryptingWriter{
+		writer:      w,
+		aead:        aead,
+		noncePrefix: noncePrefix,
+		buffer:      make([]byte, 0, plainChunkSize),
+	}, nil
+}
+
+// NewDecryptingReader returns a reader that decrypts a stream produced by NewEncryptingWriter
+func NewDecryptingReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(streamMagic)+noncePrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %w", err)
+	}
+
+	if string(header[:len(streamMagic)]) != streamMagic {
+		return nil, errors.New("stream is not encrypted by Postgresus")
+	}
+
+	return &decryptingReader{
+		reader:      bufio.NewReaderSize(r, sealedChunkSize),
+		aead:        aead,
+		noncePrefix: header[len(streamMagic):],
+		sealed:      make([]byte, sealedChunkSize),
+	}, nil
+}
+
+// NewDecryptingReadCloser is the same as NewDecryptingReader, but closes
+// the underlying reader on Close
+func NewDecryptingReadCloser(rc io.ReadCloser, key []byte) (io.ReadCloser, error) {
+	reader, err := NewDecryptingReader(rc, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptingReadCloser{reader, rc}, nil
+}
+
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	if w.isClosed {
+		return 0, errors.New("write to closed encrypting writer")
+	}
+
+	written := 0
+
+	for len(p) > 0 {
+		// a full buffer is flushed only when more data arrives, so
+		// the last chunk is always sealed as final on Close
+		if len(w.buffer) == plainChunkSize {
+			if err := w.sealChunk(false); err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(w.buffer[len(w.buffer):plainChunkSize], p)
+		w.buffer = w.buffer[:len(w.buffer)+n]
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+func (w *encryptingWriter) Close() error {
+	if w.isClosed {
+		return nil
+	}
+
+	w.isClosed = true
+
+	return w.sealChunk(true)
+}
+
+func (w *encryptingWriter) sealChunk(isFinal bool) error {
+	nonce := buildNonce(w.noncePrefix, w.counter, isFinal)
+	sealed := w.aead.Seal(nil, nonce, w.buffer, nil)
+
+	if _, err := w.writer.Write(sealed); err != nil {
+		return err
+	}
+
+	w.counter++
+	w.buffer = w.buffer[:0]
+
+	return nil
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.isFinished {
+			return 0, io.EOF
+		}
+
+		if err := r.openChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+
+	return n, nil
+}
+
+func (r *decryptingReader) openChunk() error {
+	n, err := io.ReadFull(r.reader, r.sealed)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return ErrTruncatedStream
+		}
+
+		return err
+	}
+
+	isFinal := err == io.ErrUnexpectedEOF
+	if !isFinal {
+		if _, peekErr := r.reader.Peek(1); peekErr == io.EOF {
+			isFinal = true
+		} else if peekErr != nil {
+			return peekErr
+		}
+	}
+
+	nonce := buildNonce(r.noncePrefix, r.counter, isFinal)
+	plain, openErr := r.aead.Open(r.sealed[:0], nonce, r.sealed[:n], nil)
+	if openErr != nil {
+		if isFinal {
+			return ErrTruncatedStream
+		}
+
+		return fmt.Errorf("failed to decrypt chunk %d: %w", r.counter, openErr)
+	}
+
+	r.counter++
+	r.plain = plain
+	r.isFinished = isFinal
+
+	return nil
+}
+
+func (rc *decryptingReadCloser) Close() error {
+	return rc.closer.Close()
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes", KeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func buildNonce(prefix []byte, counter uint32, isFinal bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+
+	if isFinal {
+		return append(nonce, 1)
+	}
+
+	return append(nonce, 0)
+}
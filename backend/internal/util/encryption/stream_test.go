@@ -0,0 +1,115 @@
+package encryption_utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EncryptAndDecryptStream_DataRestored(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	sizes := []int{0, 1, plainChunkSize - 1, plainChunkSize, plainChunkSize + 1, 3*plainChunkSize + 17}
+
+	for _, size := range sizes {
+		plain := randomBytes(t, size)
+
+		encrypted := encrypt(t, key, plain)
+		decrypted, err := decrypt(key, encrypted)
+
+		assert.NoError(t, err, "size %d", size)
+		assert.Equal(t, plain, decrypted, "size %d", size)
+	}
+}
+
+func Test_DecryptStream_WhenTruncatedAtChunkBoundary_ReturnsError(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	encrypted := encrypt(t, key, randomBytes(t, 2*plainChunkSize+100))
+	headerSize := len(streamMagic) + noncePrefixSize
+
+	_, err = decrypt(key, encrypted[:headerSize+sealedChunkSize])
+	assert.ErrorIs(t, err, ErrTruncatedStream)
+
+	_, err = decrypt(key, encrypted[:len(encrypted)-10])
+	assert.Error(t, err)
+}
+
+func Test_DecryptStream_WhenTamperedOrWrongKey_ReturnsError(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	encrypted := encrypt(t, key, randomBytes(t, plainChunkSize+100))
+
+	tampered := bytes.Clone(encrypted)
+	tampered[len(tampered)/2] ^= 0xFF
+	_, err = decrypt(key, tampered)
+	assert.Error(t, err)
+
+	otherKey, err := GenerateKey()
+	require.NoError(t, err)
+	_, err = decrypt(otherKey, encrypted)
+	assert.Error(t, err)
+}
+
+func Test_WrapAndUnwrapKey_KeyRestored(t *testing.T) {
+	masterKey, err := GenerateKey()
+	require.NoError(t, err)
+
+	dataKey, err := GenerateKey()
+	require.NoError(t, err)
+
+	wrapped, err := WrapKey(masterKey, dataKey)
+	require.NoError(t, err)
+
+	unwrapped, err := UnwrapKey(masterKey, wrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+
+	otherMasterKey, err := GenerateKey()
+	require.NoError(t, err)
+	_, err = UnwrapKey(otherMasterKey, wrapped)
+	assert.Error(t, err)
+}
+
+func encrypt(t *testing.T, key []byte, plain []byte) []byte {
+	var encrypted bytes.Buffer
+
+	writer, err := NewEncryptingWriter(&encrypted, key)
+	require.NoError(t, err)
+
+	// write in uneven pieces to cover partial chunk buffering
+	for len(plain) > 0 {
+		n := min(len(plain), 10_000)
+		_, err := writer.Write(plain[:n])
+		require.NoError(t, err)
+		plain = plain[n:]
+	}
+
+	require.NoError(t, writer.Close())
+
+	return encrypted.Bytes()
+}
+
+func decrypt(key []byte, encrypted []byte) ([]byte, error) {
+	reader, err := NewDecryptingReader(bytes.NewReader(encrypted), key)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(reader)
+}
+
+func randomBytes(t *testing.T, size int) []byte {
+	data := make([]byte, size)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+
+	return data
+}
@@ -0,0 +1,113 @@
+package files_utils
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+const fanOutChunkSize = 32 * 1024
+
+var ErrAllConsumersFailed = errors.New("all consumers of the stream failed")
+
+// FanOut streams the source to all consumers in parallel, each consumer reads
+// its own copy of the data. Consumer which fails stops receiving data while
+// the others go on. When every consumer failed, the source is closed with
+// error, so its writer does not block. Errors are returned in consumers order
+func FanOut(source *io.PipeReader, consumers []func(reader io.Reader) error) []error {
+	pipeWriters := make([]*io.PipeWriter, len(consumers))
+	writeErrs := make([]error, len(consumers))
+	consumeErrChs := make([]chan error, len(consumers))
+
+	for i, consume := range consumers {
+		pipeReader, pipeWriter := io.Pipe()
+		pipeWriters[i] = pipeWriter
+		consumeErrChs[i] = make(chan error, 1)
+
+		go func(consumeErrCh chan<- error) {
+			err := consume(pipeReader)
+
+			// unblocks the fan-out if the consumer stopped reading early
+			_ = pipeReader.CloseWithError(errors.Join(err, io.ErrClosedPipe))
+			consumeErrCh <- err
+		}(consumeErrChs[i])
+	}
+
+	readErr := fanOutChunks(source, pipeWriters, writeErrs)
+
+	for _, pipeWriter := range pipeWriters {
+		_ = pipeWriter.CloseWithError(readErr)
+	}
+
+	if readErr != nil {
+		_ = source.CloseWithError(readErr)
+	}
+
+	errs := make([]error, len(consumers))
+	for i, consumeErrCh := range consumeErrChs {
+		errs[i] = <-consumeErrCh
+
+		if errs[i] == nil {
+			errs[i] = writeErrs[i]
+		}
+
+		if errs[i] == nil && readErr != nil {
+			errs[i] = readErr
+		}
+	}
+
+	return errs
+}
+
+// fanOutChunks copies the source to the writers until EOF. Buffer is reused,
+// so every chunk is written to all writers before the next one is read
+func fanOutChunks(source io.Reader, pipeWriters []*io.PipeWriter, writeErrs []error) error {
+	buf := make([]byte, fanOutChunkSize)
+
+	for {
+		n, err := source.Read(buf)
+
+		if n > 0 {
+			var wg sync.WaitGroup
+
+			for i, pipeWriter := range pipeWriters {
+				if writeErrs[i] != nil {
+					continue
+				}
+
+				wg.Add(1)
+				go func(i int, pipeWriter *io.PipeWriter) {
+					defer wg.Done()
+
+					if _, err := pipeWriter.Write(buf[:n]); err != nil {
+						writeErrs[i] = err
+					}
+				}(i, pipeWriter)
+			}
+
+			wg.Wait()
+
+			if !hasNilError(writeErrs) {
+				return ErrAllConsumersFailed
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func hasNilError(errs []error) bool {
+	for _, err := range errs {
+		if err == nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,66 @@
+package files_utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FanOut_WhenOneConsumerFails_OthersReceiveAllData(t *testing.T) {
+	data := bytes.Repeat([]byte("postgresus"), 20_000)
+
+	sourceReader, sourceWriter := io.Pipe()
+	go func() {
+		_, err := sourceWriter.Write(data)
+		_ = sourceWriter.CloseWithError(err)
+	}()
+
+	var firstCopy, secondCopy bytes.Buffer
+
+	errs := FanOut(sourceReader, []func(reader io.Reader) error{
+		func(reader io.Reader) error {
+			_, err := io.Copy(&firstCopy, reader)
+			return err
+		},
+		func(reader io.Reader) error {
+			// storage rejects the upload after the first chunk
+			_, _ = reader.Read(make([]byte, 1024))
+			return errors.New("storage is full")
+		},
+		func(reader io.Reader) error {
+			_, err := io.Copy(&secondCopy, reader)
+			return err
+		},
+	})
+
+	require.Len(t, errs, 3)
+	assert.NoError(t, errs[0])
+	assert.EqualError(t, errs[1], "storage is full")
+	assert.NoError(t, errs[2])
+
+	assert.Equal(t, data, firstCopy.Bytes())
+	assert.Equal(t, data, secondCopy.Bytes())
+}
+
+func Test_FanOut_WhenAllConsumersFail_SourceWriterIsUnblocked(t *testing.T) {
+	sourceReader, sourceWriter := io.Pipe()
+
+	writeErrCh := make(chan error, 1)
+	go func() {
+		_, err := sourceWriter.Write(bytes.Repeat([]byte("x"), 1024*1024))
+		writeErrCh <- err
+	}()
+
+	errs := FanOut(sourceReader, []func(reader io.Reader) error{
+		func(reader io.Reader) error {
+			return errors.New("connection refused")
+		},
+	})
+
+	assert.EqualError(t, errs[0], "connection refused")
+	assert.ErrorIs(t, <-writeErrCh, ErrAllConsumersFailed)
+}
@@ -0,0 +1,69 @@
+package files_utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveDirInside returns the cleaned absolute path of the directory if
+// it is below the root one. Symlinks of the existing part of the path are
+// resolved, so a link inside the root cannot point the directory outside
+func ResolveDirInside(rootDir string, dir string) (string, error) {
+	dir = filepath.Clean(dir)
+	if !filepath.IsAbs(dir) {
+		return "", fmt.Errorf("directory must be an absolute path: %s", dir)
+	}
+
+	resolvedRootDir, err := filepath.EvalSymlinks(rootDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory %s: %w", rootDir, err)
+	}
+
+	resolvedDir, err := resolveExistingPart(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if resolvedDir == resolvedRootDir || !isInsideDir(resolvedRootDir, resolvedDir) {
+		return "", fmt.Errorf("directory must be inside %s: %s", rootDir, dir)
+	}
+
+	return dir, nil
+}
+
+// resolveExistingPart resolves symlinks of the longest existing
+// parent and appends the rest of the path as it is
+func resolveExistingPart(path string) (string, error) {
+	existingPath := path
+	missingParts := []string{}
+
+	for {
+		resolvedPath, err := filepath.EvalSymlinks(existingPath)
+		if err == nil {
+			return filepath.Join(append([]string{resolvedPath}, missingParts...)...), nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("failed to resolve directory %s: %w", path, err)
+		}
+
+		parentPath := filepath.Dir(existingPath)
+		if parentPath == existingPath {
+			return path, nil
+		}
+
+		missingParts = append([]string{filepath.Base(existingPath)}, missingParts...)
+		existingPath = parentPath
+	}
+}
+
+func isInsideDir(dir string, path string) bool {
+	relativePath, err := filepath.Rel(dir, path)
+
+	return err == nil &&
+		relativePath != ".." &&
+		!strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,40 @@
+package files_utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ResolveDirInside_WhenDirIsBelowRoot_DirReturned(t *testing.T) {
+	rootDir := t.TempDir()
+
+	dir, err := ResolveDirInside(rootDir, filepath.Join(rootDir, "restores", "..", "cluster-1"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(rootDir, "cluster-1"), dir)
+}
+
+func Test_ResolveDirInside_WhenDirIsOutsideRoot_ReturnsError(t *testing.T) {
+	rootDir := t.TempDir()
+	outsideDir := t.TempDir()
+
+	require.NoError(t, os.Symlink(outsideDir, filepath.Join(rootDir, "link")))
+
+	testCases := map[string]string{
+		"root itself":   rootDir,
+		"relative path": "cluster-1",
+		"parent escape": filepath.Join(rootDir, "..", "cluster-1"),
+		"other dir":     filepath.Join(outsideDir, "cluster-1"),
+		"through link":  filepath.Join(rootDir, "link", "cluster-1"),
+	}
+
+	for name, dir := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ResolveDirInside(rootDir, dir)
+			assert.Error(t, err)
+		})
+	}
+}
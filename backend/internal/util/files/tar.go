@@ -0,0 +1,240 @@
+package files_utils
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractTar unpacks tar stream into the target directory. Entries
+// which would be written outside of the directory are rejected, either
+// by their names, by targets of symlinks or through symlinks created by
+// earlier entries
+func ExtractTar(reader io.Reader, targetDir string) error {
+	tarReader := tar.NewReader(reader)
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+
+		entryPath, err := resolveEntryPath(targetDir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		if err := checkNoSymlinksOnPath(targetDir, entryPath); err != nil {
+			return err
+		}
+
+		mode := os.FileMode(header.Mode).Perm()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(entryPath, mode|0700); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", entryPath, err)
+			}
+		case tar.TypeReg:
+			if err := extractFile(tarReader, entryPath, mode); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := checkSymlinkTarget(targetDir, entryPath, header.Linkname); err != nil {
+				return err
+			}
+
+			if err := os.MkdirAll(filepath.Dir(entryPath), 0700); err != nil {
+				return fmt.Errorf("failed to create directory for %s: %w", entryPath, err)
+			}
+
+			if err := os.Symlink(header.Linkname, entryPath); err != nil {
+				return fmt.Errorf("failed to create symlink %s: %w", entryPath, err)
+			}
+		default:
+			return fmt.Errorf("unsupported tar entry type %q for %s", header.Typeflag, header.Name)
+		}
+	}
+}
+
+// ExtractTarFile unpacks a single regular file of the tar stream into the
+// target directory, other entries are skipped without being written
+func ExtractTarFile(reader io.Reader, name string, targetDir string) error {
+	tarReader := tar.NewReader(reader)
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("file %s is not found in tar", name)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+
+		if header.Name != name || header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		entryPath, err := resolveEntryPath(targetDir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		if err := checkNoSymlinksOnPath(targetDir, entryPath); err != nil {
+			return err
+		}
+
+		return extractFile(tarReader, entryPath, os.FileMode(header.Mode).Perm())
+	}
+}
+
+// WriteTar packs regular files and directories of the source directory into
+// tar stream. Names are relative to the directory, so ExtractTar restores
+// the same layout into any target directory
+func WriteTar(writer io.Writer, sourceDir string) error {
+	tarWriter := tar.NewWriter(writer)
+
+	err := filepath.WalkDir(sourceDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+
+		if relativePath == "." {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return fmt.Errorf("unsupported file type for %s", path)
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+
+		header.Name = filepath.ToSlash(relativePath)
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write tar header for %s: %w", path, err)
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		return writeFileToTar(tarWriter, path)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pack directory %s: %w", sourceDir, err)
+	}
+
+	return tarWriter.Close()
+}
+
+func writeFileToTar(tarWriter *tar.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	if _, err := io.Copy(tarWriter, file); err != nil {
+		return fmt.Errorf("failed to write %s to tar: %w", path, err)
+	}
+
+	return nil
+}
+
+func extractFile(reader io.Reader, path string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+
+	return file.Close()
+}
+
+func resolveEntryPath(targetDir string, name string) (string, error) {
+	entryPath := filepath.Join(targetDir, name)
+
+	if !isInsideDir(targetDir, entryPath) {
+		return "", fmt.Errorf("tar entry %s points outside of target directory", name)
+	}
+
+	return entryPath, nil
+}
+
+// checkSymlinkTarget allows only relative targets inside the target
+// directory, the server data directory has no links pointing outside
+func checkSymlinkTarget(targetDir string, entryPath string, linkname string) error {
+	if filepath.IsAbs(linkname) {
+		return fmt.Errorf("symlink %s points to absolute path %s", entryPath, linkname)
+	}
+
+	linkPath := filepath.Join(filepath.Dir(entryPath), linkname)
+	if !isInsideDir(targetDir, linkPath) {
+		return fmt.Errorf("symlink %s points outside of target directory", entryPath)
+	}
+
+	return nil
+}
+
+// checkNoSymlinksOnPath rejects writing through existing symlinks, e.g.
+// a symlink entry to a directory followed by a file entry below it. The
+// names are checked lexically only, so a link would let the file escape
+func checkNoSymlinksOnPath(targetDir string, entryPath string) error {
+	relativePath, err := filepath.Rel(targetDir, entryPath)
+	if err != nil {
+		return err
+	}
+
+	currentPath := targetDir
+	for _, part := range strings.Split(relativePath, string(filepath.Separator)) {
+		currentPath = filepath.Join(currentPath, part)
+
+		info, err := os.Lstat(currentPath)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", currentPath, err)
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("tar entry %s is written through symlink %s", entryPath, currentPath)
+		}
+	}
+
+	return nil
+}
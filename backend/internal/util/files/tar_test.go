@@ -0,0 +1,132 @@
+package files_utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WriteAndExtractTar_DirectoryRestored(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "toc.dat"), []byte("toc"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "nested"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "nested", "3456.dat.gz"), []byte("data"), 0600))
+
+	var archive bytes.Buffer
+	require.NoError(t, WriteTar(&archive, sourceDir))
+
+	targetDir := t.TempDir()
+	require.NoError(t, ExtractTar(&archive, targetDir))
+
+	toc, err := os.ReadFile(filepath.Join(targetDir, "toc.dat"))
+	require.NoError(t, err)
+	assert.Equal(t, "toc", string(toc))
+
+	data, err := os.ReadFile(filepath.Join(targetDir, "nested", "3456.dat.gz"))
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+func Test_ExtractTarFile_OnlyRequestedFileExtracted(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "3456.dat.gz"), []byte("data"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "toc.dat"), []byte("toc"), 0600))
+
+	var archive bytes.Buffer
+	require.NoError(t, WriteTar(&archive, sourceDir))
+
+	targetDir := t.TempDir()
+	require.NoError(t, ExtractTarFile(bytes.NewReader(archive.Bytes()), "toc.dat", targetDir))
+
+	toc, err := os.ReadFile(filepath.Join(targetDir, "toc.dat"))
+	require.NoError(t, err)
+	assert.Equal(t, "toc", string(toc))
+
+	assert.NoFileExists(t, filepath.Join(targetDir, "3456.dat.gz"))
+
+	err = ExtractTarFile(bytes.NewReader(archive.Bytes()), "missing.dat", t.TempDir())
+	assert.ErrorContains(t, err, "missing.dat")
+}
+
+func Test_ExtractTar_WhenSymlinkPointsOutside_ReturnsError(t *testing.T) {
+	testCases := map[string]string{
+		"absolute target": "/etc",
+		"escaping target": "../../outside",
+	}
+
+	for name, linkname := range testCases {
+		t.Run(name, func(t *testing.T) {
+			archive := createTar(t, &tar.Header{
+				Name:     "pg_wal",
+				Typeflag: tar.TypeSymlink,
+				Linkname: linkname,
+			})
+
+			targetDir := t.TempDir()
+			assert.ErrorContains(t, ExtractTar(archive, targetDir), "symlink")
+
+			_, err := os.Lstat(filepath.Join(targetDir, "pg_wal"))
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func Test_ExtractTar_WhenEntryIsWrittenThroughSymlink_ReturnsError(t *testing.T) {
+	outsideDir := t.TempDir()
+	targetDir := t.TempDir()
+
+	// link is inside the target directory by its target, but
+	// the directory it points to is replaced by a link outside
+	require.NoError(t, os.Symlink(outsideDir, filepath.Join(targetDir, "base")))
+
+	archive := createTar(t, &tar.Header{
+		Name:     "base/PG_VERSION",
+		Typeflag: tar.TypeReg,
+		Mode:     0600,
+		Size:     2,
+	})
+
+	assert.ErrorContains(t, ExtractTar(archive, targetDir), "through symlink")
+	assert.NoFileExists(t, filepath.Join(outsideDir, "PG_VERSION"))
+}
+
+func Test_ExtractTar_WhenSymlinkPointsInside_SymlinkCreated(t *testing.T) {
+	archive := createTar(
+		t,
+		&tar.Header{Name: "pg_wal_data", Typeflag: tar.TypeDir, Mode: 0700},
+		&tar.Header{Name: "pg_wal", Typeflag: tar.TypeSymlink, Linkname: "pg_wal_data"},
+	)
+
+	targetDir := t.TempDir()
+	require.NoError(t, ExtractTar(archive, targetDir))
+
+	linkname, err := os.Readlink(filepath.Join(targetDir, "pg_wal"))
+	require.NoError(t, err)
+	assert.Equal(t, "pg_wal_data", linkname)
+}
+
+// createTar writes the headers, regular files are filled with "x"
+func createTar(t *testing.T, headers ...*tar.Header) *bytes.Buffer {
+	var archive bytes.Buffer
+	tarWriter := tar.NewWriter(&archive)
+
+	for _, header := range headers {
+		require.NoError(t, tarWriter.WriteHeader(header))
+
+		if header.Typeflag == tar.TypeReg {
+			_, err := tarWriter.Write(bytes.Repeat([]byte("x"), int(header.Size)))
+			require.NoError(t, err)
+		}
+	}
+
+	require.NoError(t, tarWriter.Close())
+
+	return &archive
+}
@@ -18,32 +18,64 @@ const (
 	PeriodForever Period = "FOREVER"
 )
 
-// ToDuration converts Period to time.Duration
-func (p Period) ToDuration() time.Duration {
+func (p Period) IsValid() bool {
+	switch p {
+	case PeriodDay, PeriodWeek, PeriodMonth, Period3Month, Period6Month, PeriodYear,
+		Period2Years, Period3Years, Period4Years, Period5Years, PeriodForever:
+		return true
+	default:
+		return false
+	}
+}
+
+// SubtractFrom returns the start of the period which ends at t. Months and
+// years are calendar ones, day of month is clamped to the end of a shorter
+// month (e.g. month before March 31 starts on February 28). Forever period
+// has no start, zero time is returned
+func (p Period) SubtractFrom(t time.Time) time.Time {
 	switch p {
 	case PeriodDay:
-		return 24 * time.Hour
+		return t.AddDate(0, 0, -1)
 	case PeriodWeek:
-		return 7 * 24 * time.Hour
+		return t.AddDate(0, 0, -7)
 	case PeriodMonth:
-		return 30 * 24 * time.Hour
+		return subtractMonths(t, 1)
 	case Period3Month:
-		return 90 * 24 * time.Hour
+		return subtractMonths(t, 3)
 	case Period6Month:
-		return 180 * 24 * time.Hour
+		return subtractMonths(t, 6)
 	case PeriodYear:
-		return 365 * 24 * time.Hour
+		return subtractMonths(t, 12)
 	case Period2Years:
-		return 2 * 365 * 24 * time.Hour
+		return subtractMonths(t, 2*12)
 	case Period3Years:
-		return 3 * 365 * 24 * time.Hour
+		return subtractMonths(t, 3*12)
 	case Period4Years:
-		return 4 * 365 * 24 * time.Hour
+		return subtractMonths(t, 4*12)
 	case Period5Years:
-		return 5 * 365 * 24 * time.Hour
+		return subtractMonths(t, 5*12)
 	case PeriodForever:
-		return 0
+		return time.Time{}
 	default:
 		panic("unknown period: " + string(p))
 	}
 }
+
+// subtractMonths does not normalize overflowing day as AddDate does,
+// which would turn February 31 into March 3
+func subtractMonths(t time.Time, months int) time.Time {
+	firstOfMonth := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	targetMonth := firstOfMonth.AddDate(0, -months, 0)
+	daysInTargetMonth := targetMonth.AddDate(0, 1, -1).Day()
+
+	return time.Date(
+		targetMonth.Year(),
+		targetMonth.Month(),
+		min(t.Day(), daysInTargetMonth),
+		t.Hour(),
+		t.Minute(),
+		t.Second(),
+		t.Nanosecond(),
+		t.Location(),
+	)
+}
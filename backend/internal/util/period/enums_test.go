@@ -0,0 +1,39 @@
+package period
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SubtractFrom_MonthsAreCalendarOnes(t *testing.T) {
+	endOfMarch := time.Date(2024, time.March, 31, 10, 30, 0, 0, time.UTC)
+
+	assert.Equal(
+		t,
+		time.Date(2024, time.February, 29, 10, 30, 0, 0, time.UTC),
+		PeriodMonth.SubtractFrom(endOfMarch),
+	)
+	assert.Equal(
+		t,
+		time.Date(2023, time.December, 31, 10, 30, 0, 0, time.UTC),
+		Period3Month.SubtractFrom(endOfMarch),
+	)
+	assert.Equal(
+		t,
+		time.Date(2023, time.March, 31, 10, 30, 0, 0, time.UTC),
+		PeriodYear.SubtractFrom(endOfMarch),
+	)
+
+	leapDay := time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)
+	assert.Equal(
+		t,
+		time.Date(2023, time.February, 28, 0, 0, 0, 0, time.UTC),
+		PeriodYear.SubtractFrom(leapDay),
+	)
+}
+
+func Test_SubtractFrom_WhenPeriodIsForever_ZeroTimeReturned(t *testing.T) {
+	assert.True(t, PeriodForever.SubtractFrom(time.Now().UTC()).IsZero())
+}
@@ -26,8 +26,12 @@ const (
 type PostgresqlExecutable string
 
 const (
-	PostgresqlExecutablePgDump PostgresqlExecutable = "pg_dump"
-	PostgresqlExecutablePsql   PostgresqlExecutable = "psql"
+	PostgresqlExecutablePgDump       PostgresqlExecutable = "pg_dump"
+	PostgresqlExecutablePgDumpall    PostgresqlExecutable = "pg_dumpall"
+	PostgresqlExecutablePsql         PostgresqlExecutable = "psql"
+	PostgresqlExecutablePgBasebackup PostgresqlExecutable = "pg_basebackup"
+	PostgresqlExecutablePgReceivewal PostgresqlExecutable = "pg_receivewal"
+	PostgresqlExecutablePgRestore    PostgresqlExecutable = "pg_restore"
 )
 
 func GetPostgresqlVersionEnum(version string) PostgresqlVersion {
@@ -32,7 +32,7 @@ func GetPostgresqlExecutable(
 
 // VerifyPostgresesInstallation verifies that PostgreSQL versions 13-17 are installed
 // in the current environment. Each version should be installed with the required
-// client tools (pg_dump, psql) available.
+// client tools (pg_dump, psql, pg_basebackup, pg_receivewal, pg_restore) available.
 // In development: ./tools/postgresql/postgresql-{VERSION}/bin
 // In production: /usr/pgsql-{VERSION}/bin
 func VerifyPostgresesInstallation(
@@ -51,7 +51,11 @@ func VerifyPostgresesInstallation(
 
 	requiredCommands := []PostgresqlExecutable{
 		PostgresqlExecutablePgDump,
+		PostgresqlExecutablePgDumpall,
 		PostgresqlExecutablePsql,
+		PostgresqlExecutablePgBasebackup,
+		PostgresqlExecutablePgReceivewal,
+		PostgresqlExecutablePgRestore,
 	}
 
 	for _, version := range versions {
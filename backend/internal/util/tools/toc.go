@@ -0,0 +1,312 @@
+package tools
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TocEntry is a line of pg_restore --list output, e.g.
+// "215; 1259 16386 TABLE public users postgres"
+type TocEntry struct {
+	DumpID int    `json:"dumpId"`
+	Type   string `json:"type"`
+	Schema string `json:"schema"`
+	Name   string `json:"name"`
+	Owner  string `json:"owner"`
+
+	// Dependencies are dump IDs of the "; depends on:" line
+	// which follows the entry in pg_restore --list --verbose
+	Dependencies []int `json:"-"`
+}
+
+// tocRelationEntryTypes belong to a table or another relation. Entry which
+// depends on such entry is restored only when the entry is restored too,
+// e.g. foreign key of another table is skipped
+var tocRelationEntryTypes = []string{
+	"TABLE",
+	"SEQUENCE",
+	"VIEW",
+	"MATERIALIZED VIEW",
+	"FOREIGN TABLE",
+	"CONSTRAINT",
+	"INDEX",
+}
+
+// tocDependentEntryTypes are restored together with entries they depend on.
+// Most of them are not named after the table (indexes, owned sequences,
+// comments and grants), so they are found by dependencies only
+var tocDependentEntryTypes = []string{
+	"TABLE DATA",
+	"TABLE ATTACH",
+	"SEQUENCE",
+	"SEQUENCE OWNED BY",
+	"SEQUENCE SET",
+	"DEFAULT",
+	"CONSTRAINT",
+	"FK CONSTRAINT",
+	"CHECK CONSTRAINT",
+	"INDEX",
+	"INDEX ATTACH",
+	"STATISTICS",
+	"TRIGGER",
+	"RULE",
+	"POLICY",
+	"ROW SECURITY",
+	"COMMENT",
+	"SECURITY LABEL",
+	"ACL",
+}
+
+// tocEntryTypes are object types pg_dump writes to TOC which consist of several
+// words. Type is followed by schema and name which cannot be told apart from
+// the type otherwise. Single word types are parsed without the list
+var tocEntryTypes = []string{
+	"TABLE DATA",
+	"TABLE ATTACH",
+	"SEQUENCE SET",
+	"SEQUENCE OWNED BY",
+	"MATERIALIZED VIEW DATA",
+	"MATERIALIZED VIEW",
+	"FOREIGN TABLE",
+	"FOREIGN DATA WRAPPER",
+	"FK CONSTRAINT",
+	"CHECK CONSTRAINT",
+	"INDEX ATTACH",
+	"DEFAULT ACL",
+	"DATABASE PROPERTIES",
+	"EVENT TRIGGER",
+	"LARGE OBJECT",
+	"BLOB METADATA",
+	"OPERATOR CLASS",
+	"OPERATOR FAMILY",
+	"ACCESS METHOD",
+	"PROCEDURAL LANGUAGE",
+	"SHELL TYPE",
+	"SECURITY LABEL",
+	"USER MAPPING",
+	"ROW SECURITY",
+	"PUBLICATION TABLES IN SCHEMA",
+	"PUBLICATION TABLE",
+	"SUBSCRIPTION TABLE",
+	"TEXT SEARCH CONFIGURATION",
+	"TEXT SEARCH DICTIONARY",
+	"TEXT SEARCH PARSER",
+	"TEXT SEARCH TEMPLATE",
+	"STATISTICS DATA",
+}
+
+// ParseToc parses pg_restore --list output. Comment lines are skipped, except
+// dependencies of --verbose output. Name may contain spaces (functions with
+// arguments, constraints are prefixed with the table), so owner is taken from
+// the end of the line
+func ParseToc(output string) []*TocEntry {
+	var entries []*TocEntry
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if dependencies, isFound := parseTocDependencies(line); isFound {
+			if len(entries) > 0 {
+				lastEntry := entries[len(entries)-1]
+				lastEntry.Dependencies = append(lastEntry.Dependencies, dependencies...)
+			}
+
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if entry := parseTocLine(line); entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// FilterTocList keeps lines of pg_restore --list --verbose output for selected
+// entries and entries depending on them, e.g. indexes, owned sequences,
+// comments and grants of a selected table. pg_restore -L restores only entries
+// of such list, so objects can be picked by schema-qualified name which
+// pg_restore -t does not accept. Count of kept entries is returned as well
+func FilterTocList(output string, isSelected func(entry *TocEntry) bool) (string, int) {
+	selectedIDs := selectTocEntries(ParseToc(output), isSelected)
+
+	var builder strings.Builder
+	selectedCount := 0
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		entry := parseTocLine(line)
+		if entry == nil || !selectedIDs[entry.DumpID] {
+			continue
+		}
+
+		builder.WriteString(line)
+		builder.WriteString("\n")
+		selectedCount++
+	}
+
+	return builder.String(), selectedCount
+}
+
+// selectTocEntries adds dependent entries to the selected ones until nothing
+// is added. Dependent entry is added when it depends on a selected entry and
+// all relations it depends on are selected. Default of a column brings its
+// sequence, the sequence of serial column may not depend on the table
+func selectTocEntries(entries []*TocEntry, isSelected func(entry *TocEntry) bool) map[int]bool {
+	entriesByID := make(map[int]*TocEntry, len(entries))
+	selectedIDs := map[int]bool{}
+
+	for _, entry := range entries {
+		entriesByID[entry.DumpID] = entry
+
+		if isSelected(entry) {
+			selectedIDs[entry.DumpID] = true
+		}
+	}
+
+	for isAdded := true; isAdded; {
+		isAdded = false
+
+		for _, entry := range entries {
+			if selectedIDs[entry.DumpID] || !isTocEntryDependent(entry, entriesByID, selectedIDs) {
+				continue
+			}
+
+			selectedIDs[entry.DumpID] = true
+			isAdded = true
+
+			if entry.Type != "DEFAULT" {
+				continue
+			}
+
+			for _, dependencyID := range entry.Dependencies {
+				if dependency := entriesByID[dependencyID]; dependency != nil &&
+					dependency.Type == "SEQUENCE" {
+					selectedIDs[dependencyID] = true
+				}
+			}
+		}
+	}
+
+	return selectedIDs
+}
+
+func isTocEntryDependent(
+	entry *TocEntry,
+	entriesByID map[int]*TocEntry,
+	selectedIDs map[int]bool,
+) bool {
+	if !slices.Contains(tocDependentEntryTypes, entry.Type) {
+		return false
+	}
+
+	isDependingOnSelected := false
+
+	for _, dependencyID := range entry.Dependencies {
+		if selectedIDs[dependencyID] {
+			isDependingOnSelected = true
+			continue
+		}
+
+		dependency := entriesByID[dependencyID]
+		if dependency == nil || !slices.Contains(tocRelationEntryTypes, dependency.Type) {
+			continue
+		}
+
+		// sequence of the default is selected with the default
+		if entry.Type == "DEFAULT" && dependency.Type == "SEQUENCE" {
+			continue
+		}
+
+		return false
+	}
+
+	return isDependingOnSelected
+}
+
+// parseTocDependencies parses ";\tdepends on: 215 216" line
+func parseTocDependencies(line string) ([]int, bool) {
+	text, isFound := strings.CutPrefix(line, ";")
+	if !isFound {
+		return nil, false
+	}
+
+	text, isFound = strings.CutPrefix(strings.TrimSpace(text), "depends on:")
+	if !isFound {
+		return nil, false
+	}
+
+	var dependencies []int
+	for _, field := range strings.Fields(text) {
+		if dumpID, err := strconv.Atoi(field); err == nil {
+			dependencies = append(dependencies, dumpID)
+		}
+	}
+
+	return dependencies, true
+}
+
+func parseTocLine(line string) *TocEntry {
+	dumpIDText, rest, isFound := strings.Cut(line, "; ")
+	if !isFound {
+		return nil
+	}
+
+	dumpID, err := strconv.Atoi(dumpIDText)
+	if err != nil {
+		return nil
+	}
+
+	// catalog table OID and object OID are not used
+	fields := strings.SplitN(rest, " ", 3)
+	if len(fields) < 3 {
+		return nil
+	}
+
+	entryType, rest := cutTocEntryType(fields[2])
+
+	schema, rest, isFound := strings.Cut(rest, " ")
+	if !isFound {
+		return nil
+	}
+
+	name := rest
+	owner := ""
+	if lastSpaceIndex := strings.LastIndex(rest, " "); lastSpaceIndex >= 0 {
+		name = rest[:lastSpaceIndex]
+		owner = rest[lastSpaceIndex+1:]
+	}
+
+	if schema == "-" {
+		schema = ""
+	}
+
+	return &TocEntry{
+		DumpID: dumpID,
+		Type:   entryType,
+		Schema: schema,
+		Name:   name,
+		Owner:  owner,
+	}
+}
+
+func cutTocEntryType(text string) (string, string) {
+	for _, entryType := range tocEntryTypes {
+		if rest, isFound := strings.CutPrefix(text, entryType+" "); isFound {
+			return entryType, rest
+		}
+	}
+
+	entryType, rest, _ := strings.Cut(text, " ")
+	return entryType, rest
+}
@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pgRestoreListOutput = `;
+; Archive created at 2025-11-22 10:00:00 UTC
+;     dbname: shop
+;     TOC Entries: 6
+;
+; Selected TOC Entries:
+;
+215; 1259 16386 TABLE public users postgres
+216; 1255 16390 FUNCTION public calc_total(integer, numeric) shop_owner
+3342; 0 16386 TABLE DATA public users postgres
+3190; 2606 16393 CONSTRAINT public users users_pkey postgres
+3201; 2606 16400 FK CONSTRAINT public orders orders_user_id_fkey postgres
+3400; 0 0 MATERIALIZED VIEW DATA reports daily_sales
+5; 2615 2200 SCHEMA - public pg_database_owner
+`
+
+func Test_ParseToc_WithPgRestoreListOutput_ParsesEntries(t *testing.T) {
+	entries := ParseToc(pgRestoreListOutput)
+
+	require.Len(t, entries, 7)
+
+	assert.Equal(t, &TocEntry{
+		DumpID: 215,
+		Type:   "TABLE",
+		Schema: "public",
+		Name:   "users",
+		Owner:  "postgres",
+	}, entries[0])
+
+	assert.Equal(t, "FUNCTION", entries[1].Type)
+	assert.Equal(t, "calc_total(integer, numeric)", entries[1].Name)
+	assert.Equal(t, "shop_owner", entries[1].Owner)
+
+	assert.Equal(t, "TABLE DATA", entries[2].Type)
+	assert.Equal(t, "users", entries[2].Name)
+
+	assert.Equal(t, "users users_pkey", entries[3].Name)
+
+	assert.Equal(t, "FK CONSTRAINT", entries[4].Type)
+	assert.Equal(t, "orders orders_user_id_fkey", entries[4].Name)
+}
+
+func Test_ParseToc_WithoutOwnerOrSchema_LeavesThemEmpty(t *testing.T) {
+	entries := ParseToc(pgRestoreListOutput)
+
+	require.Len(t, entries, 7)
+
+	assert.Equal(t, "MATERIALIZED VIEW DATA", entries[5].Type)
+	assert.Equal(t, "reports", entries[5].Schema)
+	assert.Equal(t, "daily_sales", entries[5].Name)
+	assert.Equal(t, "", entries[5].Owner)
+
+	assert.Equal(t, "SCHEMA", entries[6].Type)
+	assert.Equal(t, "", entries[6].Schema)
+	assert.Equal(t, "public", entries[6].Name)
+}
+
+func Test_ParseToc_WithMalformedLines_SkipsThem(t *testing.T) {
+	entries := ParseToc("not a toc line\nabc; 1 2 TABLE public t o\n7; 1 2 TABLE public t o\r\n")
+
+	require.Len(t, entries, 1)
+	assert.Equal(t, 7, entries[0].DumpID)
+	assert.Equal(t, "o", entries[0].Owner)
+}
+
+func Test_FilterTocList_KeepsOnlySelectedLines(t *testing.T) {
+	list, selectedCount := FilterTocList(pgRestoreListOutput, func(entry *TocEntry) bool {
+		return entry.Schema == "public" && entry.Name == "users"
+	})
+
+	assert.Equal(t, 2, selectedCount)
+	assert.Equal(
+		t,
+		"215; 1259 16386 TABLE public users postgres\n"+
+			"3342; 0 16386 TABLE DATA public users postgres\n",
+		list,
+	)
+}
+
+func Test_ParseToc_WithVerboseOutput_ParsesDependencies(t *testing.T) {
+	entries := ParseToc(
+		"216; 1259 16386 TABLE public users postgres\n" +
+			";\tdepends on: 5\n" +
+			"3216; 1259 16410 INDEX public users_email_idx postgres\n" +
+			";\tdepends on: 216\n" +
+			"3212; 2604 16389 DEFAULT public users id postgres\n" +
+			";\tdepends on: 217 216\n",
+	)
+
+	require.Len(t, entries, 3)
+	assert.Equal(t, []int{5}, entries[0].Dependencies)
+	assert.Equal(t, []int{216}, entries[1].Dependencies)
+	assert.Equal(t, []int{217, 216}, entries[2].Dependencies)
+}
+
+func Test_FilterTocList_WithDependencies_KeepsDependentEntries(t *testing.T) {
+	output := "216; 1259 16386 TABLE public users postgres\n" +
+		"218; 1259 16399 TABLE public orders postgres\n" +
+		"3216; 1259 16410 INDEX public users_email_idx postgres\n" +
+		";\tdepends on: 216\n" +
+		"3219; 2606 16405 FK CONSTRAINT public orders orders_user_id_fkey postgres\n" +
+		";\tdepends on: 218 216\n"
+
+	list, selectedCount := FilterTocList(output, func(entry *TocEntry) bool {
+		return entry.Type == "TABLE" && entry.Name == "users"
+	})
+
+	// foreign key of orders depends on the table which is not selected
+	assert.Equal(t, 2, selectedCount)
+	assert.Equal(
+		t,
+		"216; 1259 16386 TABLE public users postgres\n"+
+			"3216; 1259 16410 INDEX public users_email_idx postgres\n",
+		list,
+	)
+}
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backup_configs
+    ADD COLUMN encryption TEXT NOT NULL DEFAULT 'NONE';
+
+ALTER TABLE backups
+    ADD COLUMN is_encrypted           BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN encryption_key_version INT;
+
+CREATE TABLE backup_encryption_keys (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    database_id   UUID NOT NULL,
+    version       INT NOT NULL,
+    encrypted_key TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE backup_encryption_keys
+    ADD CONSTRAINT fk_backup_encryption_keys_database_id
+    FOREIGN KEY (database_id)
+    REFERENCES databases (id)
+    ON DELETE CASCADE;
+
+ALTER TABLE backup_encryption_keys
+    ADD CONSTRAINT uk_backup_encryption_keys_database_id_version
+    UNIQUE (database_id, version);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS backup_encryption_keys;
+
+ALTER TABLE backups
+    DROP COLUMN is_encrypted,
+    DROP COLUMN encryption_key_version;
+
+ALTER TABLE backup_configs
+    DROP COLUMN encryption;
+
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backups
+    ADD COLUMN sha256     TEXT,
+    ADD COLUMN size_bytes BIGINT;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE backups
+    DROP COLUMN sha256,
+    DROP COLUMN size_bytes;
+
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backup_configs
+    ADD COLUMN backup_method TEXT NOT NULL DEFAULT 'LOGICAL';
+
+ALTER TABLE backups
+    ADD COLUMN backup_method TEXT NOT NULL DEFAULT 'LOGICAL';
+
+ALTER TABLE restores
+    ADD COLUMN target_data_directory TEXT;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE restores
+    DROP COLUMN target_data_directory;
+
+ALTER TABLE backups
+    DROP COLUMN backup_method;
+
+ALTER TABLE backup_configs
+    DROP COLUMN backup_method;
+
+-- +goose StatementEnd
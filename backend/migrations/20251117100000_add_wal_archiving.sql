@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backup_configs
+    ADD COLUMN is_wal_archiving_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE restores
+    ADD COLUMN recovery_target_time TIMESTAMPTZ;
+
+CREATE TABLE wal_segments (
+    id                     UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    database_id            UUID NOT NULL,
+    storage_id             UUID NOT NULL,
+    file_name              TEXT NOT NULL,
+    size_bytes             BIGINT NOT NULL,
+    sha256                 TEXT NOT NULL,
+    is_encrypted           BOOLEAN NOT NULL DEFAULT FALSE,
+    encryption_key_version INT,
+    archived_at            TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE wal_segments
+    ADD CONSTRAINT fk_wal_segments_database_id
+    FOREIGN KEY (database_id)
+    REFERENCES databases (id)
+    ON DELETE CASCADE;
+
+ALTER TABLE wal_segments
+    ADD CONSTRAINT fk_wal_segments_storage_id
+    FOREIGN KEY (storage_id)
+    REFERENCES storages (id)
+    ON DELETE RESTRICT;
+
+CREATE INDEX idx_wal_segments_database_id_archived_at ON wal_segments (database_id, archived_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS wal_segments;
+
+ALTER TABLE restores
+    DROP COLUMN recovery_target_time;
+
+ALTER TABLE backup_configs
+    DROP COLUMN is_wal_archiving_enabled;
+
+-- +goose StatementEnd
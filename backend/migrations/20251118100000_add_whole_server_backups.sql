@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE postgresql_databases
+    ADD COLUMN is_whole_server BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE backups
+    ADD COLUMN is_whole_server BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE restores
+    ADD COLUMN entry_database_name TEXT;
+
+CREATE TABLE backup_entries (
+    id            UUID PRIMARY KEY,
+    backup_id     UUID NOT NULL,
+    kind          TEXT NOT NULL,
+    database_name TEXT,
+    sha256        TEXT NOT NULL,
+    size_bytes    BIGINT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE backup_entries
+    ADD CONSTRAINT fk_backup_entries_backup_id
+    FOREIGN KEY (backup_id)
+    REFERENCES backups (id)
+    ON DELETE CASCADE;
+
+CREATE INDEX idx_backup_entries_backup_id ON backup_entries (backup_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS backup_entries;
+
+ALTER TABLE restores
+    DROP COLUMN entry_database_name;
+
+ALTER TABLE backups
+    DROP COLUMN is_whole_server;
+
+ALTER TABLE postgresql_databases
+    DROP COLUMN is_whole_server;
+
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backup_configs
+    ADD COLUMN included_schemas    TEXT NOT NULL DEFAULT '',
+    ADD COLUMN excluded_schemas    TEXT NOT NULL DEFAULT '',
+    ADD COLUMN included_tables     TEXT NOT NULL DEFAULT '',
+    ADD COLUMN excluded_tables     TEXT NOT NULL DEFAULT '',
+    ADD COLUMN excluded_table_data TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE backups
+    ADD COLUMN included_schemas    TEXT NOT NULL DEFAULT '',
+    ADD COLUMN excluded_schemas    TEXT NOT NULL DEFAULT '',
+    ADD COLUMN included_tables     TEXT NOT NULL DEFAULT '',
+    ADD COLUMN excluded_tables     TEXT NOT NULL DEFAULT '',
+    ADD COLUMN excluded_table_data TEXT NOT NULL DEFAULT '';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE backups
+    DROP COLUMN included_schemas,
+    DROP COLUMN excluded_schemas,
+    DROP COLUMN included_tables,
+    DROP COLUMN excluded_tables,
+    DROP COLUMN excluded_table_data;
+
+ALTER TABLE backup_configs
+    DROP COLUMN included_schemas,
+    DROP COLUMN excluded_schemas,
+    DROP COLUMN included_tables,
+    DROP COLUMN excluded_tables,
+    DROP COLUMN excluded_table_data;
+
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backup_configs
+    ADD COLUMN compression       TEXT NOT NULL DEFAULT 'ZSTD',
+    ADD COLUMN compression_level INT  NOT NULL DEFAULT 5;
+
+-- keep gzip for versions where pg_dump does not support zstd,
+-- it is the compression such configs were using before
+UPDATE backup_configs
+SET compression = 'GZIP'
+FROM postgresql_databases
+WHERE postgresql_databases.database_id = backup_configs.database_id
+  AND postgresql_databases.version IN ('13', '14', '15');
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE backup_configs
+    DROP COLUMN compression,
+    DROP COLUMN compression_level;
+
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backup_configs
+    ADD COLUMN dump_format TEXT NOT NULL DEFAULT 'CUSTOM';
+
+ALTER TABLE backups
+    ADD COLUMN dump_format TEXT NOT NULL DEFAULT 'CUSTOM';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE backups
+    DROP COLUMN dump_format;
+
+ALTER TABLE backup_configs
+    DROP COLUMN dump_format;
+
+-- +goose StatementEnd
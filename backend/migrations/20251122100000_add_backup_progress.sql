@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backups
+    ADD COLUMN source_size_bytes   BIGINT,
+    ADD COLUMN estimated_size_mb   DOUBLE PRECISION,
+    ADD COLUMN progress_percent    DOUBLE PRECISION,
+    ADD COLUMN estimated_finish_at TIMESTAMPTZ;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE backups
+    DROP COLUMN estimated_finish_at,
+    DROP COLUMN progress_percent,
+    DROP COLUMN estimated_size_mb,
+    DROP COLUMN source_size_bytes;
+
+-- +goose StatementEnd
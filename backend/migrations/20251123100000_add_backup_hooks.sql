@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backup_configs
+    ADD COLUMN pre_backup_sql       TEXT NOT NULL DEFAULT '',
+    ADD COLUMN pre_backup_command   TEXT NOT NULL DEFAULT '',
+    ADD COLUMN post_backup_sql      TEXT NOT NULL DEFAULT '',
+    ADD COLUMN post_backup_command  TEXT NOT NULL DEFAULT '',
+    ADD COLUMN hook_timeout_seconds INT  NOT NULL DEFAULT 60;
+
+ALTER TABLE backups
+    ADD COLUMN pre_hook_status  TEXT,
+    ADD COLUMN pre_hook_output  TEXT,
+    ADD COLUMN post_hook_status TEXT,
+    ADD COLUMN post_hook_output TEXT;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE backups
+    DROP COLUMN post_hook_output,
+    DROP COLUMN post_hook_status,
+    DROP COLUMN pre_hook_output,
+    DROP COLUMN pre_hook_status;
+
+ALTER TABLE backup_configs
+    DROP COLUMN hook_timeout_seconds,
+    DROP COLUMN post_backup_command,
+    DROP COLUMN post_backup_sql,
+    DROP COLUMN pre_backup_command,
+    DROP COLUMN pre_backup_sql;
+
+-- +goose StatementEnd
@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backup_configs
+    ADD COLUMN is_verification_enabled  BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN verification_database_id UUID,
+    ADD COLUMN verification_interval_id UUID,
+    ADD COLUMN verification_sql         TEXT    NOT NULL DEFAULT '';
+
+ALTER TABLE backup_configs
+    ADD CONSTRAINT fk_backup_configs_verification_database_id
+    FOREIGN KEY (verification_database_id)
+    REFERENCES databases (id)
+    ON DELETE SET NULL;
+
+ALTER TABLE backup_configs
+    ADD CONSTRAINT fk_backup_configs_verification_interval_id
+    FOREIGN KEY (verification_interval_id)
+    REFERENCES intervals (id)
+    ON DELETE RESTRICT;
+
+ALTER TABLE backups
+    ADD COLUMN verification_status  TEXT,
+    ADD COLUMN verification_message TEXT,
+    ADD COLUMN verified_at          TIMESTAMPTZ;
+
+ALTER TABLE restores
+    ADD COLUMN is_no_privileges BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE restores
+    DROP COLUMN is_no_privileges;
+
+ALTER TABLE backups
+    DROP COLUMN verified_at,
+    DROP COLUMN verification_message,
+    DROP COLUMN verification_status;
+
+ALTER TABLE backup_configs
+    DROP CONSTRAINT fk_backup_configs_verification_interval_id,
+    DROP CONSTRAINT fk_backup_configs_verification_database_id;
+
+ALTER TABLE backup_configs
+    DROP COLUMN verification_sql,
+    DROP COLUMN verification_interval_id,
+    DROP COLUMN verification_database_id,
+    DROP COLUMN is_verification_enabled;
+
+-- +goose StatementEnd
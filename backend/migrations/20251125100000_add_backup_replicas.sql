@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE postgresql_databases
+    ADD COLUMN replica_endpoints           TEXT NOT NULL DEFAULT '',
+    ADD COLUMN max_replication_lag_seconds INT  NOT NULL DEFAULT 300;
+
+ALTER TABLE backups
+    ADD COLUMN source_host TEXT;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE backups
+    DROP COLUMN source_host;
+
+ALTER TABLE postgresql_databases
+    DROP COLUMN max_replication_lag_seconds,
+    DROP COLUMN replica_endpoints;
+
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE backup_objects (
+    id          UUID PRIMARY KEY,
+    backup_id   UUID NOT NULL,
+    dump_id     INT  NOT NULL,
+    object_type TEXT NOT NULL,
+    schema_name TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    owner       TEXT NOT NULL,
+    size_bytes  BIGINT
+);
+
+ALTER TABLE backup_objects
+    ADD CONSTRAINT fk_backup_objects_backup_id
+    FOREIGN KEY (backup_id)
+    REFERENCES backups (id)
+    ON DELETE CASCADE;
+
+CREATE INDEX idx_backup_objects_backup_id ON backup_objects (backup_id, dump_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS backup_objects;
+
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE restores
+    ADD COLUMN filter_schemas TEXT    NOT NULL DEFAULT '',
+    ADD COLUMN filter_tables  TEXT    NOT NULL DEFAULT '',
+    ADD COLUMN is_data_only   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN is_schema_only BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE restores
+    DROP COLUMN is_schema_only,
+    DROP COLUMN is_data_only,
+    DROP COLUMN filter_tables,
+    DROP COLUMN filter_schemas;
+
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backup_configs
+    ADD COLUMN extra_storage_ids TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE backup_copies (
+    id           UUID PRIMARY KEY,
+    backup_id    UUID        NOT NULL,
+    storage_id   UUID        NOT NULL,
+    status       TEXT        NOT NULL,
+    fail_message TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE backup_copies
+    ADD CONSTRAINT fk_backup_copies_backup_id
+    FOREIGN KEY (backup_id)
+    REFERENCES backups (id)
+    ON DELETE CASCADE;
+
+CREATE INDEX idx_backup_copies_backup_id ON backup_copies (backup_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS backup_copies;
+
+ALTER TABLE backup_configs
+    DROP COLUMN IF EXISTS extra_storage_ids;
+
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE backup_storage_migrations (
+    id               UUID PRIMARY KEY,
+    database_id      UUID        NOT NULL,
+    storage_id       UUID        NOT NULL,
+    status           TEXT        NOT NULL,
+    total_backups    INT         NOT NULL DEFAULT 0,
+    migrated_backups INT         NOT NULL DEFAULT 0,
+    failed_backups   INT         NOT NULL DEFAULT 0,
+    fail_message     TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at      TIMESTAMPTZ
+);
+
+ALTER TABLE backup_storage_migrations
+    ADD CONSTRAINT fk_backup_storage_migrations_database_id
+    FOREIGN KEY (database_id)
+    REFERENCES databases (id)
+    ON DELETE CASCADE;
+
+ALTER TABLE backup_storage_migrations
+    ADD CONSTRAINT fk_backup_storage_migrations_storage_id
+    FOREIGN KEY (storage_id)
+    REFERENCES storages (id)
+    ON DELETE CASCADE;
+
+CREATE INDEX idx_backup_storage_migrations_database_id
+    ON backup_storage_migrations (database_id, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS backup_storage_migrations;
+
+-- +goose StatementEnd
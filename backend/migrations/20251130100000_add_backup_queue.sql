@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backup_configs
+    ADD COLUMN backup_priority INT NOT NULL DEFAULT 0;
+
+CREATE TABLE backup_queue_items (
+    id          UUID PRIMARY KEY,
+    database_id UUID        NOT NULL,
+    storage_id  UUID        NOT NULL,
+    source_host TEXT        NOT NULL,
+    priority    INT         NOT NULL DEFAULT 0,
+    is_manual   BOOLEAN     NOT NULL DEFAULT FALSE,
+    is_last_try BOOLEAN     NOT NULL DEFAULT FALSE,
+    status      TEXT        NOT NULL,
+    queued_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    started_at  TIMESTAMPTZ
+);
+
+ALTER TABLE backup_queue_items
+    ADD CONSTRAINT fk_backup_queue_items_database_id
+    FOREIGN KEY (database_id)
+    REFERENCES databases (id)
+    ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX idx_backup_queue_items_database_id ON backup_queue_items (database_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS backup_queue_items;
+
+ALTER TABLE backup_configs
+    DROP COLUMN IF EXISTS backup_priority;
+
+-- +goose StatementEnd
@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE instances (
+    id           UUID PRIMARY KEY,
+    hostname     TEXT        NOT NULL,
+    is_web       BOOLEAN     NOT NULL DEFAULT FALSE,
+    is_worker    BOOLEAN     NOT NULL DEFAULT FALSE,
+    started_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_instances_heartbeat_at ON instances (heartbeat_at);
+
+CREATE TABLE job_leases (
+    name        TEXT PRIMARY KEY,
+    instance_id UUID        NOT NULL,
+    acquired_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE job_leases
+    ADD CONSTRAINT fk_job_leases_instance_id
+    FOREIGN KEY (instance_id)
+    REFERENCES instances (id)
+    ON DELETE CASCADE;
+
+-- instances are removed after they stop, so jobs keep the ID without a foreign key
+ALTER TABLE backups
+    ADD COLUMN instance_id UUID;
+
+ALTER TABLE backup_queue_items
+    ADD COLUMN instance_id UUID;
+
+ALTER TABLE restores
+    ADD COLUMN instance_id UUID;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE restores
+    DROP COLUMN IF EXISTS instance_id;
+
+ALTER TABLE backup_queue_items
+    DROP COLUMN IF EXISTS instance_id;
+
+ALTER TABLE backups
+    DROP COLUMN IF EXISTS instance_id;
+
+DROP TABLE IF EXISTS job_leases;
+DROP TABLE IF EXISTS instances;
+
+-- +goose StatementEnd
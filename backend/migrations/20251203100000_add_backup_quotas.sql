@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backup_configs
+    ADD COLUMN max_backups_count   INT    NOT NULL DEFAULT 0,
+    ADD COLUMN max_backups_size_mb BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE storages
+    ADD COLUMN quota_mb BIGINT NOT NULL DEFAULT 0;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE storages
+    DROP COLUMN IF EXISTS quota_mb;
+
+ALTER TABLE backup_configs
+    DROP COLUMN IF EXISTS max_backups_count,
+    DROP COLUMN IF EXISTS max_backups_size_mb;
+
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backups
+    ADD COLUMN is_pinned   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN hold_until  TIMESTAMPTZ,
+    ADD COLUMN hold_reason TEXT;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE backups
+    DROP COLUMN IF EXISTS is_pinned,
+    DROP COLUMN IF EXISTS hold_until,
+    DROP COLUMN IF EXISTS hold_reason;
+
+-- +goose StatementEnd
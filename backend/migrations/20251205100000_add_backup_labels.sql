@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backups
+    ADD COLUMN label TEXT,
+    ADD COLUMN notes TEXT;
+
+ALTER TABLE backup_queue_items
+    ADD COLUMN label TEXT,
+    ADD COLUMN notes TEXT,
+    ADD COLUMN tags  TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE backup_tags (
+    id        UUID PRIMARY KEY,
+    backup_id UUID NOT NULL,
+    key       TEXT NOT NULL,
+    value     TEXT NOT NULL
+);
+
+ALTER TABLE backup_tags
+    ADD CONSTRAINT fk_backup_tags_backup_id
+    FOREIGN KEY (backup_id)
+    REFERENCES backups (id)
+    ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX idx_backup_tags_backup_id_key ON backup_tags (backup_id, key);
+CREATE INDEX idx_backup_tags_key_value ON backup_tags (key, value);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS backup_tags;
+
+ALTER TABLE backup_queue_items
+    DROP COLUMN IF EXISTS label,
+    DROP COLUMN IF EXISTS notes,
+    DROP COLUMN IF EXISTS tags;
+
+ALTER TABLE backups
+    DROP COLUMN IF EXISTS label,
+    DROP COLUMN IF EXISTS notes;
+
+-- +goose StatementEnd
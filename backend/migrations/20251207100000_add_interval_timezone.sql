@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE intervals
+    ADD COLUMN timezone TEXT;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE intervals
+    DROP COLUMN IF EXISTS timezone;
+
+-- +goose StatementEnd
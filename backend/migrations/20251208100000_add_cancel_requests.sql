@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backups
+    ADD COLUMN cancel_requested_at TIMESTAMPTZ;
+
+ALTER TABLE restores
+    ADD COLUMN cancel_requested_at TIMESTAMPTZ;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE restores
+    DROP COLUMN IF EXISTS cancel_requested_at;
+
+ALTER TABLE backups
+    DROP COLUMN IF EXISTS cancel_requested_at;
+
+-- +goose StatementEnd
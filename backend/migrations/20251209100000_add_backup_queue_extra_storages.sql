@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE backup_queue_items
+    ADD COLUMN extra_storage_ids TEXT NOT NULL DEFAULT '';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE backup_queue_items
+    DROP COLUMN IF EXISTS extra_storage_ids;
+
+-- +goose StatementEnd
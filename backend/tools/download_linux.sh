@@ -68,6 +68,8 @@ for version in $versions; do
         ln -sf "$pg_bin_dir/pg_dumpall" "$version_dir/bin/pg_dumpall"
         ln -sf "$pg_bin_dir/psql" "$version_dir/bin/psql"
         ln -sf "$pg_bin_dir/pg_restore" "$version_dir/bin/pg_restore"
+        ln -sf "$pg_bin_dir/pg_basebackup" "$version_dir/bin/pg_basebackup"
+        ln -sf "$pg_bin_dir/pg_receivewal" "$version_dir/bin/pg_receivewal"
         ln -sf "$pg_bin_dir/createdb" "$version_dir/bin/createdb"
         ln -sf "$pg_bin_dir/dropdb" "$version_dir/bin/dropdb"
         